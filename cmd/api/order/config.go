@@ -1,18 +1,66 @@
 package main
 
+import (
+	"github.com/Hao1995/order-matching-system/internal/api/auth"
+	"github.com/Hao1995/order-matching-system/internal/api/fix"
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+)
+
 var cfg Config
 
+// codec encodes the published events in the wire format of the config
+var codec events.Codec
+
 type Config struct {
-	App   App   `envPrefix:"APP_"`
-	Kafka Kafka `envPrefix:"KAFKA_"`
+	App       App         `envPrefix:"APP_"`
+	Kafka     Kafka       `envPrefix:"KAFKA_"`
+	Auth      auth.Config `envPrefix:"AUTH_"`
+	RateLimit RateLimit   `envPrefix:"RATE_LIMIT_"`
+	// LedgerJournal persists the ledger, which is rebuilt from it on startup
+	LedgerJournal LedgerJournal `envPrefix:"LEDGER_JOURNAL_"`
+	// FIX accepts the FIX sessions, whose orders are held in the same ledger as the REST ones
+	FIX fix.SessionConfig `envPrefix:"FIX_"`
 }
 
 type App struct {
+	// Name is the consumer group of the matching events, which are settled in the ledger of the
+	// process, so only one replica of the order API may run
 	Name string `env:"NAME,required"`
 	Port string `env:"PORT,required"`
+	// GRPCPort serves the order service of internal/api/order/proto/order.proto
+	GRPCPort string `env:"GRPC_PORT" envDefault:":9090"`
+
+	OrderTopic    string `env:"ORDER_TOPIC,required"`
+	MatchingTopic string `env:"MATCHING_TOPIC,required"`
+	// Symbol keys the order events without a symbol, e.g. the cancellations of unknown orders
+	Symbol     string `env:"SYMBOL"`
+	QuoteAsset string `env:"QUOTE_ASSET" envDefault:"USD"`
+	// MaxFeeBps is the fee reserved by the holds in basis points of the notional, in the quote
+	// asset, which must be at least the highest fee rate of the FEE_SCHEDULE_FILE of the workers
+	MaxFeeBps float64 `env:"MAX_FEE_BPS" envDefault:"0"`
+	// MaxBatchOrders is the most orders of a POST /orders/batch
+	MaxBatchOrders int `env:"MAX_BATCH_ORDERS" envDefault:"100"`
+	// Codec is the wire format of the published order events, the matching events are decoded
+	// by their content type
+	Codec events.CodecFormat `env:"CODEC" envDefault:"JSON"`
+}
+
+// RateLimit limits the orders of every account, or of every IP without one. CreateRate is the
+// creations per second, up to CreateBurst at once, and a rate of 0 is unlimited.
+type RateLimit struct {
+	CreateRate  float64 `env:"CREATE_RATE" envDefault:"10"`
+	CreateBurst int     `env:"CREATE_BURST" envDefault:"20"`
+	CancelRate  float64 `env:"CANCEL_RATE" envDefault:"20"`
+	CancelBurst int     `env:"CANCEL_BURST" envDefault:"40"`
+}
 
-	OrderTopic string `env:"ORDER_TOPIC,required"`
-	Symbol     string `env:"SYMBOL,required"`
+type LedgerJournal struct {
+	// Dir is the directory of the write-ahead journal of the ledger, which is in memory only if
+	// empty
+	Dir             string `env:"DIR"`
+	MaxSegmentBytes int64  `env:"MAX_SEGMENT_BYTES" envDefault:"67108864"`
+	// SyncBatch fsyncs every entry by default, as the matching events are committed once settled
+	SyncBatch int `env:"SYNC_BATCH" envDefault:"1"`
 }
 
 type Kafka struct {
@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os/signal"
 	"syscall"
@@ -10,17 +13,33 @@ import (
 
 	"github.com/caarlos0/env/v11"
 	"github.com/gin-gonic/gin"
+	"github.com/quickfixgo/quickfix"
+	"github.com/quickfixgo/quickfix/store/file"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 
+	"github.com/Hao1995/order-matching-system/internal/api/account"
+	"github.com/Hao1995/order-matching-system/internal/api/admin"
+	"github.com/Hao1995/order-matching-system/internal/api/auth"
+	"github.com/Hao1995/order-matching-system/internal/api/fix"
 	"github.com/Hao1995/order-matching-system/internal/api/order"
+	"github.com/Hao1995/order-matching-system/internal/api/ratelimit"
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	"github.com/Hao1995/order-matching-system/pkg/journalkit"
 	"github.com/Hao1995/order-matching-system/pkg/logger"
 	"github.com/Hao1995/order-matching-system/pkg/mqkit"
+	"github.com/Hao1995/order-matching-system/pkg/pubsubkit"
 )
 
 func init() {
 	if err := env.Parse(&cfg); err != nil {
 		log.Fatal("failed to parse config", err)
 	}
+
+	var err error
+	if codec, err = events.NewCodec(cfg.App.Codec, cfg.App.Name); err != nil {
+		log.Fatal("failed to create codec", err)
+	}
 }
 
 func main() {
@@ -35,14 +54,133 @@ func main() {
 	defer kafkaProducer.Close()
 	logger.Info("initiate a Kafka producer successfully", zap.String("topic", cfg.App.OrderTopic), zap.String("symbol", cfg.App.Symbol))
 
+	// Ledger: settle fills and release cancelled holds from the matching events
+	ledgerOpts := []account.LedgerOption{account.WithMaxFeeBps(cfg.App.MaxFeeBps)}
+	if cfg.LedgerJournal.Dir != "" {
+		journal, err := journalkit.Open(cfg.LedgerJournal.Dir, journalkit.Options{
+			MaxSegmentBytes: cfg.LedgerJournal.MaxSegmentBytes,
+			SyncBatch:       cfg.LedgerJournal.SyncBatch,
+		})
+		if err != nil {
+			logger.Fatal("failed to open ledger journal", zap.Error(err), zap.String("dir", cfg.LedgerJournal.Dir))
+		}
+		defer journal.Close()
+		ledgerOpts = append(ledgerOpts, account.WithJournal(journal))
+	}
+	ledger := account.NewLedger(cfg.App.QuoteAsset, ledgerOpts...)
+	if cfg.LedgerJournal.Dir != "" {
+		if err := ledger.Replay(cfg.LedgerJournal.Dir); err != nil {
+			logger.Fatal("failed to replay ledger journal", zap.Error(err), zap.String("dir", cfg.LedgerJournal.Dir))
+		}
+		logger.Info("replay ledger journal successfully", zap.String("dir", cfg.LedgerJournal.Dir))
+	}
+	// The settled fills are streamed to the gRPC subscribers of their accounts
+	executions := order.NewExecutions()
+	settler := account.NewSettler(ledger, account.WithSettled(executions.Settled))
+	service := order.NewService(kafkaProducer, codec, cfg.App.OrderTopic, ledger, order.WithMaxBatchOrders(cfg.App.MaxBatchOrders))
+
+	// The orders of every account are limited by the same buckets over REST, gRPC and FIX
+	rateLimitStore := ratelimit.NewMemoryStore()
+	createLimit := ratelimit.Limit{Rate: cfg.RateLimit.CreateRate, Burst: cfg.RateLimit.CreateBurst}
+	cancelLimit := ratelimit.Limit{Rate: cfg.RateLimit.CancelRate, Burst: cfg.RateLimit.CancelBurst}
+	createLimiter := ratelimit.NewLimiter(rateLimitStore, "create", createLimit)
+	cancelLimiter := ratelimit.NewLimiter(rateLimitStore, "cancel", cancelLimit)
+
+	// The order and account routes, the gRPC calls and the FIX logons are authenticated by the API
+	// keys, which own the orders and balances of their accounts
+	authenticator := auth.NewAuthenticator(cfg.Auth)
+
+	// FIX acceptor: the orders of the sessions are placed through the service
+	var gateway *fix.Gateway
+	if len(cfg.FIX.TargetCompIDs) > 0 {
+		gateway = fix.NewGateway(service, cfg.FIX.Accounts,
+			fix.WithRequestTimeout(cfg.FIX.RequestTimeout),
+			fix.WithRateLimits(createLimiter, cancelLimiter),
+			fix.WithAuthenticator(authenticator),
+		)
+		acceptor := RunFIXAcceptor(gateway)
+		defer acceptor.Stop()
+	}
+
+	subscriber := pubsubkit.NewKafkaSubscriber(cfg.Kafka.Brokers, cfg.App.MatchingTopic, cfg.App.Name, pubsubkit.WithLogger(logger.L()))
+	defer subscriber.Close()
+	go func() {
+		if err := subscriber.Subscribe(ctx, func(ctx context.Context, msg pubsubkit.Message) error {
+			// The matching events are decoded by their content type while the workers migrate
+			msgCodec, err := events.CodecFor(msg.Headers[events.ContentTypeHeader])
+			if err != nil {
+				return err
+			}
+			// A matching event not settled is not committed, so that no fill is lost
+			if err := settler.HandleEncoded(msgCodec, msg.Value); err != nil {
+				if errors.Is(err, account.ErrUnsettled) {
+					return fmt.Errorf("%w: %w", pubsubkit.ErrRetry, err)
+				}
+				return err
+			}
+			// The ExecutionReports are sent once the fills are settled
+			if gateway != nil {
+				return gateway.HandleEncoded(msgCodec, msg.Value)
+			}
+			return nil
+		}); err != nil {
+			logger.Error("failed to subscribe matching events", zap.Error(err))
+		}
+	}()
+
 	// Init Gin Router
-	hlr := order.NewHandler(kafkaProducer, cfg.App.OrderTopic)
 	router := gin.Default()
-	order.RegisterRoutes(router, hlr)
+	// The order and account routes are signed by the API keys, while deposits and trading controls
+	// are signed by the admin keys
+	authenticated := router.Group("", authenticator.Middleware())
+	administrated := router.Group("", authenticator.AdminMiddleware())
+	accountHandler := account.NewHandler(ledger)
+	order.RegisterRoutes(authenticated, order.NewHandler(service), order.WithRateLimits(rateLimitStore, createLimit, cancelLimit))
+	account.RegisterRoutes(authenticated, accountHandler)
+	account.RegisterAdminRoutes(administrated, accountHandler)
+	admin.RegisterRoutes(administrated, admin.NewHandler(kafkaProducer, codec))
+
+	// Init gRPC Server, whose calls are signed by the same API keys
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(authenticator.UnaryServerInterceptor(), order.RateLimitInterceptor(createLimiter, cancelLimiter)),
+		grpc.ChainStreamInterceptor(authenticator.StreamServerInterceptor()),
+	)
+	order.RegisterGRPC(grpcServer, order.NewGRPCHandler(service, executions))
+	go RunGRPCServer(grpcServer)
+	defer grpcServer.GracefulStop()
 
 	RunGinServer(ctx, stop, router)
 }
 
+// RunFIXAcceptor starts the acceptor of the FIX sessions, which are logged out by Stop and resume
+// from their stored sequence numbers
+func RunFIXAcceptor(gateway *fix.Gateway) *quickfix.Acceptor {
+	settings, err := fix.NewSettings(cfg.FIX)
+	if err != nil {
+		logger.Fatal("failed to create FIX settings", zap.Error(err))
+	}
+	acceptor, err := quickfix.NewAcceptor(gateway, file.NewStoreFactory(settings), settings, fix.NewLogFactory(logger.L()))
+	if err != nil {
+		logger.Fatal("failed to create FIX acceptor", zap.Error(err))
+	}
+	if err := acceptor.Start(); err != nil {
+		logger.Fatal("failed to start FIX acceptor", zap.Error(err))
+	}
+	logger.Info("accept FIX sessions", zap.Int("port", cfg.FIX.Port), zap.Strings("targetCompIDs", cfg.FIX.TargetCompIDs))
+	return acceptor
+}
+
+func RunGRPCServer(srv *grpc.Server) {
+	listener, err := net.Listen("tcp", cfg.App.GRPCPort)
+	if err != nil {
+		logger.Fatal("failed to listen", zap.Error(err), zap.String("port", cfg.App.GRPCPort))
+	}
+	logger.Info("serve gRPC", zap.String("port", cfg.App.GRPCPort))
+	if err := srv.Serve(listener); err != nil {
+		logger.Fatal("gRPC server closed", zap.Error(err))
+	}
+}
+
 func RunGinServer(ctx context.Context, stop context.CancelFunc, router *gin.Engine) {
 	srv := &http.Server{
 		Addr:    cfg.App.Port,
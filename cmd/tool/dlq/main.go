@@ -0,0 +1,130 @@
+// Command dlq inspects the messages of a dead-letter topic and re-drives them to the topic they
+// failed in, e.g. after the consumer is fixed.
+//
+//	dlq list -kafka-brokers kafka:9092 -topic AAPL_ORDER_DLQ
+//	dlq redrive -kafka-brokers kafka:9092 -topic AAPL_ORDER_DLQ -from 3 -to 3
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/Hao1995/order-matching-system/pkg/mqkit"
+)
+
+type options struct {
+	brokers   string
+	topic     string
+	partition int
+	from      int64
+	to        int64
+	target    string
+}
+
+// listed is a dead letter with its offset in the dead-letter topic and a readable value
+type listed struct {
+	DeadLetterOffset int64 `json:"dead_letter_offset"`
+	mqkit.DeadLetter
+	Value string `json:"value"`
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var opts options
+	flags := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	flags.StringVar(&opts.brokers, "kafka-brokers", os.Getenv("KAFKA_BROKERS"), "comma separated Kafka brokers, KAFKA_BROKERS by default")
+	flags.StringVar(&opts.topic, "topic", "", "dead-letter topic")
+	flags.IntVar(&opts.partition, "partition", 0, "partition of the dead-letter topic")
+	flags.Int64Var(&opts.from, "from", 0, "first offset of the dead letters")
+	flags.Int64Var(&opts.to, "to", -1, "last offset of the dead letters, -1 for the latest")
+	flags.StringVar(&opts.target, "target", "", "topic to re-drive to instead of the one the message failed in")
+
+	var run func(context.Context, options, io.Writer) error
+	switch os.Args[1] {
+	case "list":
+		run = list
+	case "redrive":
+		run = redrive
+	default:
+		usage()
+	}
+	flags.Parse(os.Args[2:])
+
+	if err := run(context.Background(), opts, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "dlq:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: dlq list|redrive -topic <dead-letter topic> [flags]")
+	os.Exit(2)
+}
+
+// list writes the dead letters as JSON lines
+func list(ctx context.Context, opts options, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return readDeadLetters(ctx, opts, func(offset int64, deadLetter mqkit.DeadLetter) error {
+		return encoder.Encode(listed{DeadLetterOffset: offset, DeadLetter: deadLetter, Value: string(deadLetter.Value)})
+	})
+}
+
+// redrive publishes the dead letters to the topic they failed in, or the target topic
+func redrive(ctx context.Context, opts options, w io.Writer) error {
+	writer := &kafka.Writer{
+		Addr: kafka.TCP(strings.Split(opts.brokers, ",")...),
+		// Back to the partition of the symbol key
+		Balancer: kafka.BalancerFunc(func(msg kafka.Message, partitions ...int) int {
+			return partitions[mqkit.PartitionFor(msg.Key, len(partitions))]
+		}),
+	}
+	defer writer.Close()
+
+	return readDeadLetters(ctx, opts, func(offset int64, deadLetter mqkit.DeadLetter) error {
+		topic := deadLetter.Topic
+		if opts.target != "" {
+			topic = opts.target
+		}
+		msg := kafka.Message{Topic: topic, Key: deadLetter.Key, Value: deadLetter.Value}
+		for key, value := range deadLetter.Headers {
+			msg.Headers = append(msg.Headers, kafka.Header{Key: key, Value: []byte(value)})
+		}
+		if err := writer.WriteMessages(ctx, msg); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "re-drove dead letter %d to %s, failed at offset %d: %s\n", offset, topic, deadLetter.Offset, deadLetter.Error)
+		return nil
+	})
+}
+
+// readDeadLetters passes the dead letters of the offset range with their offset
+func readDeadLetters(ctx context.Context, opts options, fn func(offset int64, deadLetter mqkit.DeadLetter) error) error {
+	if opts.brokers == "" || opts.topic == "" {
+		return errors.New("-kafka-brokers and -topic are required")
+	}
+
+	return mqkit.ReadKafkaRange(ctx, mqkit.KafkaRange{
+		Brokers:   strings.Split(opts.brokers, ","),
+		Topic:     opts.topic,
+		Partition: opts.partition,
+		From:      opts.from,
+		To:        opts.to,
+	}, func(msg kafka.Message) error {
+		var deadLetter mqkit.DeadLetter
+		if err := json.Unmarshal(msg.Value, &deadLetter); err != nil {
+			return fmt.Errorf("offset %d: %w", msg.Offset, err)
+		}
+		return fn(msg.Offset, deadLetter)
+	})
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+)
+
+// replayed is a matching event of the replay with the number of the input that produced it
+type replayed struct {
+	Input int
+	Event events.MatchingEvent
+}
+
+// divergence describes the first matching event which differs between the record and the replay
+type divergence struct {
+	// Index is the 1-based number of the matching event
+	Index    int
+	Input    int
+	Expected string
+	Actual   string
+}
+
+// diff compares the replayed matching events with the recorded ones in order
+func diff(actual []replayed, expected []events.MatchingEvent, ignoreTimes bool) (*divergence, error) {
+	for i := 0; i < max(len(actual), len(expected)); i++ {
+		d := divergence{Index: i + 1, Expected: "<end of record>", Actual: "<end of replay>"}
+		if i < len(actual) {
+			d.Input = actual[i].Input
+			encoded, err := normalize(actual[i].Event, ignoreTimes)
+			if err != nil {
+				return nil, err
+			}
+			d.Actual = encoded
+		}
+		if i < len(expected) {
+			encoded, err := normalize(expected[i], ignoreTimes)
+			if err != nil {
+				return nil, err
+			}
+			d.Expected = encoded
+		}
+		if d.Actual != d.Expected {
+			return &d, nil
+		}
+	}
+	return nil, nil
+}
+
+// normalize encodes a matching event, without the times derived from the clock if ignoreTimes
+func normalize(matchingEvent events.MatchingEvent, ignoreTimes bool) (string, error) {
+	if ignoreTimes {
+		transactions := make([]events.TransactionEvent, len(matchingEvent.Transactions))
+		for i, transaction := range matchingEvent.Transactions {
+			transaction.CreatedAt = time.Time{}
+			transactions[i] = transaction
+		}
+		matchingEvent.Transactions = transactions
+		if matchingEvent.Status != nil {
+			status := *matchingEvent.Status
+			status.ResumeAt = nil
+			matchingEvent.Status = &status
+		}
+	}
+
+	data, err := json.Marshal(matchingEvent)
+	return string(data), err
+}
+
+func (d *divergence) print(w io.Writer) {
+	fmt.Fprintf(w, "first divergence at matching event #%d", d.Index)
+	if d.Input > 0 {
+		fmt.Fprintf(w, " (input #%d)", d.Input)
+	}
+	fmt.Fprintf(w, "\n  recorded: %s\n  replayed: %s\n", d.Expected, d.Actual)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+)
+
+type DiffTestSuite struct {
+	suite.Suite
+	now time.Time
+}
+
+func TestDiffTestSuite(t *testing.T) {
+	suite.Run(t, new(DiffTestSuite))
+}
+
+func (suite *DiffTestSuite) SetupTest() {
+	suite.now = time.Date(2025, 1, 15, 18, 0, 0, 0, time.UTC)
+}
+
+func (suite *DiffTestSuite) matchingEvent(seq uint64, createdAt time.Time) events.MatchingEvent {
+	return events.MatchingEvent{
+		Seq:          seq,
+		Type:         events.MatchingEventTypeCreate,
+		Order:        events.OrderEvent{ID: "buy1", Symbol: "AAPL"},
+		Transactions: []events.TransactionEvent{{ID: "1", Price: 100.0, Quantity: 10, CreatedAt: createdAt}},
+	}
+}
+
+func (suite *DiffTestSuite) TestDiff() {
+	later := suite.now.Add(time.Second)
+	for _, tc := range []struct {
+		name        string
+		actual      []replayed
+		expected    []events.MatchingEvent
+		ignoreTimes bool
+		index       int
+		input       int
+		endOfRecord bool
+		endOfReplay bool
+	}{
+		{
+			name:     "identical",
+			actual:   []replayed{{Input: 1, Event: suite.matchingEvent(1, suite.now)}, {Input: 2, Event: suite.matchingEvent(2, suite.now)}},
+			expected: []events.MatchingEvent{suite.matchingEvent(1, suite.now), suite.matchingEvent(2, suite.now)},
+		},
+		{
+			name:     "first divergence",
+			actual:   []replayed{{Input: 1, Event: suite.matchingEvent(1, suite.now)}, {Input: 3, Event: suite.matchingEvent(3, suite.now)}, {Input: 4, Event: suite.matchingEvent(4, suite.now)}},
+			expected: []events.MatchingEvent{suite.matchingEvent(1, suite.now), suite.matchingEvent(2, suite.now), suite.matchingEvent(5, suite.now)},
+			index:    2,
+			input:    3,
+		},
+		{
+			name:        "replay is longer",
+			actual:      []replayed{{Input: 1, Event: suite.matchingEvent(1, suite.now)}, {Input: 2, Event: suite.matchingEvent(2, suite.now)}},
+			expected:    []events.MatchingEvent{suite.matchingEvent(1, suite.now)},
+			index:       2,
+			input:       2,
+			endOfRecord: true,
+		},
+		{
+			name:        "record is longer",
+			actual:      []replayed{{Input: 1, Event: suite.matchingEvent(1, suite.now)}},
+			expected:    []events.MatchingEvent{suite.matchingEvent(1, suite.now), suite.matchingEvent(2, suite.now)},
+			index:       2,
+			endOfReplay: true,
+		},
+		{
+			name:     "times differ",
+			actual:   []replayed{{Input: 1, Event: suite.matchingEvent(1, later)}},
+			expected: []events.MatchingEvent{suite.matchingEvent(1, suite.now)},
+			index:    1,
+			input:    1,
+		},
+		{
+			name:        "times ignored",
+			actual:      []replayed{{Input: 1, Event: suite.matchingEvent(1, later)}},
+			expected:    []events.MatchingEvent{suite.matchingEvent(1, suite.now)},
+			ignoreTimes: true,
+		},
+	} {
+		d, err := diff(tc.actual, tc.expected, tc.ignoreTimes)
+		suite.Require().NoError(err, tc.name)
+		if tc.index == 0 {
+			suite.Nil(d, tc.name)
+			continue
+		}
+
+		suite.Require().NotNil(d, tc.name)
+		suite.Equal(tc.index, d.Index, tc.name)
+		suite.Equal(tc.input, d.Input, tc.name)
+		suite.Equal(tc.endOfRecord, d.Expected == "<end of record>", tc.name)
+		suite.Equal(tc.endOfReplay, d.Actual == "<end of replay>", tc.name)
+		suite.NotEqual(d.Expected, d.Actual, tc.name)
+	}
+}
+
+func (suite *DiffTestSuite) TestNormalize() {
+	transaction := suite.matchingEvent(1, suite.now)
+	resumeAt := suite.now.Add(time.Minute)
+	status := events.MatchingEvent{
+		Seq:    1,
+		Type:   events.MatchingEventTypeStatus,
+		Order:  events.OrderEvent{Symbol: "AAPL"},
+		Status: &events.StatusEvent{Symbol: "AAPL", Status: "Halted", Reason: "manual", ResumeAt: &resumeAt},
+	}
+
+	for _, tc := range []struct {
+		name          string
+		matchingEvent events.MatchingEvent
+		ignoreTimes   bool
+		contains      []string
+		notContains   []string
+	}{
+		{
+			name:          "transaction times kept",
+			matchingEvent: transaction,
+			contains:      []string{`"created_at":"2025-01-15T18:00:00Z"`},
+		},
+		{
+			name:          "transaction times ignored",
+			matchingEvent: transaction,
+			ignoreTimes:   true,
+			contains:      []string{`"created_at":"0001-01-01T00:00:00Z"`, `"price":100`},
+			notContains:   []string{"2025-01-15"},
+		},
+		{
+			name:          "resume time kept",
+			matchingEvent: status,
+			contains:      []string{`"resume_at":"2025-01-15T18:01:00Z"`},
+		},
+		{
+			name:          "resume time ignored",
+			matchingEvent: status,
+			ignoreTimes:   true,
+			contains:      []string{`"reason":"manual"`},
+			notContains:   []string{"resume_at"},
+		},
+	} {
+		encoded, err := normalize(tc.matchingEvent, tc.ignoreTimes)
+		suite.Require().NoError(err, tc.name)
+		for _, s := range tc.contains {
+			suite.Contains(encoded, s, tc.name)
+		}
+		for _, s := range tc.notContains {
+			suite.NotContains(encoded, s, tc.name)
+		}
+	}
+
+	// The matching event being normalized is not modified
+	suite.Equal(suite.now, transaction.Transactions[0].CreatedAt)
+	suite.Equal(&resumeAt, status.Status.ResumeAt)
+}
+
+func (suite *DiffTestSuite) TestPrint() {
+	var buf bytes.Buffer
+	(&divergence{Index: 2, Input: 3, Expected: "{}", Actual: "<end of replay>"}).print(&buf)
+	suite.Equal("first divergence at matching event #2 (input #3)\n  recorded: {}\n  replayed: <end of replay>\n", buf.String())
+}
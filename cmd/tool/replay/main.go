@@ -0,0 +1,258 @@
+// Command replay rebuilds the matching engine of a symbol from a recorded order event stream,
+// outputs the matching events and diffs them against the recorded matching events.
+//
+// The engine is configured from the same environment variables as the matching engine worker.
+// The input is a journal directory, a JSONL file of journal entries or events.Event, or an
+// offset range of the order topic. The Matcher starts from an empty book, so the input must
+// start from the beginning of the book, or at least from a time it was empty. Only the matching
+// events of the symbol are output and diffed.
+//
+//	replay -symbol AAPL -journal /data/journal -expected matching.jsonl
+//	replay -symbol AAPL -kafka-brokers kafka:9092 -input-topic AAPL_ORDER \
+//	    -expected-topic AAPL_MATCHING -output -
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/caarlos0/env/v11"
+	"github.com/segmentio/kafka-go"
+
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	matchingengine "github.com/Hao1995/order-matching-system/internal/worker/matching_engine"
+	engineconfig "github.com/Hao1995/order-matching-system/internal/worker/matching_engine/config"
+	"github.com/Hao1995/order-matching-system/pkg/mqkit"
+)
+
+type options struct {
+	symbol            string
+	journal           string
+	input             string
+	start             string
+	brokers           string
+	partition         int
+	inputTopic        string
+	inputFrom         int64
+	inputTo           int64
+	expected          string
+	expectedTopic     string
+	expectedFrom      int64
+	expectedTo        int64
+	output            string
+	lastTransactionID uint64
+	ignoreTimes       bool
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.symbol, "symbol", os.Getenv("APP_SYMBOL"), "symbol of the matching engine, APP_SYMBOL by default")
+	flag.StringVar(&opts.journal, "journal", "", "journal directory of the matching engine worker to replay")
+	flag.StringVar(&opts.input, "input", "", "JSONL file of journal entries or order events to replay")
+	flag.StringVar(&opts.start, "start", "", "RFC 3339 time of the order events in -input before the first journal entry")
+	flag.StringVar(&opts.brokers, "kafka-brokers", os.Getenv("KAFKA_BROKERS"), "comma separated Kafka brokers, KAFKA_BROKERS by default")
+	flag.IntVar(&opts.partition, "partition", 0, "partition of the Kafka topics")
+	flag.StringVar(&opts.inputTopic, "input-topic", "", "order topic to replay")
+	flag.Int64Var(&opts.inputFrom, "input-from", 0, "first offset of -input-topic")
+	flag.Int64Var(&opts.inputTo, "input-to", -1, "last offset of -input-topic, -1 for the latest")
+	flag.StringVar(&opts.expected, "expected", "", "JSONL file of the recorded matching events to diff")
+	flag.StringVar(&opts.expectedTopic, "expected-topic", "", "matching topic of the recorded matching events to diff")
+	flag.Int64Var(&opts.expectedFrom, "expected-from", 0, "first offset of -expected-topic")
+	flag.Int64Var(&opts.expectedTo, "expected-to", -1, "last offset of -expected-topic, -1 for the latest")
+	flag.StringVar(&opts.output, "output", "", "JSONL file to write the replayed matching events, - for stdout")
+	flag.Uint64Var(&opts.lastTransactionID, "last-transaction-id", 0, "transaction sequence number to continue after")
+	flag.BoolVar(&opts.ignoreTimes, "ignore-times", false, "ignore the times derived from the clock, e.g. for an input without journal times")
+	flag.Parse()
+
+	code, err := run(context.Background(), opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "replay:", err)
+		os.Exit(2)
+	}
+	os.Exit(code)
+}
+
+// run replays the input and returns 1 if it diverges from the recorded matching events
+func run(ctx context.Context, opts options) (int, error) {
+	if opts.symbol == "" {
+		return 0, errors.New("-symbol is required")
+	}
+
+	var cfg engineconfig.Config
+	if err := env.Parse(&cfg); err != nil {
+		return 0, err
+	}
+	matcherOpts, err := cfg.MatcherOptions(opts.symbol)
+	if err != nil {
+		return 0, err
+	}
+	matcherOpts = append(matcherOpts, matchingengine.WithLastTransactionID(opts.lastTransactionID))
+	riskChecker, err := cfg.RiskChecker()
+	if err != nil {
+		return 0, err
+	}
+
+	// The clock is set to the time of the input being applied
+	var current time.Time
+	matcher := matchingengine.NewMatcher(matchingengine.NewOrderBook(), cfg.TickNum, matcherOpts...)
+	engine := matchingengine.NewEngine(opts.symbol, matcher, riskChecker, matchingengine.WithClock(func() time.Time {
+		return current
+	}))
+
+	var actual []replayed
+	var inputs int
+	apply := func(entry matchingengine.JournalEntry) error {
+		inputs++
+		current = entry.Time
+
+		var matchingEvents []events.MatchingEvent
+		switch entry.Type {
+		case matchingengine.JournalEntryTypeSkip:
+			// The order event was moved to the dead-letter topic
+		case matchingengine.JournalEntryTypeTick:
+			matchingEvents = engine.Tick()
+		default:
+			// The input failed the same way when it was recorded
+			matchingEvents, _ = engine.HandleAt(entry.Offset, entry.Event)
+		}
+		for _, matchingEvent := range matchingEvents {
+			actual = append(actual, replayed{Input: inputs, Event: matchingEvent})
+		}
+		return nil
+	}
+
+	switch {
+	case opts.journal != "":
+		// A journal is shared by the engines of a partition, which number their matching events in
+		// one sequence, so it is replayed through a Partition with the engines of all its symbols
+		partition := matchingengine.NewPartition(opts.partition, func(symbol string, engineOpts ...matchingengine.EngineOption) (*matchingengine.Engine, error) {
+			symbolOpts, err := cfg.MatcherOptions(symbol)
+			if err != nil {
+				return nil, err
+			}
+			if symbol == opts.symbol {
+				symbolOpts = matcherOpts
+			}
+			return matchingengine.NewEngine(symbol, matchingengine.NewMatcher(matchingengine.NewOrderBook(), cfg.TickNum, symbolOpts...), riskChecker, engineOpts...), nil
+		}, nil)
+		err = partition.Replay(opts.journal, func(seq uint64, matchingEvents []events.MatchingEvent) error {
+			inputs++
+			for _, matchingEvent := range matchingEvents {
+				if matchingEvent.Order.Symbol == opts.symbol {
+					actual = append(actual, replayed{Input: int(seq), Event: matchingEvent})
+				}
+			}
+			return nil
+		})
+	case opts.input != "":
+		var start time.Time
+		if opts.start != "" {
+			if start, err = time.Parse(time.RFC3339Nano, opts.start); err != nil {
+				return 0, err
+			}
+		}
+		err = readInputFile(opts.input, start, apply)
+	case opts.inputTopic != "":
+		err = readInputKafka(ctx, opts.kafkaRange(opts.inputTopic, opts.inputFrom, opts.inputTo), apply)
+	default:
+		return 0, errors.New("one of -journal, -input or -input-topic is required")
+	}
+	if err != nil {
+		return 0, err
+	}
+	fmt.Fprintf(os.Stderr, "replayed %d inputs into %d matching events\n", inputs, len(actual))
+
+	if err := writeOutput(opts.output, actual); err != nil {
+		return 0, err
+	}
+
+	// The partition of the matching topic has the matching events of the other symbols too
+	var expected []events.MatchingEvent
+	record := func(matchingEvent events.MatchingEvent) {
+		if matchingEvent.Order.Symbol == opts.symbol {
+			expected = append(expected, matchingEvent)
+		}
+	}
+	switch {
+	case opts.expected != "":
+		err = readLines(opts.expected, func(number int, line []byte) error {
+			matchingEvent, err := events.JSONCodec.DecodeMatchingEvent(line)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", opts.expected, number, err)
+			}
+			record(matchingEvent)
+			return nil
+		})
+	case opts.expectedTopic != "":
+		err = mqkit.ReadKafkaRange(ctx, opts.kafkaRange(opts.expectedTopic, opts.expectedFrom, opts.expectedTo), func(msg kafka.Message) error {
+			codec, err := kafkaCodec(msg)
+			if err != nil {
+				return err
+			}
+			matchingEvent, err := codec.DecodeMatchingEvent(msg.Value)
+			if err != nil {
+				return fmt.Errorf("offset %d: %w", msg.Offset, err)
+			}
+			record(matchingEvent)
+			return nil
+		})
+	default:
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	d, err := diff(actual, expected, opts.ignoreTimes)
+	if err != nil {
+		return 0, err
+	}
+	if d != nil {
+		d.print(os.Stderr)
+		return 1, nil
+	}
+	fmt.Fprintf(os.Stderr, "replay matches the %d recorded matching events\n", len(expected))
+	return 0, nil
+}
+
+func (opts options) kafkaRange(topic string, from, to int64) mqkit.KafkaRange {
+	return mqkit.KafkaRange{
+		Brokers:   strings.Split(opts.brokers, ","),
+		Topic:     topic,
+		Partition: opts.partition,
+		From:      from,
+		To:        to,
+	}
+}
+
+// writeOutput writes the replayed matching events as JSONL
+func writeOutput(path string, actual []replayed) error {
+	if path == "" {
+		return nil
+	}
+
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	encoder := json.NewEncoder(w)
+	for _, r := range actual {
+		if err := encoder.Encode(r.Event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
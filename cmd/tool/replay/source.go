@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	matchingengine "github.com/Hao1995/order-matching-system/internal/worker/matching_engine"
+	"github.com/Hao1995/order-matching-system/pkg/mqkit"
+)
+
+// readInputFile passes the lines of a JSONL file as entries. A line is either a journal entry or
+// an events.Event, bare or in an envelope, which takes the time of the previous line or the start
+// time, and the offset after the previous event.
+func readInputFile(path string, start time.Time, fn func(matchingengine.JournalEntry) error) error {
+	current := start
+	offset := int64(-1)
+	return readLines(path, func(number int, line []byte) error {
+		var probe struct {
+			Type      string `json:"type"`
+			EventType string `json:"event_type"`
+			Version   int    `json:"version"`
+		}
+		if err := json.Unmarshal(line, &probe); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, number, err)
+		}
+
+		entry := matchingengine.JournalEntry{Type: matchingengine.JournalEntryTypeEvent, Offset: offset + 1, Time: current, Event: line}
+		if probe.EventType == "" && probe.Version == 0 {
+			entry = matchingengine.JournalEntry{}
+			if err := json.Unmarshal(line, &entry); err != nil {
+				return fmt.Errorf("%s:%d: %w", path, number, err)
+			}
+		}
+		current = entry.Time
+		if entry.Type != matchingengine.JournalEntryTypeTick {
+			offset = entry.Offset
+		}
+		return fn(entry)
+	})
+}
+
+// readLines passes the non-empty lines of a file with their line number
+func readLines(path string, fn func(number int, line []byte) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for number := 1; scanner.Scan(); number++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		line := append([]byte(nil), scanner.Bytes()...)
+		if err := fn(number, line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// readInputKafka passes the order events of an offset range as entries stamped with the time
+// of their message. The entries have the order events in JSON, whatever their wire format.
+func readInputKafka(ctx context.Context, r mqkit.KafkaRange, fn func(matchingengine.JournalEntry) error) error {
+	return mqkit.ReadKafkaRange(ctx, r, func(msg kafka.Message) error {
+		val := msg.Value
+		codec, err := kafkaCodec(msg)
+		if err != nil {
+			return err
+		}
+		if codec.ContentType() != events.ContentTypeJSON {
+			envelope, err := codec.Decode(events.PayloadTypeEvent, msg.Value)
+			if err != nil {
+				return fmt.Errorf("offset %d: %w", msg.Offset, err)
+			}
+			if val, err = events.JSONCodec.Encode(envelope); err != nil {
+				return fmt.Errorf("offset %d: %w", msg.Offset, err)
+			}
+		}
+
+		return fn(matchingengine.JournalEntry{
+			Type:   matchingengine.JournalEntryTypeEvent,
+			Offset: msg.Offset,
+			Time:   msg.Time,
+			Event:  val,
+		})
+	})
+}
+
+// kafkaCodec returns the Codec of the content type of a message
+func kafkaCodec(msg kafka.Message) (events.Codec, error) {
+	codec, err := events.CodecFor(mqkit.KafkaHeaders(msg.Headers)[events.ContentTypeHeader])
+	if err != nil {
+		return nil, fmt.Errorf("offset %d: %w", msg.Offset, err)
+	}
+	return codec, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	matchingengine "github.com/Hao1995/order-matching-system/internal/worker/matching_engine"
+)
+
+type SourceTestSuite struct {
+	suite.Suite
+	start time.Time
+}
+
+func TestSourceTestSuite(t *testing.T) {
+	suite.Run(t, new(SourceTestSuite))
+}
+
+func (suite *SourceTestSuite) SetupTest() {
+	suite.start = time.Date(2025, 1, 15, 18, 0, 0, 0, time.UTC)
+}
+
+// readInputFile reads the lines written into a file
+func (suite *SourceTestSuite) readInputFile(lines ...string) ([]matchingengine.JournalEntry, error) {
+	path := filepath.Join(suite.T().TempDir(), "input.jsonl")
+	suite.Require().NoError(os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644))
+
+	var entries []matchingengine.JournalEntry
+	err := readInputFile(path, suite.start, func(entry matchingengine.JournalEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+func (suite *SourceTestSuite) TestReadInputFile() {
+	later := suite.start.Add(time.Minute)
+	bare := `{"event_type":"CreateOrder","data":{"id":"buy1"},"created_at":"0001-01-01T00:00:00Z"}`
+	envelope := `{"id":"1","version":1,"producer":"order-service","type":"Event","payload":{"event_type":"CancelOrder","data":{"id":"buy1"}}}`
+	event := `{"type":"Event","symbol":"AAPL","offset":7,"time":"2025-01-15T18:01:00Z","event":{"event_type":"CreateOrder"}}`
+	tick := `{"type":"Tick","symbol":"AAPL","offset":0,"time":"2025-01-15T18:02:00Z"}`
+
+	for _, tc := range []struct {
+		name    string
+		lines   []string
+		entries []matchingengine.JournalEntry
+	}{
+		{
+			name:  "bare events from the start",
+			lines: []string{bare, "", bare},
+			entries: []matchingengine.JournalEntry{
+				{Type: matchingengine.JournalEntryTypeEvent, Offset: 0, Time: suite.start, Event: []byte(bare)},
+				{Type: matchingengine.JournalEntryTypeEvent, Offset: 1, Time: suite.start, Event: []byte(bare)},
+			},
+		},
+		{
+			name:  "envelope is an event",
+			lines: []string{envelope},
+			entries: []matchingengine.JournalEntry{
+				{Type: matchingengine.JournalEntryTypeEvent, Offset: 0, Time: suite.start, Event: []byte(envelope)},
+			},
+		},
+		{
+			name:  "events carry over the journal entries",
+			lines: []string{event, bare, tick, envelope},
+			entries: []matchingengine.JournalEntry{
+				{Type: matchingengine.JournalEntryTypeEvent, Symbol: "AAPL", Offset: 7, Time: later, Event: []byte(`{"event_type":"CreateOrder"}`)},
+				{Type: matchingengine.JournalEntryTypeEvent, Offset: 8, Time: later, Event: []byte(bare)},
+				{Type: matchingengine.JournalEntryTypeTick, Symbol: "AAPL", Time: later.Add(time.Minute)},
+				// A tick has no offset, so the next event follows the previous event
+				{Type: matchingengine.JournalEntryTypeEvent, Offset: 9, Time: later.Add(time.Minute), Event: []byte(envelope)},
+			},
+		},
+	} {
+		entries, err := suite.readInputFile(tc.lines...)
+		suite.Require().NoError(err, tc.name)
+		suite.Require().Len(entries, len(tc.entries), tc.name)
+		for i, entry := range entries {
+			suite.Equal(tc.entries[i].Type, entry.Type, tc.name)
+			suite.Equal(tc.entries[i].Symbol, entry.Symbol, tc.name)
+			suite.Equal(tc.entries[i].Offset, entry.Offset, tc.name)
+			suite.True(tc.entries[i].Time.Equal(entry.Time), tc.name)
+			suite.Equal(string(tc.entries[i].Event), string(entry.Event), tc.name)
+		}
+	}
+}
+
+func (suite *SourceTestSuite) TestReadInputFile_Malformed() {
+	_, err := suite.readInputFile(`{"event_type":"CreateOrder"}`, `not json`)
+	suite.ErrorContains(err, "input.jsonl:2")
+}
@@ -1,22 +1,82 @@
 package main
 
+import (
+	"time"
+
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	engineconfig "github.com/Hao1995/order-matching-system/internal/worker/matching_engine/config"
+)
+
 var cfg Config
 
+// codec encodes the published events in the wire format of the config
+var codec events.Codec
+
 type Config struct {
-	App   App   `envPrefix:"APP_"`
-	Kafka Kafka `envPrefix:"KAFKA_"`
+	App        App        `envPrefix:"APP_"`
+	Kafka      Kafka      `envPrefix:"KAFKA_"`
+	Journal    Journal    `envPrefix:"JOURNAL_"`
+	Replica    Replica    `envPrefix:"REPLICA_"`
+	DeadLetter DeadLetter `envPrefix:"DEAD_LETTER_"`
+	Batch      Batch      `envPrefix:"BATCH_"`
+	Engine     engineconfig.Config
 
-	TickNum int8 `env:"TICK_NUM" envDefault:"5"`
+	// TickInterval is how often the time based transitions are checked
+	TickInterval time.Duration `env:"TICK_INTERVAL" envDefault:"1s"`
+	// ShutdownTimeout is how long the partitions drain their in-flight order events on shutdown
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"10s"`
 }
 
 type App struct {
 	Name string `env:"NAME,required"`
 	Port string `env:"PORT,required"`
 
+	// Symbol is the symbol of a worker which owns the first partition of the order topic, the
+	// symbols are the keys of the order events otherwise
+	Symbol string `env:"SYMBOL"`
+	// ConsumerGroup is the group of the workers sharing the partitions of the order topic, and the
+	// worker owns the first partition if empty. The matching topic must have the same number of
+	// partitions as the order topic.
+	ConsumerGroup string `env:"CONSUMER_GROUP"`
 	OrderTopic    string `env:"ORDER_TOPIC,required"`
 	MatchingTopic string `env:"MATCHING_TOPIC,required"`
+	// Codec is the wire format of the published events, the consumed ones are decoded by their
+	// content type
+	Codec events.CodecFormat `env:"CODEC" envDefault:"JSON"`
 }
 
 type Kafka struct {
 	Brokers []string `env:"BROKERS,required"`
 }
+
+type Journal struct {
+	// Dir is the directory of the write-ahead journals of the partitions, the engines are not
+	// journaled if empty
+	Dir             string        `env:"DIR"`
+	MaxSegmentBytes int64         `env:"MAX_SEGMENT_BYTES" envDefault:"67108864"`
+	SyncBatch       int           `env:"SYNC_BATCH" envDefault:"64"`
+	SyncInterval    time.Duration `env:"SYNC_INTERVAL" envDefault:"10ms"`
+}
+
+type Replica struct {
+	// LeaseFile is the lock file shared by the replicas of a worker without a consumer group, the
+	// worker is the only replica if empty
+	LeaseFile          string        `env:"LEASE_FILE"`
+	LeaseRetryInterval time.Duration `env:"LEASE_RETRY_INTERVAL" envDefault:"1s"`
+	// MaxPending is the number of the latest matching events a standby keeps for the failover
+	MaxPending int `env:"MAX_PENDING" envDefault:"100000"`
+}
+
+type DeadLetter struct {
+	// Topic receives the malformed order events, the worker stops at them if empty
+	Topic       string        `env:"TOPIC"`
+	MaxAttempts int           `env:"MAX_ATTEMPTS" envDefault:"3"`
+	Backoff     time.Duration `env:"BACKOFF" envDefault:"100ms"`
+}
+
+type Batch struct {
+	// MaxMessages is the most order events applied and published at once
+	MaxMessages int `env:"MAX_MESSAGES" envDefault:"500"`
+	// MaxWait is how long a batch waits for more order events after the first one
+	MaxWait time.Duration `env:"MAX_WAIT" envDefault:"5ms"`
+}
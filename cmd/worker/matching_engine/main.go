@@ -2,31 +2,33 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"log"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
+	// Embed the timezone database for the session calendars on images without it
+	_ "time/tzdata"
 
-	"github.com/avast/retry-go/v4"
 	"github.com/caarlos0/env/v11"
 	"go.uber.org/zap"
 
 	"github.com/Hao1995/order-matching-system/internal/common/models/events"
 	matchingengine "github.com/Hao1995/order-matching-system/internal/worker/matching_engine"
+	"github.com/Hao1995/order-matching-system/pkg/leasekit"
 	"github.com/Hao1995/order-matching-system/pkg/logger"
 	"github.com/Hao1995/order-matching-system/pkg/mqkit"
-	"github.com/Hao1995/order-matching-system/pkg/pubsubkit"
-)
-
-var (
-	ErrUnknownEventType = errors.New("unknown event type")
 )
 
 func init() {
 	if err := env.Parse(&cfg); err != nil {
 		log.Fatal("failed to parse config", err)
 	}
+
+	var err error
+	if codec, err = events.NewCodec(cfg.App.Codec, cfg.App.Name); err != nil {
+		log.Fatal("failed to create codec", err)
+	}
 }
 
 func main() {
@@ -37,130 +39,81 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	// Message queue
-	consumer := mqkit.NewKafkaConsumer(cfg.Kafka.Brokers, cfg.App.OrderTopic)
-	defer consumer.Close()
-
-	// Pub/Sub
-	publisher := pubsubkit.NewKafkaPublisher(cfg.Kafka.Brokers, cfg.App.MatchingTopic)
+	// Matching events are published to the partition of their symbol
+	publisher := mqkit.NewKafkaProducer(cfg.Kafka.Brokers, cfg.App.MatchingTopic, nil)
 	defer publisher.Close()
 
-	// OrderBook
-	orderBook := matchingengine.NewOrderBook()
-
-	// Matcher
-	matcher := matchingengine.NewMatcher(orderBook, cfg.TickNum)
-	logger.Info("success create a Kafka reader", zap.String("topic", cfg.App.OrderTopic))
-
-	go func() {
-		for {
-			// Consume event
-			handler := func(val []byte) error {
-				var orderEvent events.OrderEvent
-				event := events.Event{Data: &orderEvent}
-				if err := json.Unmarshal(val, &event); err != nil {
-					logger.Error("failed to unmarshal event", zap.Error(err), zap.ByteString("val", val))
-					return err
-				}
-				logger.Debug("Receive event", zap.Any("event", event))
-
-				var matching matchingengine.Matching
-				var matchingEvent events.MatchingEvent
-				switch event.EventType {
-				case events.EventTypeCreateOrder:
-					order := convertOrderEventToOrder(orderEvent)
-					matching = matcher.CreateOrder(order)
-					matchingEvent.Type = events.MatchingEventTypeCreate
-				case events.EventTypeCancelOrder:
-					var err error
-					matching, err = matcher.CancelOrder(orderEvent.ID)
-					if err != nil {
-						logger.Warn("failed to cancel order, pass it", zap.Error(err))
-						return nil
-					}
-					matchingEvent.Type = events.MatchingEventTypeCancel
-				default:
-					logger.Error("unknown event type", zap.String("eventType", event.EventType.String()))
-					return ErrUnknownEventType
-				}
+	// Pre-trade risk checks
+	riskChecker, err := cfg.Engine.RiskChecker()
+	if err != nil {
+		logger.Fatal("failed to load risk config", zap.Error(err))
+	}
 
-				// Convert matching data to matching event
-				matchingEvent.Order = orderEvent
-				matchingEvent.Transactions = convertToTransactionEvents(matching.Transactions)
-				matchingEvent.BuyTicks = convertToTickEvents(matching.BuyTicks)
-				matchingEvent.SellTicks = convertToTickEvents(matching.SellTicks)
-				logger.Debug("Get matchingEvent", zap.Any("matchingEvent", matchingEvent))
+	// Partitions being consumed, which are drained on shutdown
+	var partitions sync.WaitGroup
 
-				// Publish matching event
-				matchingMsg, err := json.Marshal(matchingEvent)
-				if err != nil {
-					logger.Error("failed to marshal matching event", zap.Error(err), zap.Any("matchingEvent", matchingEvent))
-					return err
-				}
+	// Matcher of every symbol
+	newEngine := func(symbol string, opts ...matchingengine.EngineOption) (*matchingengine.Engine, error) {
+		matcherOpts, err := cfg.Engine.MatcherOptions(symbol)
+		if err != nil {
+			logger.Error("failed to load matcher config", zap.Error(err), zap.String("symbol", symbol))
+			return nil, err
+		}
+		matcher := matchingengine.NewMatcher(matchingengine.NewOrderBook(), cfg.Engine.TickNum, matcherOpts...)
+		return matchingengine.NewEngine(symbol, matcher, riskChecker, opts...), nil
+	}
 
-				if err := publisher.Publish(matchingMsg); err != nil {
-					logger.Error("failed to publish matching event", zap.Error(err))
-					return err
+	if cfg.App.ConsumerGroup != "" {
+		// The group assigns the partitions to the workers, which rebuild the books of a claimed
+		// partition and drop them when the partition is revoked
+		group, err := mqkit.NewKafkaGroupConsumer(cfg.Kafka.Brokers, cfg.App.OrderTopic, cfg.App.ConsumerGroup)
+		if err != nil {
+			logger.Fatal("failed to join the consumer group", zap.Error(err))
+		}
+		defer group.Close()
+		go func() {
+			if err := group.Run(ctx, func(claimCtx context.Context, claim mqkit.Claim) {
+				// The partition stops when it is revoked or the worker shuts down
+				claimCtx, cancel := context.WithCancel(claimCtx)
+				defer context.AfterFunc(ctx, cancel)()
+				defer cancel()
+
+				if ctx.Err() != nil {
+					return
 				}
-				return nil
-			}
-
-			// Retry consume messages by BackOffDelay
-			if err := retry.Do(
-				func() error {
-					if err := consumer.Consume(context.Background(), handler); err != nil {
-						logger.Warn("failed to consume event from Kafka", zap.Error(err))
-						return err
-					}
-					return nil
-				},
-				retry.Attempts(3),
-			); err != nil {
-				// Temporary leave end the service
-				logger.Error("retry error achieve the max limit")
-				return
+				partitions.Add(1)
+				defer partitions.Done()
+				runPartition(claimCtx, claim.Partition, newEngine, publisher, nil)
+			}); err != nil {
+				logger.Fatal("failed to consume the consumer group", zap.Error(err))
 			}
+		}()
+	} else {
+		// The replicas of the worker elect the leader by the lease
+		var lease leasekit.Lease
+		if cfg.Replica.LeaseFile != "" {
+			lease = leasekit.NewFileLease(cfg.Replica.LeaseFile, cfg.Replica.LeaseRetryInterval)
 		}
-	}()
-
-	<-ctx.Done()
-	logger.Info("received interrupt signals from the OS, end the process")
-}
-
-func convertToTransactionEvents(transactions []matchingengine.Transaction) []events.TransactionEvent {
-	result := make([]events.TransactionEvent, 0, len(transactions))
-	for _, transaction := range transactions {
-		result = append(result, events.TransactionEvent{
-			ID:          transaction.ID,
-			Symbol:      transaction.Symbol,
-			BuyOrderID:  transaction.BuyOrderID,
-			SellOrderID: transaction.SellOrderID,
-			Price:       transaction.Price,
-			Quantity:    transaction.Quantity,
-			CreatedAt:   transaction.CreatedAt,
-		})
+		partitions.Add(1)
+		go func() {
+			defer partitions.Done()
+			runPartition(ctx, 0, newEngine, publisher, lease)
+		}()
 	}
-	return result
-}
 
-func convertToTickEvents(ticks []matchingengine.Tick) []events.TickEvent {
-	result := make([]events.TickEvent, 0, len(ticks))
-	for _, tick := range ticks {
-		result = append(result, events.TickEvent{
-			Price:    tick.Price,
-			Quantity: tick.Quantity,
-		})
-	}
-	return result
-}
+	<-ctx.Done()
+	logger.Info("received interrupt signals from the OS, drain the partitions", zap.Duration("timeout", cfg.ShutdownTimeout))
 
-func convertOrderEventToOrder(orderEvent events.OrderEvent) matchingengine.Order {
-	return matchingengine.Order{
-		ID:        orderEvent.ID,
-		Symbol:    orderEvent.Symbol,
-		Type:      matchingengine.OrderType(orderEvent.Type),
-		Price:     orderEvent.Price,
-		Quantity:  orderEvent.Quantity,
-		CreatedAt: orderEvent.CreatedAt,
+	// The partitions stop consuming, and finish applying and publishing their current batches
+	drained := make(chan struct{})
+	go func() {
+		partitions.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		logger.Info("success drain the partitions, end the process")
+	case <-time.After(cfg.ShutdownTimeout):
+		logger.Error("failed to drain the partitions in time, end the process")
 	}
 }
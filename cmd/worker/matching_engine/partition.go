@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/avast/retry-go/v4"
+	"go.uber.org/zap"
+
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	matchingengine "github.com/Hao1995/order-matching-system/internal/worker/matching_engine"
+	"github.com/Hao1995/order-matching-system/pkg/journalkit"
+	"github.com/Hao1995/order-matching-system/pkg/leasekit"
+	"github.com/Hao1995/order-matching-system/pkg/logger"
+	"github.com/Hao1995/order-matching-system/pkg/mqkit"
+)
+
+// runPartition rebuilds the engines of a partition of the order topic and applies its order events
+// until the context is done, when the partition is handed off to another worker
+func runPartition(ctx context.Context, id int, newEngine matchingengine.EngineFactory, publisher mqkit.Producer, lease leasekit.Lease) {
+
+	// Write-ahead journal of the partition
+	var journal matchingengine.Journal
+	dir := filepath.Join(cfg.Journal.Dir, strconv.Itoa(id))
+	if cfg.Journal.Dir != "" {
+		partitionJournal, err := journalkit.Open(dir, journalkit.Options{
+			MaxSegmentBytes: cfg.Journal.MaxSegmentBytes,
+			SyncBatch:       cfg.Journal.SyncBatch,
+			SyncInterval:    cfg.Journal.SyncInterval,
+		})
+		if err != nil {
+			logger.Fatal("failed to open journal", zap.Int("partition", id), zap.Error(err), zap.String("dir", dir))
+		}
+		defer partitionJournal.Close()
+		journal = partitionJournal
+	}
+	partition := matchingengine.NewPartition(id, newEngine, journal)
+	if cfg.App.ConsumerGroup == "" && cfg.App.Symbol != "" {
+		// Tick the symbol before its first order event
+		if _, err := partition.Engine(cfg.App.Symbol); err != nil {
+			logger.Fatal("failed to create the engine", zap.Int("partition", id), zap.Error(err), zap.String("symbol", cfg.App.Symbol))
+		}
+	}
+
+	// Only the leader replica publishes the matching events, to the partition of their symbol
+	replica := matchingengine.NewReplica(func(matchingEvents []events.MatchingEvent) error {
+		msgs := make([]mqkit.Message, 0, len(matchingEvents))
+		for _, matchingEvent := range matchingEvents {
+			logger.Debug("Get matchingEvent", zap.Int("partition", id), zap.Any("matchingEvent", matchingEvent))
+
+			// Publish matching event
+			matchingMsg, err := codec.EncodeMatchingEvent(matchingEvent)
+			if err != nil {
+				logger.Error("failed to encode matching event", zap.Int("partition", id), zap.Error(err), zap.Any("matchingEvent", matchingEvent))
+				return err
+			}
+			msgs = append(msgs, mqkit.Message{
+				Key:     []byte(matchingEvent.Order.Symbol),
+				Value:   matchingMsg,
+				Headers: map[string]string{events.ContentTypeHeader: codec.ContentType()},
+			})
+		}
+
+		// The in-flight matching events are published after the partition stops
+		if err := publisher.PublishBatch(context.WithoutCancel(ctx), msgs); err != nil {
+			logger.Error("failed to publish matching events", zap.Int("partition", id), zap.Error(err), zap.Int("count", len(msgs)))
+			return err
+		}
+		return nil
+	}, cfg.Replica.MaxPending)
+	publish := replica.Publish
+
+	// Rebuild the state from the journal, the matching events not published before a crash or a
+	// hand-off are kept to be published by the promotion
+	if cfg.Journal.Dir != "" {
+		var entries uint64
+		if err := partition.Replay(dir, func(seq uint64, matchingEvents []events.MatchingEvent) error {
+			entries = seq
+			return publish(matchingEvents)
+		}); err != nil {
+			logger.Fatal("failed to replay journal", zap.Int("partition", id), zap.Error(err), zap.String("dir", dir))
+		}
+		logger.Info("success replay the journal", zap.Int("partition", id), zap.Uint64("entries", entries))
+	}
+
+	// The leader drives the time based transitions of all the replicas, e.g. session phases or
+	// resuming after a circuit breaker halt, through heartbeats in the order topic
+	if lease != nil {
+		go lead(ctx, lease, replica, partition)
+	} else {
+		promote(ctx, replica, id)
+		go heartbeat(ctx, partition)
+	}
+
+	// Message queue, consumed from the order event after the applied ones. The order events which
+	// were applied but not journaled before a crash are applied again to the same matching events,
+	// and the published ones are skipped by the replica.
+	consumer, err := mqkit.NewKafkaPartitionConsumer(cfg.Kafka.Brokers, cfg.App.OrderTopic, id, partition.LastOffset()+1)
+	if err != nil {
+		logger.Fatal("failed to create a Kafka reader", zap.Int("partition", id), zap.Error(err))
+	}
+	logger.Info("success create a Kafka reader", zap.Int("partition", id), zap.String("topic", cfg.App.OrderTopic), zap.Int64("offset", partition.LastOffset()+1))
+
+	// Dead-letter queue of the malformed order events, the other failures are retried. The moved
+	// order events are journaled as skipped, as the partition consumer commits no offsets.
+	if cfg.DeadLetter.Topic != "" {
+		deadLetterProducer := mqkit.NewKafkaProducer(cfg.Kafka.Brokers, cfg.DeadLetter.Topic, nil)
+		consumer = mqkit.NewDeadLetterConsumer(consumer, deadLetterProducer, mqkit.DeadLetterOptions{
+			MaxAttempts: cfg.DeadLetter.MaxAttempts,
+			Backoff:     cfg.DeadLetter.Backoff,
+			ShouldDeadLetter: func(err error) bool {
+				return errors.Is(err, matchingengine.ErrMalformedEvent)
+			},
+			OnDeadLetter: func(msg mqkit.Message) error {
+				return partition.Skip(msg.Offset)
+			},
+		})
+	}
+	defer consumer.Close()
+
+	// Consume a batch of events, and publish their matching events at once. The matching events
+	// of the order events applied before a failure, and those of the failed one if it was applied,
+	// are published too, as the redelivered batch skips them.
+	batchOpts := mqkit.BatchOptions{
+		MaxMessages: cfg.Batch.MaxMessages,
+		MaxWait:     cfg.Batch.MaxWait,
+	}
+	handler := func(msgs []mqkit.Message) error {
+		var matchingEvents []events.MatchingEvent
+		for _, msg := range msgs {
+			// The order events are decoded by their content type, so the producers switch the
+			// wire format one at a time
+			handled, err := handleMessage(partition, msg)
+			matchingEvents = append(matchingEvents, handled...)
+			if err != nil {
+				if publishErr := publish(matchingEvents); publishErr != nil {
+					return publishErr
+				}
+				return err
+			}
+		}
+		return publish(matchingEvents)
+	}
+	for {
+		// Retry consume messages by BackOffDelay
+		if err := retry.Do(
+			func() error {
+				if err := consumer.ConsumeBatch(ctx, batchOpts, handler); err != nil {
+					logger.Warn("failed to consume event from Kafka", zap.Int("partition", id), zap.Error(err))
+					return err
+				}
+				return nil
+			},
+			retry.Context(ctx),
+			retry.Attempts(3),
+		); err != nil {
+			if ctx.Err() != nil {
+				logger.Info("stop consuming the partition", zap.Int("partition", id))
+				return
+			}
+			// Exit to be restarted, which resumes from the applied order events
+			logger.Fatal("retry error achieve the max limit", zap.Int("partition", id), zap.Error(err))
+		}
+	}
+}
+
+// handleMessage applies an order event message to the partition
+func handleMessage(partition *matchingengine.Partition, msg mqkit.Message) ([]events.MatchingEvent, error) {
+	msgCodec, err := events.CodecFor(msg.Headers[events.ContentTypeHeader])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", matchingengine.ErrMalformedEvent, err)
+	}
+	return partition.HandleEncoded(msg.Offset, msg.Key, msgCodec, msg.Value)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	matchingengine "github.com/Hao1995/order-matching-system/internal/worker/matching_engine"
+	"github.com/Hao1995/order-matching-system/pkg/leasekit"
+	"github.com/Hao1995/order-matching-system/pkg/logger"
+	"github.com/Hao1995/order-matching-system/pkg/mqkit"
+)
+
+// lead waits for the lease, then promotes the replica and drives the ticks of all the replicas
+func lead(ctx context.Context, lease leasekit.Lease, replica *matchingengine.Replica, partition *matchingengine.Partition) {
+	if err := lease.Acquire(ctx); err != nil {
+		if !errors.Is(err, context.Canceled) {
+			logger.Error("failed to acquire the lease", zap.Error(err))
+		}
+		return
+	}
+	defer lease.Release()
+	logger.Info("success acquire the lease, promote to the leader")
+
+	promote(ctx, replica, partition.ID())
+	heartbeat(ctx, partition)
+}
+
+// promote makes the replica the leader after the last matching event in the partition of the
+// matching topic. A replica which does not keep the matching events after it exits instead, which
+// releases the lease, as publishing would lose them.
+func promote(ctx context.Context, replica *matchingengine.Replica, partition int) {
+	if err := retry.Do(
+		func() error {
+			matchingEvent, err := lastPublished(ctx, partition)
+			if err != nil {
+				logger.Warn("failed to read the last published matching event", zap.Error(err), zap.Int("partition", partition))
+				return err
+			}
+			logger.Info("promote after the last published matching event", zap.Int("partition", partition),
+				zap.Uint64("seq", matchingEvent.Seq), zap.Int64("inputOffset", matchingEvent.InputOffset))
+
+			err = replica.Promote(matchingEvent.Seq)
+			if errors.Is(err, matchingengine.ErrReplicaGap) {
+				return retry.Unrecoverable(err)
+			}
+			return err
+		},
+		retry.Context(ctx),
+		retry.Attempts(5),
+	); err != nil && ctx.Err() == nil {
+		logger.Fatal("failed to promote to the leader", zap.Error(err), zap.Int("partition", partition))
+	}
+}
+
+// heartbeat publishes a heartbeat of every symbol of the partition to the order topic every tick
+// interval, so every replica ticks at the same position of the orders
+func heartbeat(ctx context.Context, partition *matchingengine.Partition) {
+	producer := mqkit.NewKafkaProducer(cfg.Kafka.Brokers, cfg.App.OrderTopic, nil)
+	defer producer.Close()
+
+	ticker := time.NewTicker(cfg.TickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			val, err := codec.EncodeEvent(events.Event{EventType: events.EventTypeHeartbeat, CreatedAt: time.Now()})
+			if err != nil {
+				logger.Error("failed to encode heartbeat", zap.Error(err))
+				continue
+			}
+			for _, symbol := range partition.Symbols() {
+				msg := mqkit.Message{
+					Key:     []byte(symbol),
+					Value:   val,
+					Headers: map[string]string{events.ContentTypeHeader: codec.ContentType()},
+				}
+				if err := producer.PublishBatch(ctx, []mqkit.Message{msg}); err != nil {
+					logger.Error("failed to publish heartbeat", zap.Error(err), zap.String("symbol", symbol))
+				}
+			}
+		}
+	}
+}
+
+// lastPublished reads the last matching event in the partition of the matching topic, which has
+// the matching events of the same symbols as the partition of the order topic. It is empty if
+// nothing is published yet.
+func lastPublished(ctx context.Context, partition int) (events.MatchingEvent, error) {
+	var matchingEvent events.MatchingEvent
+	conn, err := dialLeader(ctx, partition)
+	if err != nil {
+		return matchingEvent, err
+	}
+	defer conn.Close()
+
+	first, last, err := conn.ReadOffsets()
+	if err != nil {
+		return matchingEvent, err
+	}
+	if last <= first {
+		return matchingEvent, nil
+	}
+
+	if _, err := conn.Seek(last-1, kafka.SeekAbsolute); err != nil {
+		return matchingEvent, err
+	}
+	msg, err := conn.ReadMessage(10e6)
+	if err != nil {
+		return matchingEvent, err
+	}
+
+	msgCodec, err := events.CodecFor(mqkit.KafkaHeaders(msg.Headers)[events.ContentTypeHeader])
+	if err != nil {
+		return matchingEvent, err
+	}
+	return msgCodec.DecodeMatchingEvent(msg.Value)
+}
+
+// dialLeader connects to the leader of the partition of the matching topic, looked up through the
+// first reachable broker
+func dialLeader(ctx context.Context, partition int) (*kafka.Conn, error) {
+	var errs []error
+	for _, broker := range cfg.Kafka.Brokers {
+		conn, err := kafka.DialLeader(ctx, "tcp", broker, cfg.App.MatchingTopic, partition)
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, fmt.Errorf("broker %s: %w", broker, err))
+	}
+	return nil, errors.Join(errs...)
+}
@@ -0,0 +1,82 @@
+package account
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/Hao1995/order-matching-system/internal/api/account/requests"
+	"github.com/Hao1995/order-matching-system/internal/api/auth"
+	"github.com/Hao1995/order-matching-system/pkg/logger"
+)
+
+type Handler struct {
+	ledger *Ledger
+}
+
+func NewHandler(ledger *Ledger) *Handler {
+	return &Handler{
+		ledger: ledger,
+	}
+}
+
+// Deposit credits funds to an account, it is registered behind the admin keys.
+func (hlr *Handler) Deposit(c *gin.Context) {
+	var account requests.AccountRequest
+	if err := c.ShouldBindUri(&account); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	var request requests.DepositRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deposit data"})
+		return
+	}
+
+	entry, err := hlr.ledger.Deposit(account.ID, request.Asset, request.Amount)
+	if err != nil {
+		logger.Error("failed to deposit", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deposit"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// Balances lists the available and held balances of an account.
+func (hlr *Handler) Balances(c *gin.Context) {
+	var account requests.AccountRequest
+	if err := c.ShouldBindUri(&account); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid request data"})
+		return
+	}
+	if !owns(c, account.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	c.JSON(http.StatusOK, hlr.ledger.Balances(account.ID))
+}
+
+// Entries lists the ledger entries of an account for auditing.
+func (hlr *Handler) Entries(c *gin.Context) {
+	var account requests.AccountRequest
+	if err := c.ShouldBindUri(&account); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid request data"})
+		return
+	}
+	if !owns(c, account.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	c.JSON(http.StatusOK, hlr.ledger.Entries(account.ID))
+}
+
+// owns reports whether the authenticated account of the request, if any, is the account
+func owns(c *gin.Context, accountID string) bool {
+	authenticated, ok := auth.AccountID(c.Request.Context())
+	return !ok || authenticated == accountID
+}
@@ -0,0 +1,95 @@
+package account
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/Hao1995/order-matching-system/internal/api/auth"
+)
+
+type HandlerTestSuite struct {
+	suite.Suite
+	ledger *Ledger
+	router *gin.Engine
+	// accountID is the authenticated account of the requests, none if empty
+	accountID string
+}
+
+func TestHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(HandlerTestSuite))
+}
+
+func (suite *HandlerTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	suite.ledger = NewLedger("USD")
+	suite.accountID = ""
+
+	handler := NewHandler(suite.ledger)
+	suite.router = gin.New()
+	suite.router.Use(func(c *gin.Context) {
+		if suite.accountID != "" {
+			c.Request = c.Request.WithContext(auth.NewContext(c.Request.Context(), suite.accountID))
+		}
+	})
+	RegisterRoutes(suite.router, handler)
+	RegisterAdminRoutes(suite.router, handler)
+}
+
+func (suite *HandlerTestSuite) request(method, path, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(recorder, httptest.NewRequest(method, path, bytes.NewReader([]byte(body))))
+	return recorder
+}
+
+func (suite *HandlerTestSuite) TestDeposit() {
+	recorder := suite.request(http.MethodPost, "/accounts/alice/deposits", `{"asset":"USD","amount":1000}`)
+	suite.Equal(http.StatusCreated, recorder.Code)
+	var entry Entry
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &entry))
+	suite.Equal(EntryTypeDeposit, entry.Type)
+	suite.Equal([]Balance{{Asset: "USD", Available: 1000}}, suite.ledger.Balances("alice"))
+
+	for _, body := range []string{`{`, `{"asset":"USD"}`, `{"asset":"USD","amount":-1}`, `{"amount":1}`} {
+		suite.Equal(http.StatusBadRequest, suite.request(http.MethodPost, "/accounts/alice/deposits", body).Code, body)
+	}
+	suite.Equal([]Balance{{Asset: "USD", Available: 1000}}, suite.ledger.Balances("alice"))
+}
+
+func (suite *HandlerTestSuite) TestBalancesAndEntries() {
+	_, err := suite.ledger.Deposit("alice", "USD", 1000)
+	suite.Require().NoError(err)
+
+	recorder := suite.request(http.MethodGet, "/accounts/alice/balances", "")
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`[{"asset":"USD","available":1000,"held":0}]`, recorder.Body.String())
+	recorder = suite.request(http.MethodGet, "/accounts/alice/entries", "")
+	suite.Equal(http.StatusOK, recorder.Code)
+	var entries []Entry
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &entries))
+	suite.Len(entries, 1)
+
+	// An account without entries has none
+	recorder = suite.request(http.MethodGet, "/accounts/bob/entries", "")
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`[]`, recorder.Body.String())
+}
+
+func (suite *HandlerTestSuite) TestBalancesAndEntries_Authenticated() {
+	_, err := suite.ledger.Deposit("alice", "USD", 1000)
+	suite.Require().NoError(err)
+
+	// The authenticated account reads its own account only
+	suite.accountID = "bob"
+	suite.Equal(http.StatusForbidden, suite.request(http.MethodGet, "/accounts/alice/balances", "").Code)
+	suite.Equal(http.StatusForbidden, suite.request(http.MethodGet, "/accounts/alice/entries", "").Code)
+
+	suite.accountID = "alice"
+	suite.Equal(http.StatusOK, suite.request(http.MethodGet, "/accounts/alice/balances", "").Code)
+	suite.Equal(http.StatusOK, suite.request(http.MethodGet, "/accounts/alice/entries", "").Code)
+}
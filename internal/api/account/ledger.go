@@ -0,0 +1,544 @@
+//go:generate go-enum --marshal
+package account
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Hao1995/order-matching-system/pkg/journalkit"
+)
+
+const (
+	// ExternalAccountID is the counterparty of deposits so that every entry stays balanced
+	ExternalAccountID = "external"
+	// FeeAccountID collects the transaction fees and pays the rebates
+	FeeAccountID = "fees"
+
+	// epsilon tolerates the float rounding of price * quantity
+	epsilon = 1e-9
+
+	bpsDenominator = 10000
+)
+
+var (
+	getUUID = func() string {
+		return uuid.NewString()
+	}
+
+	now = func() time.Time {
+		return time.Now()
+	}
+
+	ErrInvalidAmount       = errors.New("invalid amount")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrHoldExists          = errors.New("hold already exists")
+	ErrHoldNotFound        = errors.New("hold not found")
+	ErrAlreadySettled      = errors.New("fill already settled")
+	ErrUnbalancedEntry     = errors.New("unbalanced entry")
+)
+
+// ENUM(Available, Held)
+type Bucket string
+
+// ENUM(Deposit, Hold, Release, Settle)
+type EntryType string
+
+// ENUM(Buy, Sell)
+type Side string
+
+// Posting is a single movement of an asset into or out of an account bucket
+type Posting struct {
+	AccountID string  `json:"account_id"`
+	Asset     string  `json:"asset"`
+	Bucket    Bucket  `json:"bucket"`
+	Amount    float64 `json:"amount"`
+}
+
+// Entry is a double-entry journal record, the postings of each asset sum up to zero
+type Entry struct {
+	ID        string    `json:"id"`
+	Type      EntryType `json:"type"`
+	Reference string    `json:"reference"`
+	Postings  []Posting `json:"postings"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Balance represents the funds of an asset owned by an account
+type Balance struct {
+	Asset     string  `json:"asset"`
+	Available float64 `json:"available"`
+	Held      float64 `json:"held"`
+}
+
+// Order is the part of an order the ledger needs to reserve funds
+type Order struct {
+	ID        string
+	AccountID string
+	Symbol    string
+	Side      Side
+	Price     float64
+	Quantity  int64
+}
+
+// Fill is an execution between a buy order and a sell order
+type Fill struct {
+	ID          string
+	BuyOrderID  string
+	SellOrderID string
+	Price       float64
+	Quantity    int64
+	BuyFee      Fee
+	SellFee     Fee
+}
+
+// Fee is charged from the available balance, a negative amount is a rebate
+type Fee struct {
+	Amount   float64
+	Currency string
+}
+
+// hold tracks the funds still reserved by an open order
+type hold struct {
+	order Order
+	asset string
+	// fee is the fee reserved in the feeAsset, the quote asset, for the remaining quantity
+	fee      float64
+	feeAsset string
+}
+
+// amount returns the reserved amount for the remaining quantity
+func (h *hold) amount() float64 {
+	if h.order.Side == SideBuy {
+		return h.order.Price * float64(h.order.Quantity)
+	}
+	return float64(h.order.Quantity)
+}
+
+// postings move the reserved funds and fee from one bucket to the other
+func (h *hold) postings(from, to Bucket) []Posting {
+	accountID, amount := h.order.AccountID, h.amount()
+	postings := []Posting{
+		{AccountID: accountID, Asset: h.asset, Bucket: from, Amount: -amount},
+		{AccountID: accountID, Asset: h.asset, Bucket: to, Amount: amount},
+	}
+	if h.fee == 0 {
+		return postings
+	}
+	return append(postings,
+		Posting{AccountID: accountID, Asset: h.feeAsset, Bucket: from, Amount: -h.fee},
+		Posting{AccountID: accountID, Asset: h.feeAsset, Bucket: to, Amount: h.fee},
+	)
+}
+
+// reservedFee returns the share of the reserved fee of a filled quantity
+func (h *hold) reservedFee(quantity int64) float64 {
+	return h.fee * float64(quantity) / float64(h.order.Quantity)
+}
+
+// feePostings take the fee of a fill from the reserved fee of its quantity, and return the rest of
+// the reserve to the available balance. A fee beyond the reserve, a rebate, or a fee in another
+// currency is settled with the available balance.
+func (h *hold) feePostings(reserved float64, fee Fee) []Posting {
+	accountID := h.order.AccountID
+	if reserved == 0 {
+		return feePostings(accountID, fee)
+	}
+	if fee.Currency != h.feeAsset {
+		return append([]Posting{
+			{AccountID: accountID, Asset: h.feeAsset, Bucket: BucketHeld, Amount: -reserved},
+			{AccountID: accountID, Asset: h.feeAsset, Bucket: BucketAvailable, Amount: reserved},
+		}, feePostings(accountID, fee)...)
+	}
+	return []Posting{
+		{AccountID: accountID, Asset: h.feeAsset, Bucket: BucketHeld, Amount: -reserved},
+		{AccountID: accountID, Asset: h.feeAsset, Bucket: BucketAvailable, Amount: reserved - fee.Amount},
+		{AccountID: FeeAccountID, Asset: fee.Currency, Bucket: BucketAvailable, Amount: fee.Amount},
+	}
+}
+
+// Ledger keeps per-asset available/held balances and the journal of every movement. With a
+// Journal its entries are appended before they are applied and rebuilt by Replay on startup. It
+// is owned by one process, so the order API runs as a single replica.
+type Ledger struct {
+	mu sync.Mutex
+	// quoteAsset is the currency orders are priced in, the base asset is the symbol
+	quoteAsset string
+	// balances maps AccountID to Asset to Balance
+	balances map[string]map[string]*Balance
+	// holds maps Order.ID to its reserved funds
+	holds map[string]*hold
+	// settled are the IDs of the latest settled fills, whose redelivery is not settled again
+	settled map[string]struct{}
+	// settledIDs are the settled IDs from the oldest, pruned beyond settledWindow
+	settledIDs    []string
+	settledWindow int
+	entries       []Entry
+	journal       Journal
+	// maxFeeBps is the fee reserved by the holds in basis points of the notional
+	maxFeeBps float64
+}
+
+// defaultSettledWindow is the number of latest settled fills whose redelivery is detected
+const defaultSettledWindow = 100000
+
+// Journal durably appends the records of the entries
+type Journal interface {
+	Append(data []byte) (uint64, error)
+}
+
+// journalRecord is a journaled entry with what its hold needs to be rebuilt
+type journalRecord struct {
+	Entry Entry   `json:"entry"`
+	Order *Order  `json:"order,omitempty"`
+	Fee   float64 `json:"fee,omitempty"`
+	Fill  *Fill   `json:"fill,omitempty"`
+}
+
+// LedgerOption configures optional behaviors of a Ledger
+type LedgerOption func(*Ledger)
+
+// WithMaxFeeBps reserves the fee of an order in the quote asset at the rate in basis points
+// beside its funds, which must be at least the highest fee rate of the fee schedule
+func WithMaxFeeBps(bps float64) LedgerOption {
+	return func(l *Ledger) {
+		l.maxFeeBps = bps
+	}
+}
+
+// WithJournal appends every entry to the journal before it is applied
+func WithJournal(journal Journal) LedgerOption {
+	return func(l *Ledger) {
+		l.journal = journal
+	}
+}
+
+// WithSettledWindow sets the number of latest settled fills whose redelivery returns
+// ErrAlreadySettled
+func WithSettledWindow(window int) LedgerOption {
+	return func(l *Ledger) {
+		l.settledWindow = window
+	}
+}
+
+// NewLedger initializes and returns a new Ledger
+func NewLedger(quoteAsset string, opts ...LedgerOption) *Ledger {
+	l := &Ledger{
+		quoteAsset: quoteAsset,
+		balances:   make(map[string]map[string]*Balance),
+		holds:      make(map[string]*hold),
+		settled:    make(map[string]struct{}),
+		// settledWindow is overridden by WithSettledWindow
+		settledWindow: defaultSettledWindow,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Replay applies the entries journaled in the directory, which rebuilds the balances, holds and
+// settled fills of the ledger before it is used
+func (l *Ledger) Replay(dir string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return journalkit.Replay(dir, func(r journalkit.Record) error {
+		var record journalRecord
+		if err := json.Unmarshal(r.Data, &record); err != nil {
+			return fmt.Errorf("ledger journal record %d: %w", r.Seq, err)
+		}
+		l.apply(record)
+		return nil
+	})
+}
+
+// Deposit credits the available balance of an account
+func (l *Ledger) Deposit(accountID, asset string, amount float64) (Entry, error) {
+	if amount <= 0 {
+		return Entry{}, ErrInvalidAmount
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.post(EntryTypeDeposit, accountID, []Posting{
+		{AccountID: ExternalAccountID, Asset: asset, Bucket: BucketAvailable, Amount: -amount},
+		{AccountID: accountID, Asset: asset, Bucket: BucketAvailable, Amount: amount},
+	}, journalRecord{})
+}
+
+// Hold reserves the funds an order needs: quote asset for a buy, base asset for a sell, and the
+// maximum fee in the quote asset for both
+func (l *Ledger) Hold(order Order) (Entry, error) {
+	if order.Price <= 0 || order.Quantity <= 0 {
+		return Entry{}, ErrInvalidAmount
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, exists := l.holds[order.ID]; exists {
+		return Entry{}, ErrHoldExists
+	}
+
+	fee := order.Price * float64(order.Quantity) * l.maxFeeBps / bpsDenominator
+	h := l.newHold(order, fee)
+	required := map[string]float64{h.asset: h.amount()}
+	if h.fee > 0 {
+		required[h.feeAsset] += h.fee
+	}
+	for asset, amount := range required {
+		if l.available(order.AccountID, asset)+epsilon < amount {
+			return Entry{}, fmt.Errorf("%w: %s requires %v %s", ErrInsufficientBalance, order.Side, amount, asset)
+		}
+	}
+
+	return l.post(EntryTypeHold, order.ID, h.postings(BucketAvailable, BucketHeld), journalRecord{Order: &order, Fee: fee})
+}
+
+// newHold returns the hold of an order reserving the fee
+func (l *Ledger) newHold(order Order, fee float64) *hold {
+	h := &hold{
+		order:    order,
+		asset:    order.Symbol,
+		fee:      fee,
+		feeAsset: l.quoteAsset,
+	}
+	if order.Side == SideBuy {
+		h.asset = l.quoteAsset
+	}
+	return h
+}
+
+// Order returns the open order of the hold
+func (l *Ledger) Order(orderID string) (Order, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	h, exists := l.holds[orderID]
+	if !exists {
+		return Order{}, false
+	}
+	return h.order, true
+}
+
+// Orders returns the open orders of an account, sorted by ID
+func (l *Ledger) Orders(accountID string) []Order {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result := []Order{}
+	for _, h := range l.holds {
+		if h.order.AccountID == accountID {
+			result = append(result, h.order)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// Release returns the remaining reserved funds of an order to the available balance
+func (l *Ledger) Release(orderID string) (Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	h, exists := l.holds[orderID]
+	if !exists {
+		return Entry{}, ErrHoldNotFound
+	}
+	return l.post(EntryTypeRelease, orderID, h.postings(BucketHeld, BucketAvailable), journalRecord{})
+}
+
+// Settle moves the funds of a fill from the holds of both orders to the counterparties.
+// The buyer is charged the fill price, the difference to its limit price is released.
+// Fees of both sides are taken from their reserved fees and moved to the FeeAccountID.
+// A fill is settled once, its ID settled again returns ErrAlreadySettled as long as it is one of
+// the latest settled ones.
+func (l *Ledger) Settle(fill Fill) (Entry, error) {
+	if fill.Price <= 0 || fill.Quantity <= 0 {
+		return Entry{}, ErrInvalidAmount
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, settled := l.settled[fill.ID]; settled {
+		return Entry{}, fmt.Errorf("%w: %s", ErrAlreadySettled, fill.ID)
+	}
+
+	buyHold, exists := l.holds[fill.BuyOrderID]
+	if !exists {
+		return Entry{}, fmt.Errorf("%w: buy order %s", ErrHoldNotFound, fill.BuyOrderID)
+	}
+	sellHold, exists := l.holds[fill.SellOrderID]
+	if !exists {
+		return Entry{}, fmt.Errorf("%w: sell order %s", ErrHoldNotFound, fill.SellOrderID)
+	}
+
+	quantity := float64(fill.Quantity)
+	reserved := buyHold.order.Price * quantity
+	cost := fill.Price * quantity
+	buyer, seller := buyHold.order.AccountID, sellHold.order.AccountID
+
+	postings := []Posting{
+		{AccountID: buyer, Asset: buyHold.asset, Bucket: BucketHeld, Amount: -reserved},
+		{AccountID: buyer, Asset: buyHold.asset, Bucket: BucketAvailable, Amount: reserved - cost},
+		{AccountID: seller, Asset: buyHold.asset, Bucket: BucketAvailable, Amount: cost},
+		{AccountID: seller, Asset: sellHold.asset, Bucket: BucketHeld, Amount: -quantity},
+		{AccountID: buyer, Asset: sellHold.asset, Bucket: BucketAvailable, Amount: quantity},
+	}
+	postings = append(postings, buyHold.feePostings(buyHold.reservedFee(fill.Quantity), fill.BuyFee)...)
+	postings = append(postings, sellHold.feePostings(sellHold.reservedFee(fill.Quantity), fill.SellFee)...)
+
+	return l.post(EntryTypeSettle, fill.ID, postings, journalRecord{Fill: &fill})
+}
+
+// settle consumes the quantity and the reserved fee of a fill from the holds of its orders, and
+// remembers the fill among the latest settled ones
+func (l *Ledger) settle(fill Fill) {
+	buyHold, sellHold := l.holds[fill.BuyOrderID], l.holds[fill.SellOrderID]
+	buyFee, sellFee := buyHold.reservedFee(fill.Quantity), sellHold.reservedFee(fill.Quantity)
+	buyHold.fee -= buyFee
+	sellHold.fee -= sellFee
+	for _, h := range []*hold{buyHold, sellHold} {
+		h.order.Quantity -= fill.Quantity
+		if h.order.Quantity <= 0 {
+			delete(l.holds, h.order.ID)
+		}
+	}
+
+	l.settled[fill.ID] = struct{}{}
+	l.settledIDs = append(l.settledIDs, fill.ID)
+	if len(l.settledIDs) > l.settledWindow {
+		delete(l.settled, l.settledIDs[0])
+		l.settledIDs = l.settledIDs[1:]
+	}
+}
+
+// feePostings moves a fee from the available balance of an account to the FeeAccountID
+func feePostings(accountID string, fee Fee) []Posting {
+	if fee.Amount == 0 {
+		return nil
+	}
+	return []Posting{
+		{AccountID: accountID, Asset: fee.Currency, Bucket: BucketAvailable, Amount: -fee.Amount},
+		{AccountID: FeeAccountID, Asset: fee.Currency, Bucket: BucketAvailable, Amount: fee.Amount},
+	}
+}
+
+// Balances returns a snapshot of every asset balance of an account
+func (l *Ledger) Balances(accountID string) []Balance {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result := make([]Balance, 0, len(l.balances[accountID]))
+	for _, balance := range l.balances[accountID] {
+		result = append(result, *balance)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Asset < result[j].Asset })
+	return result
+}
+
+// Entries returns the journal entries which touch the account
+func (l *Ledger) Entries(accountID string) []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result := []Entry{}
+	for _, entry := range l.entries {
+		for _, posting := range entry.Postings {
+			if posting.AccountID == accountID {
+				result = append(result, entry)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// post validates that the postings are balanced per asset, journals the entry with the record of
+// its holds and applies it
+func (l *Ledger) post(entryType EntryType, reference string, postings []Posting, record journalRecord) (Entry, error) {
+	sums := make(map[string]float64)
+	for _, posting := range postings {
+		sums[posting.Asset] += posting.Amount
+	}
+	for asset, sum := range sums {
+		if math.Abs(sum) > epsilon {
+			return Entry{}, fmt.Errorf("%w: %s off by %v", ErrUnbalancedEntry, asset, sum)
+		}
+	}
+
+	record.Entry = Entry{
+		ID:        getUUID(),
+		Type:      entryType,
+		Reference: reference,
+		Postings:  postings,
+		CreatedAt: now(),
+	}
+	if l.journal != nil {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return Entry{}, err
+		}
+		if _, err := l.journal.Append(data); err != nil {
+			return Entry{}, err
+		}
+	}
+	l.apply(record)
+	return record.Entry, nil
+}
+
+// apply moves the balances of the postings of the entry, appends it to the entries and updates
+// the holds
+func (l *Ledger) apply(record journalRecord) {
+	for _, posting := range record.Entry.Postings {
+		balance := l.balance(posting.AccountID, posting.Asset)
+		if posting.Bucket == BucketHeld {
+			balance.Held += posting.Amount
+		} else {
+			balance.Available += posting.Amount
+		}
+	}
+	l.entries = append(l.entries, record.Entry)
+
+	switch record.Entry.Type {
+	case EntryTypeHold:
+		l.holds[record.Order.ID] = l.newHold(*record.Order, record.Fee)
+	case EntryTypeRelease:
+		delete(l.holds, record.Entry.Reference)
+	case EntryTypeSettle:
+		l.settle(*record.Fill)
+	}
+}
+
+// available returns the available balance of an account asset, without creating it
+func (l *Ledger) available(accountID, asset string) float64 {
+	if balance, exists := l.balances[accountID][asset]; exists {
+		return balance.Available
+	}
+	return 0
+}
+
+// balance returns the balance of an account asset, creating an empty one if absent
+func (l *Ledger) balance(accountID, asset string) *Balance {
+	assets, exists := l.balances[accountID]
+	if !exists {
+		assets = make(map[string]*Balance)
+		l.balances[accountID] = assets
+	}
+
+	balance, exists := assets[asset]
+	if !exists {
+		balance = &Balance{Asset: asset}
+		assets[asset] = balance
+	}
+	return balance
+}
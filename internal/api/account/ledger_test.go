@@ -0,0 +1,345 @@
+package account
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	"github.com/Hao1995/order-matching-system/pkg/journalkit"
+)
+
+type LedgerTestSuite struct {
+	suite.Suite
+	ledger *Ledger
+
+	symbol string
+	quote  string
+	now    time.Time
+}
+
+func TestLedgerTestSuite(t *testing.T) {
+	suite.Run(t, new(LedgerTestSuite))
+}
+
+func (suite *LedgerTestSuite) SetupSuite() {
+	suite.symbol = "AAPL"
+	suite.quote = "USD"
+	suite.now = time.Date(2025, 1, 15, 18, 0, 0, 0, time.UTC)
+
+	now = func() time.Time {
+		return suite.now
+	}
+}
+
+func (suite *LedgerTestSuite) SetupTest() {
+	suite.ledger = NewLedger(suite.quote)
+
+	_, err := suite.ledger.Deposit("buyer", suite.quote, 1000)
+	suite.Require().NoError(err)
+	_, err = suite.ledger.Deposit("seller", suite.symbol, 10)
+	suite.Require().NoError(err)
+}
+
+func (suite *LedgerTestSuite) TestHold_InsufficientBalance() {
+	_, err := suite.ledger.Hold(Order{
+		ID:        "buy1",
+		AccountID: "buyer",
+		Symbol:    suite.symbol,
+		Side:      SideBuy,
+		Price:     101.0,
+		Quantity:  10,
+	})
+	suite.ErrorIs(err, ErrInsufficientBalance)
+
+	_, err = suite.ledger.Hold(Order{
+		ID:        "sell1",
+		AccountID: "seller",
+		Symbol:    suite.symbol,
+		Side:      SideSell,
+		Price:     100.0,
+		Quantity:  11,
+	})
+	suite.ErrorIs(err, ErrInsufficientBalance)
+	suite.Equal([]Balance{{Asset: suite.quote, Available: 1000}}, suite.ledger.Balances("buyer"))
+}
+
+func (suite *LedgerTestSuite) TestHoldAndRelease() {
+	_, err := suite.ledger.Hold(Order{
+		ID:        "buy1",
+		AccountID: "buyer",
+		Symbol:    suite.symbol,
+		Side:      SideBuy,
+		Price:     100.0,
+		Quantity:  4,
+	})
+	suite.NoError(err)
+	suite.Equal([]Balance{{Asset: suite.quote, Available: 600, Held: 400}}, suite.ledger.Balances("buyer"))
+
+	_, err = suite.ledger.Release("buy1")
+	suite.NoError(err)
+	suite.Equal([]Balance{{Asset: suite.quote, Available: 1000}}, suite.ledger.Balances("buyer"))
+
+	_, err = suite.ledger.Release("buy1")
+	suite.ErrorIs(err, ErrHoldNotFound)
+}
+
+func (suite *LedgerTestSuite) TestSettler_MassCancel() {
+	for _, id := range []string{"buy1", "buy2"} {
+		_, err := suite.ledger.Hold(Order{ID: id, AccountID: "buyer", Symbol: suite.symbol, Side: SideBuy, Price: 100.0, Quantity: 2})
+		suite.Require().NoError(err)
+	}
+
+	// An order without a hold is passed
+	val, err := events.JSONCodec.EncodeMatchingEvent(events.MatchingEvent{
+		Type:      events.MatchingEventTypeMassCancel,
+		Order:     events.OrderEvent{ID: "mass1", AccountID: "buyer", Symbol: suite.symbol},
+		Cancelled: []events.OrderEvent{{ID: "buy1"}, {ID: "other"}, {ID: "buy2"}},
+	})
+	suite.Require().NoError(err)
+	suite.NoError(NewSettler(suite.ledger).Handle(val))
+	suite.Equal([]Balance{{Asset: suite.quote, Available: 1000}}, suite.ledger.Balances("buyer"))
+}
+
+func (suite *LedgerTestSuite) TestSettle() {
+	_, err := suite.ledger.Hold(Order{
+		ID:        "sell1",
+		AccountID: "seller",
+		Symbol:    suite.symbol,
+		Side:      SideSell,
+		Price:     99.0,
+		Quantity:  5,
+	})
+	suite.Require().NoError(err)
+	_, err = suite.ledger.Hold(Order{
+		ID:        "buy1",
+		AccountID: "buyer",
+		Symbol:    suite.symbol,
+		Side:      SideBuy,
+		Price:     100.0,
+		Quantity:  8,
+	})
+	suite.Require().NoError(err)
+
+	// The buyer is charged the fill price and the rest of its limit price is released
+	_, err = suite.ledger.Settle(Fill{
+		ID:          "tx1",
+		BuyOrderID:  "buy1",
+		SellOrderID: "sell1",
+		Price:       99.0,
+		Quantity:    5,
+	})
+	suite.NoError(err)
+	suite.Equal([]Balance{
+		{Asset: suite.symbol, Available: 5},
+		{Asset: suite.quote, Available: 205, Held: 300},
+	}, suite.ledger.Balances("buyer"))
+	suite.Equal([]Balance{
+		{Asset: suite.symbol, Available: 5},
+		{Asset: suite.quote, Available: 495},
+	}, suite.ledger.Balances("seller"))
+
+	// The filled sell order no longer holds anything
+	_, err = suite.ledger.Release("sell1")
+	suite.ErrorIs(err, ErrHoldNotFound)
+
+	_, err = suite.ledger.Release("buy1")
+	suite.NoError(err)
+	suite.Equal([]Balance{
+		{Asset: suite.symbol, Available: 5},
+		{Asset: suite.quote, Available: 505},
+	}, suite.ledger.Balances("buyer"))
+}
+
+func (suite *LedgerTestSuite) TestEntries_Balanced() {
+	_, err := suite.ledger.Hold(Order{
+		ID:        "buy1",
+		AccountID: "buyer",
+		Symbol:    suite.symbol,
+		Side:      SideBuy,
+		Price:     100.0,
+		Quantity:  1,
+	})
+	suite.Require().NoError(err)
+
+	entries := suite.ledger.Entries("buyer")
+	suite.Len(entries, 2)
+	suite.Equal(EntryTypeDeposit, entries[0].Type)
+	suite.Equal(EntryTypeHold, entries[1].Type)
+	suite.Equal("buy1", entries[1].Reference)
+	for _, entry := range entries {
+		var sum float64
+		for _, posting := range entry.Postings {
+			sum += posting.Amount
+		}
+		suite.Zero(sum)
+		suite.Equal(suite.now, entry.CreatedAt)
+	}
+}
+
+func (suite *LedgerTestSuite) TestSettle_Fees() {
+	_, err := suite.ledger.Hold(Order{
+		ID:        "sell1",
+		AccountID: "seller",
+		Symbol:    suite.symbol,
+		Side:      SideSell,
+		Price:     100.0,
+		Quantity:  5,
+	})
+	suite.Require().NoError(err)
+	_, err = suite.ledger.Hold(Order{
+		ID:        "buy1",
+		AccountID: "buyer",
+		Symbol:    suite.symbol,
+		Side:      SideBuy,
+		Price:     100.0,
+		Quantity:  5,
+	})
+	suite.Require().NoError(err)
+
+	_, err = suite.ledger.Settle(Fill{
+		ID:          "tx1",
+		BuyOrderID:  "buy1",
+		SellOrderID: "sell1",
+		Price:       100.0,
+		Quantity:    5,
+		BuyFee:      Fee{Amount: 1, Currency: suite.quote},
+		SellFee:     Fee{Amount: -0.5, Currency: suite.quote},
+	})
+	suite.NoError(err)
+	suite.Equal([]Balance{
+		{Asset: suite.symbol, Available: 5},
+		{Asset: suite.quote, Available: 499},
+	}, suite.ledger.Balances("buyer"))
+	suite.Equal([]Balance{
+		{Asset: suite.symbol, Available: 5},
+		{Asset: suite.quote, Available: 500.5},
+	}, suite.ledger.Balances("seller"))
+	suite.Equal([]Balance{{Asset: suite.quote, Available: 0.5}}, suite.ledger.Balances(FeeAccountID))
+}
+
+func (suite *LedgerTestSuite) assertBalance(ledger *Ledger, accountID, asset string, available, held float64) {
+	for _, balance := range ledger.Balances(accountID) {
+		if balance.Asset == asset {
+			suite.InDelta(available, balance.Available, epsilon, "%s %s available", accountID, asset)
+			suite.InDelta(held, balance.Held, epsilon, "%s %s held", accountID, asset)
+			return
+		}
+	}
+	suite.Failf("no balance", "%s %s", accountID, asset)
+}
+
+func (suite *LedgerTestSuite) TestSettle_ReservedFees() {
+	ledger := NewLedger(suite.quote, WithMaxFeeBps(20))
+	_, err := ledger.Deposit("buyer", suite.quote, 1001)
+	suite.Require().NoError(err)
+	_, err = ledger.Deposit("seller", suite.symbol, 5)
+	suite.Require().NoError(err)
+
+	// The fee of a sell is reserved in the quote asset beside the base asset
+	sell := Order{ID: "sell1", AccountID: "seller", Symbol: suite.symbol, Side: SideSell, Price: 100.0, Quantity: 5}
+	_, err = ledger.Hold(sell)
+	suite.ErrorIs(err, ErrInsufficientBalance)
+	suite.Equal([]Balance{{Asset: suite.symbol, Available: 5}}, ledger.Balances("seller"))
+	_, err = ledger.Deposit("seller", suite.quote, 1)
+	suite.Require().NoError(err)
+	_, err = ledger.Hold(sell)
+	suite.Require().NoError(err)
+	suite.assertBalance(ledger, "seller", suite.symbol, 0, 5)
+	suite.assertBalance(ledger, "seller", suite.quote, 0, 1)
+
+	// The fee of a buy is reserved with its notional
+	_, err = ledger.Hold(Order{ID: "buy1", AccountID: "buyer", Symbol: suite.symbol, Side: SideBuy, Price: 100.0, Quantity: 5})
+	suite.Require().NoError(err)
+	suite.assertBalance(ledger, "buyer", suite.quote, 500, 501)
+	_, err = ledger.Hold(Order{ID: "buy2", AccountID: "buyer", Symbol: suite.symbol, Side: SideBuy, Price: 100.0, Quantity: 5})
+	suite.ErrorIs(err, ErrInsufficientBalance)
+
+	// The fees of a partial fill are taken from the reserved fees of its quantity, and the rest of
+	// them is available again, a rebate included
+	_, err = ledger.Settle(Fill{
+		ID:          "tx1",
+		BuyOrderID:  "buy1",
+		SellOrderID: "sell1",
+		Price:       100.0,
+		Quantity:    3,
+		BuyFee:      Fee{Amount: 0.45, Currency: suite.quote},
+		SellFee:     Fee{Amount: -0.06, Currency: suite.quote},
+	})
+	suite.Require().NoError(err)
+	suite.assertBalance(ledger, "buyer", suite.quote, 500.15, 200.4)
+	suite.assertBalance(ledger, "buyer", suite.symbol, 3, 0)
+	suite.assertBalance(ledger, "seller", suite.quote, 300.66, 0.4)
+	suite.assertBalance(ledger, "seller", suite.symbol, 0, 2)
+	suite.assertBalance(ledger, FeeAccountID, suite.quote, 0.39, 0)
+
+	// The reserved fees of the remaining quantity are released with the order
+	_, err = ledger.Release("buy1")
+	suite.Require().NoError(err)
+	suite.assertBalance(ledger, "buyer", suite.quote, 700.55, 0)
+	_, err = ledger.Release("sell1")
+	suite.Require().NoError(err)
+	suite.assertBalance(ledger, "seller", suite.quote, 301.06, 0)
+	suite.assertBalance(ledger, "seller", suite.symbol, 2, 0)
+}
+
+func (suite *LedgerTestSuite) TestReplay() {
+	dir := suite.T().TempDir()
+	journal, err := journalkit.Open(dir, journalkit.Options{})
+	suite.Require().NoError(err)
+	ledger := NewLedger(suite.quote, WithMaxFeeBps(10), WithJournal(journal))
+	_, err = ledger.Deposit("buyer", suite.quote, 1000)
+	suite.Require().NoError(err)
+	_, err = ledger.Deposit("seller", suite.symbol, 10)
+	suite.Require().NoError(err)
+	_, err = ledger.Deposit("seller", suite.quote, 1)
+	suite.Require().NoError(err)
+	_, err = ledger.Hold(Order{ID: "buy1", AccountID: "buyer", Symbol: suite.symbol, Side: SideBuy, Price: 100.0, Quantity: 5})
+	suite.Require().NoError(err)
+	_, err = ledger.Hold(Order{ID: "sell1", AccountID: "seller", Symbol: suite.symbol, Side: SideSell, Price: 100.0, Quantity: 5})
+	suite.Require().NoError(err)
+	fill := Fill{ID: "tx1", BuyOrderID: "buy1", SellOrderID: "sell1", Price: 100.0, Quantity: 3, BuyFee: Fee{Amount: 0.3, Currency: suite.quote}}
+	_, err = ledger.Settle(fill)
+	suite.Require().NoError(err)
+	suite.Require().NoError(journal.Close())
+
+	// The replayed ledger has the balances, the holds and the settled fills of the journal
+	replayed := NewLedger(suite.quote, WithMaxFeeBps(10))
+	suite.Require().NoError(replayed.Replay(dir))
+	suite.Equal(ledger.Balances("buyer"), replayed.Balances("buyer"))
+	suite.Equal(ledger.Balances("seller"), replayed.Balances("seller"))
+	suite.Equal(ledger.Entries("buyer"), replayed.Entries("buyer"))
+	order, ok := replayed.Order("buy1")
+	suite.True(ok)
+	suite.Equal(int64(2), order.Quantity)
+	_, err = replayed.Settle(fill)
+	suite.ErrorIs(err, ErrAlreadySettled)
+
+	_, err = replayed.Release("buy1")
+	suite.Require().NoError(err)
+	suite.assertBalance(replayed, "buyer", suite.quote, 699.7, 0)
+}
+
+func (suite *LedgerTestSuite) TestSettle_SettledWindow() {
+	ledger := NewLedger(suite.quote, WithSettledWindow(1))
+	_, err := ledger.Deposit("buyer", suite.quote, 1000)
+	suite.Require().NoError(err)
+	_, err = ledger.Deposit("seller", suite.symbol, 10)
+	suite.Require().NoError(err)
+	_, err = ledger.Hold(Order{ID: "buy1", AccountID: "buyer", Symbol: suite.symbol, Side: SideBuy, Price: 100.0, Quantity: 5})
+	suite.Require().NoError(err)
+	_, err = ledger.Hold(Order{ID: "sell1", AccountID: "seller", Symbol: suite.symbol, Side: SideSell, Price: 100.0, Quantity: 5})
+	suite.Require().NoError(err)
+
+	for _, id := range []string{"tx1", "tx2"} {
+		_, err = ledger.Settle(Fill{ID: id, BuyOrderID: "buy1", SellOrderID: "sell1", Price: 100.0, Quantity: 1})
+		suite.Require().NoError(err)
+	}
+
+	// Only the latest settled fills are remembered
+	_, err = ledger.Settle(Fill{ID: "tx2", BuyOrderID: "buy1", SellOrderID: "sell1", Price: 100.0, Quantity: 1})
+	suite.ErrorIs(err, ErrAlreadySettled)
+	suite.Len(ledger.settled, 1)
+	suite.Equal([]string{"tx2"}, ledger.settledIDs)
+}
@@ -0,0 +1,5 @@
+package requests
+
+type AccountRequest struct {
+	ID string `uri:"id" binding:"required"`
+}
@@ -0,0 +1,6 @@
+package requests
+
+type DepositRequest struct {
+	Asset  string  `form:"asset" binding:"required"`
+	Amount float64 `form:"amount" binding:"required,gt=0"`
+}
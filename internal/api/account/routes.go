@@ -0,0 +1,15 @@
+package account
+
+import "github.com/gin-gonic/gin"
+
+// RegisterRoutes registers the reads of the accounts, which are scoped to the authenticated
+// account
+func RegisterRoutes(r gin.IRouter, handler *Handler) {
+	r.GET("/accounts/:id/balances", handler.Balances)
+	r.GET("/accounts/:id/entries", handler.Entries)
+}
+
+// RegisterAdminRoutes registers the deposits, which must sit behind an admin credential
+func RegisterAdminRoutes(r gin.IRouter, handler *Handler) {
+	r.POST("/accounts/:id/deposits", handler.Deposit)
+}
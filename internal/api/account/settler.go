@@ -0,0 +1,108 @@
+package account
+
+import (
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	"github.com/Hao1995/order-matching-system/pkg/logger"
+)
+
+// ErrUnsettled is wrapped by the errors of a matching event whose fills or releases did not apply,
+// which is delivered again rather than skipped
+var ErrUnsettled = errors.New("matching event not settled")
+
+// Settler applies matching events to the ledger: fills move funds, cancels and rejections release
+// holds. The matching events are delivered at least once, so a settled fill is passed.
+type Settler struct {
+	ledger *Ledger
+	// settled is called after a fill is settled, if set
+	settled func(fill Fill, buy, sell Order)
+}
+
+// SettlerOption configures optional behaviors of a Settler
+type SettlerOption func(*Settler)
+
+// WithSettled calls the callback with every settled fill and the orders it filled, as they
+// were before the fill
+func WithSettled(settled func(fill Fill, buy, sell Order)) SettlerOption {
+	return func(s *Settler) {
+		s.settled = settled
+	}
+}
+
+func NewSettler(ledger *Ledger, opts ...SettlerOption) *Settler {
+	s := &Settler{
+		ledger: ledger,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Handle decodes a JSON matching event and settles it in the ledger
+func (s *Settler) Handle(val []byte) error {
+	return s.HandleEncoded(events.JSONCodec, val)
+}
+
+// HandleEncoded is Handle for a matching event encoded by the codec
+func (s *Settler) HandleEncoded(codec events.Codec, val []byte) error {
+	matchingEvent, err := codec.DecodeMatchingEvent(val)
+	if err != nil {
+		logger.Error("failed to decode matching event", zap.Error(err), zap.ByteString("val", val))
+		return err
+	}
+
+	switch matchingEvent.Type {
+	case events.MatchingEventTypeCreate, events.MatchingEventTypeUncross:
+		for _, transaction := range matchingEvent.Transactions {
+			fill := Fill{
+				ID:          transaction.ID,
+				BuyOrderID:  transaction.BuyOrderID,
+				SellOrderID: transaction.SellOrderID,
+				Price:       transaction.Price,
+				Quantity:    transaction.Quantity,
+				BuyFee:      Fee{Amount: transaction.BuyFee.Amount, Currency: transaction.BuyFee.Currency},
+				SellFee:     Fee{Amount: transaction.SellFee.Amount, Currency: transaction.SellFee.Currency},
+			}
+			// A filled order leaves the ledger once settled
+			buy, _ := s.ledger.Order(fill.BuyOrderID)
+			sell, _ := s.ledger.Order(fill.SellOrderID)
+			if _, err := s.ledger.Settle(fill); err != nil {
+				if errors.Is(err, ErrAlreadySettled) {
+					logger.Warn("transaction already settled, pass it", zap.String("transactionID", fill.ID))
+					continue
+				}
+				logger.Error("failed to settle transaction", zap.Error(err), zap.Any("transaction", transaction))
+				return fmt.Errorf("%w: %w", ErrUnsettled, err)
+			}
+			if s.settled != nil {
+				s.settled(fill, buy, sell)
+			}
+		}
+	case events.MatchingEventTypeCancel, events.MatchingEventTypeReject:
+		return s.release(matchingEvent.Order.ID)
+	case events.MatchingEventTypeMassCancel:
+		for _, orderEvent := range matchingEvent.Cancelled {
+			if err := s.release(orderEvent.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// release releases the hold of an order, an order without a hold is passed
+func (s *Settler) release(orderID string) error {
+	if _, err := s.ledger.Release(orderID); err != nil {
+		if errors.Is(err, ErrHoldNotFound) {
+			logger.Warn("no hold to release, pass it", zap.String("orderID", orderID))
+			return nil
+		}
+		return fmt.Errorf("%w: %w", ErrUnsettled, err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package account
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+)
+
+type SettlerTestSuite struct {
+	suite.Suite
+	ledger  *Ledger
+	settler *Settler
+	settled []Fill
+	orders  [][2]Order
+}
+
+func TestSettlerTestSuite(t *testing.T) {
+	suite.Run(t, new(SettlerTestSuite))
+}
+
+func (suite *SettlerTestSuite) SetupTest() {
+	suite.ledger = NewLedger("USD")
+	_, err := suite.ledger.Deposit("buyer", "USD", 1000)
+	suite.Require().NoError(err)
+	_, err = suite.ledger.Deposit("seller", "AAPL", 10)
+	suite.Require().NoError(err)
+	_, err = suite.ledger.Hold(Order{ID: "buy1", AccountID: "buyer", Symbol: "AAPL", Side: SideBuy, Price: 100.0, Quantity: 5})
+	suite.Require().NoError(err)
+	_, err = suite.ledger.Hold(Order{ID: "sell1", AccountID: "seller", Symbol: "AAPL", Side: SideSell, Price: 99.0, Quantity: 10})
+	suite.Require().NoError(err)
+
+	suite.settled, suite.orders = nil, nil
+	suite.settler = NewSettler(suite.ledger, WithSettled(func(fill Fill, buy, sell Order) {
+		suite.settled = append(suite.settled, fill)
+		suite.orders = append(suite.orders, [2]Order{buy, sell})
+	}))
+}
+
+func (suite *SettlerTestSuite) handle(matchingEvent events.MatchingEvent) error {
+	val, err := events.ProtobufCodec.EncodeMatchingEvent(matchingEvent)
+	suite.Require().NoError(err)
+	return suite.settler.HandleEncoded(events.ProtobufCodec, val)
+}
+
+func (suite *SettlerTestSuite) TestHandle_Fill() {
+	transaction := events.TransactionEvent{ID: "transaction1", BuyOrderID: "buy1", SellOrderID: "sell1", Price: 99.0, Quantity: 4}
+	suite.Require().NoError(suite.handle(events.MatchingEvent{
+		Type:         events.MatchingEventTypeCreate,
+		Order:        events.OrderEvent{ID: "buy1"},
+		Transactions: []events.TransactionEvent{transaction},
+	}))
+
+	// The buyer pays the fill price, and keeps the rest of its hold for the remaining quantity
+	suite.Equal([]Balance{{Asset: "AAPL", Available: 4}, {Asset: "USD", Available: 504, Held: 100}}, suite.ledger.Balances("buyer"))
+	suite.Equal([]Balance{{Asset: "AAPL", Held: 6}, {Asset: "USD", Available: 396}}, suite.ledger.Balances("seller"))
+
+	// The callback has the orders as they were before the fill
+	suite.Equal([]Fill{{ID: "transaction1", BuyOrderID: "buy1", SellOrderID: "sell1", Price: 99.0, Quantity: 4}}, suite.settled)
+	suite.Require().Len(suite.orders, 1)
+	suite.Equal(int64(5), suite.orders[0][0].Quantity)
+	suite.Equal(int64(10), suite.orders[0][1].Quantity)
+}
+
+func (suite *SettlerTestSuite) TestHandle_FillRedelivered() {
+	matchingEvent := events.MatchingEvent{
+		Type:         events.MatchingEventTypeCreate,
+		Order:        events.OrderEvent{ID: "buy1"},
+		Transactions: []events.TransactionEvent{{ID: "transaction1", BuyOrderID: "buy1", SellOrderID: "sell1", Price: 99.0, Quantity: 5}},
+	}
+	suite.Require().NoError(suite.handle(matchingEvent))
+
+	// The fill consumed the hold of the buy order, and is passed when delivered again
+	suite.Require().NoError(suite.handle(matchingEvent))
+	suite.Equal([]Balance{{Asset: "AAPL", Available: 5}, {Asset: "USD", Available: 505}}, suite.ledger.Balances("buyer"))
+	suite.Equal([]Balance{{Asset: "AAPL", Held: 5}, {Asset: "USD", Available: 495}}, suite.ledger.Balances("seller"))
+	suite.Len(suite.settled, 1)
+
+	_, err := suite.ledger.Settle(Fill{ID: "transaction1", BuyOrderID: "buy1", SellOrderID: "sell1", Price: 99.0, Quantity: 5})
+	suite.ErrorIs(err, ErrAlreadySettled)
+}
+
+func (suite *SettlerTestSuite) TestHandle_FillWithoutHold() {
+	err := suite.handle(events.MatchingEvent{
+		Type:         events.MatchingEventTypeCreate,
+		Order:        events.OrderEvent{ID: "buy2"},
+		Transactions: []events.TransactionEvent{{ID: "transaction1", BuyOrderID: "buy2", SellOrderID: "sell1", Price: 99.0, Quantity: 4}},
+	})
+	suite.ErrorIs(err, ErrHoldNotFound)
+	suite.ErrorIs(err, ErrUnsettled)
+	suite.Empty(suite.settled)
+	suite.Equal([]Balance{{Asset: "AAPL", Held: 10}}, suite.ledger.Balances("seller"))
+}
+
+func (suite *SettlerTestSuite) TestHandle_Release() {
+	for _, tc := range []struct {
+		matchingEvent events.MatchingEvent
+		accountID     string
+		expected      []Balance
+	}{
+		{events.MatchingEvent{Type: events.MatchingEventTypeCancel, Order: events.OrderEvent{ID: "buy1"}}, "buyer", []Balance{{Asset: "USD", Available: 1000}}},
+		{events.MatchingEvent{Type: events.MatchingEventTypeReject, Order: events.OrderEvent{ID: "sell1"}}, "seller", []Balance{{Asset: "AAPL", Available: 10}}},
+		// An order without a hold is passed
+		{events.MatchingEvent{Type: events.MatchingEventTypeCancel, Order: events.OrderEvent{ID: "buy1"}}, "buyer", []Balance{{Asset: "USD", Available: 1000}}},
+	} {
+		suite.Require().NoError(suite.handle(tc.matchingEvent))
+		suite.Equal(tc.expected, suite.ledger.Balances(tc.accountID))
+	}
+}
+
+func (suite *SettlerTestSuite) TestHandle_Malformed() {
+	suite.Error(suite.settler.HandleEncoded(events.ProtobufCodec, []byte("malformed")))
+	suite.Error(suite.settler.Handle([]byte("{")))
+}
@@ -0,0 +1,116 @@
+package admin
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/Hao1995/order-matching-system/internal/api/admin/requests"
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	"github.com/Hao1995/order-matching-system/pkg/logger"
+	"github.com/Hao1995/order-matching-system/pkg/mqkit"
+)
+
+var (
+	now = func() time.Time {
+		return time.Now()
+	}
+)
+
+type Handler struct {
+	producer mqkit.Producer
+	codec    events.Codec
+}
+
+func NewHandler(p mqkit.Producer, codec events.Codec) *Handler {
+	return &Handler{
+		producer: p,
+		codec:    codec,
+	}
+}
+
+// Halt handles the manual halt of a symbol.
+func (hlr *Handler) Halt(c *gin.Context) {
+	hlr.publishTradingStatus(c, events.EventTypeHaltTrading)
+}
+
+// Resume handles the manual resume of a halted symbol.
+func (hlr *Handler) Resume(c *gin.Context) {
+	hlr.publishTradingStatus(c, events.EventTypeResumeTrading)
+}
+
+// StartAuction handles the start of a call auction on a symbol.
+func (hlr *Handler) StartAuction(c *gin.Context) {
+	var symbol requests.SymbolRequest
+	if err := c.ShouldBindUri(&symbol); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	var request requests.AuctionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid auction data"})
+		return
+	}
+
+	statusEvent := events.TradingStatusEvent{
+		Symbol: symbol.Symbol,
+		Reason: request.Reason,
+	}
+	if request.DurationSeconds > 0 {
+		statusEvent.EndsAt = now().Add(time.Duration(request.DurationSeconds) * time.Second)
+	}
+	hlr.publish(c, events.EventTypeStartAuction, statusEvent)
+}
+
+// publishTradingStatus sends the trading status event through the order topic so that it is
+// applied in order with the order events
+func (hlr *Handler) publishTradingStatus(c *gin.Context, eventType events.EventType) {
+	var symbol requests.SymbolRequest
+	if err := c.ShouldBindUri(&symbol); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	var request requests.TradingStatusRequest
+	if err := c.ShouldBindJSON(&request); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trading status data"})
+		return
+	}
+
+	hlr.publish(c, eventType, events.TradingStatusEvent{
+		Symbol: symbol.Symbol,
+		Reason: request.Reason,
+	})
+}
+
+// publish sends the trading status event through the order topic
+func (hlr *Handler) publish(c *gin.Context, eventType events.EventType, statusEvent events.TradingStatusEvent) {
+	event := events.Event{
+		EventType: eventType,
+		Data:      statusEvent,
+		CreatedAt: now(),
+	}
+
+	val, err := hlr.codec.EncodeEvent(event)
+	if err != nil {
+		logger.Error("failed to encode event", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode"})
+		return
+	}
+
+	msg := mqkit.Message{
+		Key:     []byte(statusEvent.Symbol),
+		Value:   val,
+		Headers: map[string]string{events.ContentTypeHeader: hlr.codec.ContentType()},
+	}
+	if err := hlr.producer.PublishBatch(c.Request.Context(), []mqkit.Message{msg}); err != nil {
+		logger.Error("failed to publish trading status event", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed create a " + eventType.String() + " request"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "The " + eventType.String() + " request has been accepted"})
+}
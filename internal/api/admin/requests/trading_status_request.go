@@ -0,0 +1,15 @@
+package requests
+
+type SymbolRequest struct {
+	Symbol string `uri:"symbol" binding:"required"`
+}
+
+type TradingStatusRequest struct {
+	Reason string `form:"reason"`
+}
+
+type AuctionRequest struct {
+	Reason string `form:"reason"`
+	// DurationSeconds is how long the auction lasts, 0 uncrosses it manually
+	DurationSeconds int64 `form:"duration_seconds" json:"duration_seconds" binding:"gte=0"`
+}
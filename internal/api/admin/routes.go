@@ -0,0 +1,9 @@
+package admin
+
+import "github.com/gin-gonic/gin"
+
+func RegisterRoutes(r gin.IRouter, handler *Handler) {
+	r.POST("/admin/symbols/:symbol/halt", handler.Halt)
+	r.POST("/admin/symbols/:symbol/resume", handler.Resume)
+	r.POST("/admin/symbols/:symbol/auction", handler.StartAuction)
+}
@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/Hao1995/order-matching-system/pkg/logger"
+)
+
+const (
+	// KeyHeader, TimestampHeader and SignatureHeader are the headers of a signed request, the
+	// timestamp is in Unix seconds and the signature is the hex of the Sign of the request
+	KeyHeader       = "X-API-Key"
+	TimestampHeader = "X-API-Timestamp"
+	SignatureHeader = "X-API-Signature"
+)
+
+var (
+	now = func() time.Time {
+		return time.Now()
+	}
+
+	ErrInvalidKey       = errors.New("invalid API key")
+	ErrUnknownKey       = errors.New("unknown API key")
+	ErrMissingHeader    = errors.New("missing header")
+	ErrInvalidTimestamp = errors.New("invalid timestamp")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrInvalidSecret    = errors.New("invalid secret")
+	ErrReplayed         = errors.New("replayed request")
+)
+
+// Config configures the API keys of the accounts
+type Config struct {
+	// Keys are separated by commas, as <key>:<account>:<secret>
+	Keys []Key `env:"API_KEYS,required"`
+	// AdminKeys are the keys of the operators, as <key>:<operator>:<secret>, which alone may
+	// deposit funds and control the trading of the symbols
+	AdminKeys []Key `env:"ADMIN_KEYS"`
+	// ReplayWindow is how far the timestamp of a request may be from now, a signature is
+	// accepted once within it
+	ReplayWindow time.Duration `env:"REPLAY_WINDOW" envDefault:"30s"`
+}
+
+// Key is an API key of an account and the secret of its signatures
+type Key struct {
+	ID        string
+	AccountID string
+	Secret    string
+}
+
+// UnmarshalText parses a key of <key>:<account>:<secret>, the secret may contain colons
+func (k *Key) UnmarshalText(text []byte) error {
+	parts := strings.SplitN(string(text), ":", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return fmt.Errorf("%w: want <key>:<account>:<secret>", ErrInvalidKey)
+	}
+	k.ID, k.AccountID, k.Secret = parts[0], parts[1], parts[2]
+	return nil
+}
+
+// Sign returns the hex of the HMAC-SHA256 of the method, the path with its query, the timestamp
+// and the body of a request, separated by newlines
+func Sign(secret, method, path, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + path + "\n" + timestamp + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Authenticator authenticates the signed requests by the API keys of the accounts
+type Authenticator struct {
+	keys      map[string]Key
+	adminKeys map[string]Key
+	window    time.Duration
+
+	mu sync.Mutex
+	// seen are the signatures accepted within the window
+	seen map[string]struct{}
+	// expiries are the seen signatures in the order they expire, which is the order they are
+	// accepted in since every one expires after the same duration
+	expiries []expiry
+}
+
+type expiry struct {
+	signature string
+	expiresAt time.Time
+}
+
+func NewAuthenticator(cfg Config) *Authenticator {
+	return &Authenticator{
+		keys:      keyMap(cfg.Keys),
+		adminKeys: keyMap(cfg.AdminKeys),
+		window:    cfg.ReplayWindow,
+		seen:      map[string]struct{}{},
+	}
+}
+
+func keyMap(keys []Key) map[string]Key {
+	result := make(map[string]Key, len(keys))
+	for _, key := range keys {
+		result[key.ID] = key
+	}
+	return result
+}
+
+// Login authenticates an API key by its secret, for the sessions which log on once instead of
+// signing every request, e.g. the FIX sessions
+func (a *Authenticator) Login(keyID, secret string) (Key, error) {
+	key, ok := a.keys[keyID]
+	if !ok {
+		return Key{}, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	if !hmac.Equal([]byte(secret), []byte(key.Secret)) {
+		return Key{}, fmt.Errorf("%w: %s", ErrInvalidSecret, keyID)
+	}
+	return key, nil
+}
+
+// Middleware rejects the requests which are not signed by a known API key with 401, and puts
+// the account of the key in the context of the others
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, err := a.authenticate(a.keys, c.Request)
+		if err != nil {
+			logger.Warn("failed to authenticate request", zap.Error(err), zap.String("key", c.GetHeader(KeyHeader)))
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), key.AccountID))
+		c.Next()
+	}
+}
+
+// AdminMiddleware rejects the requests which are not signed by an admin key with 401
+func (a *Authenticator) AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, err := a.authenticate(a.adminKeys, c.Request)
+		if err != nil {
+			logger.Warn("failed to authenticate admin request", zap.Error(err), zap.String("key", c.GetHeader(KeyHeader)))
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		logger.Info("admin request", zap.String("operator", key.AccountID), zap.String("method", c.Request.Method), zap.String("path", c.Request.URL.Path))
+		c.Next()
+	}
+}
+
+// authenticate verifies the signature of the request by one of the keys, and returns the key.
+// The body is read and put back for the handlers.
+func (a *Authenticator) authenticate(keys map[string]Key, r *http.Request) (Key, error) {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return Key{}, err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return a.verify(keys, r.Header.Get(KeyHeader), r.Header.Get(TimestampHeader), r.Header.Get(SignatureHeader), r.Method, r.URL.RequestURI(), body)
+}
+
+// verify verifies the Sign of a request by one of the keys at the timestamp, and returns the key
+func (a *Authenticator) verify(keys map[string]Key, keyID, timestamp, signature, method, path string, body []byte) (Key, error) {
+	if keyID == "" || timestamp == "" || signature == "" {
+		return Key{}, ErrMissingHeader
+	}
+	key, ok := keys[keyID]
+	if !ok {
+		return Key{}, ErrUnknownKey
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return Key{}, fmt.Errorf("%w: %w", ErrInvalidTimestamp, err)
+	}
+	current := now()
+	if skew := current.Sub(time.Unix(seconds, 0)); skew > a.window || skew < -a.window {
+		return Key{}, fmt.Errorf("%w: %s from now", ErrInvalidTimestamp, skew)
+	}
+
+	// The hex is case insensitive, so a replay in upper case is the same signature
+	signature = strings.ToLower(signature)
+	expected := Sign(key.Secret, method, path, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return Key{}, ErrInvalidSignature
+	}
+
+	if err := a.remember(signature, current); err != nil {
+		return Key{}, err
+	}
+	return key, nil
+}
+
+// remember accepts a signature once, and forgets the signatures out of the window
+func (a *Authenticator) remember(signature string, current time.Time) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	expired := 0
+	for ; expired < len(a.expiries) && !current.Before(a.expiries[expired].expiresAt); expired++ {
+		delete(a.seen, a.expiries[expired].signature)
+	}
+	a.expiries = a.expiries[expired:]
+
+	if _, ok := a.seen[signature]; ok {
+		return ErrReplayed
+	}
+	// The timestamp of the request is within the window until twice the window at most
+	a.seen[signature] = struct{}{}
+	a.expiries = append(a.expiries, expiry{signature: signature, expiresAt: current.Add(2 * a.window)})
+	return nil
+}
+
+type accountKey struct{}
+
+// NewContext returns a context of the authenticated account
+func NewContext(ctx context.Context, accountID string) context.Context {
+	return context.WithValue(ctx, accountKey{}, accountID)
+}
+
+// AccountID returns the authenticated account of the context, if any
+func AccountID(ctx context.Context) (string, bool) {
+	accountID, ok := ctx.Value(accountKey{}).(string)
+	return accountID, ok
+}
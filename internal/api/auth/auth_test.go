@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caarlos0/env/v11"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type AuthTestSuite struct {
+	suite.Suite
+	router     *gin.Engine
+	admin      *gin.Engine
+	current    time.Time
+	restoreNow func() time.Time
+}
+
+func TestAuthTestSuite(t *testing.T) {
+	suite.Run(t, new(AuthTestSuite))
+}
+
+func (suite *AuthTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	suite.current = time.Date(2025, 1, 15, 18, 0, 0, 0, time.UTC)
+	suite.restoreNow = now
+	now = func() time.Time {
+		return suite.current
+	}
+
+	authenticator := NewAuthenticator(Config{
+		Keys:         []Key{{ID: "alice-key", AccountID: "alice", Secret: "alice-secret"}},
+		AdminKeys:    []Key{{ID: "ops-key", AccountID: "ops", Secret: "ops-secret"}},
+		ReplayWindow: 30 * time.Second,
+	})
+	suite.admin = gin.New()
+	suite.admin.Use(authenticator.AdminMiddleware())
+	suite.admin.POST("/admin/symbols/AAPL/halt", func(c *gin.Context) {
+		_, ok := AccountID(c.Request.Context())
+		c.String(http.StatusOK, strconv.FormatBool(ok))
+	})
+	suite.router = gin.New()
+	suite.router.Use(authenticator.Middleware())
+	suite.router.POST("/orders", func(c *gin.Context) {
+		accountID, _ := AccountID(c.Request.Context())
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, accountID+" "+string(body))
+	})
+}
+
+func (suite *AuthTestSuite) TearDownTest() {
+	now = suite.restoreNow
+}
+
+// request sends a request signed with the secret at the timestamp
+func (suite *AuthTestSuite) request(path string, body string, timestamp time.Time, secret string) *httptest.ResponseRecorder {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader([]byte(body)))
+	req.Header.Set(KeyHeader, "alice-key")
+	req.Header.Set(TimestampHeader, ts)
+	req.Header.Set(SignatureHeader, Sign(secret, http.MethodPost, path, ts, []byte(body)))
+
+	recorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func (suite *AuthTestSuite) TestMiddleware() {
+	// The handler reads the signed body, with the account of the key
+	recorder := suite.request("/orders?symbol=AAPL", `{"symbol":"AAPL"}`, suite.current.Add(-10*time.Second), "alice-secret")
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Equal(`alice {"symbol":"AAPL"}`, recorder.Body.String())
+
+	// Another secret, or a timestamp out of the window
+	suite.Equal(http.StatusUnauthorized, suite.request("/orders", `{}`, suite.current, "bob-secret").Code)
+	suite.Equal(http.StatusUnauthorized, suite.request("/orders", `{}`, suite.current.Add(-31*time.Second), "alice-secret").Code)
+	suite.Equal(http.StatusUnauthorized, suite.request("/orders", `{}`, suite.current.Add(31*time.Second), "alice-secret").Code)
+
+	// A request without the headers or of an unknown key
+	recorder = httptest.NewRecorder()
+	suite.router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/orders", nil))
+	suite.Equal(http.StatusUnauthorized, recorder.Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	req.Header.Set(KeyHeader, "bob-key")
+	req.Header.Set(TimestampHeader, strconv.FormatInt(suite.current.Unix(), 10))
+	req.Header.Set(SignatureHeader, "00")
+	recorder = httptest.NewRecorder()
+	suite.router.ServeHTTP(recorder, req)
+	suite.Equal(http.StatusUnauthorized, recorder.Code)
+}
+
+func (suite *AuthTestSuite) TestMiddleware_Tampered() {
+	ts := strconv.FormatInt(suite.current.Unix(), 10)
+	signature := Sign("alice-secret", http.MethodPost, "/orders?side=Buy", ts, []byte(`{"quantity":1}`))
+
+	for _, tc := range []struct {
+		path string
+		body string
+	}{
+		{"/orders?side=Sell", `{"quantity":1}`},
+		{"/orders?side=Buy", `{"quantity":100}`},
+	} {
+		req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+		req.Header.Set(KeyHeader, "alice-key")
+		req.Header.Set(TimestampHeader, ts)
+		req.Header.Set(SignatureHeader, signature)
+		recorder := httptest.NewRecorder()
+		suite.router.ServeHTTP(recorder, req)
+		suite.Equal(http.StatusUnauthorized, recorder.Code, tc.path)
+	}
+}
+
+func (suite *AuthTestSuite) TestMiddleware_Replayed() {
+	sent := suite.current
+	suite.Equal(http.StatusOK, suite.request("/orders", `{}`, sent, "alice-secret").Code)
+	suite.Equal(http.StatusUnauthorized, suite.request("/orders", `{}`, sent, "alice-secret").Code)
+
+	// A replay in upper case hex is the same signature
+	ts := strconv.FormatInt(sent.Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{}`))
+	req.Header.Set(KeyHeader, "alice-key")
+	req.Header.Set(TimestampHeader, ts)
+	req.Header.Set(SignatureHeader, strings.ToUpper(Sign("alice-secret", http.MethodPost, "/orders", ts, []byte(`{}`))))
+	recorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(recorder, req)
+	suite.Equal(http.StatusUnauthorized, recorder.Code)
+
+	// The same request at another time is signed differently
+	suite.current = suite.current.Add(time.Second)
+	suite.Equal(http.StatusOK, suite.request("/orders", `{}`, suite.current, "alice-secret").Code)
+}
+
+func (suite *AuthTestSuite) TestRemember() {
+	authenticator := NewAuthenticator(Config{ReplayWindow: 30 * time.Second})
+	for i := 0; i < 3; i++ {
+		suite.Require().NoError(authenticator.remember(strconv.Itoa(i), suite.current.Add(time.Duration(i)*10*time.Second)))
+	}
+	suite.ErrorIs(authenticator.remember("0", suite.current.Add(59*time.Second)), ErrReplayed)
+
+	// Only the signatures out of the window are forgotten, from the oldest
+	suite.NoError(authenticator.remember("0", suite.current.Add(60*time.Second)))
+	suite.ErrorIs(authenticator.remember("1", suite.current.Add(60*time.Second)), ErrReplayed)
+	suite.Len(authenticator.seen, 3)
+	suite.Len(authenticator.expiries, 3)
+	suite.NoError(authenticator.remember("3", suite.current.Add(90*time.Second)))
+	suite.Equal([]expiry{
+		{signature: "0", expiresAt: suite.current.Add(120 * time.Second)},
+		{signature: "3", expiresAt: suite.current.Add(150 * time.Second)},
+	}, authenticator.expiries)
+	suite.Len(authenticator.seen, 2)
+}
+
+func (suite *AuthTestSuite) TestAdminMiddleware() {
+	ts := strconv.FormatInt(suite.current.Unix(), 10)
+	admin := func(keyID, secret string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/symbols/AAPL/halt", nil)
+		req.Header.Set(KeyHeader, keyID)
+		req.Header.Set(TimestampHeader, ts)
+		req.Header.Set(SignatureHeader, Sign(secret, http.MethodPost, "/admin/symbols/AAPL/halt", ts, nil))
+		recorder := httptest.NewRecorder()
+		suite.admin.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// An API key of an account is no admin credential, and an admin key acts for no account
+	suite.Equal(http.StatusUnauthorized, admin("alice-key", "alice-secret").Code)
+	recorder := admin("ops-key", "ops-secret")
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Equal("false", recorder.Body.String())
+	suite.Equal(http.StatusUnauthorized, admin("ops-key", "ops-secret").Code)
+	suite.Equal(http.StatusUnauthorized, suite.request("/orders", `{}`, suite.current, "ops-secret").Code)
+}
+
+func (suite *AuthTestSuite) TestLogin() {
+	authenticator := NewAuthenticator(Config{
+		Keys:      []Key{{ID: "alice-key", AccountID: "alice", Secret: "alice-secret"}},
+		AdminKeys: []Key{{ID: "ops-key", AccountID: "ops", Secret: "ops-secret"}},
+	})
+
+	key, err := authenticator.Login("alice-key", "alice-secret")
+	suite.Require().NoError(err)
+	suite.Equal("alice", key.AccountID)
+	_, err = authenticator.Login("alice-key", "ops-secret")
+	suite.ErrorIs(err, ErrInvalidSecret)
+	// An admin key logs on no session
+	_, err = authenticator.Login("ops-key", "ops-secret")
+	suite.ErrorIs(err, ErrUnknownKey)
+}
+
+func (suite *AuthTestSuite) TestConfig() {
+	var cfg Config
+	suite.Require().NoError(env.ParseWithOptions(&cfg, env.Options{Environment: map[string]string{
+		"API_KEYS":   "alice-key:alice:alice-secret,bob-key:bob:bob:secret",
+		"ADMIN_KEYS": "ops-key:ops:ops-secret",
+	}}))
+	suite.Equal([]Key{
+		{ID: "alice-key", AccountID: "alice", Secret: "alice-secret"},
+		{ID: "bob-key", AccountID: "bob", Secret: "bob:secret"},
+	}, cfg.Keys)
+	suite.Equal([]Key{{ID: "ops-key", AccountID: "ops", Secret: "ops-secret"}}, cfg.AdminKeys)
+	suite.Equal(30*time.Second, cfg.ReplayWindow)
+
+	err := env.ParseWithOptions(&cfg, env.Options{Environment: map[string]string{"API_KEYS": "alice-key:alice"}})
+	suite.ErrorContains(err, ErrInvalidKey.Error())
+}
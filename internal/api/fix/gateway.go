@@ -0,0 +1,562 @@
+package fix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/field"
+	"github.com/quickfixgo/fix44/newordersingle"
+	"github.com/quickfixgo/fix44/ordercancelreplacerequest"
+	"github.com/quickfixgo/fix44/ordercancelrequest"
+	"github.com/quickfixgo/quickfix"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/Hao1995/order-matching-system/internal/api/account"
+	"github.com/Hao1995/order-matching-system/internal/api/auth"
+	"github.com/Hao1995/order-matching-system/internal/api/order/requests"
+	"github.com/Hao1995/order-matching-system/internal/api/ratelimit"
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	"github.com/Hao1995/order-matching-system/pkg/logger"
+)
+
+var (
+	now = func() time.Time {
+		return time.Now()
+	}
+
+	getUUID = func() string {
+		return uuid.NewString()
+	}
+
+	// sendToTarget sends a message to the session, replaced by the tests
+	sendToTarget = quickfix.SendToTarget
+)
+
+// errRateLimited is returned by a call of the OrderService beyond the rate limit of its account
+var errRateLimited = errors.New("too many requests")
+
+const (
+	defaultRequestTimeout = 5 * time.Second
+
+	// pendingID is the order ID of a ClOrdID, and of the replacement of an order, while the
+	// OrderService places the order
+	pendingID = "PENDING"
+)
+
+// OrderService holds the funds of the orders and publishes their events, as order.Service does
+// for the REST and gRPC handlers. The calls are of the authenticated account of the context.
+type OrderService interface {
+	Create(ctx context.Context, request requests.CreateRequest) (string, error)
+	Cancel(ctx context.Context, id string) error
+	Amend(ctx context.Context, request requests.AmendRequest) (string, error)
+}
+
+// Authenticator authenticates the Username and the Password of a Logon as an API key and its
+// secret, as auth.Authenticator does
+type Authenticator interface {
+	Login(keyID, secret string) (auth.Key, error)
+}
+
+// order is an order placed by a FIX session, tracked until it is filled, cancelled or rejected
+type order struct {
+	// id is the ID of the order in the order events, empty until the order is accepted
+	id        string
+	sessionID quickfix.SessionID
+	clOrdID   string
+	accountID string
+	symbol    string
+	side      enum.Side
+	price     float64
+	// quantity is the OrderQty, which includes the quantity filled before a replacement
+	quantity int64
+	cumQty   int64
+	// notional is the sum of the price times the quantity of the fills
+	notional float64
+	acked    bool
+	// cancelClOrdID is the ClOrdID of a pending cancel request
+	cancelClOrdID string
+	// replaces is the order replaced by this one, replacedBy the pending replacement of this one
+	replaces   string
+	replacedBy string
+	// cancelled is set when a replaced order is cancelled before the outcome of its replacement
+	cancelled bool
+}
+
+func (o *order) leavesQty() int64 {
+	return o.quantity - o.cumQty
+}
+
+// Gateway is the quickfix.Application of the FIX acceptor. It places the orders of the sessions
+// through the OrderService, and reports the matching events of their orders back to them.
+type Gateway struct {
+	*quickfix.MessageRouter
+	service OrderService
+	// accounts maps the TargetCompID of a session to the accounts it may trade
+	accounts map[string][]string
+	// authenticator authenticates the Logons, which are accepted without it
+	authenticator Authenticator
+
+	// requestTimeout bounds the calls of the OrderService
+	requestTimeout time.Duration
+	// create limits the new orders and the replacements of every account, cancel the
+	// cancellations, unlimited if nil
+	create *ratelimit.Limiter
+	cancel *ratelimit.Limiter
+
+	mu sync.Mutex
+	// orders maps the order ID to the open orders
+	orders map[string]*order
+	// clOrdIDs maps the ClOrdID of a session to the order ID
+	clOrdIDs map[quickfix.SessionID]map[string]string
+	// calls is the number of calls of the OrderService in flight, and early the matching events
+	// received meanwhile, as those of a placed order may arrive before its call returns
+	calls int
+	early []events.MatchingEvent
+}
+
+// GatewayOption configures optional behaviors of a Gateway
+type GatewayOption func(*Gateway)
+
+// WithRequestTimeout bounds the calls of the OrderService, which publish the order events,
+// 5 seconds by default
+func WithRequestTimeout(timeout time.Duration) GatewayOption {
+	return func(g *Gateway) {
+		g.requestTimeout = timeout
+	}
+}
+
+// WithRateLimits limits the orders of every account by the limiters, of the same store as the
+// REST and gRPC ones: the new orders and the replacements by create, and the cancellations by
+// cancel
+func WithRateLimits(create, cancel *ratelimit.Limiter) GatewayOption {
+	return func(g *Gateway) {
+		g.create = create
+		g.cancel = cancel
+	}
+}
+
+// WithAuthenticator accepts a Logon only with the Username and the Password of an API key of one
+// of the accounts of its session
+func WithAuthenticator(authenticator Authenticator) GatewayOption {
+	return func(g *Gateway) {
+		g.authenticator = authenticator
+	}
+}
+
+func NewGateway(service OrderService, accounts []SessionAccount, opts ...GatewayOption) *Gateway {
+	g := &Gateway{
+		MessageRouter:  quickfix.NewMessageRouter(),
+		service:        service,
+		accounts:       make(map[string][]string),
+		requestTimeout: defaultRequestTimeout,
+		orders:         make(map[string]*order),
+		clOrdIDs:       make(map[quickfix.SessionID]map[string]string),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	for _, sessionAccount := range accounts {
+		g.accounts[sessionAccount.TargetCompID] = append(g.accounts[sessionAccount.TargetCompID], sessionAccount.AccountID)
+	}
+	g.AddRoute(newordersingle.Route(g.onNewOrderSingle))
+	g.AddRoute(ordercancelrequest.Route(g.onOrderCancelRequest))
+	g.AddRoute(ordercancelreplacerequest.Route(g.onOrderCancelReplaceRequest))
+	return g
+}
+
+func (g *Gateway) OnCreate(sessionID quickfix.SessionID) {}
+
+func (g *Gateway) OnLogon(sessionID quickfix.SessionID) {
+	logger.Info("FIX session logged on", zap.String("session", sessionID.String()))
+}
+
+func (g *Gateway) OnLogout(sessionID quickfix.SessionID) {
+	logger.Info("FIX session logged out", zap.String("session", sessionID.String()))
+}
+
+func (g *Gateway) ToAdmin(msg *quickfix.Message, sessionID quickfix.SessionID) {}
+
+func (g *Gateway) ToApp(msg *quickfix.Message, sessionID quickfix.SessionID) error {
+	return nil
+}
+
+// FromAdmin authenticates the Logons, the other admin messages are left to the session
+func (g *Gateway) FromAdmin(msg *quickfix.Message, sessionID quickfix.SessionID) quickfix.MessageRejectError {
+	if !msg.IsMsgTypeOf(string(enum.MsgType_LOGON)) || g.authenticator == nil {
+		return nil
+	}
+
+	var username field.UsernameField
+	var password field.PasswordField
+	_ = msg.Body.Get(&username)
+	_ = msg.Body.Get(&password)
+	key, err := g.authenticator.Login(username.Value(), password.Value())
+	if err != nil {
+		logger.Warn("FIX logon rejected", zap.Error(err), zap.String("session", sessionID.String()))
+		return quickfix.RejectLogon{Text: "invalid username or password"}
+	}
+	if !slices.Contains(g.accounts[sessionID.TargetCompID], key.AccountID) {
+		logger.Warn("FIX logon rejected, the key is not of an account of the session",
+			zap.String("session", sessionID.String()), zap.String("accountID", key.AccountID))
+		return quickfix.RejectLogon{Text: "the account may not trade in the session"}
+	}
+	return nil
+}
+
+// FromApp routes the application messages to their handlers, the others are rejected
+func (g *Gateway) FromApp(msg *quickfix.Message, sessionID quickfix.SessionID) quickfix.MessageRejectError {
+	return g.Route(msg, sessionID)
+}
+
+// onNewOrderSingle places a limit order. The order is of the Account of the message, which the
+// session must be allowed to trade, or of the first account of the session without one. A
+// session without accounts may not trade.
+func (g *Gateway) onNewOrderSingle(msg newordersingle.NewOrderSingle, sessionID quickfix.SessionID) quickfix.MessageRejectError {
+	clOrdID, err := msg.GetClOrdID()
+	if err != nil {
+		return err
+	}
+	side, err := msg.GetSide()
+	if err != nil {
+		return err
+	}
+	symbol, err := msg.GetSymbol()
+	if err != nil {
+		return err
+	}
+	o := &order{
+		sessionID: sessionID,
+		clOrdID:   clOrdID,
+		symbol:    symbol,
+		side:      side,
+	}
+	var requested string
+	if msg.HasAccount() {
+		requested, _ = msg.GetAccount()
+	}
+	accountID, allowed := g.account(sessionID, requested)
+	o.accountID = accountID
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, exists := g.clOrdIDs[sessionID][clOrdID]; exists {
+		g.reject(o, enum.OrdRejReason_DUPLICATE_ORDER, "duplicate ClOrdID")
+		return nil
+	}
+	if !allowed {
+		g.reject(o, enum.OrdRejReason_UNKNOWN_ACCOUNT, "unknown account")
+		return nil
+	}
+	ordType, _ := msg.GetOrdType()
+	if ordType != enum.OrdType_LIMIT {
+		g.reject(o, enum.OrdRejReason_UNSUPPORTED_ORDER_CHARACTERISTIC, "only limit orders are supported")
+		return nil
+	}
+	timeInForce, ok := convertTimeInForce(msg.HasTimeInForce, msg.GetTimeInForce)
+	if !ok {
+		g.reject(o, enum.OrdRejReason_UNSUPPORTED_ORDER_CHARACTERISTIC, "only Day and GTC orders are supported")
+		return nil
+	}
+	var reason string
+	if o.price, o.quantity, reason = convertPriceAndQty(msg.GetPrice, msg.GetOrderQty); reason != "" {
+		g.reject(o, enum.OrdRejReason_OTHER, reason)
+		return nil
+	}
+	if side != enum.Side_BUY && side != enum.Side_SELL {
+		g.reject(o, enum.OrdRejReason_UNSUPPORTED_ORDER_CHARACTERISTIC, "side must be buy or sell")
+		return nil
+	}
+
+	// The service holds the funds of the order before it is published, as for the REST orders
+	request := requests.CreateRequest{
+		AccountID:   o.accountID,
+		Symbol:      o.symbol,
+		Type:        convertSide(side),
+		Price:       o.price,
+		Quantity:    o.quantity,
+		TimeInForce: timeInForce,
+	}
+	g.reserve(sessionID, clOrdID)
+	var id string
+	placeErr := g.call(g.create, o.accountID, func(ctx context.Context) (err error) {
+		id, err = g.service.Create(ctx, request)
+		return err
+	})
+	defer g.called()
+	if placeErr != nil {
+		delete(g.clOrdIDs[sessionID], clOrdID)
+		switch {
+		case errors.Is(placeErr, account.ErrInsufficientBalance):
+			g.reject(o, enum.OrdRejReason_ORDER_EXCEEDS_LIMIT, "insufficient balance")
+		case errors.Is(placeErr, errRateLimited):
+			g.reject(o, enum.OrdRejReason_OTHER, placeErr.Error())
+		default:
+			g.reject(o, enum.OrdRejReason_OTHER, "failed to accept the order")
+		}
+		return nil
+	}
+	o.id = id
+	g.placed(o)
+	return nil
+}
+
+// onOrderCancelRequest places the cancellation of an open order of the session
+func (g *Gateway) onOrderCancelRequest(msg ordercancelrequest.OrderCancelRequest, sessionID quickfix.SessionID) quickfix.MessageRejectError {
+	clOrdID, err := msg.GetClOrdID()
+	if err != nil {
+		return err
+	}
+	origClOrdID, err := msg.GetOrigClOrdID()
+	if err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	o, reason := g.pending(sessionID, origClOrdID)
+	if o == nil || reason != "" {
+		g.cancelReject(sessionID, o, clOrdID, origClOrdID, enum.CxlRejResponseTo_ORDER_CANCEL_REQUEST, reason)
+		return nil
+	}
+
+	// The order is pending cancel while the cancellation is placed
+	id := o.id
+	o.cancelClOrdID = clOrdID
+	cancelErr := g.call(g.cancel, o.accountID, func(ctx context.Context) error {
+		return g.service.Cancel(ctx, id)
+	})
+	defer g.called()
+	if cancelErr != nil {
+		o.cancelClOrdID = ""
+		reason := "failed to accept the request"
+		if errors.Is(cancelErr, errRateLimited) {
+			reason = cancelErr.Error()
+		}
+		g.cancelReject(sessionID, o, clOrdID, origClOrdID, enum.CxlRejResponseTo_ORDER_CANCEL_REQUEST, reason)
+	}
+	return nil
+}
+
+// onOrderCancelReplaceRequest places an amend of an open order of the session. The OrderQty
+// includes the quantity already filled, as the replacement is of the remaining quantity.
+func (g *Gateway) onOrderCancelReplaceRequest(msg ordercancelreplacerequest.OrderCancelReplaceRequest, sessionID quickfix.SessionID) quickfix.MessageRejectError {
+	clOrdID, err := msg.GetClOrdID()
+	if err != nil {
+		return err
+	}
+	origClOrdID, err := msg.GetOrigClOrdID()
+	if err != nil {
+		return err
+	}
+	side, err := msg.GetSide()
+	if err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	orig, reason := g.pending(sessionID, origClOrdID)
+	if orig == nil || reason != "" {
+		g.cancelReject(sessionID, orig, clOrdID, origClOrdID, enum.CxlRejResponseTo_ORDER_CANCEL_REPLACE_REQUEST, reason)
+		return nil
+	}
+	if _, exists := g.clOrdIDs[sessionID][clOrdID]; exists {
+		g.cancelReject(sessionID, orig, clOrdID, origClOrdID, enum.CxlRejResponseTo_ORDER_CANCEL_REPLACE_REQUEST, "duplicate ClOrdID")
+		return nil
+	}
+	ordType, _ := msg.GetOrdType()
+	if side != orig.side || ordType != enum.OrdType_LIMIT {
+		g.cancelReject(sessionID, orig, clOrdID, origClOrdID, enum.CxlRejResponseTo_ORDER_CANCEL_REPLACE_REQUEST, "only the price, quantity and time in force can be replaced")
+		return nil
+	}
+	timeInForce, ok := convertTimeInForce(msg.HasTimeInForce, msg.GetTimeInForce)
+	if !ok {
+		g.cancelReject(sessionID, orig, clOrdID, origClOrdID, enum.CxlRejResponseTo_ORDER_CANCEL_REPLACE_REQUEST, "only Day and GTC orders are supported")
+		return nil
+	}
+
+	o := &order{
+		sessionID: sessionID,
+		clOrdID:   clOrdID,
+		accountID: orig.accountID,
+		symbol:    orig.symbol,
+		side:      orig.side,
+		cumQty:    orig.cumQty,
+		notional:  orig.notional,
+		replaces:  orig.id,
+	}
+	if o.price, o.quantity, reason = convertPriceAndQty(msg.GetPrice, msg.GetOrderQty); reason == "" && o.leavesQty() <= 0 {
+		reason = "the quantity must exceed the filled quantity"
+	}
+	if reason != "" {
+		g.cancelReject(sessionID, orig, clOrdID, origClOrdID, enum.CxlRejResponseTo_ORDER_CANCEL_REPLACE_REQUEST, reason)
+		return nil
+	}
+
+	// The replacement is held in addition to the replaced order until the replaced one is cancelled,
+	// which is pending replace while the replacement is placed
+	request := requests.AmendRequest{
+		ID:          orig.id,
+		Price:       o.price,
+		Quantity:    o.leavesQty(),
+		TimeInForce: timeInForce,
+	}
+	g.reserve(sessionID, clOrdID)
+	orig.replacedBy = pendingID
+	var id string
+	placeErr := g.call(g.create, o.accountID, func(ctx context.Context) (err error) {
+		id, err = g.service.Amend(ctx, request)
+		return err
+	})
+	defer g.called()
+	if placeErr != nil {
+		delete(g.clOrdIDs[sessionID], clOrdID)
+		orig.replacedBy = ""
+		reason = "failed to accept the request"
+		switch {
+		case errors.Is(placeErr, account.ErrInsufficientBalance):
+			reason = "insufficient balance"
+		case errors.Is(placeErr, errRateLimited):
+			reason = placeErr.Error()
+		}
+		g.cancelReject(sessionID, orig, clOrdID, origClOrdID, enum.CxlRejResponseTo_ORDER_CANCEL_REPLACE_REQUEST, reason)
+		if orig.cancelled {
+			g.cancelled(orig)
+		}
+		return nil
+	}
+	o.id = id
+	orig.replacedBy = o.id
+	g.placed(o)
+	return nil
+}
+
+// call calls the OrderService for the account within the rate limit of the limiter, without the
+// lock held by the caller, so a slow publish does not block the other sessions, and takes the
+// lock back. The caller must defer called once it has applied the outcome.
+func (g *Gateway) call(limiter *ratelimit.Limiter, accountID string, fn func(ctx context.Context) error) error {
+	g.calls++
+	g.mu.Unlock()
+	defer g.mu.Lock()
+
+	ctx, cancel := context.WithTimeout(auth.NewContext(context.Background(), accountID), g.requestTimeout)
+	defer cancel()
+	if result, allowed := limiter.Take(ctx, accountID, 1); !allowed {
+		return fmt.Errorf("%w, retry after %s", errRateLimited, result.RetryAfter)
+	}
+	return fn(ctx)
+}
+
+// called ends a call of the OrderService, the early matching events are dropped once no call
+// is in flight
+func (g *Gateway) called() {
+	if g.calls--; g.calls == 0 {
+		g.early = nil
+	}
+}
+
+// placed tracks an order accepted by the OrderService, and reports the matching events of the
+// order received before its call returned
+func (g *Gateway) placed(o *order) {
+	g.track(o)
+	for _, matchingEvent := range g.early {
+		g.handle(matchingEvent, func(id string) *order {
+			if id != o.id {
+				return nil
+			}
+			return g.orders[id]
+		})
+	}
+}
+
+// reserve marks the ClOrdID of the session as used while its order is placed
+func (g *Gateway) reserve(sessionID quickfix.SessionID, clOrdID string) {
+	if g.clOrdIDs[sessionID] == nil {
+		g.clOrdIDs[sessionID] = make(map[string]string)
+	}
+	g.clOrdIDs[sessionID][clOrdID] = pendingID
+}
+
+// pending returns the open order of the ClOrdID, and the reason it may not be cancelled or
+// replaced, if any
+func (g *Gateway) pending(sessionID quickfix.SessionID, clOrdID string) (*order, string) {
+	o, ok := g.orders[g.clOrdIDs[sessionID][clOrdID]]
+	if !ok {
+		return nil, "unknown order"
+	}
+	if o.cancelClOrdID != "" || o.replacedBy != "" || o.replaces != "" {
+		return o, "the order is pending cancel or replace"
+	}
+	return o, ""
+}
+
+func (g *Gateway) track(o *order) {
+	g.orders[o.id] = o
+	g.reserve(o.sessionID, o.clOrdID)
+	g.clOrdIDs[o.sessionID][o.clOrdID] = o.id
+}
+
+func (g *Gateway) untrack(o *order) {
+	delete(g.orders, o.id)
+	delete(g.clOrdIDs[o.sessionID], o.clOrdID)
+}
+
+// account returns the account of an order of the session, the requested one or the first one
+// of the session without it, and reports whether the session may trade it
+func (g *Gateway) account(sessionID quickfix.SessionID, requested string) (string, bool) {
+	accounts := g.accounts[sessionID.TargetCompID]
+	if requested == "" {
+		if len(accounts) == 0 {
+			return "", false
+		}
+		return accounts[0], true
+	}
+	return requested, slices.Contains(accounts, requested)
+}
+
+// convertTimeInForce returns the TimeInForce of the order events, GTC if the message has none
+func convertTimeInForce(has func() bool, get func() (enum.TimeInForce, quickfix.MessageRejectError)) (string, bool) {
+	if !has() {
+		return "GTC", true
+	}
+	timeInForce, _ := get()
+	switch timeInForce {
+	case enum.TimeInForce_DAY:
+		return "Day", true
+	case enum.TimeInForce_GOOD_TILL_CANCEL:
+		return "GTC", true
+	default:
+		return "", false
+	}
+}
+
+// convertPriceAndQty returns the price and the whole quantity of a message, or the reason they
+// are invalid
+func convertPriceAndQty(getPrice, getQty func() (decimal.Decimal, quickfix.MessageRejectError)) (float64, int64, string) {
+	price, err := getPrice()
+	if err != nil || !price.IsPositive() {
+		return 0, 0, "the price must be positive"
+	}
+	qty, err := getQty()
+	if err != nil || !qty.IsPositive() || !qty.IsInteger() {
+		return 0, 0, "the quantity must be a positive whole number"
+	}
+	return price.InexactFloat64(), qty.IntPart(), ""
+}
+
+func convertSide(side enum.Side) string {
+	if side == enum.Side_BUY {
+		return "Buy"
+	}
+	return "Sell"
+}
@@ -0,0 +1,481 @@
+package fix
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/field"
+	"github.com/quickfixgo/fix44/executionreport"
+	"github.com/quickfixgo/fix44/logon"
+	"github.com/quickfixgo/fix44/newordersingle"
+	"github.com/quickfixgo/fix44/ordercancelreject"
+	"github.com/quickfixgo/fix44/ordercancelreplacerequest"
+	"github.com/quickfixgo/fix44/ordercancelrequest"
+	"github.com/quickfixgo/quickfix"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/Hao1995/order-matching-system/internal/api/account"
+	"github.com/Hao1995/order-matching-system/internal/api/auth"
+	orderapi "github.com/Hao1995/order-matching-system/internal/api/order"
+	"github.com/Hao1995/order-matching-system/internal/api/order/requests"
+	"github.com/Hao1995/order-matching-system/internal/api/ratelimit"
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	"github.com/Hao1995/order-matching-system/pkg/mqkit"
+)
+
+const orderTopic = "AAPL_ORDER"
+
+// client1 maps the session of the tests to the account of its TargetCompID
+var client1 = []SessionAccount{{TargetCompID: "CLIENT1", AccountID: "CLIENT1"}}
+
+// newService returns the order service of the order API, with funds deposited to the accounts
+func newService(broker *mqkit.MemoryBroker, accountIDs ...string) (*orderapi.Service, *account.Ledger) {
+	ledger := account.NewLedger("USD")
+	for _, accountID := range accountIDs {
+		ledger.Deposit(accountID, "USD", 10000)
+		ledger.Deposit(accountID, "AAPL", 100)
+	}
+	return orderapi.NewService(mqkit.NewMemoryProducer(broker, orderTopic, nil), events.JSONCodec, orderTopic, ledger), ledger
+}
+
+// blockingService blocks the calls of the OrderService once they are placed, until released
+type blockingService struct {
+	OrderService
+	placed  chan context.Context
+	release chan struct{}
+}
+
+func (s *blockingService) Create(ctx context.Context, request requests.CreateRequest) (string, error) {
+	id, err := s.OrderService.Create(ctx, request)
+	s.placed <- ctx
+	<-s.release
+	return id, err
+}
+
+type GatewayTestSuite struct {
+	suite.Suite
+	broker    *mqkit.MemoryBroker
+	ledger    *account.Ledger
+	gateway   *Gateway
+	sessionID quickfix.SessionID
+	sent      []*quickfix.Message
+	uuids     int
+
+	restoreSend func(quickfix.Messagable, quickfix.SessionID) error
+	restoreUUID func() string
+}
+
+func TestGatewayTestSuite(t *testing.T) {
+	suite.Run(t, new(GatewayTestSuite))
+}
+
+func (suite *GatewayTestSuite) SetupTest() {
+	suite.broker = mqkit.NewMemoryBroker()
+	var service *orderapi.Service
+	service, suite.ledger = newService(suite.broker, "CLIENT1", "alice", "bob")
+	suite.gateway = NewGateway(service, client1)
+	suite.sessionID = quickfix.SessionID{BeginString: quickfix.BeginStringFIX44, SenderCompID: "OMS", TargetCompID: "CLIENT1"}
+	suite.sent, suite.uuids = nil, 0
+
+	suite.restoreSend, suite.restoreUUID = sendToTarget, getUUID
+	sendToTarget = func(m quickfix.Messagable, sessionID quickfix.SessionID) error {
+		suite.Equal(suite.sessionID, sessionID)
+		suite.sent = append(suite.sent, m.ToMessage())
+		return nil
+	}
+	getUUID = func() string {
+		suite.uuids++
+		return fmt.Sprintf("id%d", suite.uuids)
+	}
+}
+
+func (suite *GatewayTestSuite) TearDownTest() {
+	sendToTarget, getUUID = suite.restoreSend, suite.restoreUUID
+}
+
+func (suite *GatewayTestSuite) fromApp(msg quickfix.Messagable) {
+	m := msg.ToMessage()
+	m.Header.SetString(8, quickfix.BeginStringFIX44)
+	suite.Require().Nil(suite.gateway.FromApp(m, suite.sessionID))
+}
+
+func (suite *GatewayTestSuite) newOrderSingle(clOrdID string, side enum.Side, price float64, qty int64) {
+	suite.fromApp(newOrderSingle(clOrdID, side, price, qty))
+}
+
+func newOrderSingle(clOrdID string, side enum.Side, price float64, qty int64) newordersingle.NewOrderSingle {
+	msg := newordersingle.New(field.NewClOrdID(clOrdID), field.NewSide(side), field.NewTransactTime(time.Now()), field.NewOrdType(enum.OrdType_LIMIT))
+	msg.SetSymbol("AAPL")
+	msg.SetPrice(decimal.NewFromFloat(price), 2)
+	msg.SetOrderQty(decimal.NewFromInt(qty), 0)
+	return msg
+}
+
+func (suite *GatewayTestSuite) handle(matchingEvent events.MatchingEvent) {
+	val, err := events.JSONCodec.EncodeMatchingEvent(matchingEvent)
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.gateway.Handle(val))
+}
+
+// published decodes the order events published to the order topic
+func (suite *GatewayTestSuite) published() []events.Event {
+	var published []events.Event
+	for _, msg := range suite.broker.Messages(orderTopic, 0) {
+		event, err := events.JSONCodec.DecodeEvent(msg.Value)
+		suite.Require().NoError(err)
+		published = append(published, event)
+	}
+	return published
+}
+
+// orderID returns the ID of the order of the ith published order event
+func (suite *GatewayTestSuite) orderID(i int) string {
+	published := suite.published()
+	suite.Require().Greater(len(published), i)
+	return published[i].Data.(events.OrderEvent).ID
+}
+
+// report returns the latest sent message as an execution report
+func (suite *GatewayTestSuite) report() executionreport.ExecutionReport {
+	suite.Require().NotEmpty(suite.sent)
+	msg := suite.sent[len(suite.sent)-1]
+	msgType, _ := msg.MsgType()
+	suite.Require().Equal("8", msgType)
+	return executionreport.FromMessage(msg)
+}
+
+func (suite *GatewayTestSuite) assertReport(report executionreport.ExecutionReport, execType enum.ExecType, ordStatus enum.OrdStatus, clOrdID string, cumQty, leavesQty int64) {
+	actualExecType, _ := report.GetExecType()
+	suite.Equal(execType, actualExecType)
+	actualOrdStatus, _ := report.GetOrdStatus()
+	suite.Equal(ordStatus, actualOrdStatus)
+	actualClOrdID, _ := report.GetClOrdID()
+	suite.Equal(clOrdID, actualClOrdID)
+	actualCumQty, _ := report.GetCumQty()
+	suite.Equal(cumQty, actualCumQty.IntPart())
+	actualLeavesQty, _ := report.GetLeavesQty()
+	suite.Equal(leavesQty, actualLeavesQty.IntPart())
+}
+
+func (suite *GatewayTestSuite) TestNewOrderSingle() {
+	suite.newOrderSingle("c1", enum.Side_SELL, 100, 10)
+
+	published := suite.published()
+	suite.Require().Len(published, 1)
+	suite.Equal(events.EventTypeCreateOrder, published[0].EventType)
+	orderEvent := published[0].Data.(events.OrderEvent)
+	suite.Equal("CLIENT1", orderEvent.AccountID)
+	suite.Equal("Sell", orderEvent.Type)
+	suite.Equal("GTC", orderEvent.TimeInForce)
+	suite.Empty(suite.sent)
+
+	// The order is held in the ledger of the service, as the REST orders are
+	held, ok := suite.ledger.Order(orderEvent.ID)
+	suite.Require().True(ok)
+	suite.Equal("CLIENT1", held.AccountID)
+	suite.Equal(int64(10), held.Quantity)
+
+	// The order is acknowledged once accepted by the matching engine
+	suite.handle(events.MatchingEvent{Type: events.MatchingEventTypeCreate, Order: orderEvent})
+	report := suite.report()
+	suite.assertReport(report, enum.ExecType_NEW, enum.OrdStatus_NEW, "c1", 0, 10)
+	orderID, _ := report.GetOrderID()
+	suite.Equal(orderEvent.ID, orderID)
+
+	// The resting order is filled by the order of another service
+	transaction := events.TransactionEvent{ID: "transaction1", BuyOrderID: "other", SellOrderID: orderEvent.ID, Price: 100, Quantity: 4}
+	suite.handle(events.MatchingEvent{Type: events.MatchingEventTypeCreate, Order: events.OrderEvent{ID: "other"}, Transactions: []events.TransactionEvent{transaction}})
+	report = suite.report()
+	suite.assertReport(report, enum.ExecType_TRADE, enum.OrdStatus_PARTIALLY_FILLED, "c1", 4, 6)
+	lastQty, _ := report.GetLastQty()
+	suite.Equal(int64(4), lastQty.IntPart())
+	suite.Len(suite.sent, 2)
+
+	transaction.Quantity = 6
+	suite.handle(events.MatchingEvent{Type: events.MatchingEventTypeCreate, Order: events.OrderEvent{ID: "other"}, Transactions: []events.TransactionEvent{transaction}})
+	suite.assertReport(suite.report(), enum.ExecType_TRADE, enum.OrdStatus_FILLED, "c1", 10, 0)
+}
+
+func (suite *GatewayTestSuite) TestNewOrderSingle_Rejected() {
+	suite.newOrderSingle("c1", enum.Side_BUY, 100, 10)
+
+	// A duplicate ClOrdID is rejected by the gateway
+	suite.newOrderSingle("c1", enum.Side_BUY, 100, 10)
+	report := suite.report()
+	suite.assertReport(report, enum.ExecType_REJECTED, enum.OrdStatus_REJECTED, "c1", 0, 0)
+	orderID, _ := report.GetOrderID()
+	suite.Equal("NONE", orderID)
+
+	// A market order is rejected by the gateway
+	market := newordersingle.New(field.NewClOrdID("c2"), field.NewSide(enum.Side_BUY), field.NewTransactTime(time.Now()), field.NewOrdType(enum.OrdType_MARKET))
+	market.SetSymbol("AAPL")
+	market.SetOrderQty(decimal.NewFromInt(10), 0)
+	suite.fromApp(market)
+	suite.assertReport(suite.report(), enum.ExecType_REJECTED, enum.OrdStatus_REJECTED, "c2", 0, 0)
+	suite.Len(suite.published(), 1)
+
+	// An order without the funds is rejected by the service
+	suite.newOrderSingle("c3", enum.Side_BUY, 100, 100)
+	report = suite.report()
+	suite.assertReport(report, enum.ExecType_REJECTED, enum.OrdStatus_REJECTED, "c3", 0, 0)
+	reason, _ := report.GetOrdRejReason()
+	suite.Equal(enum.OrdRejReason_ORDER_EXCEEDS_LIMIT, reason)
+	suite.Len(suite.published(), 1)
+
+	// An order rejected by the matching engine
+	suite.handle(events.MatchingEvent{
+		Type:      events.MatchingEventTypeReject,
+		Order:     events.OrderEvent{ID: suite.orderID(0)},
+		Rejection: &events.RejectionEvent{Rule: "MaxQuantity", Reason: "quantity exceeds 5"},
+	})
+	report = suite.report()
+	suite.assertReport(report, enum.ExecType_REJECTED, enum.OrdStatus_REJECTED, "c1", 0, 0)
+	text, _ := report.GetText()
+	suite.Equal("quantity exceeds 5", text)
+
+	// The ClOrdID of a rejected order may be used again
+	suite.newOrderSingle("c1", enum.Side_BUY, 100, 10)
+	suite.Len(suite.published(), 2)
+}
+
+func (suite *GatewayTestSuite) TestNewOrderSingle_Accounts() {
+	service, _ := newService(suite.broker, "alice", "bob")
+	suite.gateway = NewGateway(service, []SessionAccount{
+		{TargetCompID: "CLIENT1", AccountID: "alice"},
+		{TargetCompID: "CLIENT1", AccountID: "bob"},
+		{TargetCompID: "CLIENT2", AccountID: "carol"},
+	})
+
+	// The first account of the session without an Account, or one of its accounts
+	suite.newOrderSingle("c1", enum.Side_BUY, 100, 10)
+	bob := newOrderSingle("c2", enum.Side_BUY, 100, 10)
+	bob.SetAccount("bob")
+	suite.fromApp(bob)
+	suite.Equal("alice", suite.published()[0].Data.(events.OrderEvent).AccountID)
+	suite.Equal("bob", suite.published()[1].Data.(events.OrderEvent).AccountID)
+
+	// Any other account is rejected, the TargetCompID too once the session has accounts
+	for _, accountID := range []string{"carol", "CLIENT1"} {
+		msg := newOrderSingle("c3", enum.Side_BUY, 100, 10)
+		msg.SetAccount(accountID)
+		suite.fromApp(msg)
+		report := suite.report()
+		suite.assertReport(report, enum.ExecType_REJECTED, enum.OrdStatus_REJECTED, "c3", 0, 0)
+		reason, _ := report.GetOrdRejReason()
+		suite.Equal(enum.OrdRejReason_UNKNOWN_ACCOUNT, reason, accountID)
+	}
+	suite.Len(suite.published(), 2)
+
+	// A session without accounts may not trade, not even for its TargetCompID
+	suite.sessionID.TargetCompID = "CLIENT3"
+	for _, accountID := range []string{"", "CLIENT3"} {
+		msg := newOrderSingle("c4", enum.Side_BUY, 100, 10)
+		if accountID != "" {
+			msg.SetAccount(accountID)
+		}
+		suite.fromApp(msg)
+		reason, _ := suite.report().GetOrdRejReason()
+		suite.Equal(enum.OrdRejReason_UNKNOWN_ACCOUNT, reason, accountID)
+	}
+	suite.Len(suite.published(), 2)
+}
+
+// fakeAuthenticator accepts the secret of the key, which is of the account of the same name
+type fakeAuthenticator struct{}
+
+func (fakeAuthenticator) Login(keyID, secret string) (auth.Key, error) {
+	if secret != keyID+"-secret" {
+		return auth.Key{}, auth.ErrInvalidSecret
+	}
+	return auth.Key{ID: keyID, AccountID: keyID, Secret: secret}, nil
+}
+
+func (suite *GatewayTestSuite) TestFromAdmin_Logon() {
+	service, _ := newService(suite.broker)
+	suite.gateway = NewGateway(service, []SessionAccount{{TargetCompID: "CLIENT1", AccountID: "alice"}}, WithAuthenticator(fakeAuthenticator{}))
+	logOn := func(username, password string) quickfix.MessageRejectError {
+		msg := logon.New(field.NewEncryptMethod(enum.EncryptMethod_NONE_OTHER), field.NewHeartBtInt(30))
+		msg.SetUsername(username)
+		msg.SetPassword(password)
+		return suite.gateway.FromAdmin(msg.ToMessage(), suite.sessionID)
+	}
+
+	suite.Nil(logOn("alice", "alice-secret"))
+	suite.IsType(quickfix.RejectLogon{}, logOn("alice", "bob-secret"))
+	// The key of an account of another session
+	suite.IsType(quickfix.RejectLogon{}, logOn("bob", "bob-secret"))
+}
+
+func (suite *GatewayTestSuite) TestNewOrderSingle_RateLimited() {
+	service, _ := newService(suite.broker, "CLIENT1")
+	store := ratelimit.NewMemoryStore()
+	suite.gateway = NewGateway(service, client1, WithRateLimits(
+		ratelimit.NewLimiter(store, "create", ratelimit.Limit{Rate: 0.001, Burst: 1}),
+		ratelimit.NewLimiter(store, "cancel", ratelimit.Limit{Rate: 0.001, Burst: 1}),
+	))
+
+	// The orders of the account share the bucket of its REST and gRPC orders
+	_, allowed := ratelimit.NewLimiter(store, "create", ratelimit.Limit{Rate: 0.001, Burst: 1}).Take(context.Background(), "CLIENT1", 1)
+	suite.Require().True(allowed)
+	suite.newOrderSingle("c1", enum.Side_BUY, 100, 10)
+	report := suite.report()
+	suite.assertReport(report, enum.ExecType_REJECTED, enum.OrdStatus_REJECTED, "c1", 0, 0)
+	text, _ := report.GetText()
+	suite.Contains(text, "too many requests")
+	suite.Empty(suite.published())
+}
+
+func (suite *GatewayTestSuite) TestOrderCancelRequest() {
+	suite.newOrderSingle("c1", enum.Side_BUY, 100, 10)
+	id := suite.orderID(0)
+	suite.handle(events.MatchingEvent{Type: events.MatchingEventTypeCreate, Order: events.OrderEvent{ID: id}})
+
+	cancel := ordercancelrequest.New(field.NewOrigClOrdID("c1"), field.NewClOrdID("c2"), field.NewSide(enum.Side_BUY), field.NewTransactTime(time.Now()))
+	suite.fromApp(cancel)
+	published := suite.published()
+	suite.Require().Len(published, 2)
+	suite.Equal(events.EventTypeCancelOrder, published[1].EventType)
+	suite.Equal(events.OrderEvent{ID: id, Symbol: "AAPL"}, published[1].Data)
+
+	suite.handle(events.MatchingEvent{Type: events.MatchingEventTypeCancel, Order: events.OrderEvent{ID: id}})
+	report := suite.report()
+	suite.assertReport(report, enum.ExecType_CANCELED, enum.OrdStatus_CANCELED, "c2", 0, 0)
+	origClOrdID, _ := report.GetOrigClOrdID()
+	suite.Equal("c1", origClOrdID)
+
+	// The order is no longer open
+	suite.fromApp(ordercancelrequest.New(field.NewOrigClOrdID("c1"), field.NewClOrdID("c3"), field.NewSide(enum.Side_BUY), field.NewTransactTime(time.Now())))
+	msgType, _ := suite.sent[len(suite.sent)-1].MsgType()
+	suite.Require().Equal("9", msgType)
+	reject := ordercancelreject.FromMessage(suite.sent[len(suite.sent)-1])
+	reason, _ := reject.GetCxlRejReason()
+	suite.Equal(enum.CxlRejReason_UNKNOWN_ORDER, reason)
+	suite.Len(suite.published(), 2)
+}
+
+func (suite *GatewayTestSuite) TestMassCancel() {
+	suite.newOrderSingle("c1", enum.Side_BUY, 100, 10)
+	suite.newOrderSingle("c2", enum.Side_BUY, 99, 5)
+	id1, id2 := suite.orderID(0), suite.orderID(1)
+	suite.handle(events.MatchingEvent{Type: events.MatchingEventTypeCreate, Order: events.OrderEvent{ID: id1}})
+	suite.handle(events.MatchingEvent{Type: events.MatchingEventTypeCreate, Order: events.OrderEvent{ID: id2}})
+	suite.Len(suite.sent, 2)
+
+	// The mass cancel of another service cancels the orders of the session
+	suite.handle(events.MatchingEvent{
+		Type:      events.MatchingEventTypeMassCancel,
+		Order:     events.OrderEvent{ID: "mass1", AccountID: "CLIENT1"},
+		Cancelled: []events.OrderEvent{{ID: id1}, {ID: "other"}, {ID: id2}},
+	})
+	suite.Require().Len(suite.sent, 4)
+	suite.assertReport(executionreport.FromMessage(suite.sent[2]), enum.ExecType_CANCELED, enum.OrdStatus_CANCELED, "c1", 0, 0)
+	suite.assertReport(executionreport.FromMessage(suite.sent[3]), enum.ExecType_CANCELED, enum.OrdStatus_CANCELED, "c2", 0, 0)
+}
+
+func (suite *GatewayTestSuite) TestOrderCancelReplaceRequest() {
+	suite.newOrderSingle("c1", enum.Side_BUY, 100, 10)
+	id := suite.orderID(0)
+	suite.handle(events.MatchingEvent{
+		Type:         events.MatchingEventTypeCreate,
+		Order:        events.OrderEvent{ID: id},
+		Transactions: []events.TransactionEvent{{ID: "transaction1", BuyOrderID: id, SellOrderID: "other", Price: 100, Quantity: 4}},
+	})
+
+	// The OrderQty includes the filled quantity
+	replace := ordercancelreplacerequest.New(field.NewOrigClOrdID("c1"), field.NewClOrdID("c2"), field.NewSide(enum.Side_BUY), field.NewTransactTime(time.Now()), field.NewOrdType(enum.OrdType_LIMIT))
+	replace.SetPrice(decimal.NewFromFloat(99), 2)
+	replace.SetOrderQty(decimal.NewFromInt(8), 0)
+	suite.fromApp(replace)
+	published := suite.published()
+	suite.Require().Len(published, 2)
+	suite.Equal(events.EventTypeAmendOrder, published[1].EventType)
+	orderEvent := published[1].Data.(events.OrderEvent)
+	suite.Equal(id, orderEvent.OrigID)
+	suite.Equal("CLIENT1", orderEvent.AccountID)
+	suite.Equal(int64(4), orderEvent.Quantity)
+	suite.Equal(99.0, orderEvent.Price)
+	_, held := suite.ledger.Order(orderEvent.ID)
+	suite.True(held)
+
+	// The cancellation of the replaced order is reported by the replacement
+	sent := len(suite.sent)
+	suite.handle(events.MatchingEvent{Type: events.MatchingEventTypeCancel, Order: events.OrderEvent{ID: id}})
+	suite.Len(suite.sent, sent)
+
+	suite.handle(events.MatchingEvent{Type: events.MatchingEventTypeCreate, Order: orderEvent})
+	report := suite.report()
+	suite.assertReport(report, enum.ExecType_REPLACED, enum.OrdStatus_PARTIALLY_FILLED, "c2", 4, 4)
+	origClOrdID, _ := report.GetOrigClOrdID()
+	suite.Equal("c1", origClOrdID)
+	orderID, _ := report.GetOrderID()
+	suite.Equal(orderEvent.ID, orderID)
+}
+
+func (suite *GatewayTestSuite) TestOrderCancelReplaceRequest_Rejected() {
+	suite.newOrderSingle("c1", enum.Side_BUY, 100, 10)
+	suite.handle(events.MatchingEvent{Type: events.MatchingEventTypeCreate, Order: events.OrderEvent{ID: suite.orderID(0)}})
+
+	// The replacement is held in addition to the replaced order
+	replace := ordercancelreplacerequest.New(field.NewOrigClOrdID("c1"), field.NewClOrdID("c2"), field.NewSide(enum.Side_BUY), field.NewTransactTime(time.Now()), field.NewOrdType(enum.OrdType_LIMIT))
+	replace.SetPrice(decimal.NewFromFloat(99), 2)
+	replace.SetOrderQty(decimal.NewFromInt(100), 0)
+	suite.fromApp(replace)
+	msgType, _ := suite.sent[len(suite.sent)-1].MsgType()
+	suite.Require().Equal("9", msgType)
+	text, _ := ordercancelreject.FromMessage(suite.sent[len(suite.sent)-1]).GetText()
+	suite.Equal("insufficient balance", text)
+	suite.Len(suite.published(), 1)
+
+	replace.SetOrderQty(decimal.NewFromInt(8), 0)
+	suite.fromApp(replace)
+	replacement := suite.published()[1].Data.(events.OrderEvent)
+
+	// The replaced order is kept when the matching engine rejects the replacement
+	suite.handle(events.MatchingEvent{
+		Type:      events.MatchingEventTypeReject,
+		Order:     events.OrderEvent{ID: replacement.ID},
+		Rejection: &events.RejectionEvent{Rule: "PriceCollar", Reason: "outside the collar"},
+	})
+	msgType, _ = suite.sent[len(suite.sent)-1].MsgType()
+	suite.Require().Equal("9", msgType)
+	reject := ordercancelreject.FromMessage(suite.sent[len(suite.sent)-1])
+	responseTo, _ := reject.GetCxlRejResponseTo()
+	suite.Equal(enum.CxlRejResponseTo_ORDER_CANCEL_REPLACE_REQUEST, responseTo)
+	status, _ := reject.GetOrdStatus()
+	suite.Equal(enum.OrdStatus_NEW, status)
+
+	suite.fromApp(ordercancelrequest.New(field.NewOrigClOrdID("c1"), field.NewClOrdID("c3"), field.NewSide(enum.Side_BUY), field.NewTransactTime(time.Now())))
+	suite.Equal(events.EventTypeCancelOrder, suite.published()[2].EventType)
+}
+
+func (suite *GatewayTestSuite) TestNewOrderSingle_PlacedWithoutLock() {
+	service, _ := newService(suite.broker, "CLIENT1")
+	blocking := &blockingService{OrderService: service, placed: make(chan context.Context), release: make(chan struct{})}
+	suite.gateway = NewGateway(blocking, client1, WithRequestTimeout(time.Minute))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		suite.newOrderSingle("c1", enum.Side_BUY, 100, 10)
+	}()
+	ctx := <-blocking.placed
+	deadline, ok := ctx.Deadline()
+	suite.True(ok)
+	suite.WithinDuration(time.Now().Add(time.Minute), deadline, 10*time.Second)
+
+	// The matching events are handled while the order is placed, and the ones of the order
+	// received before the service returned are reported once it is tracked
+	id := suite.orderID(0)
+	suite.handle(events.MatchingEvent{Type: events.MatchingEventTypeCreate, Order: events.OrderEvent{ID: id}})
+	suite.Empty(suite.sent)
+
+	close(blocking.release)
+	<-done
+	suite.Require().Len(suite.sent, 1)
+	suite.assertReport(suite.report(), enum.ExecType_NEW, enum.OrdStatus_NEW, "c1", 0, 10)
+	suite.Empty(suite.gateway.early)
+}
@@ -0,0 +1,239 @@
+package fix
+
+import (
+	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/field"
+	"github.com/quickfixgo/fix44/executionreport"
+	"github.com/quickfixgo/fix44/ordercancelreject"
+	"github.com/quickfixgo/quickfix"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	"github.com/Hao1995/order-matching-system/pkg/logger"
+)
+
+// priceScale is the number of decimals of the prices in the reports
+const priceScale = 4
+
+// Handle decodes a JSON matching event and reports it to the sessions of its orders
+func (g *Gateway) Handle(val []byte) error {
+	return g.HandleEncoded(events.JSONCodec, val)
+}
+
+// HandleEncoded is Handle for a matching event encoded by the codec. The matching events of the
+// orders of other services are skipped.
+func (g *Gateway) HandleEncoded(codec events.Codec, val []byte) error {
+	matchingEvent, err := codec.DecodeMatchingEvent(val)
+	if err != nil {
+		logger.Error("failed to decode matching event", zap.Error(err), zap.ByteString("val", val))
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.handle(matchingEvent, func(id string) *order {
+		return g.orders[id]
+	})
+	if g.calls > 0 {
+		g.early = append(g.early, matchingEvent)
+	}
+	return nil
+}
+
+// handle reports a matching event to the sessions of the orders found by lookup
+func (g *Gateway) handle(matchingEvent events.MatchingEvent, lookup func(id string) *order) {
+	o := lookup(matchingEvent.Order.ID)
+	switch matchingEvent.Type {
+	case events.MatchingEventTypeCreate, events.MatchingEventTypeQueue:
+		if o != nil && !o.acked {
+			g.ack(o)
+		}
+	case events.MatchingEventTypeCancel:
+		if o != nil {
+			g.cancelled(o)
+		}
+	case events.MatchingEventTypeReject:
+		if o != nil {
+			g.rejected(o, matchingEvent.Rejection)
+		}
+	case events.MatchingEventTypeMassCancel:
+		for _, orderEvent := range matchingEvent.Cancelled {
+			if o := lookup(orderEvent.ID); o != nil {
+				g.cancelled(o)
+			}
+		}
+	}
+
+	// The fills of the resting orders are in the matching events of the incoming ones
+	for _, transaction := range matchingEvent.Transactions {
+		for _, id := range []string{transaction.BuyOrderID, transaction.SellOrderID} {
+			if o := lookup(id); o != nil {
+				g.fill(o, transaction)
+			}
+		}
+	}
+}
+
+// ack reports a new order, or the replacement of an order
+func (g *Gateway) ack(o *order) {
+	o.acked = true
+	if o.replaces == "" {
+		g.send(o, g.report(o, enum.ExecType_NEW))
+		return
+	}
+
+	if orig, ok := g.orders[o.replaces]; ok {
+		g.untrack(orig)
+		report := g.report(o, enum.ExecType_REPLACED)
+		report.SetOrigClOrdID(orig.clOrdID)
+		g.send(o, report)
+	}
+	o.replaces = ""
+}
+
+// cancelled reports a cancelled order, unless it is replaced, as the replacement reports it
+func (g *Gateway) cancelled(o *order) {
+	if o.replacedBy != "" {
+		o.cancelled = true
+		return
+	}
+
+	g.untrack(o)
+	report := g.report(o, enum.ExecType_CANCELED)
+	if o.cancelClOrdID != "" {
+		report.SetClOrdID(o.cancelClOrdID)
+		report.SetOrigClOrdID(o.clOrdID)
+	}
+	g.send(o, report)
+}
+
+// rejected reports a rejected order. A rejected replacement rejects the replace request, and
+// reports the replaced order cancelled if the matching engine cancelled it.
+func (g *Gateway) rejected(o *order, rejection *events.RejectionEvent) {
+	var text string
+	if rejection != nil {
+		text = rejection.Reason
+	}
+
+	g.untrack(o)
+	if o.replaces == "" {
+		report := g.report(o, enum.ExecType_REJECTED)
+		report.SetOrdRejReason(enum.OrdRejReason_OTHER)
+		report.SetText(text)
+		g.send(o, report)
+		return
+	}
+
+	orig, ok := g.orders[o.replaces]
+	if !ok {
+		return
+	}
+	orig.replacedBy = ""
+	g.cancelReject(o.sessionID, orig, o.clOrdID, orig.clOrdID, enum.CxlRejResponseTo_ORDER_CANCEL_REPLACE_REQUEST, text)
+	if orig.cancelled {
+		g.cancelled(orig)
+	}
+}
+
+// fill reports the fill of an order by a transaction
+func (g *Gateway) fill(o *order, transaction events.TransactionEvent) {
+	o.cumQty += transaction.Quantity
+	o.notional += transaction.Price * float64(transaction.Quantity)
+	if o.leavesQty() <= 0 {
+		g.untrack(o)
+	}
+
+	report := g.report(o, enum.ExecType_TRADE)
+	report.SetLastQty(decimal.NewFromInt(transaction.Quantity), 0)
+	report.SetLastPx(decimal.NewFromFloat(transaction.Price), priceScale)
+	g.send(o, report)
+}
+
+// reject reports an order rejected by the gateway before it is published
+func (g *Gateway) reject(o *order, reason enum.OrdRejReason, text string) {
+	report := g.report(o, enum.ExecType_REJECTED)
+	report.SetOrdRejReason(reason)
+	report.SetText(text)
+	g.send(o, report)
+}
+
+// report returns the execution report of the order with its current quantities
+func (g *Gateway) report(o *order, execType enum.ExecType) executionreport.ExecutionReport {
+	var avgPx float64
+	if o.cumQty > 0 {
+		avgPx = o.notional / float64(o.cumQty)
+	}
+	leavesQty := o.leavesQty()
+	if execType == enum.ExecType_CANCELED || execType == enum.ExecType_REJECTED {
+		leavesQty = 0
+	}
+
+	// An order rejected before it is accepted has no ID
+	orderID := o.id
+	if orderID == "" {
+		orderID = "NONE"
+	}
+	report := executionreport.New(
+		field.NewOrderID(orderID),
+		field.NewExecID(getUUID()),
+		field.NewExecType(execType),
+		field.NewOrdStatus(ordStatus(o, execType)),
+		field.NewSide(o.side),
+		field.NewLeavesQty(decimal.NewFromInt(leavesQty), 0),
+		field.NewCumQty(decimal.NewFromInt(o.cumQty), 0),
+		field.NewAvgPx(decimal.NewFromFloat(avgPx), priceScale),
+	)
+	report.SetClOrdID(o.clOrdID)
+	report.SetAccount(o.accountID)
+	report.SetSymbol(o.symbol)
+	report.SetOrderQty(decimal.NewFromInt(o.quantity), 0)
+	report.SetPrice(decimal.NewFromFloat(o.price), priceScale)
+	report.SetTransactTime(now())
+	return report
+}
+
+func ordStatus(o *order, execType enum.ExecType) enum.OrdStatus {
+	switch {
+	case execType == enum.ExecType_CANCELED:
+		return enum.OrdStatus_CANCELED
+	case execType == enum.ExecType_REJECTED:
+		return enum.OrdStatus_REJECTED
+	case o.cumQty == 0:
+		return enum.OrdStatus_NEW
+	case o.leavesQty() > 0:
+		return enum.OrdStatus_PARTIALLY_FILLED
+	default:
+		return enum.OrdStatus_FILLED
+	}
+}
+
+// cancelReject rejects a cancel or replace request of the order, which is nil if unknown
+func (g *Gateway) cancelReject(sessionID quickfix.SessionID, o *order, clOrdID, origClOrdID string, responseTo enum.CxlRejResponseTo, text string) {
+	orderID, status, reason := "NONE", enum.OrdStatus_REJECTED, enum.CxlRejReason_UNKNOWN_ORDER
+	if o != nil {
+		orderID, status, reason = o.id, ordStatus(o, enum.ExecType_NEW), enum.CxlRejReason_OTHER
+	}
+
+	reject := ordercancelreject.New(
+		field.NewOrderID(orderID),
+		field.NewClOrdID(clOrdID),
+		field.NewOrigClOrdID(origClOrdID),
+		field.NewOrdStatus(status),
+		field.NewCxlRejResponseTo(responseTo),
+	)
+	reject.SetCxlRejReason(reason)
+	reject.SetText(text)
+	if err := sendToTarget(reject, sessionID); err != nil {
+		logger.Error("failed to send order cancel reject", zap.Error(err), zap.String("session", sessionID.String()))
+	}
+}
+
+// send sends a report to the session of its order. The report to a session which is logged out
+// is stored with its sequence number, and resent when the session asks for it after its logon.
+func (g *Gateway) send(o *order, report executionreport.ExecutionReport) {
+	if err := sendToTarget(report, o.sessionID); err != nil {
+		logger.Error("failed to send execution report", zap.Error(err), zap.String("session", o.sessionID.String()))
+	}
+}
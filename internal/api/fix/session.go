@@ -0,0 +1,107 @@
+package fix
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quickfixgo/quickfix"
+	"github.com/quickfixgo/quickfix/config"
+	"go.uber.org/zap"
+)
+
+var ErrInvalidSessionAccount = errors.New("invalid session account, expect <TargetCompID>:<account>")
+
+// SessionConfig configures the FIX 4.4 sessions of the acceptor
+type SessionConfig struct {
+	SenderCompID string `env:"SENDER_COMP_ID" envDefault:"OMS"`
+	// TargetCompIDs are the clients, one session each, and no acceptor is started without any
+	TargetCompIDs []string `env:"TARGET_COMP_IDS"`
+	// Accounts are the accounts each session may trade, the first of a session is the one of its
+	// orders without an Account. A session without accounts may not trade, and logs on only with
+	// the Username and the Password of an API key of one of its accounts.
+	Accounts   []SessionAccount `env:"ACCOUNTS"`
+	Port       int              `env:"PORT" envDefault:"9878"`
+	HeartBtInt int              `env:"HEARTBT_INT" envDefault:"30"`
+	// StorePath keeps the sequence numbers and the sent messages of the sessions, so they are
+	// resumed after a restart and resent on a ResendRequest
+	StorePath string `env:"STORE_PATH" envDefault:"/data/fix"`
+	// RequestTimeout bounds the placing of an order, a cancel or a replace of the sessions
+	RequestTimeout time.Duration `env:"REQUEST_TIMEOUT" envDefault:"5s"`
+}
+
+// SessionAccount is an account a session may trade
+type SessionAccount struct {
+	TargetCompID string
+	AccountID    string
+}
+
+// UnmarshalText parses a session account as <TargetCompID>:<account>
+func (a *SessionAccount) UnmarshalText(text []byte) error {
+	targetCompID, accountID, ok := strings.Cut(string(text), ":")
+	if !ok || targetCompID == "" || accountID == "" {
+		return ErrInvalidSessionAccount
+	}
+	a.TargetCompID, a.AccountID = targetCompID, accountID
+	return nil
+}
+
+// NewSettings returns the settings of the acceptor. The sessions have no schedule, so their
+// sequence numbers are never reset by the acceptor.
+func NewSettings(cfg SessionConfig) (*quickfix.Settings, error) {
+	settings := quickfix.NewSettings()
+	global := settings.GlobalSettings()
+	global.Set(config.BeginString, quickfix.BeginStringFIX44)
+	global.Set(config.SenderCompID, cfg.SenderCompID)
+	global.Set(config.SocketAcceptPort, strconv.Itoa(cfg.Port))
+	global.Set(config.HeartBtInt, strconv.Itoa(cfg.HeartBtInt))
+	global.Set(config.FileStorePath, cfg.StorePath)
+	global.Set(config.PersistMessages, "Y")
+
+	for _, targetCompID := range cfg.TargetCompIDs {
+		session := quickfix.NewSessionSettings()
+		session.Set(config.TargetCompID, targetCompID)
+		if _, err := settings.AddSession(session); err != nil {
+			return nil, err
+		}
+	}
+	return settings, nil
+}
+
+// NewLogFactory logs the events of the sessions, and their messages at the debug level
+func NewLogFactory(logger *zap.Logger) quickfix.LogFactory {
+	return logFactory{logger: logger}
+}
+
+type logFactory struct {
+	logger *zap.Logger
+}
+
+func (f logFactory) Create() (quickfix.Log, error) {
+	return sessionLog{logger: f.logger}, nil
+}
+
+func (f logFactory) CreateSessionLog(sessionID quickfix.SessionID) (quickfix.Log, error) {
+	return sessionLog{logger: f.logger.With(zap.String("session", sessionID.String()))}, nil
+}
+
+type sessionLog struct {
+	logger *zap.Logger
+}
+
+func (l sessionLog) OnIncoming(msg []byte) {
+	l.logger.Debug("FIX incoming", zap.ByteString("msg", msg))
+}
+
+func (l sessionLog) OnOutgoing(msg []byte) {
+	l.logger.Debug("FIX outgoing", zap.ByteString("msg", msg))
+}
+
+func (l sessionLog) OnEvent(event string) {
+	l.logger.Info(event)
+}
+
+func (l sessionLog) OnEventf(format string, args ...interface{}) {
+	l.logger.Sugar().Infof(format, args...)
+}
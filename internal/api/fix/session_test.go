@@ -0,0 +1,171 @@
+package fix
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caarlos0/env/v11"
+	"github.com/quickfixgo/quickfix"
+	"github.com/quickfixgo/quickfix/store/file"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+
+	"github.com/Hao1995/order-matching-system/internal/api/auth"
+	"github.com/Hao1995/order-matching-system/pkg/mqkit"
+)
+
+// SessionTestSuite runs the acceptor of the settings, and talks to it as a raw FIX client
+type SessionTestSuite struct {
+	suite.Suite
+	cfg      SessionConfig
+	acceptor *quickfix.Acceptor
+	conn     net.Conn
+	reader   *bufio.Reader
+}
+
+func TestSessionTestSuite(t *testing.T) {
+	suite.Run(t, new(SessionTestSuite))
+}
+
+func (suite *SessionTestSuite) SetupTest() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	suite.Require().NoError(listener.Close())
+
+	suite.cfg = SessionConfig{
+		SenderCompID:  "OMS",
+		TargetCompIDs: []string{"CLIENT1"},
+		Port:          port,
+		HeartBtInt:    30,
+		StorePath:     suite.T().TempDir(),
+	}
+}
+
+func (suite *SessionTestSuite) TearDownTest() {
+	suite.stop()
+}
+
+func (suite *SessionTestSuite) start() {
+	settings, err := NewSettings(suite.cfg)
+	suite.Require().NoError(err)
+	service, _ := newService(mqkit.NewMemoryBroker())
+	gateway := NewGateway(service, []SessionAccount{{TargetCompID: "CLIENT1", AccountID: "alice"}}, WithAuthenticator(auth.NewAuthenticator(auth.Config{
+		Keys: []auth.Key{{ID: "alice-key", AccountID: "alice", Secret: "alice-secret"}},
+	})))
+	suite.acceptor, err = quickfix.NewAcceptor(gateway, file.NewStoreFactory(settings), settings, NewLogFactory(zap.NewNop()))
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.acceptor.Start())
+
+	suite.conn, err = net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", suite.cfg.Port))
+	suite.Require().NoError(err)
+	suite.reader = bufio.NewReader(suite.conn)
+}
+
+func (suite *SessionTestSuite) stop() {
+	if suite.conn != nil {
+		suite.conn.Close()
+		suite.conn = nil
+	}
+	if suite.acceptor != nil {
+		suite.acceptor.Stop()
+		suite.acceptor = nil
+	}
+}
+
+// send writes a message of the client with the header, body length and checksum
+func (suite *SessionTestSuite) send(msgType string, seqNum int, fields ...string) {
+	body := fmt.Sprintf("35=%s\x0149=CLIENT1\x0156=OMS\x0134=%d\x0152=%s\x01", msgType, seqNum, time.Now().UTC().Format("20060102-15:04:05.000"))
+	for _, f := range fields {
+		body += f + "\x01"
+	}
+	msg := fmt.Sprintf("8=FIX.4.4\x019=%d\x01%s", len(body), body)
+	var sum int
+	for i := 0; i < len(msg); i++ {
+		sum += int(msg[i])
+	}
+	msg += fmt.Sprintf("10=%03d\x01", sum%256)
+
+	_, err := suite.conn.Write([]byte(msg))
+	suite.Require().NoError(err)
+}
+
+// receive reads the next message of the acceptor as its fields by tag
+func (suite *SessionTestSuite) receive() map[string]string {
+	suite.Require().NoError(suite.conn.SetReadDeadline(time.Now().Add(5 * time.Second)))
+	fields := make(map[string]string)
+	for {
+		f, err := suite.reader.ReadString('\x01')
+		suite.Require().NoError(err)
+		tag, value, _ := strings.Cut(strings.TrimSuffix(f, "\x01"), "=")
+		fields[tag] = value
+		if tag == "10" {
+			return fields
+		}
+	}
+}
+
+func (suite *SessionTestSuite) TestLogon_PersistentSequenceNumbers() {
+	suite.start()
+	suite.send("A", 1, "98=0", "108=30", "553=alice-key", "554=alice-secret")
+	logon := suite.receive()
+	suite.Equal("A", logon["35"])
+	suite.Equal("1", logon["34"])
+
+	suite.send("1", 2, "112=ping")
+	heartbeat := suite.receive()
+	suite.Equal("0", heartbeat["35"])
+	suite.Equal("ping", heartbeat["112"])
+	suite.Equal("2", heartbeat["34"])
+
+	// The sequence numbers continue after a restart
+	suite.stop()
+	suite.start()
+	suite.send("A", 3, "98=0", "108=30", "553=alice-key", "554=alice-secret")
+	logon = suite.receive()
+	suite.Equal("A", logon["35"])
+	suite.Equal("4", logon["34"])
+}
+
+func (suite *SessionTestSuite) TestLogon_Rejected() {
+	suite.start()
+	suite.send("A", 1, "98=0", "108=30", "553=alice-key", "554=wrong")
+	logout := suite.receive()
+	suite.Equal("5", logout["35"])
+	suite.Equal("invalid username or password", logout["58"])
+}
+
+func (suite *SessionTestSuite) TestResendRequest() {
+	suite.start()
+	suite.send("A", 1, "98=0", "108=30", "553=alice-key", "554=alice-secret")
+	suite.Equal("A", suite.receive()["35"])
+
+	// The admin messages are resent as a gap fill
+	suite.send("2", 2, "7=1", "16=0")
+	gapFill := suite.receive()
+	suite.Equal("4", gapFill["35"])
+	suite.Equal("Y", gapFill["123"])
+	suite.Equal("1", gapFill["34"])
+	suite.Equal("2", gapFill["36"])
+}
+
+func (suite *SessionTestSuite) TestConfig() {
+	var cfg SessionConfig
+	suite.Require().NoError(env.ParseWithOptions(&cfg, env.Options{Environment: map[string]string{
+		"TARGET_COMP_IDS": "CLIENT1,CLIENT2",
+		"ACCOUNTS":        "CLIENT1:alice,CLIENT1:bob,CLIENT2:carol",
+	}}))
+	suite.Equal([]SessionAccount{
+		{TargetCompID: "CLIENT1", AccountID: "alice"},
+		{TargetCompID: "CLIENT1", AccountID: "bob"},
+		{TargetCompID: "CLIENT2", AccountID: "carol"},
+	}, cfg.Accounts)
+	suite.Equal("OMS", cfg.SenderCompID)
+
+	err := env.ParseWithOptions(&cfg, env.Options{Environment: map[string]string{"ACCOUNTS": "CLIENT1"}})
+	suite.ErrorContains(err, ErrInvalidSessionAccount.Error())
+}
@@ -0,0 +1,88 @@
+//go:generate go-enum --marshal
+package order
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/Hao1995/order-matching-system/internal/api/order/requests"
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	"github.com/Hao1995/order-matching-system/pkg/logger"
+	"github.com/Hao1995/order-matching-system/pkg/mqkit"
+)
+
+// ENUM(Accepted, Rejected)
+type BatchStatus string
+
+// BatchResult is the result of an order of a batch, in the order of the request
+type BatchResult struct {
+	// ID is the ID of an accepted order
+	ID     string      `json:"id,omitempty"`
+	Status BatchStatus `json:"status"`
+	// Error is the reason an order is rejected
+	Error string `json:"error,omitempty"`
+}
+
+// CreateBatch holds the funds of the orders of the batch one by one and publishes the accepted
+// ones at once. An invalid order or one without the funds is rejected alone, while a failure
+// to publish fails the whole batch and releases its holds.
+func (s *Service) CreateBatch(ctx context.Context, request requests.BatchCreateRequest) ([]BatchResult, error) {
+	if err := validate(request); err != nil {
+		return nil, err
+	}
+	if len(request.Orders) > s.maxBatchOrders {
+		return nil, fmt.Errorf("%w: %d orders exceed %d", ErrBatchTooLarge, len(request.Orders), s.maxBatchOrders)
+	}
+
+	results := make([]BatchResult, len(request.Orders))
+	var msgs []mqkit.Message
+	var held []string
+	for i, order := range request.Orders {
+		results[i].Status = BatchStatusRejected
+		if err := stamp(ctx, &order.AccountID); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		if err := validate(order); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+
+		data := newOrderEvent(order)
+		if err := s.hold(data); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		held = append(held, data.ID)
+
+		msg, err := s.message([]byte(data.Symbol), events.Event{
+			EventType: events.EventTypeCreateOrder,
+			Data:      data,
+			CreatedAt: data.CreatedAt,
+		})
+		if err != nil {
+			s.releaseAll(held)
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+		results[i] = BatchResult{ID: data.ID, Status: BatchStatusAccepted}
+	}
+	if len(msgs) == 0 {
+		return results, nil
+	}
+
+	logger.Debug("order batch.", zap.Int("orders", len(request.Orders)), zap.Int("accepted", len(msgs)))
+	if err := s.producer.PublishBatch(ctx, msgs); err != nil {
+		s.releaseAll(held)
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *Service) releaseAll(orderIDs []string) {
+	for _, orderID := range orderIDs {
+		s.release(orderID)
+	}
+}
@@ -0,0 +1,104 @@
+package order
+
+import (
+	"sync"
+
+	"github.com/Hao1995/order-matching-system/internal/api/account"
+)
+
+// executionBuffer is the number of executions a subscriber may fall behind before it is dropped
+const executionBuffer = 64
+
+// Execution is the part of a settled fill of an order of an account
+type Execution struct {
+	ID          string
+	OrderID     string
+	Symbol      string
+	Side        account.Side
+	Price       float64
+	Quantity    int64
+	Fee         float64
+	FeeCurrency string
+}
+
+// Executions fans out the settled fills to the subscribers of the accounts of their orders
+type Executions struct {
+	mu sync.Mutex
+	// subscribers maps AccountID to the channels of its subscribers
+	subscribers map[string]map[chan Execution]struct{}
+}
+
+func NewExecutions() *Executions {
+	return &Executions{
+		subscribers: make(map[string]map[chan Execution]struct{}),
+	}
+}
+
+// Subscribe returns the executions of the account and a function to unsubscribe. The channel is
+// closed once unsubscribed, or if the subscriber falls too far behind.
+func (e *Executions) Subscribe(accountID string) (<-chan Execution, func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ch := make(chan Execution, executionBuffer)
+	if e.subscribers[accountID] == nil {
+		e.subscribers[accountID] = make(map[chan Execution]struct{})
+	}
+	e.subscribers[accountID][ch] = struct{}{}
+
+	return ch, func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+
+		e.remove(accountID, ch)
+	}
+}
+
+// Settled publishes the executions of both orders of a settled fill, see account.WithSettled
+func (e *Executions) Settled(fill account.Fill, buy, sell account.Order) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.send(buy.AccountID, Execution{
+		ID:          fill.ID,
+		OrderID:     fill.BuyOrderID,
+		Symbol:      buy.Symbol,
+		Side:        account.SideBuy,
+		Price:       fill.Price,
+		Quantity:    fill.Quantity,
+		Fee:         fill.BuyFee.Amount,
+		FeeCurrency: fill.BuyFee.Currency,
+	})
+	e.send(sell.AccountID, Execution{
+		ID:          fill.ID,
+		OrderID:     fill.SellOrderID,
+		Symbol:      sell.Symbol,
+		Side:        account.SideSell,
+		Price:       fill.Price,
+		Quantity:    fill.Quantity,
+		Fee:         fill.SellFee.Amount,
+		FeeCurrency: fill.SellFee.Currency,
+	})
+}
+
+// send never blocks the settlement, a subscriber with a full buffer is dropped
+func (e *Executions) send(accountID string, execution Execution) {
+	for ch := range e.subscribers[accountID] {
+		select {
+		case ch <- execution:
+		default:
+			e.remove(accountID, ch)
+		}
+	}
+}
+
+func (e *Executions) remove(accountID string, ch chan Execution) {
+	if _, ok := e.subscribers[accountID][ch]; !ok {
+		return
+	}
+	close(ch)
+	delete(e.subscribers[accountID], ch)
+	if len(e.subscribers[accountID]) == 0 {
+		delete(e.subscribers, accountID)
+	}
+}
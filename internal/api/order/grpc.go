@@ -0,0 +1,163 @@
+//go:generate buf generate --template proto/buf.gen.yaml proto
+package order
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/Hao1995/order-matching-system/internal/api/account"
+	"github.com/Hao1995/order-matching-system/internal/api/auth"
+	"github.com/Hao1995/order-matching-system/internal/api/order/orderpb"
+	"github.com/Hao1995/order-matching-system/internal/api/order/requests"
+	"github.com/Hao1995/order-matching-system/internal/api/ratelimit"
+)
+
+// errUnauthenticated is returned without the auth interceptors, as the account of a call is
+// the one of its API key
+var errUnauthenticated = status.Error(codes.Unauthenticated, "unauthenticated")
+
+// GRPCHandler serves the orderpb.OrderService with the same Service as the REST Handler
+type GRPCHandler struct {
+	orderpb.UnimplementedOrderServiceServer
+	service    *Service
+	executions *Executions
+}
+
+func NewGRPCHandler(service *Service, executions *Executions) *GRPCHandler {
+	return &GRPCHandler{
+		service:    service,
+		executions: executions,
+	}
+}
+
+// RegisterGRPC registers the order service, of a server with the auth interceptors
+func RegisterGRPC(s grpc.ServiceRegistrar, handler *GRPCHandler) {
+	orderpb.RegisterOrderServiceServer(s, handler)
+}
+
+// RateLimitInterceptor limits the order calls of every account by the buckets of the REST routes,
+// the creations and amends by the create limiter and the cancellations by the cancel one. It is
+// chained after the auth interceptor, a call without an account is rejected by its handler.
+func RateLimitInterceptor(create, cancel *ratelimit.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var limiter *ratelimit.Limiter
+		switch info.FullMethod {
+		case orderpb.OrderService_CreateOrder_FullMethodName, orderpb.OrderService_AmendOrder_FullMethodName:
+			limiter = create
+		case orderpb.OrderService_CancelOrder_FullMethodName:
+			limiter = cancel
+		}
+		if accountID, ok := auth.AccountID(ctx); ok && limiter != nil {
+			if result, allowed := limiter.Take(ctx, accountID, 1); !allowed {
+				return nil, status.Errorf(codes.ResourceExhausted, "too many requests, retry after %s", result.RetryAfter)
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// CreateOrder handles the creation of a new order.
+func (hlr *GRPCHandler) CreateOrder(ctx context.Context, req *orderpb.CreateOrderRequest) (*orderpb.OrderReply, error) {
+	if _, ok := auth.AccountID(ctx); !ok {
+		return nil, errUnauthenticated
+	}
+	id, err := hlr.service.Create(ctx, requests.CreateRequest{
+		Symbol:      req.GetSymbol(),
+		Type:        req.GetType(),
+		Price:       req.GetPrice(),
+		Quantity:    req.GetQuantity(),
+		TimeInForce: req.GetTimeInForce(),
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &orderpb.OrderReply{Id: id}, nil
+}
+
+// CancelOrder handles the cancellation of an order.
+func (hlr *GRPCHandler) CancelOrder(ctx context.Context, req *orderpb.CancelOrderRequest) (*orderpb.OrderReply, error) {
+	if _, ok := auth.AccountID(ctx); !ok {
+		return nil, errUnauthenticated
+	}
+	if err := hlr.service.Cancel(ctx, req.GetId()); err != nil {
+		return nil, grpcError(err)
+	}
+	return &orderpb.OrderReply{Id: req.GetId()}, nil
+}
+
+// AmendOrder handles the replacement of an open order.
+func (hlr *GRPCHandler) AmendOrder(ctx context.Context, req *orderpb.AmendOrderRequest) (*orderpb.OrderReply, error) {
+	if _, ok := auth.AccountID(ctx); !ok {
+		return nil, errUnauthenticated
+	}
+	id, err := hlr.service.Amend(ctx, requests.AmendRequest{
+		ID:          req.GetId(),
+		Price:       req.GetPrice(),
+		Quantity:    req.GetQuantity(),
+		TimeInForce: req.GetTimeInForce(),
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &orderpb.OrderReply{Id: id}, nil
+}
+
+// SubscribeExecutions streams the executions of an account until the client leaves, or it falls
+// too far behind.
+func (hlr *GRPCHandler) SubscribeExecutions(req *orderpb.SubscribeExecutionsRequest, stream orderpb.OrderService_SubscribeExecutionsServer) error {
+	accountID, ok := auth.AccountID(stream.Context())
+	if !ok {
+		return errUnauthenticated
+	}
+
+	executions, unsubscribe := hlr.executions.Subscribe(accountID)
+	defer unsubscribe()
+	// The header tells the client it is subscribed, so it may wait for it before placing orders
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case execution, ok := <-executions:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "subscriber fell behind")
+			}
+			if err := stream.Send(&orderpb.Execution{
+				Id:          execution.ID,
+				OrderId:     execution.OrderID,
+				Symbol:      execution.Symbol,
+				Side:        execution.Side.String(),
+				Price:       execution.Price,
+				Quantity:    execution.Quantity,
+				Fee:         execution.Fee,
+				FeeCurrency: execution.FeeCurrency,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// grpcError maps an error of the Service to its status
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, ErrInvalidRequest):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrOrderNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, account.ErrInsufficientBalance):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, "failed to accept the order")
+	}
+}
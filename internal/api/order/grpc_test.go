@@ -0,0 +1,280 @@
+package order
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Hao1995/order-matching-system/internal/api/account"
+	"github.com/Hao1995/order-matching-system/internal/api/auth"
+	"github.com/Hao1995/order-matching-system/internal/api/order/orderpb"
+	"github.com/Hao1995/order-matching-system/internal/api/ratelimit"
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	"github.com/Hao1995/order-matching-system/pkg/mqkit"
+)
+
+const orderTopic = "AAPL_ORDER"
+
+type GRPCTestSuite struct {
+	suite.Suite
+	broker  *mqkit.MemoryBroker
+	ledger  *account.Ledger
+	settler *account.Settler
+	server  *grpc.Server
+	conn    *grpc.ClientConn
+	client  orderpb.OrderServiceClient
+}
+
+func TestGRPCTestSuite(t *testing.T) {
+	suite.Run(t, new(GRPCTestSuite))
+}
+
+func (suite *GRPCTestSuite) SetupTest() {
+	suite.broker = mqkit.NewMemoryBroker()
+	suite.ledger = account.NewLedger("USD")
+	executions := NewExecutions()
+	suite.settler = account.NewSettler(suite.ledger, account.WithSettled(executions.Settled))
+	producer := mqkit.NewMemoryProducer(suite.broker, orderTopic, []byte("AAPL"))
+	service := NewService(producer, events.JSONCodec, orderTopic, suite.ledger)
+
+	listener := bufconn.Listen(1024 * 1024)
+	authenticator := auth.NewAuthenticator(auth.Config{
+		Keys:         []auth.Key{{ID: "alice-key", AccountID: "alice", Secret: "alice-secret"}, {ID: "bob-key", AccountID: "bob", Secret: "bob-secret"}},
+		ReplayWindow: time.Minute,
+	})
+	suite.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(authenticator.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(authenticator.StreamServerInterceptor()),
+	)
+	RegisterGRPC(suite.server, NewGRPCHandler(service, executions))
+	go suite.server.Serve(listener)
+
+	var err error
+	suite.conn, err = grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	suite.Require().NoError(err)
+	suite.client = orderpb.NewOrderServiceClient(suite.conn)
+
+	_, err = suite.ledger.Deposit("alice", "USD", 10000)
+	suite.Require().NoError(err)
+	_, err = suite.ledger.Deposit("bob", "AAPL", 100)
+	suite.Require().NoError(err)
+}
+
+func (suite *GRPCTestSuite) TearDownTest() {
+	suite.conn.Close()
+	suite.server.Stop()
+}
+
+// signed returns the context of a call of the request, signed by the key of the account
+func (suite *GRPCTestSuite) signed(accountID, fullMethod string, req proto.Message) context.Context {
+	ctx, err := auth.NewOutgoingContext(context.Background(), accountID+"-key", accountID+"-secret", fullMethod, req)
+	suite.Require().NoError(err)
+	return ctx
+}
+
+func (suite *GRPCTestSuite) create(accountID string, req *orderpb.CreateOrderRequest) (*orderpb.OrderReply, error) {
+	return suite.client.CreateOrder(suite.signed(accountID, orderpb.OrderService_CreateOrder_FullMethodName, req), req)
+}
+
+func (suite *GRPCTestSuite) cancel(accountID string, req *orderpb.CancelOrderRequest) (*orderpb.OrderReply, error) {
+	return suite.client.CancelOrder(suite.signed(accountID, orderpb.OrderService_CancelOrder_FullMethodName, req), req)
+}
+
+func (suite *GRPCTestSuite) amend(accountID string, req *orderpb.AmendOrderRequest) (*orderpb.OrderReply, error) {
+	return suite.client.AmendOrder(suite.signed(accountID, orderpb.OrderService_AmendOrder_FullMethodName, req), req)
+}
+
+// published decodes the order events published to the order topic
+func (suite *GRPCTestSuite) published() []events.Event {
+	var published []events.Event
+	for _, msg := range suite.broker.Messages(orderTopic, 0) {
+		suite.Equal(events.ContentTypeJSON, msg.Headers[events.ContentTypeHeader])
+		event, err := events.JSONCodec.DecodeEvent(msg.Value)
+		suite.Require().NoError(err)
+		published = append(published, event)
+	}
+	return published
+}
+
+func (suite *GRPCTestSuite) TestCreateOrder() {
+	reply, err := suite.create("alice", &orderpb.CreateOrderRequest{
+		Symbol: "AAPL", Type: "Buy", Price: 100, Quantity: 10,
+	})
+	suite.Require().NoError(err)
+
+	published := suite.published()
+	suite.Require().Len(published, 1)
+	suite.Equal(events.EventTypeCreateOrder, published[0].EventType)
+	orderEvent := published[0].Data.(events.OrderEvent)
+	suite.Equal(reply.GetId(), orderEvent.ID)
+	suite.Equal(int64(10), orderEvent.Quantity)
+
+	order, ok := suite.ledger.Order(reply.GetId())
+	suite.True(ok)
+	suite.Equal("alice", order.AccountID)
+}
+
+func (suite *GRPCTestSuite) TestCreateOrder_Errors() {
+	// The request is validated as requests.CreateRequest
+	_, err := suite.create("alice", &orderpb.CreateOrderRequest{
+		Symbol: "AAPL", Type: "Hold", Price: 100, Quantity: 10,
+	})
+	suite.Equal(codes.InvalidArgument, status.Code(err))
+
+	_, err = suite.create("alice", &orderpb.CreateOrderRequest{
+		Symbol: "AAPL", Type: "Buy", Price: 100, Quantity: 1000,
+	})
+	suite.Equal(codes.FailedPrecondition, status.Code(err))
+	suite.Empty(suite.published())
+}
+
+func (suite *GRPCTestSuite) TestCancelOrder() {
+	created, err := suite.create("bob", &orderpb.CreateOrderRequest{
+		Symbol: "AAPL", Type: "Sell", Price: 100, Quantity: 10,
+	})
+	suite.Require().NoError(err)
+
+	_, err = suite.cancel("bob", &orderpb.CancelOrderRequest{Id: created.GetId()})
+	suite.Require().NoError(err)
+	published := suite.published()
+	suite.Require().Len(published, 2)
+	suite.Equal(events.EventTypeCancelOrder, published[1].EventType)
+	suite.Equal(events.OrderEvent{ID: created.GetId(), Symbol: "AAPL"}, published[1].Data)
+
+	_, err = suite.cancel("bob", &orderpb.CancelOrderRequest{Id: "order1"})
+	suite.Equal(codes.InvalidArgument, status.Code(err))
+}
+
+func (suite *GRPCTestSuite) TestAmendOrder() {
+	created, err := suite.create("alice", &orderpb.CreateOrderRequest{
+		Symbol: "AAPL", Type: "Buy", Price: 100, Quantity: 10,
+	})
+	suite.Require().NoError(err)
+
+	amended, err := suite.amend("alice", &orderpb.AmendOrderRequest{
+		Id: created.GetId(), Price: 99, Quantity: 5,
+	})
+	suite.Require().NoError(err)
+	suite.NotEqual(created.GetId(), amended.GetId())
+
+	published := suite.published()
+	suite.Require().Len(published, 2)
+	suite.Equal(events.EventTypeAmendOrder, published[1].EventType)
+	orderEvent := published[1].Data.(events.OrderEvent)
+	suite.Equal(amended.GetId(), orderEvent.ID)
+	suite.Equal(created.GetId(), orderEvent.OrigID)
+	suite.Equal("alice", orderEvent.AccountID)
+	suite.Equal("Buy", orderEvent.Type)
+	suite.Equal(99.0, orderEvent.Price)
+
+	_, err = suite.amend("alice", &orderpb.AmendOrderRequest{
+		Id: "0f0c6d4e-8f7a-4b8e-9a51-7c2d3e4f5a6b", Price: 99, Quantity: 5,
+	})
+	suite.Equal(codes.NotFound, status.Code(err))
+}
+
+func (suite *GRPCTestSuite) TestSubscribeExecutions() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req := &orderpb.SubscribeExecutionsRequest{}
+	signed, err := auth.NewOutgoingContext(ctx, "alice-key", "alice-secret", orderpb.OrderService_SubscribeExecutions_FullMethodName, req)
+	suite.Require().NoError(err)
+	stream, err := suite.client.SubscribeExecutions(signed, req)
+	suite.Require().NoError(err)
+	// Wait for the subscription
+	_, err = stream.Header()
+	suite.Require().NoError(err)
+
+	buy, err := suite.create("alice", &orderpb.CreateOrderRequest{
+		Symbol: "AAPL", Type: "Buy", Price: 100, Quantity: 10,
+	})
+	suite.Require().NoError(err)
+	sell, err := suite.create("bob", &orderpb.CreateOrderRequest{
+		Symbol: "AAPL", Type: "Sell", Price: 100, Quantity: 4,
+	})
+	suite.Require().NoError(err)
+
+	val, err := events.JSONCodec.EncodeMatchingEvent(events.MatchingEvent{
+		Type: events.MatchingEventTypeCreate,
+		Transactions: []events.TransactionEvent{{
+			ID: "transaction1", Symbol: "AAPL", BuyOrderID: buy.GetId(), SellOrderID: sell.GetId(), Price: 100, Quantity: 4,
+			BuyFee: events.FeeEvent{Amount: 0.4, Currency: "USD"},
+		}},
+	})
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.settler.Handle(val))
+
+	execution, err := stream.Recv()
+	suite.Require().NoError(err)
+	suite.True(proto.Equal(&orderpb.Execution{
+		Id: "transaction1", OrderId: buy.GetId(), Symbol: "AAPL", Side: "Buy", Price: 100, Quantity: 4, Fee: 0.4, FeeCurrency: "USD",
+	}, execution), execution.String())
+}
+
+func (suite *GRPCTestSuite) TestAuth() {
+	req := &orderpb.CreateOrderRequest{Symbol: "AAPL", Type: "Buy", Price: 100, Quantity: 10}
+	_, err := suite.client.CreateOrder(context.Background(), req)
+	suite.Equal(codes.Unauthenticated, status.Code(err))
+
+	// The signature covers the request
+	ctx := suite.signed("alice", orderpb.OrderService_CreateOrder_FullMethodName, req)
+	_, err = suite.client.CreateOrder(ctx, &orderpb.CreateOrderRequest{Symbol: "AAPL", Type: "Buy", Price: 100, Quantity: 100})
+	suite.Equal(codes.Unauthenticated, status.Code(err))
+	created, err := suite.client.CreateOrder(ctx, req)
+	suite.Require().NoError(err)
+	_, err = suite.client.CreateOrder(ctx, req)
+	suite.Equal(codes.Unauthenticated, status.Code(err), "replayed")
+
+	// The orders of another account are neither cancelled nor amended
+	_, err = suite.cancel("bob", &orderpb.CancelOrderRequest{Id: created.GetId()})
+	suite.Equal(codes.PermissionDenied, status.Code(err))
+	_, err = suite.amend("bob", &orderpb.AmendOrderRequest{Id: created.GetId(), Price: 99, Quantity: 5})
+	suite.Equal(codes.PermissionDenied, status.Code(err))
+	suite.Len(suite.published(), 1)
+
+	stream, err := suite.client.SubscribeExecutions(context.Background(), &orderpb.SubscribeExecutionsRequest{})
+	suite.Require().NoError(err)
+	_, err = stream.Recv()
+	suite.Equal(codes.Unauthenticated, status.Code(err))
+}
+
+func (suite *GRPCTestSuite) TestRateLimitInterceptor() {
+	store := ratelimit.NewMemoryStore()
+	interceptor := RateLimitInterceptor(
+		ratelimit.NewLimiter(store, "create", ratelimit.Limit{Rate: 0.001, Burst: 1}),
+		ratelimit.NewLimiter(store, "cancel", ratelimit.Limit{Rate: 0.001, Burst: 1}),
+	)
+	handler := func(ctx context.Context, req any) (any, error) {
+		return &orderpb.OrderReply{}, nil
+	}
+	call := func(ctx context.Context, fullMethod string) error {
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: fullMethod}, handler)
+		return err
+	}
+	alice := auth.NewContext(context.Background(), "alice")
+
+	// The creations and the amends share a bucket
+	suite.NoError(call(alice, orderpb.OrderService_CreateOrder_FullMethodName))
+	suite.Equal(codes.ResourceExhausted, status.Code(call(alice, orderpb.OrderService_AmendOrder_FullMethodName)))
+
+	// The cancellations and the other accounts have buckets of their own
+	suite.NoError(call(alice, orderpb.OrderService_CancelOrder_FullMethodName))
+	suite.NoError(call(auth.NewContext(context.Background(), "bob"), orderpb.OrderService_CreateOrder_FullMethodName))
+
+	// A call without an account is left to its handler
+	suite.NoError(call(context.Background(), orderpb.OrderService_CreateOrder_FullMethodName))
+}
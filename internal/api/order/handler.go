@@ -2,71 +2,56 @@ package order
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
+	"github.com/gin-gonic/gin/binding"
 	"go.uber.org/zap"
 
+	"github.com/Hao1995/order-matching-system/internal/api/account"
 	"github.com/Hao1995/order-matching-system/internal/api/order/requests"
-	"github.com/Hao1995/order-matching-system/internal/common/models/events"
 	"github.com/Hao1995/order-matching-system/pkg/logger"
-	"github.com/Hao1995/order-matching-system/pkg/mqkit"
-)
-
-var (
-	now = func() time.Time {
-		return time.Now()
-	}
 )
 
 type Handler struct {
-	producer mqkit.Producer
-	topic    string
+	service *Service
 }
 
-func NewHandler(p mqkit.Producer, topic string) *Handler {
+func NewHandler(service *Service) *Handler {
 	return &Handler{
-		producer: p,
-		topic:    topic,
+		service: service,
 	}
 }
 
-// Create handles the creation of a new order.
+// Create handles the creation of a new order, and responds with its ID which cancels or amends
+// it. The request is validated by the Service once the account is stamped, as the account may
+// be left to the authenticated one.
 func (hlr *Handler) Create(c *gin.Context) {
 	var request requests.CreateRequest
-	if err := c.ShouldBindJSON(&request); err != nil {
+	if err := json.NewDecoder(c.Request.Body).Decode(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order data"})
 		return
 	}
 
-	orderEvent := events.Event{
-		EventType: events.EventTypeCreateOrder,
-		Data: events.OrderEvent{
-			ID:        uuid.NewString(),
-			Symbol:    request.Symbol,
-			Type:      request.Type,
-			Price:     request.Price,
-			Quantity:  request.Quantity,
-			CreatedAt: now(),
-		},
-	}
-
-	logger.Debug("CreateOrder. order event.", zap.Any("orderEvent", orderEvent))
-	val, err := json.Marshal(orderEvent)
-	if err != nil {
-		logger.Error("failed to json marshal event", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to json marshal"})
-	}
-
-	err = hlr.producer.Publish(c.Request.Context(), val)
+	id, err := hlr.service.Create(c.Request.Context(), request)
 	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidRequest):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order data"})
+			return
+		case errors.Is(err, ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		case errors.Is(err, account.ErrInsufficientBalance):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Insufficient balance"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed create a Create Order request"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "The Create Order request has been accepted"})
+	c.JSON(http.StatusCreated, gin.H{"id": id, "message": "The Create Order request has been accepted"})
 }
 
 // Cancel handles the cancellation of an order.
@@ -79,24 +64,67 @@ func (hlr *Handler) Cancel(c *gin.Context) {
 		return
 	}
 
-	event := events.Event{
-		EventType: events.EventTypeCancelOrder,
-		Data: events.OrderEvent{
-			ID: request.ID,
-		},
+	if err := hlr.service.Cancel(c.Request.Context(), request.ID); err != nil {
+		switch {
+		case errors.Is(err, ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		case errors.Is(err, ErrOrderNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed create a Cancel Order request"})
+		return
 	}
 
-	val, err := json.Marshal(event)
-	if err != nil {
-		logger.Error("failed to json marshal event", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to json marshal"})
+	c.JSON(http.StatusCreated, gin.H{"message": "The Cancel Order request has been accepted"})
+}
+
+// CreateBatch handles the creation of a batch of orders, and responds with the result of every
+// order of the batch.
+func (hlr *Handler) CreateBatch(c *gin.Context) {
+	// The body may be bound already by the rate limit of the batch
+	var request requests.BatchCreateRequest
+	if err := c.ShouldBindBodyWith(&request, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid batch data"})
+		return
 	}
 
-	err = hlr.producer.Publish(c.Request.Context(), val)
+	results, err := hlr.service.CreateBatch(c.Request.Context(), request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed create a Cancel Order request"})
+		if errors.Is(err, ErrBatchTooLarge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed create a Batch Create Order request"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "The Cancel Order request has been accepted"})
+	c.JSON(http.StatusMultiStatus, gin.H{"results": results})
+}
+
+// MassCancel handles the cancellation of all the open orders of an account in a symbol. The
+// query is validated by the Service once the account is stamped.
+func (hlr *Handler) MassCancel(c *gin.Context) {
+	var request requests.MassCancelRequest
+	if err := binding.MapFormWithTag(&request, c.Request.URL.Query(), "form"); err != nil {
+		logger.Error("failed to bind query", zap.Error(err))
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	if _, err := hlr.service.MassCancel(c.Request.Context(), request); err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidRequest):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid request data"})
+			return
+		case errors.Is(err, ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed create a Mass Cancel request"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "The Mass Cancel request has been accepted"})
 }
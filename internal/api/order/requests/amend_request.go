@@ -0,0 +1,9 @@
+package requests
+
+type AmendRequest struct {
+	ID       string  `uri:"id" binding:"required,uuid"`
+	Price    float64 `form:"price" binding:"required,gt=0"`
+	Quantity int64   `form:"quantity" binding:"required,gt=0"`
+	// TimeInForce is GTC by default, the one of the replaced order is not kept
+	TimeInForce string `form:"time_in_force" json:"time_in_force" binding:"omitempty,oneof=GTC Day"`
+}
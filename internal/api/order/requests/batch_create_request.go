@@ -0,0 +1,6 @@
+package requests
+
+type BatchCreateRequest struct {
+	// Orders are validated one by one, an invalid order rejects only itself
+	Orders []CreateRequest `json:"orders" binding:"required,min=1"`
+}
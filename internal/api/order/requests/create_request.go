@@ -1,8 +1,11 @@
 package requests
 
 type CreateRequest struct {
-	Symbol   string  `form:"symbol" binding:"required"`
-	Type     string  `form:"type" binding:"required,oneof=Buy Sell"`
-	Price    float64 `form:"price" binding:"required,gt=0"`
-	Quantity int64   `form:"quantity" binding:"required,gt=0"`
+	AccountID string  `form:"account_id" json:"account_id" binding:"required"`
+	Symbol    string  `form:"symbol" binding:"required"`
+	Type      string  `form:"type" binding:"required,oneof=Buy Sell"`
+	Price     float64 `form:"price" binding:"required,gt=0"`
+	Quantity  int64   `form:"quantity" binding:"required,gt=0"`
+	// TimeInForce is GTC by default, Day orders are cancelled when the session closes
+	TimeInForce string `form:"time_in_force" json:"time_in_force" binding:"omitempty,oneof=GTC Day"`
 }
@@ -0,0 +1,8 @@
+package requests
+
+type MassCancelRequest struct {
+	AccountID string `form:"account_id" binding:"required"`
+	Symbol    string `form:"symbol" binding:"required"`
+	// Side cancels the orders of both sides if empty
+	Side string `form:"side" binding:"omitempty,oneof=Buy Sell"`
+}
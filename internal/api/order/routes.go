@@ -1,8 +1,76 @@
 package order
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 
-func RegisterRoutes(r *gin.Engine, handler *Handler) {
-	r.POST("/orders", handler.Create)
-	r.DELETE("/orders/:id", handler.Cancel)
+	"github.com/Hao1995/order-matching-system/internal/api/order/requests"
+	"github.com/Hao1995/order-matching-system/internal/api/ratelimit"
+)
+
+// RouteOption configures the middlewares of the order routes
+type RouteOption func(*routes)
+
+type routes struct {
+	store  ratelimit.Store
+	create ratelimit.Limit
+	cancel ratelimit.Limit
+}
+
+// WithRateLimits limits the creations and the cancellations of every account by their own token
+// buckets in the store. A batch takes a token per order, and a mass cancel a token per open order
+// it cancels. A limit without a rate is unlimited.
+func WithRateLimits(store ratelimit.Store, create, cancel ratelimit.Limit) RouteOption {
+	return func(r *routes) {
+		r.store = store
+		r.create = create
+		r.cancel = cancel
+	}
+}
+
+// RegisterRoutes registers the order routes, of a group with the auth middleware in production
+func RegisterRoutes(r gin.IRouter, handler *Handler, opts ...RouteOption) {
+	var rs routes
+	for _, opt := range opts {
+		opt(&rs)
+	}
+
+	var create, createBatch, cancel, massCancel []gin.HandlerFunc
+	if rs.store != nil && rs.create.Rate > 0 {
+		create = append(create, ratelimit.Middleware(rs.store, "create", rs.create))
+		createBatch = append(createBatch, ratelimit.CostMiddleware(rs.store, "create", rs.create, batchCost))
+	}
+	if rs.store != nil && rs.cancel.Rate > 0 {
+		cancel = append(cancel, ratelimit.Middleware(rs.store, "cancel", rs.cancel))
+		massCancel = append(massCancel, ratelimit.CostMiddleware(rs.store, "cancel", rs.cancel, handler.massCancelCost))
+	}
+
+	r.POST("/orders", chain(create, handler.Create)...)
+	r.POST("/orders/batch", chain(createBatch, handler.CreateBatch)...)
+	r.DELETE("/orders", chain(massCancel, handler.MassCancel)...)
+	r.DELETE("/orders/:id", chain(cancel, handler.Cancel)...)
+}
+
+// batchCost is the number of orders of a batch, whose body is kept for the handler. An invalid
+// batch takes a token and is rejected by the handler.
+func batchCost(c *gin.Context) int {
+	var request requests.BatchCreateRequest
+	if err := c.ShouldBindBodyWith(&request, binding.JSON); err != nil {
+		return 1
+	}
+	return max(len(request.Orders), 1)
+}
+
+// massCancelCost is the number of open orders a mass cancel cancels, at least a token
+func (hlr *Handler) massCancelCost(c *gin.Context) int {
+	var request requests.MassCancelRequest
+	if err := binding.MapFormWithTag(&request, c.Request.URL.Query(), "form"); err != nil {
+		return 1
+	}
+	return max(hlr.service.massCancelled(c.Request.Context(), request), 1)
+}
+
+// chain returns the middlewares followed by the handler, in a slice of its own
+func chain(middlewares []gin.HandlerFunc, handler gin.HandlerFunc) []gin.HandlerFunc {
+	return append(middlewares[:len(middlewares):len(middlewares)], handler)
 }
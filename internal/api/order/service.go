@@ -0,0 +1,300 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/Hao1995/order-matching-system/internal/api/account"
+	"github.com/Hao1995/order-matching-system/internal/api/auth"
+	"github.com/Hao1995/order-matching-system/internal/api/order/requests"
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	"github.com/Hao1995/order-matching-system/pkg/logger"
+	"github.com/Hao1995/order-matching-system/pkg/mqkit"
+)
+
+const defaultMaxBatchOrders = 100
+
+var (
+	ErrInvalidRequest = errors.New("invalid request")
+	ErrOrderNotFound  = errors.New("order not found")
+	ErrBatchTooLarge  = errors.New("batch too large")
+	ErrForbidden      = errors.New("forbidden")
+)
+
+var (
+	now = func() time.Time {
+		return time.Now()
+	}
+)
+
+// Service holds the funds of the orders and publishes their events to the order topic. It is
+// shared by the REST and gRPC handlers.
+type Service struct {
+	producer mqkit.Producer
+	codec    events.Codec
+	topic    string
+	ledger   *account.Ledger
+	// maxBatchOrders is the most orders of a CreateBatch
+	maxBatchOrders int
+}
+
+// ServiceOption configures optional behaviors of a Service
+type ServiceOption func(*Service)
+
+// WithMaxBatchOrders limits the number of orders of a batch, 100 by default
+func WithMaxBatchOrders(n int) ServiceOption {
+	return func(s *Service) {
+		s.maxBatchOrders = n
+	}
+}
+
+func NewService(p mqkit.Producer, codec events.Codec, topic string, ledger *account.Ledger, opts ...ServiceOption) *Service {
+	s := &Service{
+		producer:       p,
+		codec:          codec,
+		topic:          topic,
+		ledger:         ledger,
+		maxBatchOrders: defaultMaxBatchOrders,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Create holds the funds of a new order and publishes it, and returns the ID of the order
+func (s *Service) Create(ctx context.Context, request requests.CreateRequest) (string, error) {
+	if err := stamp(ctx, &request.AccountID); err != nil {
+		return "", err
+	}
+	if err := validate(request); err != nil {
+		return "", err
+	}
+
+	data := newOrderEvent(request)
+	return data.ID, s.holdAndPublish(ctx, events.EventTypeCreateOrder, data)
+}
+
+// Cancel publishes the cancellation of an order
+func (s *Service) Cancel(ctx context.Context, id string) error {
+	if err := validate(requests.CancelRequest{ID: id}); err != nil {
+		return err
+	}
+
+	data := events.OrderEvent{
+		ID: id,
+	}
+	order, ok := s.ledger.Order(id)
+	if _, authenticated := auth.AccountID(ctx); authenticated && !ok {
+		// The owner of an order which is not open is unknown
+		return fmt.Errorf("%w: %s", ErrOrderNotFound, id)
+	}
+	// The open order is cancelled in the partition of its symbol
+	if ok {
+		if err := checkOwner(ctx, order); err != nil {
+			return err
+		}
+		data.Symbol = order.Symbol
+	}
+	event := events.Event{
+		EventType: events.EventTypeCancelOrder,
+		Data:      data,
+		CreatedAt: now(),
+	}
+
+	// An unknown order has no symbol, and is published with the key of the producer
+	var key []byte
+	if data.Symbol != "" {
+		key = []byte(data.Symbol)
+	}
+	return s.publish(ctx, key, event)
+}
+
+// Amend publishes a new order which replaces the open order of the request, and returns the ID
+// of the new order. The new order is held in addition to the replaced one until the replaced
+// one is cancelled by the matching engine.
+func (s *Service) Amend(ctx context.Context, request requests.AmendRequest) (string, error) {
+	if err := validate(request); err != nil {
+		return "", err
+	}
+
+	order, ok := s.ledger.Order(request.ID)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrOrderNotFound, request.ID)
+	}
+	if err := checkOwner(ctx, order); err != nil {
+		return "", err
+	}
+	data := events.OrderEvent{
+		ID:          uuid.NewString(),
+		OrigID:      order.ID,
+		AccountID:   order.AccountID,
+		Symbol:      order.Symbol,
+		Type:        string(order.Side),
+		Price:       request.Price,
+		Quantity:    request.Quantity,
+		TimeInForce: request.TimeInForce,
+		CreatedAt:   now(),
+	}
+	return data.ID, s.holdAndPublish(ctx, events.EventTypeAmendOrder, data)
+}
+
+// MassCancel publishes the cancellation of all the open orders of an account in a symbol, of
+// one side or both, which the matching engine cancels at once. It returns the ID of the event.
+func (s *Service) MassCancel(ctx context.Context, request requests.MassCancelRequest) (string, error) {
+	if err := stamp(ctx, &request.AccountID); err != nil {
+		return "", err
+	}
+	if err := validate(request); err != nil {
+		return "", err
+	}
+
+	data := events.OrderEvent{
+		ID:        uuid.NewString(),
+		AccountID: request.AccountID,
+		Symbol:    request.Symbol,
+		Type:      request.Side,
+		CreatedAt: now(),
+	}
+	event := events.Event{
+		EventType: events.EventTypeMassCancel,
+		Data:      data,
+		CreatedAt: data.CreatedAt,
+	}
+	return data.ID, s.publish(ctx, []byte(data.Symbol), event)
+}
+
+// massCancelled returns the number of open orders the mass cancel of the request cancels
+func (s *Service) massCancelled(ctx context.Context, request requests.MassCancelRequest) int {
+	if err := stamp(ctx, &request.AccountID); err != nil {
+		return 0
+	}
+
+	n := 0
+	for _, order := range s.ledger.Orders(request.AccountID) {
+		if order.Symbol == request.Symbol && (request.Side == "" || string(order.Side) == request.Side) {
+			n++
+		}
+	}
+	return n
+}
+
+// newOrderEvent returns the order event of a new order
+func newOrderEvent(request requests.CreateRequest) events.OrderEvent {
+	return events.OrderEvent{
+		ID:        uuid.NewString(),
+		AccountID: request.AccountID,
+		Symbol:    request.Symbol,
+		Type:      request.Type,
+		Price:     request.Price,
+		Quantity:  request.Quantity,
+		// TimeInForce is GTC by default
+		TimeInForce: request.TimeInForce,
+		CreatedAt:   now(),
+	}
+}
+
+// holdAndPublish holds the funds of the order before it reaches the matching engine, and
+// releases them if the order fails to be published
+func (s *Service) holdAndPublish(ctx context.Context, eventType events.EventType, data events.OrderEvent) error {
+	if err := s.hold(data); err != nil {
+		return err
+	}
+
+	event := events.Event{
+		EventType: eventType,
+		Data:      data,
+		CreatedAt: data.CreatedAt,
+	}
+	logger.Debug("order event.", zap.Any("orderEvent", event))
+
+	// The order events of a symbol are in the same partition of the order topic
+	if err := s.publish(ctx, []byte(data.Symbol), event); err != nil {
+		s.release(data.ID)
+		return err
+	}
+	return nil
+}
+
+func (s *Service) hold(data events.OrderEvent) error {
+	if _, err := s.ledger.Hold(account.Order{
+		ID:        data.ID,
+		AccountID: data.AccountID,
+		Symbol:    data.Symbol,
+		Side:      account.Side(data.Type),
+		Price:     data.Price,
+		Quantity:  data.Quantity,
+	}); err != nil {
+		if !errors.Is(err, account.ErrInsufficientBalance) {
+			logger.Error("failed to hold funds", zap.Error(err))
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Service) release(orderID string) {
+	if _, err := s.ledger.Release(orderID); err != nil {
+		logger.Error("failed to release funds", zap.Error(err))
+	}
+}
+
+// publish encodes the event with the codec, and sends it to the partition of the key with its
+// content type
+func (s *Service) publish(ctx context.Context, key []byte, event events.Event) error {
+	msg, err := s.message(key, event)
+	if err != nil {
+		return err
+	}
+	return s.producer.PublishBatch(ctx, []mqkit.Message{msg})
+}
+
+// message encodes the event with the codec in a message of the key with its content type
+func (s *Service) message(key []byte, event events.Event) (mqkit.Message, error) {
+	val, err := s.codec.EncodeEvent(event)
+	if err != nil {
+		logger.Error("failed to encode event", zap.Error(err))
+		return mqkit.Message{}, err
+	}
+	return mqkit.Message{
+		Key:     key,
+		Value:   val,
+		Headers: map[string]string{events.ContentTypeHeader: s.codec.ContentType()},
+	}, nil
+}
+
+// stamp sets the account of a request to the authenticated account of the context, if any. A
+// request of another account is forbidden.
+func stamp(ctx context.Context, accountID *string) error {
+	authenticated, ok := auth.AccountID(ctx)
+	if !ok {
+		return nil
+	}
+	if *accountID != "" && *accountID != authenticated {
+		return fmt.Errorf("%w: account %s", ErrForbidden, *accountID)
+	}
+	*accountID = authenticated
+	return nil
+}
+
+// checkOwner checks the open order is of the authenticated account of the context, if any
+func checkOwner(ctx context.Context, order account.Order) error {
+	if accountID, ok := auth.AccountID(ctx); ok && order.AccountID != accountID {
+		return fmt.Errorf("%w: order %s", ErrForbidden, order.ID)
+	}
+	return nil
+}
+
+// validate validates the binding tags of a request, as gin does for the REST handlers
+func validate(request interface{}) error {
+	if err := binding.Validator.ValidateStruct(request); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the full buckets are dropped, as they are the same as new ones
+const sweepInterval = time.Minute
+
+var (
+	now = func() time.Time {
+		return time.Now()
+	}
+)
+
+type bucket struct {
+	limit  Limit
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens of the time since the last refill
+func (b *bucket) refill(t time.Time) {
+	if elapsed := t.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate)
+	}
+	b.last = t
+}
+
+// MemoryStore keeps the token buckets in memory, so every replica of the API limits on its own
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   map[string]*bucket{},
+		lastSweep: now(),
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit, cost int) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := now()
+	s.sweep(t)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: t}
+		s.buckets[key] = b
+	}
+	b.limit = limit
+	b.refill(t)
+
+	tokens := float64(min(cost, limit.Burst))
+	var result Result
+	if b.tokens >= tokens {
+		b.tokens -= tokens
+		result.Allowed = true
+	} else {
+		result.RetryAfter = duration((tokens - b.tokens) / limit.Rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = duration((float64(limit.Burst) - b.tokens) / limit.Rate)
+	return result, nil
+}
+
+// sweep drops the buckets which are full by now
+func (s *MemoryStore) sweep(t time.Time) {
+	if t.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = t
+	for key, b := range s.buckets {
+		b.refill(t)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// duration converts seconds to a duration
+func duration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/Hao1995/order-matching-system/internal/api/auth"
+	"github.com/Hao1995/order-matching-system/pkg/logger"
+)
+
+const (
+	// LimitHeader, RemainingHeader and ResetHeader report the bucket of a request, the reset is
+	// the seconds until the bucket is full again
+	LimitHeader      = "RateLimit-Limit"
+	RemainingHeader  = "RateLimit-Remaining"
+	ResetHeader      = "RateLimit-Reset"
+	RetryAfterHeader = "Retry-After"
+)
+
+// Limit is a token bucket of Burst tokens, which refills Rate tokens per second
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result is the state of a bucket after a Take
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until the tokens are available, if not allowed
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full
+	Reset time.Duration
+}
+
+// Store keeps the token buckets of the keys, so the replicas of the API may share them
+type Store interface {
+	// Take takes cost tokens of the bucket of the key, which is created full. A cost above the
+	// burst takes the full bucket.
+	Take(ctx context.Context, key string, limit Limit, cost int) (Result, error)
+}
+
+// Middleware limits the requests of every account by the token bucket of the limit, or of every
+// IP without an authenticated account. The name separates the buckets of the middlewares. A
+// request is allowed if the store fails, so the orders are not stopped by the store.
+func Middleware(store Store, name string, limit Limit) gin.HandlerFunc {
+	return CostMiddleware(store, name, limit, func(*gin.Context) int {
+		return 1
+	})
+}
+
+// CostMiddleware is Middleware for requests which take the tokens of their cost, e.g. the
+// number of orders of a batch
+func CostMiddleware(store Store, name string, limit Limit, cost func(c *gin.Context) int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := key(name, subject(c))
+		result, err := store.Take(c.Request.Context(), key, limit, cost(c))
+		if err != nil {
+			logger.Error("failed to take rate limit token, pass it", zap.Error(err), zap.String("key", key))
+			c.Next()
+			return
+		}
+
+		c.Header(LimitHeader, strconv.Itoa(limit.Burst))
+		c.Header(RemainingHeader, strconv.Itoa(result.Remaining))
+		c.Header(ResetHeader, seconds(result.Reset))
+		if !result.Allowed {
+			logger.Warn("rate limited", zap.String("key", key))
+			c.Header(RetryAfterHeader, seconds(result.RetryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Limiter limits the requests of every account outside of the routes, e.g. of gRPC and FIX, by
+// the same token buckets of the store as Middleware of the same name
+type Limiter struct {
+	store Store
+	name  string
+	limit Limit
+}
+
+func NewLimiter(store Store, name string, limit Limit) *Limiter {
+	return &Limiter{
+		store: store,
+		name:  name,
+		limit: limit,
+	}
+}
+
+// Take takes cost tokens of the bucket of the account, and reports whether the request is
+// allowed. A nil Limiter or a limit without a rate allows every request, and a request is
+// allowed if the store fails.
+func (l *Limiter) Take(ctx context.Context, accountID string, cost int) (Result, bool) {
+	if l == nil || l.limit.Rate <= 0 {
+		return Result{Allowed: true}, true
+	}
+
+	key := key(l.name, "account:"+accountID)
+	result, err := l.store.Take(ctx, key, l.limit, cost)
+	if err != nil {
+		logger.Error("failed to take rate limit token, pass it", zap.Error(err), zap.String("key", key))
+		return Result{Allowed: true}, true
+	}
+	if !result.Allowed {
+		logger.Warn("rate limited", zap.String("key", key))
+	}
+	return result, result.Allowed
+}
+
+// key is the key of the bucket of the subject in the store
+func key(name, subject string) string {
+	return name + ":" + subject
+}
+
+// subject is the authenticated account of the request, or its IP
+func subject(c *gin.Context) string {
+	if accountID, ok := auth.AccountID(c.Request.Context()); ok {
+		return "account:" + accountID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// seconds rounds a duration up to whole seconds
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
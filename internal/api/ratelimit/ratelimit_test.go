@@ -0,0 +1,179 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/Hao1995/order-matching-system/internal/api/auth"
+)
+
+type RateLimitTestSuite struct {
+	suite.Suite
+	store      *MemoryStore
+	current    time.Time
+	restoreNow func() time.Time
+}
+
+func TestRateLimitTestSuite(t *testing.T) {
+	suite.Run(t, new(RateLimitTestSuite))
+}
+
+func (suite *RateLimitTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	suite.current = time.Date(2025, 1, 15, 18, 0, 0, 0, time.UTC)
+	suite.restoreNow = now
+	now = func() time.Time {
+		return suite.current
+	}
+	suite.store = NewMemoryStore()
+}
+
+func (suite *RateLimitTestSuite) TearDownTest() {
+	now = suite.restoreNow
+}
+
+func (suite *RateLimitTestSuite) TestMemoryStore_Take() {
+	limit := Limit{Rate: 2, Burst: 3}
+	for i := 2; i >= 0; i-- {
+		result, err := suite.store.Take(context.Background(), "alice", limit, 1)
+		suite.Require().NoError(err)
+		suite.True(result.Allowed)
+		suite.Equal(i, result.Remaining)
+	}
+
+	result, err := suite.store.Take(context.Background(), "alice", limit, 1)
+	suite.Require().NoError(err)
+	suite.False(result.Allowed)
+	suite.Equal(500*time.Millisecond, result.RetryAfter)
+	suite.Equal(1500*time.Millisecond, result.Reset)
+
+	// The buckets of the keys are apart
+	result, err = suite.store.Take(context.Background(), "bob", limit, 1)
+	suite.Require().NoError(err)
+	suite.True(result.Allowed)
+
+	// A token is refilled every half second, up to the burst
+	suite.current = suite.current.Add(500 * time.Millisecond)
+	result, err = suite.store.Take(context.Background(), "alice", limit, 1)
+	suite.Require().NoError(err)
+	suite.True(result.Allowed)
+	suite.Equal(0, result.Remaining)
+
+	suite.current = suite.current.Add(time.Hour)
+	result, err = suite.store.Take(context.Background(), "alice", limit, 1)
+	suite.Require().NoError(err)
+	suite.Equal(2, result.Remaining)
+}
+
+func (suite *RateLimitTestSuite) TestMemoryStore_TakeCost() {
+	limit := Limit{Rate: 2, Burst: 3}
+	result, err := suite.store.Take(context.Background(), "alice", limit, 2)
+	suite.Require().NoError(err)
+	suite.True(result.Allowed)
+	suite.Equal(1, result.Remaining)
+
+	// A cost above the remaining tokens takes none of them
+	result, err = suite.store.Take(context.Background(), "alice", limit, 2)
+	suite.Require().NoError(err)
+	suite.False(result.Allowed)
+	suite.Equal(1, result.Remaining)
+	suite.Equal(500*time.Millisecond, result.RetryAfter)
+
+	// A cost above the burst takes the full bucket
+	suite.current = suite.current.Add(time.Second)
+	result, err = suite.store.Take(context.Background(), "alice", limit, 10)
+	suite.Require().NoError(err)
+	suite.True(result.Allowed)
+	suite.Equal(0, result.Remaining)
+}
+
+func (suite *RateLimitTestSuite) TestMemoryStore_Sweep() {
+	limit := Limit{Rate: 1, Burst: 2}
+	_, err := suite.store.Take(context.Background(), "alice", limit, 1)
+	suite.Require().NoError(err)
+	_, err = suite.store.Take(context.Background(), "bob", Limit{Rate: 0.001, Burst: 2}, 1)
+	suite.Require().NoError(err)
+
+	// The bucket of alice is full again, while bob still waits for a token
+	suite.current = suite.current.Add(sweepInterval)
+	_, err = suite.store.Take(context.Background(), "carol", limit, 1)
+	suite.Require().NoError(err)
+	suite.NotContains(suite.store.buckets, "alice")
+	suite.Contains(suite.store.buckets, "bob")
+}
+
+func (suite *RateLimitTestSuite) serve(router *gin.Engine, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	req.RemoteAddr = remoteAddr
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func (suite *RateLimitTestSuite) TestMiddleware() {
+	router := gin.New()
+	router.POST("/orders", Middleware(suite.store, "create", Limit{Rate: 1, Burst: 2}), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	recorder := suite.serve(router, "10.0.0.1:1234")
+	suite.Equal(http.StatusCreated, recorder.Code)
+	suite.Equal("2", recorder.Header().Get(LimitHeader))
+	suite.Equal("1", recorder.Header().Get(RemainingHeader))
+	suite.Equal("1", recorder.Header().Get(ResetHeader))
+	suite.Equal(http.StatusCreated, suite.serve(router, "10.0.0.1:1234").Code)
+
+	recorder = suite.serve(router, "10.0.0.1:1234")
+	suite.Equal(http.StatusTooManyRequests, recorder.Code)
+	suite.Equal("1", recorder.Header().Get(RetryAfterHeader))
+	suite.Equal("0", recorder.Header().Get(RemainingHeader))
+	suite.Equal("2", recorder.Header().Get(ResetHeader))
+
+	// Another IP has a bucket of its own
+	suite.Equal(http.StatusCreated, suite.serve(router, "10.0.0.2:1234").Code)
+}
+
+func (suite *RateLimitTestSuite) TestMiddleware_Account() {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(auth.NewContext(c.Request.Context(), c.GetHeader("Account")))
+	})
+	router.POST("/orders", Middleware(suite.store, "create", Limit{Rate: 1, Burst: 1}), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+	serve := func(accountID, remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Account", accountID)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// The account is limited whatever its IP
+	suite.Equal(http.StatusCreated, serve("alice", "10.0.0.1:1234"))
+	suite.Equal(http.StatusTooManyRequests, serve("alice", "10.0.0.2:1234"))
+	suite.Equal(http.StatusCreated, serve("bob", "10.0.0.1:1234"))
+	suite.Contains(suite.store.buckets, "create:account:alice")
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit, int) (Result, error) {
+	return Result{}, errors.New("store is down")
+}
+
+func (suite *RateLimitTestSuite) TestMiddleware_StoreError() {
+	router := gin.New()
+	router.POST("/orders", Middleware(failingStore{}, "create", Limit{Rate: 1, Burst: 1}), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+	suite.Equal(http.StatusCreated, suite.serve(router, "10.0.0.1:1234").Code)
+}
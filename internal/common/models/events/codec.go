@@ -0,0 +1,233 @@
+//go:generate go-enum --marshal
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownContentType = errors.New("unknown content type")
+)
+
+// ENUM(JSON, Protobuf)
+type CodecFormat string
+
+const (
+	// ContentTypeHeader is the message header with the content type of an encoded event, a
+	// message without it is JSON
+	ContentTypeHeader = "content-type"
+
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// Codec encodes the events of the order and matching topics in the envelopes of a wire format
+type Codec interface {
+	// ContentType is the value of the ContentTypeHeader of the encoded events
+	ContentType() string
+	// Encode encodes the envelope of an Event or a MatchingEvent
+	Encode(envelope Envelope) ([]byte, error)
+	// Decode decodes the envelope of the payload type, whose payload is upcast to the
+	// SchemaVersion. A bare event without an envelope is of version 1.
+	Decode(payloadType PayloadType, data []byte) (Envelope, error)
+
+	// EncodeEvent encodes the Event in a new envelope of the producer of the codec
+	EncodeEvent(event Event) ([]byte, error)
+	// DecodeEvent decodes an Event with the Data of its EventType, OrderEvent or
+	// TradingStatusEvent. The Data of the other event types is nil.
+	DecodeEvent(data []byte) (Event, error)
+	EncodeMatchingEvent(matchingEvent MatchingEvent) ([]byte, error)
+	DecodeMatchingEvent(data []byte) (MatchingEvent, error)
+}
+
+// wireFormat encodes the envelopes in a format
+type wireFormat interface {
+	contentType() string
+	encodeEnvelope(envelope Envelope) ([]byte, error)
+	decodeEnvelope(payloadType PayloadType, data []byte) (Envelope, error)
+}
+
+var (
+	// JSONCodec and ProtobufCodec decode the events of their format, and encode them without
+	// a producer
+	JSONCodec     Codec = codec{format: jsonFormat{}}
+	ProtobufCodec Codec = codec{format: protobufFormat{}}
+)
+
+// NewCodec returns the Codec of the format, which stamps the encoded events with the producer
+func NewCodec(format CodecFormat, producer string) (Codec, error) {
+	switch format {
+	case CodecFormatJSON:
+		return codec{format: jsonFormat{}, producer: producer}, nil
+	case CodecFormatProtobuf:
+		return codec{format: protobufFormat{}, producer: producer}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCodecFormat, format)
+	}
+}
+
+// CodecFor returns the Codec of the content type, so the consumers decode the events of both
+// formats while the producers migrate. An empty content type is JSON.
+func CodecFor(contentType string) (Codec, error) {
+	switch contentType {
+	case "", ContentTypeJSON:
+		return JSONCodec, nil
+	case ContentTypeProtobuf:
+		return ProtobufCodec, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownContentType, contentType)
+	}
+}
+
+type codec struct {
+	format   wireFormat
+	producer string
+}
+
+func (c codec) ContentType() string {
+	return c.format.contentType()
+}
+
+func (c codec) Encode(envelope Envelope) ([]byte, error) {
+	return c.format.encodeEnvelope(envelope)
+}
+
+func (c codec) Decode(payloadType PayloadType, data []byte) (Envelope, error) {
+	return c.format.decodeEnvelope(payloadType, data)
+}
+
+func (c codec) EncodeEvent(event Event) ([]byte, error) {
+	return c.Encode(NewEnvelope(c.producer, event))
+}
+
+func (c codec) DecodeEvent(data []byte) (Event, error) {
+	envelope, err := c.Decode(PayloadTypeEvent, data)
+	if err != nil {
+		return Event{}, err
+	}
+	return envelope.Payload.(Event), nil
+}
+
+func (c codec) EncodeMatchingEvent(matchingEvent MatchingEvent) ([]byte, error) {
+	return c.Encode(NewEnvelope(c.producer, matchingEvent))
+}
+
+func (c codec) DecodeMatchingEvent(data []byte) (MatchingEvent, error) {
+	envelope, err := c.Decode(PayloadTypeMatchingEvent, data)
+	if err != nil {
+		return MatchingEvent{}, err
+	}
+	return envelope.Payload.(MatchingEvent), nil
+}
+
+// jsonFormat encodes the envelopes in JSON by their tags
+type jsonFormat struct{}
+
+func (jsonFormat) contentType() string {
+	return ContentTypeJSON
+}
+
+func (jsonFormat) encodeEnvelope(envelope Envelope) ([]byte, error) {
+	switch envelope.Payload.(type) {
+	case Event, MatchingEvent:
+		return json.Marshal(envelope)
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnexpectedPayload, envelope.Payload)
+	}
+}
+
+// decodeEnvelope decodes an envelope, or a bare event of version 1 which has no version
+func (jsonFormat) decodeEnvelope(payloadType PayloadType, data []byte) (Envelope, error) {
+	// The type of a bare matching event is not a PayloadType, so the version is probed first
+	var probe struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return Envelope{}, err
+	}
+
+	envelope := Envelope{Version: 1, Type: payloadType}
+	payload := data
+	if probe.Version != 0 {
+		var raw struct {
+			Envelope
+			Payload json.RawMessage `json:"payload"`
+		}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return Envelope{}, err
+		}
+		envelope, payload = raw.Envelope, raw.Payload
+	}
+	if err := envelope.check(payloadType); err != nil {
+		return Envelope{}, err
+	}
+
+	payload, err := upcast(payloadType, envelope.Version, payload)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("upcast %s of version %d: %w", payloadType, envelope.Version, err)
+	}
+	switch payloadType {
+	case PayloadTypeEvent:
+		envelope.Payload, err = decodeJSONEvent(payload)
+	default:
+		var matchingEvent MatchingEvent
+		err = json.Unmarshal(payload, &matchingEvent)
+		envelope.Payload = matchingEvent
+	}
+	if err != nil {
+		return Envelope{}, err
+	}
+	envelope.Version = SchemaVersion
+	return envelope, nil
+}
+
+func decodeJSONEvent(data []byte) (Event, error) {
+	var event Event
+	var raw json.RawMessage
+	event.Data = &raw
+	if err := json.Unmarshal(data, &event); err != nil {
+		return Event{}, err
+	}
+	event.Data = nil
+
+	switch event.EventType {
+	case EventTypeCreateOrder, EventTypeCancelOrder, EventTypeAmendOrder, EventTypeMassCancel:
+		var orderEvent OrderEvent
+		if err := json.Unmarshal(raw, &orderEvent); err != nil {
+			return Event{}, fmt.Errorf("order event: %w", err)
+		}
+		event.Data = orderEvent
+	case EventTypeHaltTrading, EventTypeResumeTrading, EventTypeStartAuction:
+		var statusEvent TradingStatusEvent
+		if err := json.Unmarshal(raw, &statusEvent); err != nil {
+			return Event{}, fmt.Errorf("trading status event: %w", err)
+		}
+		event.Data = statusEvent
+	}
+	return event, nil
+}
+
+// upcast transforms a JSON payload of the version to the SchemaVersion by the upcasters of its
+// payload type
+func upcast(payloadType PayloadType, version int, payload []byte) ([]byte, error) {
+	if version == SchemaVersion {
+		return payload, nil
+	}
+
+	// Keep the numbers as they are, e.g. the int64 quantities
+	var doc map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	for v := version; v < SchemaVersion; v++ {
+		if err := upcasters[payloadType][v-1](doc); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(doc)
+}
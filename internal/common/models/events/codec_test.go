@@ -0,0 +1,285 @@
+package events
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/protobuf/proto"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the current schema version")
+
+type CodecTestSuite struct {
+	suite.Suite
+	current     time.Time
+	restoreNow  func() time.Time
+	restoreUUID func() string
+}
+
+func TestCodecTestSuite(t *testing.T) {
+	suite.Run(t, new(CodecTestSuite))
+}
+
+func (suite *CodecTestSuite) SetupTest() {
+	suite.current = time.Date(2025, 1, 15, 18, 0, 0, 500, time.UTC)
+	suite.restoreNow, suite.restoreUUID = now, getUUID
+	now = func() time.Time {
+		return suite.current
+	}
+	getUUID = func() string {
+		return "5f0c6d4e-8f7a-4b8e-9a51-7c2d3e4f5a6b"
+	}
+}
+
+func (suite *CodecTestSuite) TearDownTest() {
+	now, getUUID = suite.restoreNow, suite.restoreUUID
+}
+
+func (suite *CodecTestSuite) matchingEvent() MatchingEvent {
+	resumeAt := suite.current.Add(5 * time.Minute)
+	return MatchingEvent{
+		Seq:         7,
+		InputOffset: 42,
+		Type:        MatchingEventTypeCreate,
+		Order: OrderEvent{
+			ID:          "order2",
+			AccountID:   "alice",
+			Symbol:      "AAPL",
+			Type:        "buy",
+			Price:       100.5,
+			Quantity:    10,
+			TimeInForce: "Day",
+			CreatedAt:   suite.current,
+		},
+		Transactions: []TransactionEvent{{
+			ID:           "transaction1",
+			Symbol:       "AAPL",
+			BuyOrderID:   "order2",
+			SellOrderID:  "order1",
+			MakerOrderID: "order1",
+			TakerOrderID: "order2",
+			TakerSide:    "buy",
+			Price:        100,
+			Quantity:     6,
+			BuyFee:       FeeEvent{Amount: 1.2, Currency: "USD"},
+			SellFee:      FeeEvent{Amount: -0.3, Currency: "USD"},
+			CreatedAt:    suite.current,
+		}},
+		BuyTicks:   []TickEvent{{Price: 100.5, Quantity: 4}},
+		SellTicks:  []TickEvent{{Price: 100, Quantity: 0}, {Price: 101, Quantity: 3}},
+		Rejection:  &RejectionEvent{Rule: "price_band", Reason: "outside the band"},
+		Status:     &StatusEvent{Symbol: "AAPL", Status: "Halted", Reason: "circuit breaker", ResumeAt: &resumeAt},
+		Indicative: &IndicativeEvent{Price: 100, Volume: 6, Imbalance: -2},
+		Session:    &SessionEvent{Symbol: "AAPL", Phase: "Open"},
+	}
+}
+
+func (suite *CodecTestSuite) event() Event {
+	return Event{
+		EventType: EventTypeCreateOrder,
+		Data:      OrderEvent{ID: "order1", AccountID: "bob", Symbol: "AAPL", Type: "sell", Price: 100, Quantity: 6, CreatedAt: suite.current},
+		CreatedAt: suite.current,
+	}
+}
+
+func (suite *CodecTestSuite) TestMatchingEvent_RoundTrip() {
+	for _, codec := range []Codec{JSONCodec, ProtobufCodec} {
+		val, err := codec.EncodeMatchingEvent(suite.matchingEvent())
+		suite.Require().NoError(err)
+
+		matchingEvent, err := codec.DecodeMatchingEvent(val)
+		suite.Require().NoError(err)
+		suite.Equal(suite.matchingEvent(), matchingEvent, codec.ContentType())
+	}
+}
+
+func (suite *CodecTestSuite) TestMatchingEvent_MassCancel_RoundTrip() {
+	matchingEvent := MatchingEvent{
+		Seq:         8,
+		InputOffset: 43,
+		Type:        MatchingEventTypeMassCancel,
+		Order:       OrderEvent{ID: "mass1", AccountID: "bob", Symbol: "AAPL", CreatedAt: suite.current},
+		Cancelled: []OrderEvent{
+			{ID: "order1", AccountID: "bob", Symbol: "AAPL", Type: "sell", Price: 100, Quantity: 6, CreatedAt: suite.current},
+			{ID: "order3", AccountID: "bob", Symbol: "AAPL", Type: "buy", Price: 99, Quantity: 2, TimeInForce: "Day", CreatedAt: suite.current},
+		},
+		BuyTicks:  []TickEvent{{Price: 99, Quantity: 0}},
+		SellTicks: []TickEvent{{Price: 100, Quantity: 0}},
+	}
+	for _, codec := range []Codec{JSONCodec, ProtobufCodec} {
+		val, err := codec.EncodeMatchingEvent(matchingEvent)
+		suite.Require().NoError(err)
+
+		decoded, err := codec.DecodeMatchingEvent(val)
+		suite.Require().NoError(err)
+		suite.Equal(matchingEvent, decoded, codec.ContentType())
+	}
+}
+
+func (suite *CodecTestSuite) TestEvent_RoundTrip() {
+	orderEvent := Event{
+		EventType: EventTypeCreateOrder,
+		Data:      OrderEvent{ID: "order1", Symbol: "AAPL", Type: "sell", Price: 100, Quantity: 6, CreatedAt: suite.current},
+		CreatedAt: suite.current,
+	}
+	statusEvent := Event{
+		EventType: EventTypeStartAuction,
+		Data:      TradingStatusEvent{Symbol: "AAPL", Reason: "open", EndsAt: suite.current.Add(time.Minute)},
+		CreatedAt: suite.current,
+	}
+	amendEvent := Event{
+		EventType: EventTypeAmendOrder,
+		Data:      OrderEvent{ID: "order2", OrigID: "order1", Symbol: "AAPL", Type: "sell", Price: 101, Quantity: 4, CreatedAt: suite.current},
+		CreatedAt: suite.current,
+	}
+	massCancelEvent := Event{
+		EventType: EventTypeMassCancel,
+		Data:      OrderEvent{ID: "mass1", AccountID: "bob", Symbol: "AAPL", Type: "sell", CreatedAt: suite.current},
+		CreatedAt: suite.current,
+	}
+	heartbeat := Event{EventType: EventTypeHeartbeat, CreatedAt: suite.current}
+
+	for _, codec := range []Codec{JSONCodec, ProtobufCodec} {
+		for _, event := range []Event{orderEvent, statusEvent, amendEvent, massCancelEvent, heartbeat} {
+			val, err := codec.EncodeEvent(event)
+			suite.Require().NoError(err)
+
+			decoded, err := codec.DecodeEvent(val)
+			suite.Require().NoError(err)
+			suite.Equal(event, decoded, codec.ContentType())
+		}
+	}
+}
+
+func (suite *CodecTestSuite) TestProtobuf_Payload() {
+	// Field 1 is a fixed64 double and field 2 a varint, as in proto/events.proto
+	val, err := proto.Marshal(toProtoMatchingEvent(MatchingEvent{BuyTicks: []TickEvent{{Price: 100, Quantity: 6}}}))
+	suite.Require().NoError(err)
+	suite.Equal([]byte{
+		0x22, 0x00, // order
+		0x32, 0x0b, // buy_ticks
+		0x09, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x59, 0x40, // price
+		0x10, 0x06, // quantity
+	}, val)
+}
+
+func (suite *CodecTestSuite) TestJSON_LegacyTransactionEvent() {
+	// The transactions were encoded with the Go field names before their tags were fixed
+	val := []byte(`{"type":"Create","transactions":[{"ID":"transaction1","Symbol":"AAPL","BuyOrderID":"order2","SellOrderID":"order1","Price":100,"Quantity":6,"CreatedAt":"2025-01-15T18:00:00.0000005Z"}]}`)
+
+	matchingEvent, err := JSONCodec.DecodeMatchingEvent(val)
+	suite.Require().NoError(err)
+	suite.Equal([]TransactionEvent{{
+		ID:          "transaction1",
+		Symbol:      "AAPL",
+		BuyOrderID:  "order2",
+		SellOrderID: "order1",
+		Price:       100,
+		Quantity:    6,
+		CreatedAt:   suite.current,
+	}}, matchingEvent.Transactions)
+}
+
+func (suite *CodecTestSuite) TestCodecFor() {
+	codec, err := CodecFor("")
+	suite.NoError(err)
+	suite.Equal(JSONCodec, codec)
+
+	codec, err = CodecFor(ContentTypeProtobuf)
+	suite.NoError(err)
+	suite.Equal(ProtobufCodec, codec)
+
+	_, err = CodecFor("text/plain")
+	suite.ErrorIs(err, ErrUnknownContentType)
+
+	_, err = NewCodec(CodecFormat("Avro"), "order")
+	suite.ErrorIs(err, ErrInvalidCodecFormat)
+}
+
+func (suite *CodecTestSuite) TestEncode_Envelope() {
+	codec, err := NewCodec(CodecFormatProtobuf, "order")
+	suite.Require().NoError(err)
+	val, err := codec.EncodeMatchingEvent(suite.matchingEvent())
+	suite.Require().NoError(err)
+
+	envelope, err := ProtobufCodec.Decode(PayloadTypeMatchingEvent, val)
+	suite.Require().NoError(err)
+	suite.Equal(Envelope{
+		ID:        "5f0c6d4e-8f7a-4b8e-9a51-7c2d3e4f5a6b",
+		Version:   SchemaVersion,
+		Producer:  "order",
+		CreatedAt: suite.current,
+		Type:      PayloadTypeMatchingEvent,
+		Payload:   suite.matchingEvent(),
+	}, envelope)
+
+	// The payload type is checked
+	_, err = ProtobufCodec.Decode(PayloadTypeEvent, val)
+	suite.ErrorIs(err, ErrUnexpectedPayload)
+}
+
+func (suite *CodecTestSuite) TestDecode_UnsupportedVersion() {
+	for _, codec := range []Codec{JSONCodec, ProtobufCodec} {
+		envelope := NewEnvelope("matching-engine", suite.matchingEvent())
+		envelope.Version = SchemaVersion + 1
+		val, err := codec.Encode(envelope)
+		suite.Require().NoError(err)
+
+		_, err = codec.DecodeMatchingEvent(val)
+		suite.ErrorIs(err, ErrUnsupportedVersion, codec.ContentType())
+	}
+}
+
+func (suite *CodecTestSuite) TestUpcasters() {
+	for _, payloadType := range []PayloadType{PayloadTypeEvent, PayloadTypeMatchingEvent} {
+		suite.Len(upcasters[payloadType], SchemaVersion-1, payloadType.String())
+	}
+}
+
+// TestGolden decodes the golden files of every schema version in testdata/v<version>, which are
+// never changed once the version is released. Run with -update to write the current version.
+func (suite *CodecTestSuite) TestGolden() {
+	codecs := map[string]Codec{"json": JSONCodec, "pb": ProtobufCodec}
+	payloads := map[PayloadType]interface{}{
+		PayloadTypeEvent:         suite.event(),
+		PayloadTypeMatchingEvent: suite.matchingEvent(),
+	}
+	names := map[PayloadType]string{
+		PayloadTypeEvent:         "event",
+		PayloadTypeMatchingEvent: "matching_event",
+	}
+
+	for version := 1; version <= SchemaVersion; version++ {
+		for ext, codec := range codecs {
+			for payloadType, payload := range payloads {
+				path := filepath.Join("testdata", fmt.Sprintf("v%d", version), names[payloadType]+"."+ext)
+				expected := Envelope{Version: SchemaVersion, Type: payloadType, Payload: payload}
+				if version > 1 {
+					expected.ID = "5f0c6d4e-8f7a-4b8e-9a51-7c2d3e4f5a6b"
+					expected.Producer = "golden"
+					expected.CreatedAt = suite.current
+				}
+
+				if version == SchemaVersion {
+					val, err := codec.Encode(NewEnvelope("golden", payload))
+					suite.Require().NoError(err)
+					if *update {
+						suite.Require().NoError(os.MkdirAll(filepath.Dir(path), 0o755))
+						suite.Require().NoError(os.WriteFile(path, val, 0o644))
+					}
+				}
+
+				data, err := os.ReadFile(path)
+				suite.Require().NoError(err)
+				envelope, err := codec.Decode(payloadType, data)
+				suite.Require().NoError(err, path)
+				suite.Equal(expected, envelope, path)
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+//go:generate go-enum --marshal
+package events
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SchemaVersion is the version of the envelopes and payloads written by the codecs. Version 1 is
+// the bare events before the envelope, version 2 adds the envelope.
+const SchemaVersion = 2
+
+var (
+	ErrUnexpectedPayload = errors.New("unexpected payload")
+	// ErrUnsupportedVersion is a payload of a newer version than the SchemaVersion, the consumers
+	// must be upgraded before the producers
+	ErrUnsupportedVersion = errors.New("unsupported schema version")
+)
+
+var (
+	getUUID = func() string {
+		return uuid.NewString()
+	}
+
+	now = func() time.Time {
+		return time.Now()
+	}
+)
+
+// ENUM(Event, MatchingEvent)
+type PayloadType string
+
+// Envelope carries an event with the metadata to evolve its schema
+type Envelope struct {
+	ID string `json:"id"`
+	// Version is the schema version of the envelope and its payload
+	Version int `json:"version"`
+	// Producer is the name of the service which produced the event
+	Producer  string      `json:"producer"`
+	CreatedAt time.Time   `json:"created_at"`
+	Type      PayloadType `json:"type"`
+	// Payload is an Event or a MatchingEvent by the Type
+	Payload interface{} `json:"payload"`
+}
+
+// NewEnvelope returns an envelope of the current version for the Event or MatchingEvent
+func NewEnvelope(producer string, payload interface{}) Envelope {
+	envelope := Envelope{
+		ID:        getUUID(),
+		Version:   SchemaVersion,
+		Producer:  producer,
+		CreatedAt: now(),
+		Payload:   payload,
+	}
+	switch payload.(type) {
+	case Event:
+		envelope.Type = PayloadTypeEvent
+	case MatchingEvent:
+		envelope.Type = PayloadTypeMatchingEvent
+	}
+	return envelope
+}
+
+// check returns an error if a decoded envelope is not of the payload type, or of a version this
+// decoder does not know
+func (e Envelope) check(payloadType PayloadType) error {
+	if e.Type != payloadType {
+		return fmt.Errorf("%w: %s instead of %s", ErrUnexpectedPayload, e.Type, payloadType)
+	}
+	if e.Version > SchemaVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedVersion, e.Version)
+	}
+	return nil
+}
+
+// Upcaster transforms a JSON payload of a version to the next version
+type Upcaster func(payload map[string]interface{}) error
+
+// upcasters of the payload types, the upcaster at i transforms version i+1 to i+2. The protobuf
+// payloads need none, as their messages only gain fields.
+var upcasters = map[PayloadType][]Upcaster{
+	PayloadTypeEvent: {
+		unchanged,
+	},
+	PayloadTypeMatchingEvent: {
+		upcastTransactionFieldNames,
+	},
+}
+
+// unchanged keeps a payload whose version only changed the envelope
+func unchanged(payload map[string]interface{}) error {
+	return nil
+}
+
+// legacyTransactionFields are the Go field names the transactions were encoded with before
+// their fields had tags
+var legacyTransactionFields = map[string]string{
+	"ID":          "id",
+	"Symbol":      "symbol",
+	"BuyOrderID":  "buy_order_id",
+	"SellOrderID": "sell_order_id",
+	"Price":       "price",
+	"Quantity":    "quantity",
+	"CreatedAt":   "created_at",
+}
+
+// upcastTransactionFieldNames renames the legacy fields of the transactions in a version 1
+// matching event
+func upcastTransactionFieldNames(payload map[string]interface{}) error {
+	transactions, _ := payload["transactions"].([]interface{})
+	for _, transaction := range transactions {
+		fields, ok := transaction.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("transaction is %T", transaction)
+		}
+		for legacy, name := range legacyTransactionFields {
+			if value, ok := fields[legacy]; ok {
+				delete(fields, legacy)
+				fields[name] = value
+			}
+		}
+	}
+	return nil
+}
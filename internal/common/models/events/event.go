@@ -3,19 +3,35 @@ package events
 
 import "time"
 
-// ENUM(CreateOrder, CancelOrder, Matching)
+// ENUM(CreateOrder, CancelOrder, AmendOrder, Matching, HaltTrading, ResumeTrading, StartAuction, Heartbeat, MassCancel)
 type EventType string
 
 type Event struct {
 	EventType EventType   `json:"event_type"`
 	Data      interface{} `json:"data"`
+	// CreatedAt is when the event was produced, the matching engine applies the event at this
+	// time so every replica consuming the topic reaches the same state
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type OrderEvent struct {
-	ID        string    `json:"id"`
-	Symbol    string    `json:"symbol"`
-	Type      string    `json:"type"`
-	Price     float64   `json:"price"`
-	Quantity  int64     `json:"quantity"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        string  `json:"id"`
+	AccountID string  `json:"account_id"`
+	Symbol    string  `json:"symbol"`
+	Type      string  `json:"type"`
+	Price     float64 `json:"price"`
+	Quantity  int64   `json:"quantity"`
+	// TimeInForce is either GTC or Day, empty means GTC
+	TimeInForce string    `json:"time_in_force,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	// OrigID is the order replaced by an AmendOrder event
+	OrigID string `json:"orig_id,omitempty"`
+}
+
+// TradingStatusEvent requests to halt, resume or start an auction on a symbol
+type TradingStatusEvent struct {
+	Symbol string `json:"symbol"`
+	Reason string `json:"reason"`
+	// EndsAt is when an auction uncrosses
+	EndsAt time.Time `json:"ends_at"`
 }
@@ -16,8 +16,20 @@ const (
 	EventTypeCreateOrder EventType = "CreateOrder"
 	// EventTypeCancelOrder is a EventType of type CancelOrder.
 	EventTypeCancelOrder EventType = "CancelOrder"
+	// EventTypeAmendOrder is a EventType of type AmendOrder.
+	EventTypeAmendOrder EventType = "AmendOrder"
 	// EventTypeMatching is a EventType of type Matching.
 	EventTypeMatching EventType = "Matching"
+	// EventTypeHaltTrading is a EventType of type HaltTrading.
+	EventTypeHaltTrading EventType = "HaltTrading"
+	// EventTypeResumeTrading is a EventType of type ResumeTrading.
+	EventTypeResumeTrading EventType = "ResumeTrading"
+	// EventTypeStartAuction is a EventType of type StartAuction.
+	EventTypeStartAuction EventType = "StartAuction"
+	// EventTypeHeartbeat is a EventType of type Heartbeat.
+	EventTypeHeartbeat EventType = "Heartbeat"
+	// EventTypeMassCancel is a EventType of type MassCancel.
+	EventTypeMassCancel EventType = "MassCancel"
 )
 
 var ErrInvalidEventType = errors.New("not a valid EventType")
@@ -35,9 +47,15 @@ func (x EventType) IsValid() bool {
 }
 
 var _EventTypeValue = map[string]EventType{
-	"CreateOrder": EventTypeCreateOrder,
-	"CancelOrder": EventTypeCancelOrder,
-	"Matching":    EventTypeMatching,
+	"CreateOrder":   EventTypeCreateOrder,
+	"CancelOrder":   EventTypeCancelOrder,
+	"AmendOrder":    EventTypeAmendOrder,
+	"Matching":      EventTypeMatching,
+	"HaltTrading":   EventTypeHaltTrading,
+	"ResumeTrading": EventTypeResumeTrading,
+	"StartAuction":  EventTypeStartAuction,
+	"Heartbeat":     EventTypeHeartbeat,
+	"MassCancel":    EventTypeMassCancel,
 }
 
 // ParseEventType attempts to convert a string to a EventType.
@@ -3,25 +3,73 @@ package events
 
 import "time"
 
-// ENUM(Create, Cancel)
+// ENUM(Create, Cancel, Reject, Queue, Status, Indicative, Uncross, Session, MassCancel)
 type MatchingEventType string
 
 type MatchingEvent struct {
+	// Seq is the sequence number of the matching event in the matching engine of the symbol
+	Seq uint64 `json:"seq"`
+	// InputOffset is the offset of the order event in the order topic which led to the matching event
+	InputOffset  int64              `json:"input_offset"`
 	Type         MatchingEventType  `json:"type"`
 	Order        OrderEvent         `json:"order"`
 	Transactions []TransactionEvent `json:"transactions,omitempty"`
 	BuyTicks     []TickEvent        `json:"buy_ticks"`
 	SellTicks    []TickEvent        `json:"sell_ticks"`
+	Rejection    *RejectionEvent    `json:"rejection,omitempty"`
+	Status       *StatusEvent       `json:"status,omitempty"`
+	Indicative   *IndicativeEvent   `json:"indicative,omitempty"`
+	Session      *SessionEvent      `json:"session,omitempty"`
+	// Cancelled are the orders cancelled by a MassCancel, in their time priority
+	Cancelled []OrderEvent `json:"cancelled,omitempty"`
+}
+
+// SessionEvent describes a change of the session phase of a symbol
+type SessionEvent struct {
+	Symbol string `json:"symbol"`
+	Phase  string `json:"phase"`
+}
+
+// IndicativeEvent is the equilibrium price and volume of an auction
+type IndicativeEvent struct {
+	Price     float64 `json:"price"`
+	Volume    int64   `json:"volume"`
+	Imbalance int64   `json:"imbalance"`
+}
+
+// StatusEvent describes a change of the trading status of a symbol
+type StatusEvent struct {
+	Symbol   string     `json:"symbol"`
+	Status   string     `json:"status"`
+	Reason   string     `json:"reason"`
+	ResumeAt *time.Time `json:"resume_at,omitempty"`
+}
+
+// RejectionEvent describes the rule which rejected an order
+type RejectionEvent struct {
+	Rule   string `json:"rule"`
+	Reason string `json:"reason"`
 }
 
 type TransactionEvent struct {
-	ID          string    `jsno:"id"`
-	Symbol      string    `jsno:"symbol"`
-	BuyOrderID  string    `jsno:"buy_order_id"`
-	SellOrderID string    `jsno:"sell_order_id"`
-	Price       float64   `jsno:"price"`
-	Quantity    int64     `jsno:"quantity"`
-	CreatedAt   time.Time `jsno:"created_at"`
+	ID           string    `json:"id"`
+	Symbol       string    `json:"symbol"`
+	BuyOrderID   string    `json:"buy_order_id"`
+	SellOrderID  string    `json:"sell_order_id"`
+	MakerOrderID string    `json:"maker_order_id"`
+	TakerOrderID string    `json:"taker_order_id"`
+	TakerSide    string    `json:"taker_side"`
+	Price        float64   `json:"price"`
+	Quantity     int64     `json:"quantity"`
+	BuyFee       FeeEvent  `json:"buy_fee"`
+	SellFee      FeeEvent  `json:"sell_fee"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// FeeEvent is the fee charged to one side of a transaction, a negative amount is a rebate
+type FeeEvent struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
 }
 
 type TickEvent struct {
@@ -16,6 +16,20 @@ const (
 	MatchingEventTypeCreate MatchingEventType = "Create"
 	// MatchingEventTypeCancel is a MatchingEventType of type Cancel.
 	MatchingEventTypeCancel MatchingEventType = "Cancel"
+	// MatchingEventTypeReject is a MatchingEventType of type Reject.
+	MatchingEventTypeReject MatchingEventType = "Reject"
+	// MatchingEventTypeQueue is a MatchingEventType of type Queue.
+	MatchingEventTypeQueue MatchingEventType = "Queue"
+	// MatchingEventTypeStatus is a MatchingEventType of type Status.
+	MatchingEventTypeStatus MatchingEventType = "Status"
+	// MatchingEventTypeIndicative is a MatchingEventType of type Indicative.
+	MatchingEventTypeIndicative MatchingEventType = "Indicative"
+	// MatchingEventTypeUncross is a MatchingEventType of type Uncross.
+	MatchingEventTypeUncross MatchingEventType = "Uncross"
+	// MatchingEventTypeSession is a MatchingEventType of type Session.
+	MatchingEventTypeSession MatchingEventType = "Session"
+	// MatchingEventTypeMassCancel is a MatchingEventType of type MassCancel.
+	MatchingEventTypeMassCancel MatchingEventType = "MassCancel"
 )
 
 var ErrInvalidMatchingEventType = errors.New("not a valid MatchingEventType")
@@ -33,8 +47,15 @@ func (x MatchingEventType) IsValid() bool {
 }
 
 var _MatchingEventTypeValue = map[string]MatchingEventType{
-	"Create": MatchingEventTypeCreate,
-	"Cancel": MatchingEventTypeCancel,
+	"Create":     MatchingEventTypeCreate,
+	"Cancel":     MatchingEventTypeCancel,
+	"Reject":     MatchingEventTypeReject,
+	"Queue":      MatchingEventTypeQueue,
+	"Status":     MatchingEventTypeStatus,
+	"Indicative": MatchingEventTypeIndicative,
+	"Uncross":    MatchingEventTypeUncross,
+	"Session":    MatchingEventTypeSession,
+	"MassCancel": MatchingEventTypeMassCancel,
 }
 
 // ParseMatchingEventType attempts to convert a string to a MatchingEventType.
@@ -0,0 +1,298 @@
+//go:generate buf generate --template proto/buf.gen.yaml proto
+package events
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/Hao1995/order-matching-system/internal/common/models/events/eventspb"
+)
+
+// protobufFormat encodes the envelopes in the protobuf wire format of proto/events.proto, by the
+// messages of eventspb generated from it
+type protobufFormat struct{}
+
+func (protobufFormat) contentType() string {
+	return ContentTypeProtobuf
+}
+
+func (protobufFormat) encodeEnvelope(envelope Envelope) ([]byte, error) {
+	var msg proto.Message
+	var err error
+	switch data := envelope.Payload.(type) {
+	case Event:
+		msg, err = toProtoEvent(data)
+	case MatchingEvent:
+		msg = toProtoMatchingEvent(data)
+	default:
+		err = fmt.Errorf("%w: %T", ErrUnexpectedPayload, envelope.Payload)
+	}
+	if err != nil {
+		return nil, err
+	}
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.Marshal(&eventspb.Envelope{
+		Id:        envelope.ID,
+		Version:   uint32(envelope.Version),
+		Producer:  envelope.Producer,
+		CreatedAt: toTimestamp(envelope.CreatedAt),
+		Type:      envelope.Type.String(),
+		Payload:   payload,
+	})
+}
+
+// decodeEnvelope decodes an envelope, or a bare event of version 1 which has none of its
+// fields. The payload messages only gain fields, so every version decodes the same way.
+func (protobufFormat) decodeEnvelope(payloadType PayloadType, data []byte) (Envelope, error) {
+	// The fields of a bare event are unknown to the envelope
+	var msg eventspb.Envelope
+	if err := proto.Unmarshal(data, &msg); err != nil {
+		return Envelope{}, err
+	}
+	envelope := Envelope{
+		ID:        msg.GetId(),
+		Version:   int(msg.GetVersion()),
+		Producer:  msg.GetProducer(),
+		CreatedAt: fromTimestamp(msg.GetCreatedAt()),
+		Type:      payloadType,
+	}
+	if msg.GetType() != "" {
+		envelope.Type = PayloadType(msg.GetType())
+	}
+	payload := msg.GetPayload()
+	if envelope.Version == 0 {
+		envelope.Version = 1
+		payload = data
+	}
+	if err := envelope.check(payloadType); err != nil {
+		return Envelope{}, err
+	}
+
+	switch payloadType {
+	case PayloadTypeEvent:
+		var event eventspb.Event
+		if err := proto.Unmarshal(payload, &event); err != nil {
+			return Envelope{}, err
+		}
+		envelope.Payload = fromProtoEvent(&event)
+	default:
+		var matchingEvent eventspb.MatchingEvent
+		if err := proto.Unmarshal(payload, &matchingEvent); err != nil {
+			return Envelope{}, err
+		}
+		envelope.Payload = fromProtoMatchingEvent(&matchingEvent)
+	}
+	envelope.Version = SchemaVersion
+	return envelope, nil
+}
+
+func toProtoEvent(event Event) (*eventspb.Event, error) {
+	msg := &eventspb.Event{
+		EventType: event.EventType.String(),
+		CreatedAt: toTimestamp(event.CreatedAt),
+	}
+	switch data := event.Data.(type) {
+	case nil:
+	case OrderEvent:
+		msg.Data = &eventspb.Event_Order{Order: toProtoOrderEvent(data)}
+	case *OrderEvent:
+		msg.Data = &eventspb.Event_Order{Order: toProtoOrderEvent(*data)}
+	case TradingStatusEvent:
+		msg.Data = &eventspb.Event_TradingStatus{TradingStatus: toProtoTradingStatusEvent(data)}
+	case *TradingStatusEvent:
+		msg.Data = &eventspb.Event_TradingStatus{TradingStatus: toProtoTradingStatusEvent(*data)}
+	default:
+		return nil, fmt.Errorf("unsupported event data %T", event.Data)
+	}
+	return msg, nil
+}
+
+func fromProtoEvent(msg *eventspb.Event) Event {
+	event := Event{
+		EventType: EventType(msg.GetEventType()),
+		CreatedAt: fromTimestamp(msg.GetCreatedAt()),
+	}
+	switch data := msg.GetData().(type) {
+	case *eventspb.Event_Order:
+		event.Data = fromProtoOrderEvent(data.Order)
+	case *eventspb.Event_TradingStatus:
+		event.Data = fromProtoTradingStatusEvent(data.TradingStatus)
+	}
+	return event
+}
+
+func toProtoMatchingEvent(matchingEvent MatchingEvent) *eventspb.MatchingEvent {
+	msg := &eventspb.MatchingEvent{
+		Seq:         matchingEvent.Seq,
+		InputOffset: matchingEvent.InputOffset,
+		Type:        matchingEvent.Type.String(),
+		Order:       toProtoOrderEvent(matchingEvent.Order),
+	}
+	for _, transaction := range matchingEvent.Transactions {
+		msg.Transactions = append(msg.Transactions, toProtoTransactionEvent(transaction))
+	}
+	for _, tick := range matchingEvent.BuyTicks {
+		msg.BuyTicks = append(msg.BuyTicks, &eventspb.TickEvent{Price: tick.Price, Quantity: tick.Quantity})
+	}
+	for _, tick := range matchingEvent.SellTicks {
+		msg.SellTicks = append(msg.SellTicks, &eventspb.TickEvent{Price: tick.Price, Quantity: tick.Quantity})
+	}
+	if rejection := matchingEvent.Rejection; rejection != nil {
+		msg.Rejection = &eventspb.RejectionEvent{Rule: rejection.Rule, Reason: rejection.Reason}
+	}
+	if status := matchingEvent.Status; status != nil {
+		msg.Status = &eventspb.StatusEvent{Symbol: status.Symbol, Status: status.Status, Reason: status.Reason}
+		if status.ResumeAt != nil {
+			msg.Status.ResumeAt = timestamppb.New(*status.ResumeAt)
+		}
+	}
+	if indicative := matchingEvent.Indicative; indicative != nil {
+		msg.Indicative = &eventspb.IndicativeEvent{Price: indicative.Price, Volume: indicative.Volume, Imbalance: indicative.Imbalance}
+	}
+	if session := matchingEvent.Session; session != nil {
+		msg.Session = &eventspb.SessionEvent{Symbol: session.Symbol, Phase: session.Phase}
+	}
+	for _, orderEvent := range matchingEvent.Cancelled {
+		msg.Cancelled = append(msg.Cancelled, toProtoOrderEvent(orderEvent))
+	}
+	return msg
+}
+
+func fromProtoMatchingEvent(msg *eventspb.MatchingEvent) MatchingEvent {
+	matchingEvent := MatchingEvent{
+		Seq:         msg.GetSeq(),
+		InputOffset: msg.GetInputOffset(),
+		Type:        MatchingEventType(msg.GetType()),
+		Order:       fromProtoOrderEvent(msg.GetOrder()),
+	}
+	for _, transaction := range msg.GetTransactions() {
+		matchingEvent.Transactions = append(matchingEvent.Transactions, fromProtoTransactionEvent(transaction))
+	}
+	for _, tick := range msg.GetBuyTicks() {
+		matchingEvent.BuyTicks = append(matchingEvent.BuyTicks, TickEvent{Price: tick.GetPrice(), Quantity: tick.GetQuantity()})
+	}
+	for _, tick := range msg.GetSellTicks() {
+		matchingEvent.SellTicks = append(matchingEvent.SellTicks, TickEvent{Price: tick.GetPrice(), Quantity: tick.GetQuantity()})
+	}
+	if rejection := msg.GetRejection(); rejection != nil {
+		matchingEvent.Rejection = &RejectionEvent{Rule: rejection.GetRule(), Reason: rejection.GetReason()}
+	}
+	if status := msg.GetStatus(); status != nil {
+		matchingEvent.Status = &StatusEvent{Symbol: status.GetSymbol(), Status: status.GetStatus(), Reason: status.GetReason()}
+		if status.GetResumeAt() != nil {
+			resumeAt := status.GetResumeAt().AsTime()
+			matchingEvent.Status.ResumeAt = &resumeAt
+		}
+	}
+	if indicative := msg.GetIndicative(); indicative != nil {
+		matchingEvent.Indicative = &IndicativeEvent{Price: indicative.GetPrice(), Volume: indicative.GetVolume(), Imbalance: indicative.GetImbalance()}
+	}
+	if session := msg.GetSession(); session != nil {
+		matchingEvent.Session = &SessionEvent{Symbol: session.GetSymbol(), Phase: session.GetPhase()}
+	}
+	for _, orderEvent := range msg.GetCancelled() {
+		matchingEvent.Cancelled = append(matchingEvent.Cancelled, fromProtoOrderEvent(orderEvent))
+	}
+	return matchingEvent
+}
+
+func toProtoOrderEvent(orderEvent OrderEvent) *eventspb.OrderEvent {
+	return &eventspb.OrderEvent{
+		Id:          orderEvent.ID,
+		AccountId:   orderEvent.AccountID,
+		Symbol:      orderEvent.Symbol,
+		Type:        orderEvent.Type,
+		Price:       orderEvent.Price,
+		Quantity:    orderEvent.Quantity,
+		TimeInForce: orderEvent.TimeInForce,
+		CreatedAt:   toTimestamp(orderEvent.CreatedAt),
+		OrigId:      orderEvent.OrigID,
+	}
+}
+
+func fromProtoOrderEvent(msg *eventspb.OrderEvent) OrderEvent {
+	return OrderEvent{
+		ID:          msg.GetId(),
+		AccountID:   msg.GetAccountId(),
+		Symbol:      msg.GetSymbol(),
+		Type:        msg.GetType(),
+		Price:       msg.GetPrice(),
+		Quantity:    msg.GetQuantity(),
+		TimeInForce: msg.GetTimeInForce(),
+		CreatedAt:   fromTimestamp(msg.GetCreatedAt()),
+		OrigID:      msg.GetOrigId(),
+	}
+}
+
+func toProtoTradingStatusEvent(statusEvent TradingStatusEvent) *eventspb.TradingStatusEvent {
+	return &eventspb.TradingStatusEvent{
+		Symbol: statusEvent.Symbol,
+		Reason: statusEvent.Reason,
+		EndsAt: toTimestamp(statusEvent.EndsAt),
+	}
+}
+
+func fromProtoTradingStatusEvent(msg *eventspb.TradingStatusEvent) TradingStatusEvent {
+	return TradingStatusEvent{
+		Symbol: msg.GetSymbol(),
+		Reason: msg.GetReason(),
+		EndsAt: fromTimestamp(msg.GetEndsAt()),
+	}
+}
+
+func toProtoTransactionEvent(transaction TransactionEvent) *eventspb.TransactionEvent {
+	return &eventspb.TransactionEvent{
+		Id:           transaction.ID,
+		Symbol:       transaction.Symbol,
+		BuyOrderId:   transaction.BuyOrderID,
+		SellOrderId:  transaction.SellOrderID,
+		MakerOrderId: transaction.MakerOrderID,
+		TakerOrderId: transaction.TakerOrderID,
+		TakerSide:    transaction.TakerSide,
+		Price:        transaction.Price,
+		Quantity:     transaction.Quantity,
+		BuyFee:       &eventspb.FeeEvent{Amount: transaction.BuyFee.Amount, Currency: transaction.BuyFee.Currency},
+		SellFee:      &eventspb.FeeEvent{Amount: transaction.SellFee.Amount, Currency: transaction.SellFee.Currency},
+		CreatedAt:    toTimestamp(transaction.CreatedAt),
+	}
+}
+
+func fromProtoTransactionEvent(msg *eventspb.TransactionEvent) TransactionEvent {
+	return TransactionEvent{
+		ID:           msg.GetId(),
+		Symbol:       msg.GetSymbol(),
+		BuyOrderID:   msg.GetBuyOrderId(),
+		SellOrderID:  msg.GetSellOrderId(),
+		MakerOrderID: msg.GetMakerOrderId(),
+		TakerOrderID: msg.GetTakerOrderId(),
+		TakerSide:    msg.GetTakerSide(),
+		Price:        msg.GetPrice(),
+		Quantity:     msg.GetQuantity(),
+		BuyFee:       FeeEvent{Amount: msg.GetBuyFee().GetAmount(), Currency: msg.GetBuyFee().GetCurrency()},
+		SellFee:      FeeEvent{Amount: msg.GetSellFee().GetAmount(), Currency: msg.GetSellFee().GetCurrency()},
+		CreatedAt:    fromTimestamp(msg.GetCreatedAt()),
+	}
+}
+
+// toTimestamp returns the google.protobuf.Timestamp of a time, which is omitted for the zero time
+func toTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// fromTimestamp returns the time of a google.protobuf.Timestamp in UTC, the zero time if omitted
+func fromTimestamp(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
@@ -0,0 +1,123 @@
+//go:generate go-enum --marshal
+package matchingengine
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// ENUM(FIFO, ProRata, TopOrderProRata)
+type AllocationMethod string
+
+// AllocationConfig selects how a price level allocates an incoming quantity
+type AllocationConfig struct {
+	Method AllocationMethod `json:"method"`
+	// MinAllocation is the smallest pro-rata share, smaller shares go to the FIFO remainder
+	MinAllocation int64 `json:"min_allocation"`
+}
+
+// Allocation is the quantity filled on a resting order
+type Allocation struct {
+	Node     *OrderNode
+	Quantity int64
+}
+
+// AllocationPolicy splits an incoming quantity among the resting orders of a price level.
+// The allocations are appended to the buffer in execution order and never exceed the quantity.
+type AllocationPolicy interface {
+	Allocate(level *PriceLevel, quantity int64, allocations []Allocation) []Allocation
+}
+
+// NewAllocationPolicy returns the AllocationPolicy of the configured method
+func NewAllocationPolicy(config AllocationConfig) (AllocationPolicy, error) {
+	switch config.Method {
+	case AllocationMethodFIFO, "":
+		return FIFOPolicy{}, nil
+	case AllocationMethodProRata:
+		return ProRataPolicy{MinAllocation: config.MinAllocation}, nil
+	case AllocationMethodTopOrderProRata:
+		return ProRataPolicy{MinAllocation: config.MinAllocation, TopOrderPriority: true}, nil
+	default:
+		return nil, fmt.Errorf("%s is %w", config.Method, ErrInvalidAllocationMethod)
+	}
+}
+
+// FIFOPolicy fills the orders of a price level by time priority
+type FIFOPolicy struct{}
+
+func (FIFOPolicy) Allocate(level *PriceLevel, quantity int64, allocations []Allocation) []Allocation {
+	for node := level.HeadOrders; node != nil && quantity > 0; node = node.Next {
+		allocated := min(quantity, node.Order.Quantity)
+		allocations = append(allocations, Allocation{Node: node, Quantity: allocated})
+		quantity -= allocated
+	}
+	return allocations
+}
+
+// ProRataPolicy fills the orders of a price level in proportion to their quantity. Shares are
+// rounded down, shares below MinAllocation are dropped, and the remainder is distributed by
+// time priority. With TopOrderPriority, the earliest order is filled first.
+type ProRataPolicy struct {
+	MinAllocation    int64
+	TopOrderPriority bool
+}
+
+func (p ProRataPolicy) Allocate(level *PriceLevel, quantity int64, allocations []Allocation) []Allocation {
+	// The whole level is filled
+	if quantity >= level.TotalQuantity {
+		return FIFOPolicy{}.Allocate(level, quantity, allocations)
+	}
+
+	// Compute the shares in place of the buffer, then drop the empty ones
+	start := len(allocations)
+	var total int64
+	for node := level.HeadOrders; node != nil; node = node.Next {
+		allocations = append(allocations, Allocation{Node: node})
+		total += node.Order.Quantity
+	}
+	shares := allocations[start:]
+
+	remaining := quantity
+	first := 0
+	if p.TopOrderPriority && len(shares) > 0 {
+		shares[0].Quantity = min(remaining, shares[0].Node.Order.Quantity)
+		remaining -= shares[0].Quantity
+		total -= shares[0].Node.Order.Quantity
+		first = 1
+	}
+
+	if remaining > 0 && total > 0 {
+		pool := remaining
+		for i := first; i < len(shares); i++ {
+			share := mulDiv(pool, shares[i].Node.Order.Quantity, total)
+			if share < p.MinAllocation {
+				share = 0
+			}
+			shares[i].Quantity += share
+			remaining -= share
+		}
+	}
+
+	// Distribute the rounding remainder by time priority
+	for i := 0; i < len(shares) && remaining > 0; i++ {
+		extra := min(remaining, shares[i].Node.Order.Quantity-shares[i].Quantity)
+		shares[i].Quantity += extra
+		remaining -= extra
+	}
+
+	allocations = allocations[:start]
+	for _, share := range shares {
+		if share.Quantity > 0 {
+			allocations = append(allocations, share)
+		}
+	}
+	return allocations
+}
+
+// mulDiv returns a * b / c rounded down, the product is in 128 bits so large quantities do not
+// overflow. The quotient must fit in an int64, as a share is below the quantity of its order.
+func mulDiv(a, b, c int64) int64 {
+	hi, lo := bits.Mul64(uint64(a), uint64(b))
+	quotient, _ := bits.Div64(hi, lo, uint64(c))
+	return int64(quotient)
+}
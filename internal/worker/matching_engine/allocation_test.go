@@ -0,0 +1,97 @@
+package matchingengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type AllocationTestSuite struct {
+	suite.Suite
+	orderBook *OrderBook
+	symbol    string
+}
+
+func TestAllocationTestSuite(t *testing.T) {
+	suite.Run(t, new(AllocationTestSuite))
+}
+
+func (suite *AllocationTestSuite) SetupTest() {
+	suite.symbol = "ES"
+	suite.orderBook = NewOrderBook()
+
+	// Resting sells at the same price in time priority
+	for _, order := range []Order{
+		{ID: "sell1", Quantity: 10},
+		{ID: "sell2", Quantity: 30},
+		{ID: "sell3", Quantity: 60},
+	} {
+		order.Symbol = suite.symbol
+		order.Type = OrderTypeSell
+		order.Price = 100.0
+		suite.orderBook.InsertOrder(order)
+	}
+}
+
+func (suite *AllocationTestSuite) allocate(policy AllocationPolicy, quantity int64) map[string]int64 {
+	result := make(map[string]int64)
+	var total int64
+	for _, allocation := range policy.Allocate(suite.orderBook.SellLevels, quantity, nil) {
+		result[allocation.Node.Order.ID] = allocation.Quantity
+		total += allocation.Quantity
+	}
+	suite.LessOrEqual(total, quantity)
+	return result
+}
+
+func (suite *AllocationTestSuite) TestNewAllocationPolicy() {
+	policy, err := NewAllocationPolicy(AllocationConfig{})
+	suite.NoError(err)
+	suite.Equal(FIFOPolicy{}, policy)
+
+	policy, err = NewAllocationPolicy(AllocationConfig{Method: AllocationMethodTopOrderProRata, MinAllocation: 2})
+	suite.NoError(err)
+	suite.Equal(ProRataPolicy{MinAllocation: 2, TopOrderPriority: true}, policy)
+
+	_, err = NewAllocationPolicy(AllocationConfig{Method: "Random"})
+	suite.ErrorIs(err, ErrInvalidAllocationMethod)
+}
+
+func (suite *AllocationTestSuite) TestFIFO() {
+	suite.Equal(map[string]int64{"sell1": 10, "sell2": 15}, suite.allocate(FIFOPolicy{}, 25))
+}
+
+func (suite *AllocationTestSuite) TestProRata() {
+	// 25 splits into 2.5, 7.5 and 15 rounded down, the remainder goes to the earliest order
+	suite.Equal(map[string]int64{"sell1": 3, "sell2": 7, "sell3": 15}, suite.allocate(ProRataPolicy{}, 25))
+
+	// The share of sell1 is below the minimum allocation
+	suite.Equal(map[string]int64{"sell1": 2, "sell2": 3, "sell3": 6}, suite.allocate(ProRataPolicy{MinAllocation: 2}, 11))
+
+	// The whole level is filled
+	suite.Equal(map[string]int64{"sell1": 10, "sell2": 30, "sell3": 60}, suite.allocate(ProRataPolicy{}, 150))
+}
+
+func (suite *AllocationTestSuite) TestProRata_LargeQuantities() {
+	// The products of the quantities overflow an int64
+	suite.orderBook = NewOrderBook()
+	suite.orderBook.InsertOrder(Order{ID: "sell1", Symbol: suite.symbol, Type: OrderTypeSell, Price: 100.0, Quantity: 1 << 40})
+	suite.orderBook.InsertOrder(Order{ID: "sell2", Symbol: suite.symbol, Type: OrderTypeSell, Price: 100.0, Quantity: 3 << 40})
+
+	suite.Equal(map[string]int64{"sell1": 1 << 39, "sell2": 3 << 39}, suite.allocate(ProRataPolicy{}, 2<<40))
+	suite.Equal(map[string]int64{"sell1": 1 << 40, "sell2": 1 << 40}, suite.allocate(ProRataPolicy{TopOrderPriority: true}, 2<<40))
+}
+
+func (suite *AllocationTestSuite) TestTopOrderProRata() {
+	// sell1 is filled first, the remaining 40 splits into 13.3 and 26.7 rounded down,
+	// the remainder goes to sell2 as sell1 is already filled
+	suite.Equal(map[string]int64{"sell1": 10, "sell2": 14, "sell3": 26}, suite.allocate(ProRataPolicy{TopOrderPriority: true}, 50))
+}
+
+func (suite *AllocationTestSuite) TestMatcher() {
+	matcher := NewMatcher(suite.orderBook, 5, WithAllocationPolicy(ProRataPolicy{}))
+	matching, err := matcher.CreateOrder(Order{ID: "buy1", Symbol: suite.symbol, Type: OrderTypeBuy, Price: 100.0, Quantity: 50})
+	suite.NoError(err)
+	suite.Len(matching.Transactions, 3)
+	suite.Equal([]Tick{{Price: 100.0, Quantity: 50}}, matching.SellTicks)
+}
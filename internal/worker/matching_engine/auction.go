@@ -0,0 +1,224 @@
+package matchingengine
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+var (
+	ErrNotInAuction = errors.New("trading is not in auction")
+)
+
+// AuctionConfig configures the call auctions of a Matcher
+type AuctionConfig struct {
+	// IndicativeInterval is how often the indicative price and volume are published
+	IndicativeInterval time.Duration
+}
+
+// Indicative is the equilibrium an auction would uncross at if it closed now
+type Indicative struct {
+	Price     float64
+	Volume    int64
+	Imbalance int64
+}
+
+// StartAuction stops the continuous matching and accumulates the orders until endsAt,
+// when the auction uncrosses at a single price. Starting from a halt replaces it: the orders
+// queued while halted join the auction in their arrival order.
+func (me *Matcher) StartAuction(reason string, endsAt time.Time) (Matching, error) {
+	if me.status == TradingStatusAuction {
+		return Matching{}, ErrAlreadyInAuction
+	}
+
+	me.status = TradingStatusAuction
+	me.auctionEndsAt = endsAt
+	me.lastIndicativeAt = time.Time{}
+	me.resumeAt = time.Time{}
+	for _, order := range me.queuedOrders {
+		me.orderBook.InsertOrder(order)
+	}
+	me.queuedOrders = nil
+
+	var matching Matching
+	matching.Status = &StatusChange{Status: TradingStatusAuction, Reason: reason, ResumeAt: endsAt}
+	matching.BuyTicks, matching.SellTicks = me.orderBook.GetTopTicks(me.tickNum)
+	return matching, nil
+}
+
+// Indicative returns the price and volume the auction would uncross at now
+func (me *Matcher) Indicative() Indicative {
+	buys, sells := me.auctionOrders()
+	return equilibrium(buys, sells, me.ReferencePrice())
+}
+
+// Uncross closes the auction: every crossing order is executed at the equilibrium price
+// and the trading transitions to continuous
+func (me *Matcher) Uncross() (Matching, error) {
+	if me.status != TradingStatusAuction {
+		return Matching{}, ErrNotInAuction
+	}
+
+	buys, sells := me.auctionOrders()
+	indicative := equilibrium(buys, sells, me.ReferencePrice())
+
+	var matching Matching
+	matching.Indicative = &indicative
+	matching.Transactions = me.executeAuction(buys, sells, indicative)
+	if len(matching.Transactions) > 0 {
+		me.lastTradePrice = indicative.Price
+	}
+
+	me.status = TradingStatusContinuous
+	me.auctionEndsAt = time.Time{}
+	if me.circuitBreaker != nil {
+		me.circuitBreaker.Reset()
+	}
+
+	matching.Status = &StatusChange{Status: TradingStatusContinuous, Reason: "auction uncrossed"}
+	matching.BuyTicks, matching.SellTicks = me.orderBook.GetTopTicks(me.tickNum)
+	return matching, nil
+}
+
+// tickAuction uncrosses the auction once it ends, otherwise publishes the indicative periodically
+func (me *Matcher) tickAuction() []Matching {
+	current := me.now()
+	if !me.auctionEndsAt.IsZero() && !current.Before(me.auctionEndsAt) {
+		matching, _ := me.Uncross()
+		return []Matching{matching}
+	}
+
+	if me.auctionConfig.IndicativeInterval <= 0 || current.Sub(me.lastIndicativeAt) < me.auctionConfig.IndicativeInterval {
+		return nil
+	}
+	me.lastIndicativeAt = current
+
+	indicative := me.Indicative()
+	var matching Matching
+	matching.Indicative = &indicative
+	matching.BuyTicks, matching.SellTicks = me.orderBook.GetTopTicks(me.tickNum)
+	return []Matching{matching}
+}
+
+// auctionOrders returns the buy orders by descending price and the sell orders by ascending
+// price, both in time priority within a price
+func (me *Matcher) auctionOrders() ([]*OrderNode, []*OrderNode) {
+	collect := func(head *PriceLevel) []*OrderNode {
+		var nodes []*OrderNode
+		for level := head; level != nil; level = level.Next {
+			for node := level.HeadOrders; node != nil; node = node.Next {
+				nodes = append(nodes, node)
+			}
+		}
+		return nodes
+	}
+
+	return collect(me.orderBook.BuyLevels), collect(me.orderBook.SellLevels)
+}
+
+// equilibrium finds the uncrossing price which maximizes the executable volume, then
+// minimizes the imbalance, then is the closest to the reference price, then is the lowest
+func equilibrium(buys, sells []*OrderNode, referencePrice float64) Indicative {
+	var prices []float64
+	for _, node := range append(append([]*OrderNode{}, buys...), sells...) {
+		prices = append(prices, node.Order.Price)
+	}
+	sort.Float64s(prices)
+
+	var best Indicative
+	for i, price := range prices {
+		if i > 0 && prices[i-1] == price {
+			continue
+		}
+
+		var buyVolume, sellVolume int64
+		for _, node := range buys {
+			if node.Order.Price >= price {
+				buyVolume += node.Order.Quantity
+			}
+		}
+		for _, node := range sells {
+			if node.Order.Price <= price {
+				sellVolume += node.Order.Quantity
+			}
+		}
+
+		candidate := Indicative{
+			Price:     price,
+			Volume:    min(buyVolume, sellVolume),
+			Imbalance: buyVolume - sellVolume,
+		}
+		if candidate.Volume == 0 {
+			continue
+		}
+		if isBetterEquilibrium(candidate, best, referencePrice) {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// isBetterEquilibrium reports whether the candidate uncrossing price is preferred over the current one
+func isBetterEquilibrium(candidate, current Indicative, referencePrice float64) bool {
+	if candidate.Volume != current.Volume {
+		return candidate.Volume > current.Volume
+	}
+
+	candidateImbalance, currentImbalance := abs(candidate.Imbalance), abs(current.Imbalance)
+	if candidateImbalance != currentImbalance {
+		return candidateImbalance < currentImbalance
+	}
+
+	if referencePrice > 0 {
+		candidateDistance := math.Abs(candidate.Price - referencePrice)
+		currentDistance := math.Abs(current.Price - referencePrice)
+		if candidateDistance != currentDistance {
+			return candidateDistance < currentDistance
+		}
+	}
+	return candidate.Price < current.Price
+}
+
+// executeAuction matches the orders in priority until the equilibrium volume is executed
+func (me *Matcher) executeAuction(buys, sells []*OrderNode, indicative Indicative) []Transaction {
+	transactions := []Transaction{}
+	remaining := indicative.Volume
+
+	for remaining > 0 && len(buys) > 0 && len(sells) > 0 {
+		buy, sell := buys[0], sells[0]
+		matchedQuantity := min(remaining, buy.Order.Quantity, sell.Order.Quantity)
+
+		transaction := Transaction{
+			ID:          me.nextTransactionID(),
+			Symbol:      buy.Order.Symbol,
+			BuyOrderID:  buy.Order.ID,
+			SellOrderID: sell.Order.ID,
+			Price:       indicative.Price,
+			Quantity:    matchedQuantity,
+			CreatedAt:   me.now(),
+		}
+		// Both sides of an auction trade provide liquidity
+		if me.feeSchedule != nil {
+			transaction.BuyFee = me.feeSchedule.Fee(transaction.Symbol, buy.Order.AccountID, true, transaction.Price, matchedQuantity)
+			transaction.SellFee = me.feeSchedule.Fee(transaction.Symbol, sell.Order.AccountID, true, transaction.Price, matchedQuantity)
+		}
+		transactions = append(transactions, transaction)
+
+		remaining -= matchedQuantity
+		if me.fillOrder(buy, matchedQuantity) {
+			buys = buys[1:]
+		}
+		if me.fillOrder(sell, matchedQuantity) {
+			sells = sells[1:]
+		}
+	}
+	return transactions
+}
+
+func abs(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,80 @@
+package matchingengine
+
+import (
+	"math"
+	"time"
+)
+
+// CircuitBreakerConfig configures the price band which halts the trading once breached
+type CircuitBreakerConfig struct {
+	// BandPct is the percentage the price may move within the Window, 0 disables the breaker
+	BandPct float64
+	Window  time.Duration
+	// Cooldown is how long the trading stays halted before it resumes
+	Cooldown time.Duration
+	// QueueOrders queues the new orders while halted instead of rejecting them. The rest of the
+	// order which breached the band is queued either way.
+	QueueOrders bool
+	// ReopenAuction is the duration of the auction which re-opens the trading after the
+	// cooldown, 0 resumes the continuous trading directly
+	ReopenAuction time.Duration
+}
+
+// tradePoint is the price of a trade at a time
+type tradePoint struct {
+	price float64
+	at    time.Time
+}
+
+// CircuitBreaker tracks the trade prices within a rolling window
+type CircuitBreaker struct {
+	config CircuitBreakerConfig
+	// trades are the trades within the window, sorted by time
+	trades []tradePoint
+}
+
+func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
+	return &CircuitBreaker{
+		config: config,
+	}
+}
+
+// Breaches reports whether a trade at the price would move more than the band from any trade
+// within the window
+func (cb *CircuitBreaker) Breaches(price float64, at time.Time) bool {
+	if cb.config.BandPct <= 0 {
+		return false
+	}
+
+	cb.expire(at)
+	low, high := price, price
+	for _, trade := range cb.trades {
+		low = math.Min(low, trade.price)
+		high = math.Max(high, trade.price)
+	}
+	return (high-low)/low*100 > cb.config.BandPct
+}
+
+// Observe records a trade within the window
+func (cb *CircuitBreaker) Observe(price float64, at time.Time) {
+	if cb.config.BandPct <= 0 {
+		return
+	}
+
+	cb.expire(at)
+	cb.trades = append(cb.trades, tradePoint{price: price, at: at})
+}
+
+// expire drops the trades which are out of the window at the time
+func (cb *CircuitBreaker) expire(at time.Time) {
+	start := 0
+	for start < len(cb.trades) && at.Sub(cb.trades[start].at) > cb.config.Window {
+		start++
+	}
+	cb.trades = append(cb.trades[:0], cb.trades[start:]...)
+}
+
+// Reset forgets the tracked trades so that the band restarts after a halt
+func (cb *CircuitBreaker) Reset() {
+	cb.trades = nil
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	matchingengine "github.com/Hao1995/order-matching-system/internal/worker/matching_engine"
+)
+
+// Config is the configuration of the matching engine of a symbol, shared by the worker and the
+// tools which rebuild the same engine
+type Config struct {
+	CircuitBreaker CircuitBreaker `envPrefix:"CIRCUIT_BREAKER_"`
+	Auction        Auction        `envPrefix:"AUCTION_"`
+
+	TickNum int8 `env:"TICK_NUM" envDefault:"5"`
+
+	// FeeScheduleFile is a JSON file of matchingengine.FeeSchedule, no fees are charged if empty
+	FeeScheduleFile string `env:"FEE_SCHEDULE_FILE"`
+	// RiskConfigFile is a JSON file of matchingengine.RiskConfig, no limits are checked if empty
+	RiskConfigFile string `env:"RISK_CONFIG_FILE"`
+	// SessionCalendarFile is a JSON file mapping Symbol to matchingengine.SessionCalendar,
+	// the symbol trades continuously if it is not listed
+	SessionCalendarFile string `env:"SESSION_CALENDAR_FILE"`
+	// AllocationConfigFile is a JSON file mapping Symbol to matchingengine.AllocationConfig,
+	// the symbol matches FIFO if it is not listed
+	AllocationConfigFile string `env:"ALLOCATION_CONFIG_FILE"`
+}
+
+type CircuitBreaker struct {
+	// BandPct is the percentage the price may move within the Window, 0 disables the breaker
+	BandPct     float64       `env:"BAND_PCT"`
+	Window      time.Duration `env:"WINDOW" envDefault:"1m"`
+	Cooldown    time.Duration `env:"COOLDOWN" envDefault:"5m"`
+	QueueOrders bool          `env:"QUEUE_ORDERS"`
+	// ReopenAuction is the duration of the re-opening auction after a halt, 0 resumes directly
+	ReopenAuction time.Duration `env:"REOPEN_AUCTION"`
+}
+
+type Auction struct {
+	IndicativeInterval time.Duration `env:"INDICATIVE_INTERVAL" envDefault:"5s"`
+}
+
+// MatcherOptions loads the configured behaviors of the Matcher of a symbol
+func (cfg Config) MatcherOptions(symbol string) ([]matchingengine.MatcherOption, error) {
+	var matcherOpts []matchingengine.MatcherOption
+	if cfg.FeeScheduleFile != "" {
+		var feeSchedule matchingengine.FeeSchedule
+		if err := loadJSONFile(cfg.FeeScheduleFile, &feeSchedule); err != nil {
+			return nil, fmt.Errorf("failed to load fee schedule %s: %w", cfg.FeeScheduleFile, err)
+		}
+		matcherOpts = append(matcherOpts, matchingengine.WithFeeSchedule(&feeSchedule))
+	}
+	if cfg.CircuitBreaker.BandPct > 0 {
+		matcherOpts = append(matcherOpts, matchingengine.WithCircuitBreaker(matchingengine.CircuitBreakerConfig{
+			BandPct:       cfg.CircuitBreaker.BandPct,
+			Window:        cfg.CircuitBreaker.Window,
+			Cooldown:      cfg.CircuitBreaker.Cooldown,
+			QueueOrders:   cfg.CircuitBreaker.QueueOrders,
+			ReopenAuction: cfg.CircuitBreaker.ReopenAuction,
+		}))
+	}
+	if cfg.SessionCalendarFile != "" {
+		sessionScheduler, err := loadSessionScheduler(cfg.SessionCalendarFile, symbol)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load session calendar %s: %w", cfg.SessionCalendarFile, err)
+		}
+		if sessionScheduler != nil {
+			matcherOpts = append(matcherOpts, matchingengine.WithSessionScheduler(sessionScheduler))
+		}
+	}
+	if cfg.AllocationConfigFile != "" {
+		allocationPolicy, err := loadAllocationPolicy(cfg.AllocationConfigFile, symbol)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load allocation config %s: %w", cfg.AllocationConfigFile, err)
+		}
+		matcherOpts = append(matcherOpts, matchingengine.WithAllocationPolicy(allocationPolicy))
+	}
+	matcherOpts = append(matcherOpts, matchingengine.WithAuction(matchingengine.AuctionConfig{
+		IndicativeInterval: cfg.Auction.IndicativeInterval,
+	}))
+	return matcherOpts, nil
+}
+
+// RiskChecker loads the pre-trade risk limits
+func (cfg Config) RiskChecker() (*matchingengine.RiskChecker, error) {
+	var riskConfig matchingengine.RiskConfig
+	if cfg.RiskConfigFile != "" {
+		if err := loadJSONFile(cfg.RiskConfigFile, &riskConfig); err != nil {
+			return nil, fmt.Errorf("failed to load risk config %s: %w", cfg.RiskConfigFile, err)
+		}
+	}
+	return matchingengine.NewRiskChecker(riskConfig), nil
+}
+
+// loadSessionScheduler reads the session calendar of a symbol from a JSON file, it returns
+// nil if the symbol has no calendar
+func loadSessionScheduler(path, symbol string) (*matchingengine.SessionScheduler, error) {
+	var calendars map[string]matchingengine.SessionCalendar
+	if err := loadJSONFile(path, &calendars); err != nil {
+		return nil, err
+	}
+
+	calendar, exists := calendars[symbol]
+	if !exists {
+		return nil, nil
+	}
+	return matchingengine.NewSessionScheduler(calendar)
+}
+
+// loadAllocationPolicy reads the allocation config of a symbol from a JSON file
+func loadAllocationPolicy(path, symbol string) (matchingengine.AllocationPolicy, error) {
+	var configs map[string]matchingengine.AllocationConfig
+	if err := loadJSONFile(path, &configs); err != nil {
+		return nil, err
+	}
+	return matchingengine.NewAllocationPolicy(configs[symbol])
+}
+
+// loadJSONFile decodes a JSON file into v
+func loadJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
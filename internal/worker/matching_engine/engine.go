@@ -0,0 +1,558 @@
+package matchingengine
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	"github.com/Hao1995/order-matching-system/pkg/logger"
+)
+
+var (
+	ErrUnknownEventType = errors.New("unknown event type")
+	ErrMalformedEvent   = errors.New("malformed event")
+)
+
+// Engine applies the order events of a symbol to its Matcher and converts the results to
+// matching events. It is safe to be driven by both the consumer and a periodic ticker.
+type Engine struct {
+	mu          sync.Mutex
+	symbol      string
+	matcher     *Matcher
+	riskChecker *RiskChecker
+	journal     Journal
+	// clock stamps the inputs without a time, the current time if nil
+	clock func() time.Time
+	// lastSeq is the sequence number of the latest matching event
+	lastSeq uint64
+	// seq is lastSeq unless it is shared with other engines
+	seq *uint64
+	// lastOffset is the offset of the latest applied order event, -1 before any
+	lastOffset int64
+	// matching is reused by every created order, so the Matcher recycles its buffers
+	matching Matching
+}
+
+// EngineOption configures optional behaviors of an Engine
+type EngineOption func(*Engine)
+
+// WithSequence numbers the matching events from a sequence shared with other engines, e.g. the
+// engines of the symbols in a partition
+func WithSequence(seq *uint64) EngineOption {
+	return func(e *Engine) {
+		e.seq = seq
+	}
+}
+
+// WithClock stamps the inputs with the time of the clock instead of the current time, e.g. to
+// replay a recorded stream
+func WithClock(clock func() time.Time) EngineOption {
+	return func(e *Engine) {
+		e.clock = clock
+	}
+}
+
+func NewEngine(symbol string, matcher *Matcher, riskChecker *RiskChecker, opts ...EngineOption) *Engine {
+	e := &Engine{
+		symbol:      symbol,
+		matcher:     matcher,
+		riskChecker: riskChecker,
+		lastOffset:  -1,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if e.seq == nil {
+		e.seq = &e.lastSeq
+	}
+	return e
+}
+
+// Handle applies an order event at the offset after the latest applied one
+func (e *Engine) Handle(val []byte) ([]events.MatchingEvent, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.handleAt(e.lastOffset+1, events.JSONCodec, val)
+}
+
+// HandleAt journals the order event at the offset of the order topic, applies it at the time it
+// was produced and returns the matching events to publish. An order event at or before the
+// latest applied offset is a redelivery and is skipped.
+func (e *Engine) HandleAt(offset int64, val []byte) ([]events.MatchingEvent, error) {
+	return e.HandleEncodedAt(offset, events.JSONCodec, val)
+}
+
+// HandleEncodedAt is HandleAt for an order event encoded by the codec
+func (e *Engine) HandleEncodedAt(offset int64, codec events.Codec, val []byte) ([]events.MatchingEvent, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if offset <= e.lastOffset {
+		logger.Warn("skip the applied event", zap.Int64("offset", offset), zap.Int64("lastOffset", e.lastOffset))
+		return nil, nil
+	}
+	return e.handleAt(offset, codec, val)
+}
+
+// LastOffset returns the offset of the latest applied order event, -1 before any
+func (e *Engine) LastOffset() int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.lastOffset
+}
+
+func (e *Engine) handleAt(offset int64, codec events.Codec, val []byte) ([]events.MatchingEvent, error) {
+	in, err := decodeInput(codec, val)
+	if err != nil {
+		// A malformed event can never be applied, it is journaled by Partition.Skip once dead-lettered
+		return nil, err
+	}
+
+	// Apply the event at the time it was produced, or the time of the clock if unknown
+	eventTime := in.event.CreatedAt
+	if eventTime.IsZero() {
+		eventTime = e.now()
+	}
+
+	// An idle heartbeat is not journaled, consuming it again after a restart changes nothing
+	if in.event.EventType == events.EventTypeHeartbeat && !e.tickDue(eventTime) {
+		e.lastOffset = offset
+		return nil, nil
+	}
+
+	// The journal keeps the order events in JSON, whatever their wire format
+	if codec.ContentType() != events.ContentTypeJSON {
+		if val, err = events.JSONCodec.Encode(in.envelope); err != nil {
+			logger.Error("failed to encode event", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	entry := JournalEntry{Type: JournalEntryTypeEvent, Symbol: e.symbol, Offset: offset, Time: eventTime, Event: val}
+	if err := e.record(entry); err != nil {
+		logger.Error("failed to journal event", zap.Error(err))
+		return nil, err
+	}
+	return e.applyInput(entry, in)
+}
+
+// Tick journals and drives the time based transitions of the Matcher. The tick is skipped if
+// it would change nothing, so idle ticks do not grow the journal, or if it fails to be journaled.
+func (e *Engine) Tick() []events.MatchingEvent {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	entry := JournalEntry{Type: JournalEntryTypeTick, Symbol: e.symbol, Time: e.now()}
+	if !e.tickDue(entry.Time) {
+		return nil
+	}
+	if err := e.record(entry); err != nil {
+		logger.Error("failed to journal tick", zap.Error(err))
+		return nil
+	}
+	matchingEvents, _ := e.apply(entry)
+	return matchingEvents
+}
+
+// tickDue pins the time of the Matcher and reports whether a tick would change its state
+func (e *Engine) tickDue(t time.Time) bool {
+	e.matcher.setInputTime(t)
+	return e.matcher.tickDue()
+}
+
+func (e *Engine) now() time.Time {
+	if e.clock == nil {
+		return now()
+	}
+	return e.clock()
+}
+
+// input is a decoded order event
+type input struct {
+	envelope    events.Envelope
+	event       events.Event
+	orderEvent  events.OrderEvent
+	statusEvent events.TradingStatusEvent
+}
+
+// decodeInput decodes an order event with the codec. The error wraps ErrMalformedEvent, as the
+// event can never be applied.
+func decodeInput(codec events.Codec, val []byte) (input, error) {
+	var in input
+	var err error
+	if in.envelope, err = codec.Decode(events.PayloadTypeEvent, val); err != nil {
+		logger.Error("failed to decode event", zap.Error(err), zap.ByteString("val", val))
+		return in, fmt.Errorf("%w: %w", ErrMalformedEvent, err)
+	}
+	in.event = in.envelope.Payload.(events.Event)
+	logger.Debug("Receive event", zap.String("id", in.envelope.ID), zap.String("producer", in.envelope.Producer), zap.Any("event", in.event))
+
+	switch in.event.EventType {
+	case events.EventTypeCreateOrder, events.EventTypeCancelOrder, events.EventTypeAmendOrder, events.EventTypeMassCancel:
+		if in.orderEvent, err = decodeData[events.OrderEvent](in.event); err != nil {
+			return in, err
+		}
+	case events.EventTypeHeartbeat:
+	case events.EventTypeHaltTrading, events.EventTypeResumeTrading, events.EventTypeStartAuction:
+		if in.statusEvent, err = decodeData[events.TradingStatusEvent](in.event); err != nil {
+			return in, err
+		}
+	default:
+		logger.Error("unknown event type", zap.String("eventType", in.event.EventType.String()))
+		return in, fmt.Errorf("%w: %w", ErrMalformedEvent, ErrUnknownEventType)
+	}
+	return in, nil
+}
+
+// decodeData returns the Data of a decoded event, which is the zero value if the event has none
+func decodeData[T any](event events.Event) (T, error) {
+	var data T
+	if event.Data == nil {
+		return data, nil
+	}
+	data, ok := event.Data.(T)
+	if !ok {
+		return data, fmt.Errorf("%w: %T data of %s event", ErrMalformedEvent, event.Data, event.EventType)
+	}
+	return data, nil
+}
+
+// handle applies a decoded order event to the Matcher
+func (e *Engine) handle(in input) ([]events.MatchingEvent, error) {
+	switch in.event.EventType {
+	case events.EventTypeCreateOrder:
+		return e.createOrder(in.orderEvent)
+	case events.EventTypeCancelOrder:
+		return e.cancelOrder(in.orderEvent)
+	case events.EventTypeAmendOrder:
+		return e.amendOrder(in.orderEvent)
+	case events.EventTypeMassCancel:
+		return e.massCancel(in.orderEvent), nil
+	case events.EventTypeHeartbeat:
+		// The leader replica drives the time based transitions of all the replicas
+		return e.convertMatchings(e.matcher.Tick()), nil
+	}
+
+	// A trading status event of another symbol, e.g. one sharing the partition, is not applied
+	if in.statusEvent.Symbol != "" && in.statusEvent.Symbol != e.symbol {
+		logger.Warn("skip the trading status event of another symbol", zap.String("symbol", e.symbol), zap.String("eventSymbol", in.statusEvent.Symbol))
+		return nil, nil
+	}
+	switch in.event.EventType {
+	case events.EventTypeHaltTrading:
+		return e.halt(in.statusEvent)
+	case events.EventTypeStartAuction:
+		return e.startAuction(in.statusEvent)
+	default:
+		return e.resume()
+	}
+}
+
+func (e *Engine) createOrder(orderEvent events.OrderEvent) ([]events.MatchingEvent, error) {
+	order := convertOrderEventToOrder(orderEvent)
+	if err := e.riskChecker.Check(order, e.matcher.ReferencePrice()); err != nil {
+		return e.reject(orderEvent, err)
+	}
+
+	if err := e.matcher.CreateOrderInto(order, &e.matching); err != nil {
+		return e.reject(orderEvent, err)
+	}
+	return e.convertMatchings([]Matching{e.matching}), nil
+}
+
+func (e *Engine) cancelOrder(orderEvent events.OrderEvent) ([]events.MatchingEvent, error) {
+	matching, err := e.matcher.CancelOrder(orderEvent.ID)
+	if err != nil {
+		logger.Warn("failed to cancel order, pass it", zap.Error(err))
+		return nil, nil
+	}
+
+	// The cancel event may have no symbol, which keys the matching event
+	orderEvent.Symbol = e.symbol
+	return []events.MatchingEvent{{
+		Type:         events.MatchingEventTypeCancel,
+		Order:        orderEvent,
+		Transactions: convertToTransactionEvents(matching.Transactions),
+		BuyTicks:     convertToTickEvents(matching.BuyTicks),
+		SellTicks:    convertToTickEvents(matching.SellTicks),
+	}}, nil
+}
+
+// amendOrder replaces the order OrigID by the order of the event, which loses the time priority
+// of the replaced one. The replaced order is kept if the new one breaks a risk limit, and an
+// amend of an order no longer in the book, or of another account or side, is rejected.
+func (e *Engine) amendOrder(orderEvent events.OrderEvent) ([]events.MatchingEvent, error) {
+	order := convertOrderEventToOrder(orderEvent)
+	if err := e.riskChecker.Check(order, e.matcher.ReferencePrice()); err != nil {
+		return e.reject(orderEvent, err)
+	}
+
+	// Another account is told the order is unknown, which does not reveal the orders of others
+	replaced, ok := e.matcher.Order(orderEvent.OrigID)
+	if !ok || replaced.AccountID != order.AccountID || replaced.Type != order.Type {
+		return e.reject(orderEvent, &RejectionError{Rule: RejectRuleUnknownOrder, Message: ErrOrderNotFound.Error()})
+	}
+	cancelled, err := e.matcher.CancelOrder(orderEvent.OrigID)
+	if err != nil {
+		return e.reject(orderEvent, &RejectionError{Rule: RejectRuleUnknownOrder, Message: err.Error()})
+	}
+	matchingEvents := []events.MatchingEvent{{
+		Type:         events.MatchingEventTypeCancel,
+		Order:        events.OrderEvent{ID: orderEvent.OrigID, AccountID: replaced.AccountID, Symbol: e.symbol},
+		Transactions: convertToTransactionEvents(cancelled.Transactions),
+		BuyTicks:     convertToTickEvents(cancelled.BuyTicks),
+		SellTicks:    convertToTickEvents(cancelled.SellTicks),
+	}}
+
+	if err := e.matcher.CreateOrderInto(order, &e.matching); err != nil {
+		rejected, err := e.reject(orderEvent, err)
+		return append(matchingEvents, rejected...), err
+	}
+	return append(matchingEvents, e.convertMatchings([]Matching{e.matching})...), nil
+}
+
+// massCancel cancels the orders of the account of the event at once, of the side of its Type or
+// both sides without one. It reports all the cancelled orders in one matching event, which has
+// none if nothing was open.
+func (e *Engine) massCancel(orderEvent events.OrderEvent) []events.MatchingEvent {
+	side := OrderType(orderEvent.Type)
+	matching := e.matcher.CancelOrders(func(order Order) bool {
+		return order.AccountID == orderEvent.AccountID && (side == "" || order.Type == side)
+	})
+
+	var cancelled []events.OrderEvent
+	for _, order := range matching.Cancelled {
+		cancelled = append(cancelled, convertOrderToOrderEvent(order))
+	}
+	orderEvent.Symbol = e.symbol
+	return []events.MatchingEvent{{
+		Type:      events.MatchingEventTypeMassCancel,
+		Order:     orderEvent,
+		Cancelled: cancelled,
+		BuyTicks:  convertToTickEvents(matching.BuyTicks),
+		SellTicks: convertToTickEvents(matching.SellTicks),
+	}}
+}
+
+func (e *Engine) halt(statusEvent events.TradingStatusEvent) ([]events.MatchingEvent, error) {
+	matching, err := e.matcher.Halt(statusEvent.Reason)
+	if err != nil {
+		logger.Warn("failed to halt trading, pass it", zap.Error(err))
+		return nil, nil
+	}
+	return e.convertMatchings([]Matching{matching}), nil
+}
+
+// resume restarts the trading, the queued orders rejected as it resumes are reject events, so
+// the resume is applied whole and its matching events are all published
+func (e *Engine) resume() ([]events.MatchingEvent, error) {
+	matchings, err := e.matcher.Resume()
+	if errors.Is(err, ErrNotHalted) {
+		logger.Warn("failed to resume trading, pass it", zap.Error(err))
+		return nil, nil
+	}
+	return e.convertMatchings(matchings), err
+}
+
+func (e *Engine) startAuction(statusEvent events.TradingStatusEvent) ([]events.MatchingEvent, error) {
+	matching, err := e.matcher.StartAuction(statusEvent.Reason, statusEvent.EndsAt)
+	if err != nil {
+		logger.Warn("failed to start auction, pass it", zap.Error(err))
+		return nil, nil
+	}
+	return e.convertMatchings([]Matching{matching}), nil
+}
+
+// reject converts a RejectionError to a rejection matching event
+func (e *Engine) reject(orderEvent events.OrderEvent, err error) ([]events.MatchingEvent, error) {
+	var rejection *RejectionError
+	if !errors.As(err, &rejection) {
+		return nil, err
+	}
+
+	logger.Info("reject order", zap.Error(err), zap.String("orderID", orderEvent.ID))
+	return []events.MatchingEvent{{
+		Type:  events.MatchingEventTypeReject,
+		Order: orderEvent,
+		Rejection: &events.RejectionEvent{
+			Rule:   rejection.Rule.String(),
+			Reason: rejection.Message,
+		},
+		BuyTicks:  []events.TickEvent{},
+		SellTicks: []events.TickEvent{},
+	}}, nil
+}
+
+// convertMatchings converts the Matchings of created, cancelled or rejected orders, auctions,
+// status and session changes to matching events
+func (e *Engine) convertMatchings(matchings []Matching) []events.MatchingEvent {
+	result := make([]events.MatchingEvent, 0, len(matchings))
+	for _, matching := range matchings {
+		if matching.Rejection != nil {
+			rejected, _ := e.reject(convertOrderToOrderEvent(matching.Order), matching.Rejection)
+			result = append(result, rejected...)
+			continue
+		}
+
+		buyTicks := convertToTickEvents(matching.BuyTicks)
+		sellTicks := convertToTickEvents(matching.SellTicks)
+
+		if matching.Type == MatchingTypeCancel {
+			result = append(result, events.MatchingEvent{
+				Type:      events.MatchingEventTypeCancel,
+				Order:     convertOrderToOrderEvent(matching.Order),
+				BuyTicks:  buyTicks,
+				SellTicks: sellTicks,
+			})
+		} else if matching.Order.ID != "" {
+			matchingEvent := events.MatchingEvent{
+				Type:         events.MatchingEventTypeCreate,
+				Order:        convertOrderToOrderEvent(matching.Order),
+				Transactions: convertToTransactionEvents(matching.Transactions),
+				BuyTicks:     buyTicks,
+				SellTicks:    sellTicks,
+			}
+			if matching.Queued {
+				matchingEvent.Type = events.MatchingEventTypeQueue
+			}
+			result = append(result, matchingEvent)
+		} else if matching.Indicative != nil {
+			// An uncross carries the auction transactions along with the status change
+			matchingEvent := events.MatchingEvent{
+				Type:         events.MatchingEventTypeIndicative,
+				Order:        events.OrderEvent{Symbol: e.symbol},
+				Transactions: convertToTransactionEvents(matching.Transactions),
+				BuyTicks:     buyTicks,
+				SellTicks:    sellTicks,
+				Indicative: &events.IndicativeEvent{
+					Price:     matching.Indicative.Price,
+					Volume:    matching.Indicative.Volume,
+					Imbalance: matching.Indicative.Imbalance,
+				},
+			}
+			if matching.Status != nil {
+				matchingEvent.Type = events.MatchingEventTypeUncross
+			}
+			result = append(result, matchingEvent)
+		}
+
+		if matching.Status != nil {
+			result = append(result, events.MatchingEvent{
+				Type:      events.MatchingEventTypeStatus,
+				Order:     events.OrderEvent{Symbol: e.symbol},
+				Status:    convertToStatusEvent(e.symbol, matching.Status),
+				BuyTicks:  buyTicks,
+				SellTicks: sellTicks,
+			})
+		}
+
+		if matching.Session != nil {
+			result = append(result, events.MatchingEvent{
+				Type:  events.MatchingEventTypeSession,
+				Order: events.OrderEvent{Symbol: e.symbol},
+				Session: &events.SessionEvent{
+					Symbol: e.symbol,
+					Phase:  matching.Session.Phase.String(),
+				},
+				BuyTicks:  buyTicks,
+				SellTicks: sellTicks,
+			})
+		}
+	}
+	return result
+}
+
+func convertToStatusEvent(symbol string, status *StatusChange) *events.StatusEvent {
+	statusEvent := &events.StatusEvent{
+		Symbol: symbol,
+		Status: status.Status.String(),
+		Reason: status.Reason,
+	}
+	if !status.ResumeAt.IsZero() {
+		resumeAt := status.ResumeAt
+		statusEvent.ResumeAt = &resumeAt
+	}
+	return statusEvent
+}
+
+func convertToTransactionEvents(transactions []Transaction) []events.TransactionEvent {
+	result := make([]events.TransactionEvent, 0, len(transactions))
+	for _, transaction := range transactions {
+		result = append(result, events.TransactionEvent{
+			ID:           formatTransactionID(transaction),
+			Symbol:       transaction.Symbol,
+			BuyOrderID:   transaction.BuyOrderID,
+			SellOrderID:  transaction.SellOrderID,
+			MakerOrderID: transaction.MakerOrderID,
+			TakerOrderID: transaction.TakerOrderID,
+			TakerSide:    transaction.TakerSide.String(),
+			Price:        transaction.Price,
+			Quantity:     transaction.Quantity,
+			BuyFee:       convertToFeeEvent(transaction.BuyFee),
+			SellFee:      convertToFeeEvent(transaction.SellFee),
+			CreatedAt:    transaction.CreatedAt,
+		})
+	}
+	return result
+}
+
+// formatTransactionID qualifies the sequence number of a transaction with its symbol
+func formatTransactionID(transaction Transaction) string {
+	return transaction.Symbol + "-" + strconv.FormatUint(transaction.ID, 10)
+}
+
+func convertToFeeEvent(fee Fee) events.FeeEvent {
+	return events.FeeEvent{
+		Amount:   fee.Amount,
+		Currency: fee.Currency,
+	}
+}
+
+func convertToTickEvents(ticks []Tick) []events.TickEvent {
+	result := make([]events.TickEvent, 0, len(ticks))
+	for _, tick := range ticks {
+		result = append(result, events.TickEvent{
+			Price:    tick.Price,
+			Quantity: tick.Quantity,
+		})
+	}
+	return result
+}
+
+func convertOrderEventToOrder(orderEvent events.OrderEvent) Order {
+	timeInForce := TimeInForce(orderEvent.TimeInForce)
+	if timeInForce == "" {
+		timeInForce = TimeInForceGTC
+	}
+
+	return Order{
+		ID:          orderEvent.ID,
+		AccountID:   orderEvent.AccountID,
+		Symbol:      orderEvent.Symbol,
+		Type:        OrderType(orderEvent.Type),
+		Price:       orderEvent.Price,
+		Quantity:    orderEvent.Quantity,
+		TimeInForce: timeInForce,
+		CreatedAt:   orderEvent.CreatedAt,
+	}
+}
+
+func convertOrderToOrderEvent(order Order) events.OrderEvent {
+	return events.OrderEvent{
+		ID:          order.ID,
+		AccountID:   order.AccountID,
+		Symbol:      order.Symbol,
+		Type:        order.Type.String(),
+		Price:       order.Price,
+		Quantity:    order.Quantity,
+		TimeInForce: order.TimeInForce.String(),
+		CreatedAt:   order.CreatedAt,
+	}
+}
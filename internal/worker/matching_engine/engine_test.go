@@ -0,0 +1,263 @@
+package matchingengine
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+)
+
+type EngineTestSuite struct {
+	suite.Suite
+	engine *Engine
+	symbol string
+}
+
+func TestEngineTestSuite(t *testing.T) {
+	suite.Run(t, new(EngineTestSuite))
+}
+
+func (suite *EngineTestSuite) SetupTest() {
+	suite.symbol = "AAPL"
+	matcher := NewMatcher(NewOrderBook(), 5)
+	riskChecker := NewRiskChecker(RiskConfig{Default: RiskLimits{MaxQuantity: 100}})
+	suite.engine = NewEngine(suite.symbol, matcher, riskChecker)
+}
+
+func (suite *EngineTestSuite) handle(eventType events.EventType, data interface{}) []events.MatchingEvent {
+	val, err := json.Marshal(events.Event{EventType: eventType, Data: data})
+	suite.Require().NoError(err)
+
+	matchingEvents, err := suite.engine.Handle(val)
+	suite.Require().NoError(err)
+	return matchingEvents
+}
+
+func (suite *EngineTestSuite) TestHandle_CreateAndCancel() {
+	orderEvent := events.OrderEvent{ID: "order1", Symbol: suite.symbol, Type: "Buy", Price: 100.0, Quantity: 10, TimeInForce: "GTC"}
+	matchingEvents := suite.handle(events.EventTypeCreateOrder, orderEvent)
+	suite.Require().Len(matchingEvents, 1)
+	suite.Equal(events.MatchingEventTypeCreate, matchingEvents[0].Type)
+	suite.Equal(orderEvent, matchingEvents[0].Order)
+	suite.Equal([]events.TickEvent{{Price: 100.0, Quantity: 10}}, matchingEvents[0].BuyTicks)
+
+	matchingEvents = suite.handle(events.EventTypeCancelOrder, events.OrderEvent{ID: "order1"})
+	suite.Require().Len(matchingEvents, 1)
+	suite.Equal(events.MatchingEventTypeCancel, matchingEvents[0].Type)
+	suite.Empty(matchingEvents[0].BuyTicks)
+
+	// Cancelling an unknown order publishes nothing
+	suite.Empty(suite.handle(events.EventTypeCancelOrder, events.OrderEvent{ID: "order1"}))
+}
+
+func (suite *EngineTestSuite) TestHandle_Amend() {
+	suite.handle(events.EventTypeCreateOrder, events.OrderEvent{ID: "order1", Symbol: suite.symbol, Type: "Sell", Price: 101.0, Quantity: 10})
+	suite.handle(events.EventTypeCreateOrder, events.OrderEvent{ID: "order2", Symbol: suite.symbol, Type: "Buy", Price: 99.0, Quantity: 4})
+
+	// The replacement crosses the buy order after cancelling the replaced one
+	amendEvent := events.OrderEvent{ID: "order3", OrigID: "order1", Symbol: suite.symbol, Type: "Sell", Price: 99.0, Quantity: 6}
+	matchingEvents := suite.handle(events.EventTypeAmendOrder, amendEvent)
+	suite.Require().Len(matchingEvents, 2)
+	suite.Equal(events.MatchingEventTypeCancel, matchingEvents[0].Type)
+	suite.Equal("order1", matchingEvents[0].Order.ID)
+	suite.Equal(events.MatchingEventTypeCreate, matchingEvents[1].Type)
+	suite.Require().Len(matchingEvents[1].Transactions, 1)
+	suite.Equal(int64(4), matchingEvents[1].Transactions[0].Quantity)
+	suite.Equal([]events.TickEvent{{Price: 99.0, Quantity: 2}}, matchingEvents[1].SellTicks)
+
+	// The replaced order must still be in the book
+	matchingEvents = suite.handle(events.EventTypeAmendOrder, events.OrderEvent{ID: "order4", OrigID: "order1", Symbol: suite.symbol, Type: "Sell", Price: 99.0, Quantity: 6})
+	suite.Require().Len(matchingEvents, 1)
+	suite.Equal(RejectRuleUnknownOrder.String(), matchingEvents[0].Rejection.Rule)
+
+	// The replaced order is kept if the replacement breaks a risk limit
+	matchingEvents = suite.handle(events.EventTypeAmendOrder, events.OrderEvent{ID: "order5", OrigID: "order3", Symbol: suite.symbol, Type: "Sell", Price: 99.0, Quantity: 101})
+	suite.Require().Len(matchingEvents, 1)
+	suite.Equal(RejectRuleMaxQuantity.String(), matchingEvents[0].Rejection.Rule)
+	matchingEvents = suite.handle(events.EventTypeCancelOrder, events.OrderEvent{ID: "order3"})
+	suite.Require().Len(matchingEvents, 1)
+	suite.Empty(matchingEvents[0].SellTicks)
+}
+
+func (suite *EngineTestSuite) TestHandle_AmendOwnership() {
+	suite.handle(events.EventTypeCreateOrder, events.OrderEvent{ID: "order1", AccountID: "alice", Symbol: suite.symbol, Type: "Sell", Price: 101.0, Quantity: 10})
+
+	// Another account or side is rejected as an unknown order, and the replaced order is kept
+	for _, amendEvent := range []events.OrderEvent{
+		{ID: "order2", OrigID: "order1", AccountID: "bob", Symbol: suite.symbol, Type: "Sell", Price: 100.0, Quantity: 10},
+		{ID: "order3", OrigID: "order1", AccountID: "alice", Symbol: suite.symbol, Type: "Buy", Price: 100.0, Quantity: 10},
+	} {
+		matchingEvents := suite.handle(events.EventTypeAmendOrder, amendEvent)
+		suite.Require().Len(matchingEvents, 1, amendEvent.ID)
+		suite.Equal(RejectRuleUnknownOrder.String(), matchingEvents[0].Rejection.Rule, amendEvent.ID)
+		suite.Equal(amendEvent.AccountID, matchingEvents[0].Order.AccountID, amendEvent.ID)
+	}
+	_, ok := suite.engine.matcher.Order("order1")
+	suite.True(ok)
+
+	// The cancel of the replaced order reports its owner
+	matchingEvents := suite.handle(events.EventTypeAmendOrder, events.OrderEvent{ID: "order4", OrigID: "order1", AccountID: "alice", Symbol: suite.symbol, Type: "Sell", Price: 100.0, Quantity: 10})
+	suite.Require().Len(matchingEvents, 2)
+	suite.Equal(events.OrderEvent{ID: "order1", AccountID: "alice", Symbol: suite.symbol}, matchingEvents[0].Order)
+	suite.Equal([]events.TickEvent{{Price: 100.0, Quantity: 10}}, matchingEvents[1].SellTicks)
+}
+
+func (suite *EngineTestSuite) TestHandle_MassCancel() {
+	suite.handle(events.EventTypeCreateOrder, events.OrderEvent{ID: "order1", AccountID: "alice", Symbol: suite.symbol, Type: "Sell", Price: 101.0, Quantity: 10})
+	suite.handle(events.EventTypeCreateOrder, events.OrderEvent{ID: "order2", AccountID: "bob", Symbol: suite.symbol, Type: "Sell", Price: 101.0, Quantity: 4})
+	suite.handle(events.EventTypeCreateOrder, events.OrderEvent{ID: "order3", AccountID: "alice", Symbol: suite.symbol, Type: "Buy", Price: 99.0, Quantity: 6})
+	suite.handle(events.EventTypeCreateOrder, events.OrderEvent{ID: "order4", AccountID: "alice", Symbol: suite.symbol, Type: "Sell", Price: 102.0, Quantity: 2})
+
+	// Only the sell orders of the account are cancelled, in one matching event
+	matchingEvents := suite.handle(events.EventTypeMassCancel, events.OrderEvent{ID: "mass1", AccountID: "alice", Type: "Sell"})
+	suite.Require().Len(matchingEvents, 1)
+	suite.Equal(events.MatchingEventTypeMassCancel, matchingEvents[0].Type)
+	suite.Equal(events.OrderEvent{ID: "mass1", AccountID: "alice", Symbol: suite.symbol, Type: "Sell"}, matchingEvents[0].Order)
+	suite.Require().Len(matchingEvents[0].Cancelled, 2)
+	suite.Equal([]string{"order1", "order4"}, []string{matchingEvents[0].Cancelled[0].ID, matchingEvents[0].Cancelled[1].ID})
+	suite.Equal([]events.TickEvent{{Price: 101.0, Quantity: 4}}, matchingEvents[0].SellTicks)
+	suite.Equal([]events.TickEvent{{Price: 99.0, Quantity: 6}}, matchingEvents[0].BuyTicks)
+
+	// Both sides without a side
+	matchingEvents = suite.handle(events.EventTypeMassCancel, events.OrderEvent{ID: "mass2", AccountID: "alice"})
+	suite.Require().Len(matchingEvents, 1)
+	suite.Require().Len(matchingEvents[0].Cancelled, 1)
+	suite.Equal("order3", matchingEvents[0].Cancelled[0].ID)
+	suite.Empty(matchingEvents[0].BuyTicks)
+
+	// Nothing to cancel is still reported
+	matchingEvents = suite.handle(events.EventTypeMassCancel, events.OrderEvent{ID: "mass3", AccountID: "alice"})
+	suite.Require().Len(matchingEvents, 1)
+	suite.Empty(matchingEvents[0].Cancelled)
+}
+
+func (suite *EngineTestSuite) TestHandle_MassCancel_Queued() {
+	matcher := NewMatcher(NewOrderBook(), 5, WithCircuitBreaker(CircuitBreakerConfig{QueueOrders: true}))
+	suite.engine = NewEngine(suite.symbol, matcher, NewRiskChecker(RiskConfig{}))
+	suite.handle(events.EventTypeHaltTrading, events.TradingStatusEvent{Symbol: suite.symbol, Reason: "news"})
+	suite.handle(events.EventTypeCreateOrder, events.OrderEvent{ID: "order1", AccountID: "alice", Symbol: suite.symbol, Type: "Buy", Price: 100.0, Quantity: 10})
+	suite.handle(events.EventTypeCreateOrder, events.OrderEvent{ID: "order2", AccountID: "bob", Symbol: suite.symbol, Type: "Buy", Price: 100.0, Quantity: 4})
+	suite.Len(suite.engine.matcher.queuedOrders, 2)
+
+	matchingEvents := suite.handle(events.EventTypeMassCancel, events.OrderEvent{ID: "mass1", AccountID: "alice"})
+	suite.Require().Len(matchingEvents, 1)
+	suite.Require().Len(matchingEvents[0].Cancelled, 1)
+	suite.Equal("order1", matchingEvents[0].Cancelled[0].ID)
+	suite.Require().Len(suite.engine.matcher.queuedOrders, 1)
+	suite.Equal("order2", suite.engine.matcher.queuedOrders[0].ID)
+}
+
+func (suite *EngineTestSuite) TestHandle_Reject() {
+	matchingEvents := suite.handle(events.EventTypeCreateOrder, events.OrderEvent{
+		ID: "order1", Symbol: suite.symbol, Type: "Buy", Price: 100.0, Quantity: 101,
+	})
+	suite.Require().Len(matchingEvents, 1)
+	suite.Equal(events.MatchingEventTypeReject, matchingEvents[0].Type)
+	suite.Equal(RejectRuleMaxQuantity.String(), matchingEvents[0].Rejection.Rule)
+}
+
+func (suite *EngineTestSuite) TestHandle_HaltAndResume() {
+	matchingEvents := suite.handle(events.EventTypeHaltTrading, events.TradingStatusEvent{Symbol: suite.symbol, Reason: "news"})
+	suite.Require().Len(matchingEvents, 1)
+	suite.Equal(events.MatchingEventTypeStatus, matchingEvents[0].Type)
+	suite.Equal(&events.StatusEvent{Symbol: suite.symbol, Status: TradingStatusHalted.String(), Reason: "news"}, matchingEvents[0].Status)
+
+	matchingEvents = suite.handle(events.EventTypeCreateOrder, events.OrderEvent{
+		ID: "order1", Symbol: suite.symbol, Type: "Buy", Price: 100.0, Quantity: 10,
+	})
+	suite.Require().Len(matchingEvents, 1)
+	suite.Equal(RejectRuleHalted.String(), matchingEvents[0].Rejection.Rule)
+
+	matchingEvents = suite.handle(events.EventTypeResumeTrading, events.TradingStatusEvent{Symbol: suite.symbol})
+	suite.Require().Len(matchingEvents, 1)
+	suite.Equal(TradingStatusContinuous.String(), matchingEvents[0].Status.Status)
+}
+
+func (suite *EngineTestSuite) TestHandle_ResumeRejectsQueued() {
+	matcher := NewMatcher(NewOrderBook(), 5, WithCircuitBreaker(CircuitBreakerConfig{QueueOrders: true}))
+	suite.engine = NewEngine(suite.symbol, matcher, NewRiskChecker(RiskConfig{}))
+	suite.handle(events.EventTypeHaltTrading, events.TradingStatusEvent{Symbol: suite.symbol, Reason: "news"})
+	suite.handle(events.EventTypeCreateOrder, events.OrderEvent{ID: "order1", Symbol: suite.symbol, Type: "Buy", Price: 100.0, Quantity: 10})
+	suite.handle(events.EventTypeCreateOrder, events.OrderEvent{ID: "order2", Symbol: suite.symbol, Type: "Buy", Price: 100.0, Quantity: 4})
+
+	// The session closed during the halt, the resume is applied with the rejections of the
+	// queued orders numbered after the status event
+	matcher.phase = SessionPhaseClosed
+	matchingEvents := suite.handle(events.EventTypeResumeTrading, events.TradingStatusEvent{Symbol: suite.symbol})
+	suite.Require().Len(matchingEvents, 3)
+	suite.Equal(events.MatchingEventTypeStatus, matchingEvents[0].Type)
+	for i, id := range []string{"order1", "order2"} {
+		suite.Equal(events.MatchingEventTypeReject, matchingEvents[i+1].Type)
+		suite.Equal(id, matchingEvents[i+1].Order.ID)
+		suite.Equal(RejectRuleSessionClosed.String(), matchingEvents[i+1].Rejection.Rule)
+		suite.Equal(matchingEvents[i].Seq+1, matchingEvents[i+1].Seq)
+	}
+	suite.Equal(int64(3), suite.engine.LastOffset())
+	suite.Empty(matcher.queuedOrders)
+}
+
+func (suite *EngineTestSuite) TestHandle_HaltOtherSymbol() {
+	suite.Empty(suite.handle(events.EventTypeHaltTrading, events.TradingStatusEvent{Symbol: "MSFT", Reason: "news"}))
+	suite.Empty(suite.handle(events.EventTypeStartAuction, events.TradingStatusEvent{Symbol: "MSFT", Reason: "opening"}))
+
+	matchingEvents := suite.handle(events.EventTypeCreateOrder, events.OrderEvent{
+		ID: "order1", Symbol: suite.symbol, Type: "Buy", Price: 100.0, Quantity: 10,
+	})
+	suite.Require().Len(matchingEvents, 1)
+	suite.Equal(events.MatchingEventTypeCreate, matchingEvents[0].Type)
+}
+
+func (suite *EngineTestSuite) TestHandle_UnknownEventType() {
+	_, err := suite.engine.Handle([]byte(`{"event_type":"Matching","data":{}}`))
+	suite.ErrorIs(err, ErrUnknownEventType)
+	suite.ErrorIs(err, ErrMalformedEvent)
+
+	// A malformed event is not applied, so it fails again when redelivered
+	_, err = suite.engine.HandleAt(0, []byte(`{"event_type":"CreateOrder","data":[]}`))
+	suite.ErrorIs(err, ErrMalformedEvent)
+	_, err = suite.engine.HandleAt(0, []byte(`{"event_type":"CreateOrder","data":[]}`))
+	suite.ErrorIs(err, ErrMalformedEvent)
+	suite.Equal(int64(-1), suite.engine.LastOffset())
+}
+
+func (suite *EngineTestSuite) TestHandle_Auction() {
+	matchingEvents := suite.handle(events.EventTypeStartAuction, events.TradingStatusEvent{Symbol: suite.symbol, Reason: "opening"})
+	suite.Require().Len(matchingEvents, 1)
+	suite.Equal(TradingStatusAuction.String(), matchingEvents[0].Status.Status)
+
+	suite.handle(events.EventTypeCreateOrder, events.OrderEvent{ID: "buy1", Symbol: suite.symbol, Type: "Buy", Price: 101.0, Quantity: 10})
+	suite.handle(events.EventTypeCreateOrder, events.OrderEvent{ID: "sell1", Symbol: suite.symbol, Type: "Sell", Price: 99.0, Quantity: 10})
+
+	// Resuming an auction uncrosses it
+	matchingEvents = suite.handle(events.EventTypeResumeTrading, events.TradingStatusEvent{Symbol: suite.symbol})
+	suite.Require().Len(matchingEvents, 2)
+	suite.Equal(events.MatchingEventTypeUncross, matchingEvents[0].Type)
+	suite.Equal(&events.IndicativeEvent{Price: 99.0, Volume: 10}, matchingEvents[0].Indicative)
+	suite.Require().Len(matchingEvents[0].Transactions, 1)
+	suite.Equal("AAPL-1", matchingEvents[0].Transactions[0].ID)
+	suite.Equal(events.MatchingEventTypeStatus, matchingEvents[1].Type)
+	suite.Equal(TradingStatusContinuous.String(), matchingEvents[1].Status.Status)
+}
+
+func (suite *EngineTestSuite) TestHandleAt_SkipsRedelivery() {
+	val, err := json.Marshal(events.Event{EventType: events.EventTypeCreateOrder, Data: events.OrderEvent{
+		ID: "order1", Symbol: suite.symbol, Type: "Buy", Price: 100.0, Quantity: 10,
+	}})
+	suite.Require().NoError(err)
+
+	matchingEvents, err := suite.engine.HandleAt(7, val)
+	suite.Require().NoError(err)
+	suite.Require().Len(matchingEvents, 1)
+	suite.Equal(uint64(1), matchingEvents[0].Seq)
+	suite.Equal(int64(7), matchingEvents[0].InputOffset)
+	suite.Equal(int64(7), suite.engine.LastOffset())
+
+	// The order is not applied twice
+	matchingEvents, err = suite.engine.HandleAt(7, val)
+	suite.NoError(err)
+	suite.Empty(matchingEvents)
+	buyTicks, _ := suite.engine.matcher.orderBook.GetTopTicks(5)
+	suite.Equal([]Tick{{Price: 100.0, Quantity: 10}}, buyTicks)
+}
@@ -0,0 +1,65 @@
+package matchingengine
+
+const (
+	// DefaultFeeTier is the tier of accounts which are not listed in the FeeSchedule
+	DefaultFeeTier = "default"
+
+	bpsDenominator = 10000
+)
+
+// Fee is the amount charged to one side of a transaction, a negative amount is a rebate
+type Fee struct {
+	Amount   float64
+	Currency string
+}
+
+// FeeRate represents the maker and taker fees in basis points, a negative rate is a rebate
+type FeeRate struct {
+	MakerBps float64 `json:"maker_bps"`
+	TakerBps float64 `json:"taker_bps"`
+}
+
+// FeeSchedule resolves the FeeRate of an account on a symbol
+type FeeSchedule struct {
+	// Currency is the currency fees are charged in
+	Currency string `json:"currency"`
+	// Tiers maps tier name to its FeeRate
+	Tiers map[string]FeeRate `json:"tiers"`
+	// Symbols maps Symbol to the tiers overriding Tiers on that symbol
+	Symbols map[string]map[string]FeeRate `json:"symbols"`
+	// Accounts maps AccountID to tier name
+	Accounts map[string]string `json:"accounts"`
+}
+
+// Rate returns the FeeRate of an account on a symbol, falling back from the symbol
+// tier to the global tier and then to the DefaultFeeTier
+func (fs *FeeSchedule) Rate(symbol, accountID string) FeeRate {
+	tier, exists := fs.Accounts[accountID]
+	if !exists {
+		tier = DefaultFeeTier
+	}
+
+	for _, t := range []string{tier, DefaultFeeTier} {
+		if rate, exists := fs.Symbols[symbol][t]; exists {
+			return rate
+		}
+		if rate, exists := fs.Tiers[t]; exists {
+			return rate
+		}
+	}
+	return FeeRate{}
+}
+
+// Fee computes the fee of one side of a transaction
+func (fs *FeeSchedule) Fee(symbol, accountID string, isMaker bool, price float64, quantity int64) Fee {
+	rate := fs.Rate(symbol, accountID)
+	bps := rate.TakerBps
+	if isMaker {
+		bps = rate.MakerBps
+	}
+
+	return Fee{
+		Amount:   price * float64(quantity) * bps / bpsDenominator,
+		Currency: fs.Currency,
+	}
+}
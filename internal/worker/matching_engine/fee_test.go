@@ -0,0 +1,49 @@
+package matchingengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type FeeScheduleTestSuite struct {
+	suite.Suite
+	feeSchedule *FeeSchedule
+}
+
+func TestFeeScheduleTestSuite(t *testing.T) {
+	suite.Run(t, new(FeeScheduleTestSuite))
+}
+
+func (suite *FeeScheduleTestSuite) SetupTest() {
+	suite.feeSchedule = &FeeSchedule{
+		Currency: "USD",
+		Tiers: map[string]FeeRate{
+			DefaultFeeTier: {MakerBps: 10, TakerBps: 20},
+			"vip":          {MakerBps: -1, TakerBps: 5},
+		},
+		Symbols: map[string]map[string]FeeRate{
+			"MSFT": {DefaultFeeTier: {MakerBps: 0, TakerBps: 1}},
+		},
+		Accounts: map[string]string{
+			"account1": "vip",
+			"account2": "unknown",
+		},
+	}
+}
+
+func (suite *FeeScheduleTestSuite) TestRate() {
+	suite.Equal(FeeRate{MakerBps: -1, TakerBps: 5}, suite.feeSchedule.Rate("AAPL", "account1"))
+	suite.Equal(FeeRate{MakerBps: 10, TakerBps: 20}, suite.feeSchedule.Rate("AAPL", "account2"))
+	suite.Equal(FeeRate{MakerBps: 10, TakerBps: 20}, suite.feeSchedule.Rate("AAPL", "account3"))
+
+	// The global vip tier is preferred over the symbol default
+	suite.Equal(FeeRate{MakerBps: -1, TakerBps: 5}, suite.feeSchedule.Rate("MSFT", "account1"))
+	suite.Equal(FeeRate{MakerBps: 0, TakerBps: 1}, suite.feeSchedule.Rate("MSFT", "account3"))
+}
+
+func (suite *FeeScheduleTestSuite) TestFee() {
+	suite.Equal(Fee{Amount: 0.4, Currency: "USD"}, suite.feeSchedule.Fee("AAPL", "account3", false, 100.0, 2))
+	suite.Equal(Fee{Amount: 0.2, Currency: "USD"}, suite.feeSchedule.Fee("AAPL", "account3", true, 100.0, 2))
+	suite.Equal(Fee{Amount: -0.02, Currency: "USD"}, suite.feeSchedule.Fee("AAPL", "account1", true, 100.0, 2))
+}
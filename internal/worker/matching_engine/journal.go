@@ -0,0 +1,129 @@
+//go:generate go-enum --marshal
+package matchingengine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	"github.com/Hao1995/order-matching-system/pkg/journalkit"
+	"github.com/Hao1995/order-matching-system/pkg/logger"
+)
+
+// ENUM(Event, Tick, Skip)
+type JournalEntryType string
+
+// JournalEntry is an input of the Engine. The Time is the clock of the Matcher while the entry
+// is applied, so the replay of the entries reproduces the same matching output. A Skip entry is
+// an order event moved to the dead-letter topic, which is not consumed again.
+type JournalEntry struct {
+	Type JournalEntryType `json:"type"`
+	// Symbol is the symbol of the Engine, which shares the journal with the other engines of a
+	// partition
+	Symbol string `json:"symbol,omitempty"`
+	// Offset is the offset of an Event or Skip entry in the order topic
+	Offset int64     `json:"offset"`
+	Time   time.Time `json:"time"`
+	// Event is the encoded events.Event of an Event entry
+	Event json.RawMessage `json:"event,omitempty"`
+}
+
+// Journal records the entries before the Engine applies them
+type Journal interface {
+	Append(data []byte) (uint64, error)
+}
+
+// WithJournal records every input to the journal before applying it
+func WithJournal(journal Journal) EngineOption {
+	return func(e *Engine) {
+		e.journal = journal
+	}
+}
+
+// Replay applies the entries of the journal in dir to the Engine, which must be configured like
+// the one that wrote the journal and start from an empty OrderBook. The entries of the other
+// symbols are skipped, and so are the entries which were rejected or malformed when they were
+// handled; any other failure stops the replay. The matching events of every entry are passed to
+// output with the sequence number of the entry.
+func Replay(dir string, engine *Engine, output func(seq uint64, matchingEvents []events.MatchingEvent) error) error {
+	return journalkit.Replay(dir, func(record journalkit.Record) error {
+		var entry JournalEntry
+		if err := json.Unmarshal(record.Data, &entry); err != nil {
+			return err
+		}
+		if entry.Type == JournalEntryTypeSkip || (entry.Symbol != "" && entry.Symbol != engine.symbol) {
+			return nil
+		}
+
+		engine.mu.Lock()
+		matchingEvents, err := engine.apply(entry)
+		engine.mu.Unlock()
+		if err != nil {
+			if !skippable(err) {
+				return fmt.Errorf("journal entry %d: %w", record.Seq, err)
+			}
+			logger.Warn("failed to replay journal entry, pass it", zap.Error(err), zap.Uint64("seq", record.Seq))
+		}
+		return output(record.Seq, matchingEvents)
+	})
+}
+
+// skippable reports whether an entry which failed to be replayed failed the same way when it was
+// handled, i.e. it was rejected or its event is malformed
+func skippable(err error) bool {
+	var rejection *RejectionError
+	return errors.As(err, &rejection) || errors.Is(err, ErrMalformedEvent)
+}
+
+// record appends an entry to the journal, if any
+func (e *Engine) record(entry JournalEntry) error {
+	if e.journal == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = e.journal.Append(data)
+	return err
+}
+
+// apply decodes and applies an entry
+func (e *Engine) apply(entry JournalEntry) ([]events.MatchingEvent, error) {
+	var in input
+	if entry.Type == JournalEntryTypeEvent {
+		var err error
+		if in, err = decodeInput(events.JSONCodec, entry.Event); err != nil {
+			return nil, err
+		}
+	}
+	return e.applyInput(entry, in)
+}
+
+// applyInput pins the time of the Matcher to the entry, applies it and numbers the matching
+// events with the offset of the latest applied order event
+func (e *Engine) applyInput(entry JournalEntry, in input) ([]events.MatchingEvent, error) {
+	e.matcher.setInputTime(entry.Time)
+
+	var matchingEvents []events.MatchingEvent
+	var err error
+	switch entry.Type {
+	case JournalEntryTypeTick:
+		matchingEvents = e.convertMatchings(e.matcher.Tick())
+	default:
+		e.lastOffset = entry.Offset
+		matchingEvents, err = e.handle(in)
+	}
+
+	for i := range matchingEvents {
+		*e.seq++
+		matchingEvents[i].Seq = *e.seq
+		matchingEvents[i].InputOffset = e.lastOffset
+	}
+	return matchingEvents, err
+}
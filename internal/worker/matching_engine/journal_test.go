@@ -0,0 +1,192 @@
+package matchingengine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	"github.com/Hao1995/order-matching-system/pkg/journalkit"
+)
+
+type JournalTestSuite struct {
+	suite.Suite
+	dir     string
+	symbol  string
+	current time.Time
+	restore func() time.Time
+}
+
+func TestJournalTestSuite(t *testing.T) {
+	suite.Run(t, new(JournalTestSuite))
+}
+
+func (suite *JournalTestSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+	suite.symbol = "AAPL"
+	suite.current = time.Date(2025, 1, 15, 18, 0, 0, 0, time.UTC)
+	suite.restore = now
+	now = func() time.Time {
+		return suite.current
+	}
+}
+
+func (suite *JournalTestSuite) TearDownTest() {
+	now = suite.restore
+}
+
+func (suite *JournalTestSuite) newEngine(opts ...EngineOption) *Engine {
+	matcher := NewMatcher(NewOrderBook(), 5,
+		WithCircuitBreaker(CircuitBreakerConfig{BandPct: 5, Window: time.Minute, Cooldown: time.Minute, QueueOrders: true}),
+		WithAuction(AuctionConfig{IndicativeInterval: time.Second}),
+	)
+	return NewEngine(suite.symbol, matcher, NewRiskChecker(RiskConfig{}), opts...)
+}
+
+func (suite *JournalTestSuite) encode(eventType events.EventType, data interface{}) []byte {
+	val, err := json.Marshal(events.Event{EventType: eventType, Data: data})
+	suite.Require().NoError(err)
+	return val
+}
+
+func (suite *JournalTestSuite) TestReplay() {
+	journal, err := journalkit.Open(suite.dir, journalkit.Options{MaxSegmentBytes: 1024, SyncBatch: 4})
+	suite.Require().NoError(err)
+	engine := suite.newEngine(WithJournal(journal))
+
+	var outputs [][]byte
+	collect := func(matchingEvents []events.MatchingEvent) {
+		output, err := json.Marshal(matchingEvents)
+		suite.Require().NoError(err)
+		outputs = append(outputs, output)
+	}
+
+	inputs := [][]byte{
+		suite.encode(events.EventTypeCreateOrder, events.OrderEvent{ID: "sell1", Symbol: suite.symbol, Type: "Sell", Price: 100.0, Quantity: 10}),
+		suite.encode(events.EventTypeCreateOrder, events.OrderEvent{ID: "sell2", Symbol: suite.symbol, Type: "Sell", Price: 110.0, Quantity: 10}),
+		suite.encode(events.EventTypeCreateOrder, events.OrderEvent{ID: "buy1", Symbol: suite.symbol, Type: "Buy", Price: 100.0, Quantity: 4}),
+		// Trips the circuit breaker, then the next order is queued
+		suite.encode(events.EventTypeCreateOrder, events.OrderEvent{ID: "buy2", Symbol: suite.symbol, Type: "Buy", Price: 110.0, Quantity: 8}),
+		suite.encode(events.EventTypeCreateOrder, events.OrderEvent{ID: "buy3", Symbol: suite.symbol, Type: "Buy", Price: 95.0, Quantity: 5}),
+		suite.encode(events.EventTypeCancelOrder, events.OrderEvent{ID: "sell1"}),
+		[]byte(`{"event_type":"Unknown"}`),
+	}
+	for i, input := range inputs {
+		suite.current = suite.current.Add(time.Second)
+		matchingEvents, err := engine.Handle(input)
+		if errors.Is(err, ErrMalformedEvent) {
+			// A malformed event is not journaled
+			continue
+		}
+		collect(matchingEvents)
+
+		if i%2 == 0 {
+			// An idle tick is not journaled
+			suite.Nil(engine.Tick())
+		}
+	}
+	// The cooldown resumes the trading
+	suite.current = suite.current.Add(2 * time.Minute)
+	collect(engine.Tick())
+	suite.NoError(journal.Close())
+
+	replayed := suite.newEngine()
+	var replayedOutputs [][]byte
+	var lastSeq uint64
+	suite.Require().NoError(Replay(suite.dir, replayed, func(seq uint64, matchingEvents []events.MatchingEvent) error {
+		suite.Equal(lastSeq+1, seq)
+		lastSeq = seq
+		output, err := json.Marshal(matchingEvents)
+		replayedOutputs = append(replayedOutputs, output)
+		return err
+	}))
+
+	suite.Equal(len(outputs), int(lastSeq))
+	for i := range outputs {
+		suite.Equal(string(outputs[i]), string(replayedOutputs[i]), "output of entry %d", i+1)
+	}
+	suite.Equal(snapshotOrderBook(engine.matcher.orderBook), snapshotOrderBook(replayed.matcher.orderBook))
+	suite.Equal(engine.matcher.Status(), replayed.matcher.Status())
+	suite.Equal(engine.matcher.queuedOrders, replayed.matcher.queuedOrders)
+}
+
+func (suite *JournalTestSuite) TestTick_Idle() {
+	journal, err := journalkit.Open(suite.dir, journalkit.Options{})
+	suite.Require().NoError(err)
+	engine := suite.newEngine(WithJournal(journal))
+
+	// Trips the circuit breaker
+	inputs := [][]byte{
+		suite.encode(events.EventTypeCreateOrder, events.OrderEvent{ID: "sell1", Symbol: suite.symbol, Type: "Sell", Price: 100.0, Quantity: 10}),
+		suite.encode(events.EventTypeCreateOrder, events.OrderEvent{ID: "sell2", Symbol: suite.symbol, Type: "Sell", Price: 110.0, Quantity: 10}),
+		suite.encode(events.EventTypeCreateOrder, events.OrderEvent{ID: "buy1", Symbol: suite.symbol, Type: "Buy", Price: 110.0, Quantity: 12}),
+	}
+	for offset, input := range inputs {
+		suite.current = suite.current.Add(time.Second)
+		_, err := engine.HandleAt(int64(offset), input)
+		suite.Require().NoError(err)
+	}
+	suite.Require().Equal(TradingStatusHalted, engine.matcher.Status())
+
+	// The ticks and the heartbeats before the cooldown ends change nothing, and are not journaled
+	for offset := int64(3); offset < 6; offset++ {
+		suite.current = suite.current.Add(time.Second)
+		suite.Nil(engine.Tick())
+		matchingEvents, err := engine.HandleAt(offset, suite.encode(events.EventTypeHeartbeat, nil))
+		suite.NoError(err)
+		suite.Nil(matchingEvents)
+	}
+	suite.Equal(int64(5), engine.LastOffset())
+
+	// The tick once the cooldown ends resumes the trading
+	suite.current = suite.current.Add(time.Minute)
+	matchingEvents := engine.Tick()
+	suite.Require().NotEmpty(matchingEvents)
+	suite.Equal(TradingStatusContinuous.String(), matchingEvents[0].Status.Status)
+	suite.Nil(engine.Tick())
+	suite.NoError(journal.Close())
+
+	var types []JournalEntryType
+	suite.Require().NoError(journalkit.Replay(suite.dir, func(record journalkit.Record) error {
+		var entry JournalEntry
+		if err := json.Unmarshal(record.Data, &entry); err != nil {
+			return err
+		}
+		types = append(types, entry.Type)
+		return nil
+	}))
+	suite.Equal([]JournalEntryType{JournalEntryTypeEvent, JournalEntryTypeEvent, JournalEntryTypeEvent, JournalEntryTypeTick}, types)
+}
+
+func (suite *JournalTestSuite) TestSkippable() {
+	for _, tc := range []struct {
+		err       error
+		skippable bool
+	}{
+		{&RejectionError{Rule: RejectRuleHalted, Message: "trading is halted"}, true},
+		{fmt.Errorf("resume: %w", &RejectionError{Rule: RejectRuleSessionClosed, Message: "session is closed"}), true},
+		{fmt.Errorf("%w: %w", ErrMalformedEvent, ErrUnknownEventType), true},
+		{ErrOrderNotFound, false},
+		{errors.New("disk full"), false},
+	} {
+		suite.Equal(tc.skippable, skippable(tc.err), tc.err.Error())
+	}
+}
+
+// snapshotOrderBook describes the price levels and the orders of both sides in priority
+func snapshotOrderBook(orderBook *OrderBook) []string {
+	var snapshot []string
+	for _, head := range []*PriceLevel{orderBook.BuyLevels, orderBook.SellLevels} {
+		for level := head; level != nil; level = level.Next {
+			snapshot = append(snapshot, fmt.Sprintf("%s %v x %d", level.Type, level.Price, level.TotalQuantity))
+			for node := level.HeadOrders; node != nil; node = node.Next {
+				snapshot = append(snapshot, fmt.Sprintf("  %+v", node.Order))
+			}
+		}
+	}
+	return snapshot
+}
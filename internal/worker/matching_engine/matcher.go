@@ -3,8 +3,6 @@ package matchingengine
 import (
 	"errors"
 	"time"
-
-	"github.com/google/uuid"
 )
 
 const (
@@ -12,10 +10,6 @@ const (
 )
 
 var (
-	getUUID = func() string {
-		return uuid.NewString()
-	}
-
 	now = func() time.Time {
 		return time.Now()
 	}
@@ -24,21 +18,117 @@ var (
 )
 
 type Matcher struct {
-	orderBook *OrderBook
-	tickNum   int8
+	orderBook   *OrderBook
+	tickNum     int8
+	feeSchedule *FeeSchedule
+	// lastTradePrice is the price of the latest transaction, 0 if nothing traded yet
+	lastTradePrice float64
+
+	status         TradingStatus
+	circuitBreaker *CircuitBreaker
+	// resumeAt is when a halted trading resumes, zero for a manual halt
+	resumeAt time.Time
+	// queuedOrders are accepted while halted and matched on resume, or moved into an auction
+	queuedOrders []Order
+
+	auctionConfig AuctionConfig
+	// auctionEndsAt is when the auction uncrosses, zero to uncross manually
+	auctionEndsAt    time.Time
+	lastIndicativeAt time.Time
+
+	// allocationPolicy splits an incoming quantity among the orders of a price level
+	allocationPolicy AllocationPolicy
+	// allocations is the buffer reused by the allocationPolicy
+	allocations []Allocation
+	// lastTransactionID is the sequence number of the latest transaction
+	lastTransactionID uint64
+
+	sessionScheduler *SessionScheduler
+	// phase is the current session phase, empty until the first Tick
+	phase SessionPhase
+
+	// inputTime is the time of the input being applied, so a replay reproduces the same state
+	inputTime time.Time
+}
+
+// MatcherOption configures optional behaviors of a Matcher
+type MatcherOption func(*Matcher)
+
+// WithFeeSchedule charges maker/taker fees on every transaction
+func WithFeeSchedule(feeSchedule *FeeSchedule) MatcherOption {
+	return func(me *Matcher) {
+		me.feeSchedule = feeSchedule
+	}
 }
 
-func NewMatcher(orderBook *OrderBook, tickNum int8) *Matcher {
-	return &Matcher{
-		orderBook: orderBook,
-		tickNum:   tickNum,
+// WithCircuitBreaker halts the trading when the trade price moves out of the band
+func WithCircuitBreaker(config CircuitBreakerConfig) MatcherOption {
+	return func(me *Matcher) {
+		me.circuitBreaker = NewCircuitBreaker(config)
 	}
 }
 
-// CancelOrder delete the order from the order book
+// WithAuction configures the call auctions
+func WithAuction(config AuctionConfig) MatcherOption {
+	return func(me *Matcher) {
+		me.auctionConfig = config
+	}
+}
+
+// WithSessionScheduler drives the session phases from a trading calendar on Tick
+func WithSessionScheduler(sessionScheduler *SessionScheduler) MatcherOption {
+	return func(me *Matcher) {
+		me.sessionScheduler = sessionScheduler
+	}
+}
+
+// WithAllocationPolicy replaces the default FIFO allocation within a price level
+func WithAllocationPolicy(allocationPolicy AllocationPolicy) MatcherOption {
+	return func(me *Matcher) {
+		me.allocationPolicy = allocationPolicy
+	}
+}
+
+// WithLastTransactionID continues the transaction sequence after id, e.g. to replay a stream
+// recorded after the Matcher had already traded
+func WithLastTransactionID(id uint64) MatcherOption {
+	return func(me *Matcher) {
+		me.lastTransactionID = id
+	}
+}
+
+func NewMatcher(orderBook *OrderBook, tickNum int8, opts ...MatcherOption) *Matcher {
+	me := &Matcher{
+		orderBook:        orderBook,
+		tickNum:          tickNum,
+		status:           TradingStatusContinuous,
+		allocationPolicy: FIFOPolicy{},
+	}
+	for _, opt := range opts {
+		opt(me)
+	}
+	return me
+}
+
+// setInputTime pins the time of the Matcher to the time of the input being applied
+func (me *Matcher) setInputTime(t time.Time) {
+	me.inputTime = t
+}
+
+// now returns the time of the input being applied, or the current time if not pinned
+func (me *Matcher) now() time.Time {
+	if me.inputTime.IsZero() {
+		return now()
+	}
+	return me.inputTime
+}
+
+// CancelOrder delete the order from the order book, cancels are allowed while halted
 func (me *Matcher) CancelOrder(orderID string) (Matching, error) {
-	if err := me.orderBook.DeleteOrder(orderID); err != nil {
-		return Matching{}, err
+	if !me.dequeueOrder(orderID) {
+		if err := me.orderBook.DeleteOrder(orderID); err != nil {
+			return Matching{}, err
+		}
 	}
 
 	var matching Matching
@@ -46,75 +136,210 @@ func (me *Matcher) CancelOrder(orderID string) (Matching, error) {
 	return matching, nil
 }
 
-// CreateOrder inserts a new order
-func (me *Matcher) CreateOrder(order Order) Matching {
+// Order returns the order of the ID resting in the order book or queued while halted, it reports
+// whether the order is open
+func (me *Matcher) Order(orderID string) (Order, bool) {
+	if order, ok := me.orderBook.GetOrder(orderID); ok {
+		return order, true
+	}
+	for _, order := range me.queuedOrders {
+		if order.ID == orderID {
+			return order, true
+		}
+	}
+	return Order{}, false
+}
+
+// CancelOrders deletes the orders matched by the filter from the order book and the queue at once,
+// the Cancelled of the Matching are in their time priority
+func (me *Matcher) CancelOrders(match func(order Order) bool) Matching {
 	var matching Matching
-	matching.Transactions = me.matchOrder(order)
+	matching.Cancelled = me.orderBook.DeleteOrders(match)
+
+	queuedOrders := me.queuedOrders[:0]
+	for _, order := range me.queuedOrders {
+		if match(order) {
+			matching.Cancelled = append(matching.Cancelled, order)
+		} else {
+			queuedOrders = append(queuedOrders, order)
+		}
+	}
+	me.queuedOrders = queuedOrders
+	sortByTime(matching.Cancelled)
+
 	matching.BuyTicks, matching.SellTicks = me.orderBook.GetTopTicks(me.tickNum)
 	return matching
 }
 
-// matchOrder attempts to match an incoming order with existing orders
-func (me *Matcher) matchOrder(order Order) []Transaction {
+// ReferencePrice returns the last trade price, or the mid price if nothing traded yet.
+// It returns 0 if neither is known.
+func (me *Matcher) ReferencePrice() float64 {
+	if me.lastTradePrice > 0 {
+		return me.lastTradePrice
+	}
+	if me.orderBook.BuyLevels != nil && me.orderBook.SellLevels != nil {
+		return (me.orderBook.BuyLevels.Price + me.orderBook.SellLevels.Price) / 2
+	}
+	return 0
+}
+
+// CreateOrder inserts a new order. While halted, the order is either queued or rejected.
+// Orders are rejected while the session is closed.
+// During an auction, the order rests in the book until the auction uncrosses.
+func (me *Matcher) CreateOrder(order Order) (Matching, error) {
+	matching := Matching{
+		Transactions: []Transaction{},
+		BuyTicks:     []Tick{},
+		SellTicks:    []Tick{},
+	}
+	if err := me.CreateOrderInto(order, &matching); err != nil {
+		return Matching{}, err
+	}
+	return matching, nil
+}
+
+// CreateOrderInto is CreateOrder reusing the Transactions, BuyTicks and SellTicks buffers of
+// the matching, so a caller recycling its Matching creates orders without allocations
+func (me *Matcher) CreateOrderInto(order Order, matching *Matching) error {
+	*matching = Matching{
+		Order:        order,
+		Transactions: matching.Transactions[:0],
+		BuyTicks:     matching.BuyTicks[:0],
+		SellTicks:    matching.SellTicks[:0],
+	}
+	if me.phase == SessionPhaseClosed {
+		return &RejectionError{Rule: RejectRuleSessionClosed, Message: "session is closed"}
+	}
+	if me.status == TradingStatusHalted {
+		if me.circuitBreaker == nil || !me.circuitBreaker.config.QueueOrders {
+			return &RejectionError{Rule: RejectRuleHalted, Message: "trading is halted"}
+		}
+		me.queuedOrders = append(me.queuedOrders, order)
+		matching.Queued = true
+		matching.BuyTicks, matching.SellTicks = me.orderBook.AppendTopTicks(matching.BuyTicks, matching.SellTicks, me.tickNum)
+		return nil
+	}
+
+	// Orders accumulate without matching during an auction
+	if me.status == TradingStatusAuction {
+		me.orderBook.InsertOrder(order)
+		matching.BuyTicks, matching.SellTicks = me.orderBook.AppendTopTicks(matching.BuyTicks, matching.SellTicks, me.tickNum)
+		return nil
+	}
+
+	matching.Transactions, matching.Status = me.matchOrder(order, matching.Transactions)
+	matching.BuyTicks, matching.SellTicks = me.orderBook.AppendTopTicks(matching.BuyTicks, matching.SellTicks, me.tickNum)
+	return nil
+}
+
+// matchOrder attempts to match an incoming order with existing orders, the transactions are
+// appended to the buffer. The matching stops before a price level which breaches the band of the
+// circuit breaker: the trading halts and the rest of the order is queued first, to be matched
+// when the trading resumes.
+func (me *Matcher) matchOrder(order Order, transactions []Transaction) ([]Transaction, *StatusChange) {
 	// Use two pointers to sync the updates back to the OrderBook
 	var matchingLevels **PriceLevel
-	var priceComparator func(float64, float64) bool
-	transactions := []Transaction{}
+	start := len(transactions)
 
 	if order.Type == OrderTypeBuy {
 		matchingLevels = &me.orderBook.SellLevels
-		priceComparator = func(price1, price2 float64) bool { return price1 >= price2 }
 	} else {
 		matchingLevels = &me.orderBook.BuyLevels
-		priceComparator = func(price1, price2 float64) bool { return price1 <= price2 }
 	}
 
-	for *matchingLevels != nil && priceComparator(order.Price, (*matchingLevels).Price) {
+	var status *StatusChange
+	for *matchingLevels != nil && crosses(order, (*matchingLevels).Price) {
 		currentLevel := *matchingLevels
+		if status = me.checkCircuitBreaker(currentLevel.Price); status != nil {
+			break
+		}
 
-		for currentLevel.HeadOrders != nil && order.Quantity > 0 {
-			matchedQuantity := min(order.Quantity, currentLevel.HeadOrders.Order.Quantity)
-
-			transactions = append(transactions, Transaction{
-				ID:     getUUID(),
-				Symbol: order.Symbol,
-				BuyOrderID: func() string {
-					if order.Type == OrderTypeBuy {
-						return order.ID
-					} else {
-						return currentLevel.HeadOrders.Order.ID
-					}
-				}(),
-				SellOrderID: func() string {
-					if order.Type == OrderTypeSell {
-						return order.ID
-					} else {
-						return currentLevel.HeadOrders.Order.ID
-					}
-				}(),
-				Price:     currentLevel.Price,
-				Quantity:  matchedQuantity,
-				CreatedAt: now(),
-			})
-
-			order.Quantity -= matchedQuantity
-			currentLevel.HeadOrders.Order.Quantity -= matchedQuantity
+		me.allocations = me.allocationPolicy.Allocate(currentLevel, order.Quantity, me.allocations[:0])
+		for _, allocation := range me.allocations {
+			maker := &allocation.Node.Order
+			matchedQuantity := allocation.Quantity
 
-			if currentLevel.HeadOrders.Order.Quantity == 0 {
-				nextOrder := currentLevel.HeadOrders.Next
-				me.orderBook.DeleteOrder(currentLevel.HeadOrders.Order.ID)
-				currentLevel.HeadOrders = nextOrder
+			transaction := Transaction{
+				ID:           me.nextTransactionID(),
+				Symbol:       order.Symbol,
+				BuyOrderID:   maker.ID,
+				SellOrderID:  order.ID,
+				MakerOrderID: maker.ID,
+				TakerOrderID: order.ID,
+				TakerSide:    order.Type,
+				Price:        currentLevel.Price,
+				Quantity:     matchedQuantity,
+				CreatedAt:    me.now(),
 			}
+			if order.Type == OrderTypeBuy {
+				transaction.BuyOrderID, transaction.SellOrderID = order.ID, maker.ID
+			}
+			me.chargeFees(&transaction, order, *maker)
+			transactions = append(transactions, transaction)
+			if me.circuitBreaker != nil {
+				me.circuitBreaker.Observe(transaction.Price, transaction.CreatedAt)
+			}
+
+			order.Quantity -= matchedQuantity
+			me.fillOrder(allocation.Node, matchedQuantity)
 		}
 
-		if order.Quantity == 0 {
+		// The level is left partially filled only once the order is fully filled
+		if order.Quantity == 0 || *matchingLevels == currentLevel {
 			break
 		}
 	}
 
-	if order.Quantity > 0 {
+	if status != nil {
+		me.queuedOrders = append([]Order{order}, me.queuedOrders...)
+	} else if order.Quantity > 0 {
 		me.orderBook.InsertOrder(order)
 	}
 
-	return transactions
+	if len(transactions) > start {
+		me.lastTradePrice = transactions[len(transactions)-1].Price
+	}
+
+	return transactions, status
+}
+
+// crosses reports whether an order is marketable against the price of the opposite side
+func crosses(order Order, price float64) bool {
+	if order.Type == OrderTypeBuy {
+		return order.Price >= price
+	}
+	return order.Price <= price
+}
+
+// nextTransactionID returns the sequence number of a new transaction
+func (me *Matcher) nextTransactionID() uint64 {
+	me.lastTransactionID++
+	return me.lastTransactionID
+}
+
+// fillOrder reduces the quantity of a resting order, it reports whether the order is fully filled
+func (me *Matcher) fillOrder(node *OrderNode, quantity int64) bool {
+	node.PriceLevel.TotalQuantity -= quantity
+	node.Order.Quantity -= quantity
+	if node.Order.Quantity > 0 {
+		return false
+	}
+
+	me.orderBook.DeleteOrder(node.Order.ID)
+	return true
+}
+
+// chargeFees fills in the fees of both sides of a transaction from the fee schedule
+func (me *Matcher) chargeFees(transaction *Transaction, taker Order, maker Order) {
+	if me.feeSchedule == nil {
+		return
+	}
+
+	takerFee := me.feeSchedule.Fee(taker.Symbol, taker.AccountID, false, transaction.Price, transaction.Quantity)
+	makerFee := me.feeSchedule.Fee(taker.Symbol, maker.AccountID, true, transaction.Price, transaction.Quantity)
+	if taker.Type == OrderTypeBuy {
+		transaction.BuyFee, transaction.SellFee = takerFee, makerFee
+	} else {
+		transaction.BuyFee, transaction.SellFee = makerFee, takerFee
+	}
 }
@@ -1,6 +1,7 @@
 package matchingengine
 
 import (
+	"fmt"
 	"testing"
 
 	"time"
@@ -15,9 +16,8 @@ type MatcherTestSuite struct {
 	orderBook *OrderBook
 	tickNum   int8
 
-	symbol  string
-	now     time.Time
-	uuidStr string
+	symbol string
+	now    time.Time
 }
 
 func TestMatcherTestSuite(t *testing.T) {
@@ -27,15 +27,10 @@ func TestMatcherTestSuite(t *testing.T) {
 func (suite *MatcherTestSuite) SetupSuite() {
 	suite.symbol = "AAPL"
 	suite.now = time.Date(2025, 1, 15, 18, 0, 0, 0, time.UTC)
-	suite.uuidStr = uuid.NewString()
 
 	now = func() time.Time {
 		return suite.now
 	}
-
-	getUUID = func() string {
-		return suite.uuidStr
-	}
 }
 
 func (suite *MatcherTestSuite) SetupTest() {
@@ -74,7 +69,8 @@ func (suite *MatcherTestSuite) TestCreateOrder_NoMatch() {
 		CreatedAt: time.Now(),
 	}
 
-	matching := suite.matcher.CreateOrder(order)
+	matching, err := suite.matcher.CreateOrder(order)
+	suite.NoError(err)
 	suite.Empty(matching.Transactions)
 	suite.Equal(1, len(matching.SellTicks))
 	suite.Equal(Tick{
@@ -103,16 +99,20 @@ func (suite *MatcherTestSuite) TestCreateOrder_PartialFill() {
 		CreatedAt: time.Now(),
 	}
 
-	matching := suite.matcher.CreateOrder(buyOrder)
+	matching, err := suite.matcher.CreateOrder(buyOrder)
+	suite.NoError(err)
 	suite.Equal(1, len(matching.Transactions))
 	suite.Equal(Transaction{
-		ID:          getUUID(),
-		Symbol:      suite.symbol,
-		BuyOrderID:  buyOrder.ID,
-		SellOrderID: sellOrder.ID,
-		Price:       100.0,
-		Quantity:    5,
-		CreatedAt:   now(),
+		ID:           1,
+		Symbol:       suite.symbol,
+		BuyOrderID:   buyOrder.ID,
+		SellOrderID:  sellOrder.ID,
+		MakerOrderID: sellOrder.ID,
+		TakerOrderID: buyOrder.ID,
+		TakerSide:    OrderTypeBuy,
+		Price:        100.0,
+		Quantity:     5,
+		CreatedAt:    now(),
 	}, matching.Transactions[0])
 }
 
@@ -145,25 +145,32 @@ func (suite *MatcherTestSuite) TestCreateOrder_MultipleMatches() {
 		CreatedAt: time.Now(),
 	}
 
-	matching := suite.matcher.CreateOrder(buyOrder)
+	matching, err := suite.matcher.CreateOrder(buyOrder)
+	suite.NoError(err)
 	suite.Equal(2, len(matching.Transactions))
 	suite.Equal(Transaction{
-		ID:          getUUID(),
-		Symbol:      suite.symbol,
-		BuyOrderID:  buyOrder.ID,
-		SellOrderID: sellOrder1.ID,
-		Price:       100.0,
-		Quantity:    5,
-		CreatedAt:   now(),
+		ID:           1,
+		Symbol:       suite.symbol,
+		BuyOrderID:   buyOrder.ID,
+		SellOrderID:  sellOrder1.ID,
+		MakerOrderID: sellOrder1.ID,
+		TakerOrderID: buyOrder.ID,
+		TakerSide:    OrderTypeBuy,
+		Price:        100.0,
+		Quantity:     5,
+		CreatedAt:    now(),
 	}, matching.Transactions[0])
 	suite.Equal(Transaction{
-		ID:          getUUID(),
-		Symbol:      suite.symbol,
-		BuyOrderID:  buyOrder.ID,
-		SellOrderID: sellOrder2.ID,
-		Price:       100.0,
-		Quantity:    5,
-		CreatedAt:   now(),
+		ID:           2,
+		Symbol:       suite.symbol,
+		BuyOrderID:   buyOrder.ID,
+		SellOrderID:  sellOrder2.ID,
+		MakerOrderID: sellOrder2.ID,
+		TakerOrderID: buyOrder.ID,
+		TakerSide:    OrderTypeBuy,
+		Price:        100.0,
+		Quantity:     5,
+		CreatedAt:    now(),
 	}, matching.Transactions[1])
 }
 
@@ -187,13 +194,15 @@ func (suite *MatcherTestSuite) TestCreateOrder_ExceedTickLimit() {
 		Quantity:  50,
 		CreatedAt: time.Now(),
 	}
-	matching := suite.matcher.CreateOrder(buyOrder)
+	matching, err := suite.matcher.CreateOrder(buyOrder)
+	suite.NoError(err)
 
 	suite.Len(matching.SellTicks, 5)
+	// The 102.00 level is partially filled by the remaining 20
 	suite.Equal([]Tick{
 		{
 			Price:    102.00,
-			Quantity: 30,
+			Quantity: 10,
 		},
 		{
 			Price:    103.00,
@@ -213,3 +222,442 @@ func (suite *MatcherTestSuite) TestCreateOrder_ExceedTickLimit() {
 		},
 	}, matching.SellTicks)
 }
+
+func (suite *MatcherTestSuite) TestCreateOrder_MakerTakerFees() {
+	suite.matcher = NewMatcher(suite.orderBook, suite.tickNum, WithFeeSchedule(&FeeSchedule{
+		Currency: "USD",
+		Tiers: map[string]FeeRate{
+			DefaultFeeTier: {MakerBps: 10, TakerBps: 20},
+			"vip":          {MakerBps: -1, TakerBps: 5},
+		},
+		Symbols: map[string]map[string]FeeRate{
+			"MSFT": {DefaultFeeTier: {MakerBps: 0, TakerBps: 0}},
+		},
+		Accounts: map[string]string{
+			"maker": "vip",
+		},
+	}))
+
+	buyOrder := Order{
+		ID:        uuid.NewString(),
+		AccountID: "maker",
+		Symbol:    suite.symbol,
+		Type:      OrderTypeBuy,
+		Price:     100.0,
+		Quantity:  10,
+		CreatedAt: time.Now(),
+	}
+	suite.orderBook.InsertOrder(buyOrder)
+
+	sellOrder := Order{
+		ID:        uuid.NewString(),
+		AccountID: "taker",
+		Symbol:    suite.symbol,
+		Type:      OrderTypeSell,
+		Price:     100.0,
+		Quantity:  10,
+		CreatedAt: time.Now(),
+	}
+
+	matching, err := suite.matcher.CreateOrder(sellOrder)
+	suite.NoError(err)
+	suite.Require().Len(matching.Transactions, 1)
+	transaction := matching.Transactions[0]
+	suite.Equal(buyOrder.ID, transaction.MakerOrderID)
+	suite.Equal(sellOrder.ID, transaction.TakerOrderID)
+	suite.Equal(OrderTypeSell, transaction.TakerSide)
+	// The vip maker gets a 1 bps rebate and the default taker pays 20 bps
+	suite.Equal(Fee{Amount: -0.1, Currency: "USD"}, transaction.BuyFee)
+	suite.Equal(Fee{Amount: 2, Currency: "USD"}, transaction.SellFee)
+}
+
+func (suite *MatcherTestSuite) TestReferencePrice() {
+	suite.Zero(suite.matcher.ReferencePrice())
+
+	suite.orderBook.InsertOrder(Order{
+		ID:       uuid.NewString(),
+		Symbol:   suite.symbol,
+		Type:     OrderTypeBuy,
+		Price:    98.0,
+		Quantity: 10,
+	})
+	suite.orderBook.InsertOrder(Order{
+		ID:       uuid.NewString(),
+		Symbol:   suite.symbol,
+		Type:     OrderTypeSell,
+		Price:    102.0,
+		Quantity: 10,
+	})
+	suite.Equal(100.0, suite.matcher.ReferencePrice())
+
+	suite.matcher.CreateOrder(Order{
+		ID:       uuid.NewString(),
+		Symbol:   suite.symbol,
+		Type:     OrderTypeBuy,
+		Price:    102.0,
+		Quantity: 5,
+	})
+	suite.Equal(102.0, suite.matcher.ReferencePrice())
+}
+
+func (suite *MatcherTestSuite) TestHaltAndResume() {
+	_, err := suite.matcher.Resume()
+	suite.ErrorIs(err, ErrNotHalted)
+
+	matching, err := suite.matcher.Halt("manual")
+	suite.NoError(err)
+	suite.Equal(&StatusChange{Status: TradingStatusHalted, Reason: "manual"}, matching.Status)
+	suite.Equal(TradingStatusHalted, suite.matcher.Status())
+
+	_, err = suite.matcher.Halt("manual")
+	suite.ErrorIs(err, ErrAlreadyHalted)
+
+	// Orders are rejected while halted without queueing
+	_, err = suite.matcher.CreateOrder(Order{
+		ID:       uuid.NewString(),
+		Symbol:   suite.symbol,
+		Type:     OrderTypeBuy,
+		Price:    100.0,
+		Quantity: 10,
+	})
+	var rejection *RejectionError
+	suite.Require().ErrorAs(err, &rejection)
+	suite.Equal(RejectRuleHalted, rejection.Rule)
+
+	// A manual halt is not resumed by Tick
+	suite.Nil(suite.matcher.Tick())
+
+	matchings, err := suite.matcher.Resume()
+	suite.NoError(err)
+	suite.Len(matchings, 1)
+	suite.Equal(TradingStatusContinuous, matchings[0].Status.Status)
+	suite.Equal(TradingStatusContinuous, suite.matcher.Status())
+}
+
+func (suite *MatcherTestSuite) TestCircuitBreaker() {
+	current := suite.now
+	now = func() time.Time {
+		return current
+	}
+	defer func() {
+		now = func() time.Time {
+			return suite.now
+		}
+	}()
+
+	suite.matcher = NewMatcher(suite.orderBook, suite.tickNum, WithCircuitBreaker(CircuitBreakerConfig{
+		BandPct:     10,
+		Window:      time.Minute,
+		Cooldown:    5 * time.Minute,
+		QueueOrders: true,
+	}))
+
+	for i, price := range []float64{100.0, 120.0} {
+		suite.orderBook.InsertOrder(Order{
+			ID:       fmt.Sprintf("sell%d", i),
+			Symbol:   suite.symbol,
+			Type:     OrderTypeSell,
+			Price:    price,
+			Quantity: 10,
+		})
+	}
+
+	// Sweeping both levels would move the price 20% within the window, so the matching stops
+	// before the second level and the rest of the order is queued
+	matching, err := suite.matcher.CreateOrder(Order{
+		ID:       "buy1",
+		Symbol:   suite.symbol,
+		Type:     OrderTypeBuy,
+		Price:    120.0,
+		Quantity: 20,
+	})
+	suite.NoError(err)
+	suite.Require().Len(matching.Transactions, 1)
+	suite.Equal(100.0, matching.Transactions[0].Price)
+	suite.Require().NotNil(matching.Status)
+	suite.Equal(TradingStatusHalted, matching.Status.Status)
+	suite.Equal(current.Add(5*time.Minute), matching.Status.ResumeAt)
+	suite.Equal([]Tick{{Price: 120.0, Quantity: 10}}, matching.SellTicks)
+	suite.Empty(matching.BuyTicks)
+
+	// New orders are queued and can still be cancelled
+	for _, id := range []string{"sell2", "sell3"} {
+		matching, err = suite.matcher.CreateOrder(Order{
+			ID:       id,
+			Symbol:   suite.symbol,
+			Type:     OrderTypeSell,
+			Price:    110.0,
+			Quantity: 5,
+		})
+		suite.NoError(err)
+		suite.True(matching.Queued)
+	}
+	_, err = suite.matcher.CancelOrder("sell3")
+	suite.NoError(err)
+	suite.Equal(120.0, suite.orderBook.SellLevels.Price)
+
+	// The trading resumes after the cooldown and the queued orders are matched in their order
+	current = current.Add(4 * time.Minute)
+	suite.Nil(suite.matcher.Tick())
+
+	current = current.Add(time.Minute)
+	matchings := suite.matcher.Tick()
+	suite.Require().Len(matchings, 3)
+	suite.Equal(TradingStatusContinuous, matchings[0].Status.Status)
+	suite.Equal("buy1", matchings[1].Order.ID)
+	suite.Require().Len(matchings[1].Transactions, 1)
+	suite.Equal(120.0, matchings[1].Transactions[0].Price)
+	suite.Equal("sell2", matchings[2].Order.ID)
+	suite.Equal([]Tick{{Price: 110.0, Quantity: 5}}, matchings[2].SellTicks)
+}
+
+func (suite *MatcherTestSuite) TestCircuitBreaker_WithoutQueue() {
+	suite.matcher = NewMatcher(suite.orderBook, suite.tickNum, WithCircuitBreaker(CircuitBreakerConfig{
+		BandPct:  10,
+		Window:   time.Minute,
+		Cooldown: 5 * time.Minute,
+	}))
+
+	for i, price := range []float64{100.0, 105.0, 120.0} {
+		suite.orderBook.InsertOrder(Order{
+			ID:       fmt.Sprintf("sell%d", i),
+			Symbol:   suite.symbol,
+			Type:     OrderTypeSell,
+			Price:    price,
+			Quantity: 10,
+		})
+	}
+
+	// The levels within the band are matched, and the rest of the order is queued even though
+	// the new orders are rejected while halted
+	matching, err := suite.matcher.CreateOrder(Order{
+		ID:       "buy1",
+		Symbol:   suite.symbol,
+		Type:     OrderTypeBuy,
+		Price:    120.0,
+		Quantity: 25,
+	})
+	suite.NoError(err)
+	suite.Require().Len(matching.Transactions, 2)
+	suite.Equal(105.0, matching.Transactions[1].Price)
+	suite.Equal(TradingStatusHalted, matching.Status.Status)
+	suite.Equal([]Order{{ID: "buy1", Symbol: suite.symbol, Type: OrderTypeBuy, Price: 120.0, Quantity: 5}}, suite.matcher.queuedOrders)
+	suite.Nil(suite.orderBook.BuyLevels)
+
+	_, err = suite.matcher.CreateOrder(Order{ID: "buy2", Symbol: suite.symbol, Type: OrderTypeBuy, Price: 120.0, Quantity: 5})
+	var rejection *RejectionError
+	suite.Require().ErrorAs(err, &rejection)
+	suite.Equal(RejectRuleHalted, rejection.Rule)
+
+	// The queued rest can be cancelled while halted
+	_, err = suite.matcher.CancelOrder("buy1")
+	suite.NoError(err)
+	suite.Empty(suite.matcher.queuedOrders)
+}
+
+func (suite *MatcherTestSuite) TestAuction() {
+	_, err := suite.matcher.Uncross()
+	suite.ErrorIs(err, ErrNotInAuction)
+
+	matching, err := suite.matcher.StartAuction("opening auction", time.Time{})
+	suite.NoError(err)
+	suite.Equal(TradingStatusAuction, matching.Status.Status)
+
+	orders := []Order{
+		{ID: "buy1", Type: OrderTypeBuy, Price: 101.0, Quantity: 10},
+		{ID: "buy2", Type: OrderTypeBuy, Price: 100.0, Quantity: 10},
+		{ID: "buy3", Type: OrderTypeBuy, Price: 99.0, Quantity: 10},
+		{ID: "sell1", Type: OrderTypeSell, Price: 98.0, Quantity: 5},
+		{ID: "sell2", Type: OrderTypeSell, Price: 100.0, Quantity: 10},
+		{ID: "sell3", Type: OrderTypeSell, Price: 101.0, Quantity: 20},
+	}
+	for _, order := range orders {
+		order.Symbol = suite.symbol
+		matching, err := suite.matcher.CreateOrder(order)
+		suite.NoError(err)
+		suite.Empty(matching.Transactions)
+	}
+
+	// At 100 the buys of 20 cross the sells of 15, the most volume of every price
+	suite.Equal(Indicative{Price: 100.0, Volume: 15, Imbalance: 5}, suite.matcher.Indicative())
+
+	matching, err = suite.matcher.Uncross()
+	suite.NoError(err)
+	suite.Equal(TradingStatusContinuous, matching.Status.Status)
+	suite.Equal(TradingStatusContinuous, suite.matcher.Status())
+	suite.Require().Len(matching.Transactions, 3)
+	for _, transaction := range matching.Transactions {
+		suite.Equal(100.0, transaction.Price)
+	}
+	suite.Equal("buy1", matching.Transactions[0].BuyOrderID)
+	suite.Equal("sell1", matching.Transactions[0].SellOrderID)
+	suite.Equal(int64(5), matching.Transactions[0].Quantity)
+	suite.Equal("buy1", matching.Transactions[1].BuyOrderID)
+	suite.Equal("sell2", matching.Transactions[1].SellOrderID)
+	suite.Equal(int64(5), matching.Transactions[1].Quantity)
+	suite.Equal("buy2", matching.Transactions[2].BuyOrderID)
+	suite.Equal("sell2", matching.Transactions[2].SellOrderID)
+	suite.Equal(int64(5), matching.Transactions[2].Quantity)
+
+	suite.Equal([]Tick{{Price: 100.0, Quantity: 5}, {Price: 99.0, Quantity: 10}}, matching.BuyTicks)
+	suite.Equal([]Tick{{Price: 101.0, Quantity: 20}}, matching.SellTicks)
+	suite.Equal(100.0, suite.matcher.ReferencePrice())
+}
+
+func (suite *MatcherTestSuite) TestAuction_Halt() {
+	_, err := suite.matcher.StartAuction("opening auction", time.Time{})
+	suite.Require().NoError(err)
+
+	_, err = suite.matcher.Halt("manual")
+	suite.ErrorIs(err, ErrInAuction)
+	suite.Equal(TradingStatusAuction, suite.matcher.Status())
+}
+
+func (suite *MatcherTestSuite) TestAuction_FromHalt() {
+	suite.matcher = NewMatcher(suite.orderBook, suite.tickNum, WithCircuitBreaker(CircuitBreakerConfig{
+		BandPct:     10,
+		Window:      time.Minute,
+		Cooldown:    5 * time.Minute,
+		QueueOrders: true,
+	}))
+	suite.matcher.halt("breached", suite.now.Add(5*time.Minute))
+
+	for _, order := range []Order{
+		{ID: "buy1", Type: OrderTypeBuy, Price: 100.0, Quantity: 10},
+		{ID: "buy2", Type: OrderTypeBuy, Price: 100.0, Quantity: 10},
+		{ID: "sell1", Type: OrderTypeSell, Price: 100.0, Quantity: 10},
+	} {
+		order.Symbol = suite.symbol
+		matching, err := suite.matcher.CreateOrder(order)
+		suite.Require().NoError(err)
+		suite.True(matching.Queued)
+	}
+
+	// The queued orders join the auction in their arrival order
+	matching, err := suite.matcher.StartAuction("closing auction", time.Time{})
+	suite.NoError(err)
+	suite.Equal([]Tick{{Price: 100.0, Quantity: 20}}, matching.BuyTicks)
+	suite.Equal([]Tick{{Price: 100.0, Quantity: 10}}, matching.SellTicks)
+	suite.Empty(suite.matcher.queuedOrders)
+	suite.True(suite.matcher.resumeAt.IsZero())
+
+	matching, err = suite.matcher.Uncross()
+	suite.NoError(err)
+	suite.Require().Len(matching.Transactions, 1)
+	suite.Equal("buy1", matching.Transactions[0].BuyOrderID)
+	suite.Equal("sell1", matching.Transactions[0].SellOrderID)
+	suite.Equal(TradingStatusContinuous, suite.matcher.Status())
+}
+
+func (suite *MatcherTestSuite) TestAuction_ReferencePriceTieBreak() {
+	suite.matcher.lastTradePrice = 104.0
+	_, err := suite.matcher.StartAuction("closing auction", time.Time{})
+	suite.Require().NoError(err)
+
+	// Both 100 and 105 execute 10 without imbalance, 105 is closer to the last trade
+	for _, order := range []Order{
+		{ID: "buy1", Type: OrderTypeBuy, Price: 105.0, Quantity: 10},
+		{ID: "sell1", Type: OrderTypeSell, Price: 100.0, Quantity: 10},
+	} {
+		order.Symbol = suite.symbol
+		_, err := suite.matcher.CreateOrder(order)
+		suite.Require().NoError(err)
+	}
+	suite.Equal(Indicative{Price: 105.0, Volume: 10}, suite.matcher.Indicative())
+}
+
+func (suite *MatcherTestSuite) TestAuction_Tick() {
+	current := suite.now
+	now = func() time.Time {
+		return current
+	}
+	defer func() {
+		now = func() time.Time {
+			return suite.now
+		}
+	}()
+
+	suite.matcher = NewMatcher(suite.orderBook, suite.tickNum, WithAuction(AuctionConfig{IndicativeInterval: 10 * time.Second}))
+	_, err := suite.matcher.StartAuction("opening auction", current.Add(time.Minute))
+	suite.Require().NoError(err)
+
+	for _, order := range []Order{
+		{ID: "buy1", Type: OrderTypeBuy, Price: 100.0, Quantity: 10},
+		{ID: "sell1", Type: OrderTypeSell, Price: 100.0, Quantity: 10},
+	} {
+		order.Symbol = suite.symbol
+		_, err := suite.matcher.CreateOrder(order)
+		suite.Require().NoError(err)
+	}
+
+	matchings := suite.matcher.Tick()
+	suite.Require().Len(matchings, 1)
+	suite.Equal(&Indicative{Price: 100.0, Volume: 10}, matchings[0].Indicative)
+	suite.Nil(matchings[0].Status)
+
+	// The indicative is only published once per interval
+	current = current.Add(5 * time.Second)
+	suite.Empty(suite.matcher.Tick())
+
+	current = current.Add(time.Minute)
+	matchings = suite.matcher.Tick()
+	suite.Require().Len(matchings, 1)
+	suite.Len(matchings[0].Transactions, 1)
+	suite.Equal(TradingStatusContinuous, matchings[0].Status.Status)
+}
+
+func (suite *MatcherTestSuite) TestCreateOrderInto_ReusesBuffers() {
+	var matching Matching
+	for i := 0; i < 3; i++ {
+		suite.orderBook.InsertOrder(Order{ID: fmt.Sprintf("sell%d", i), Symbol: suite.symbol, Type: OrderTypeSell, Price: 100.0, Quantity: 5})
+	}
+
+	suite.Require().NoError(suite.matcher.CreateOrderInto(Order{ID: "buy1", Symbol: suite.symbol, Type: OrderTypeBuy, Price: 100.0, Quantity: 10}, &matching))
+	suite.Require().Len(matching.Transactions, 2)
+	suite.Equal([]uint64{1, 2}, []uint64{matching.Transactions[0].ID, matching.Transactions[1].ID})
+	transactions := matching.Transactions
+
+	suite.Require().NoError(suite.matcher.CreateOrderInto(Order{ID: "buy2", Symbol: suite.symbol, Type: OrderTypeBuy, Price: 100.0, Quantity: 5}, &matching))
+	suite.Require().Len(matching.Transactions, 1)
+	suite.Equal(uint64(3), matching.Transactions[0].ID)
+	suite.Equal("buy2", matching.Order.ID)
+	// The transaction is written to the buffer of the previous call
+	suite.Same(&transactions[0], &matching.Transactions[0])
+	suite.Equal([]Tick{}, matching.SellTicks)
+}
+
+// BenchmarkCreateOrderInto rests a sell order and matches it with a buy order in a steady state
+// book, so both OrderNode and PriceLevel are reused from the pools
+func BenchmarkCreateOrderInto(b *testing.B) {
+	for _, method := range []AllocationMethod{AllocationMethodFIFO, AllocationMethodProRata} {
+		b.Run(method.String(), func(b *testing.B) {
+			policy, err := NewAllocationPolicy(AllocationConfig{Method: method})
+			if err != nil {
+				b.Fatal(err)
+			}
+			orderBook := NewOrderBook()
+			matcher := NewMatcher(orderBook, TOP_TICK_NUMBER, WithAllocationPolicy(policy))
+			for i := 0; i < 100; i++ {
+				orderBook.InsertOrder(Order{ID: fmt.Sprintf("restingSell%d", i), Symbol: "AAPL", Type: OrderTypeSell, Price: float64(101 + i), Quantity: 10})
+				orderBook.InsertOrder(Order{ID: fmt.Sprintf("restingBuy%d", i), Symbol: "AAPL", Type: OrderTypeBuy, Price: float64(99 - i), Quantity: 10})
+			}
+			sell := Order{ID: "sell", Symbol: "AAPL", Type: OrderTypeSell, Price: 100, Quantity: 10}
+			buy := Order{ID: "buy", Symbol: "AAPL", Type: OrderTypeBuy, Price: 100, Quantity: 10}
+
+			var matching Matching
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := matcher.CreateOrderInto(sell, &matching); err != nil {
+					b.Fatal(err)
+				}
+				if err := matcher.CreateOrderInto(buy, &matching); err != nil {
+					b.Fatal(err)
+				}
+				if len(matching.Transactions) != 1 {
+					b.Fatalf("expected 1 transaction, got %d", len(matching.Transactions))
+				}
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package matchingengine
 
 import (
 	"errors"
+	"sort"
 	"time"
 )
 
@@ -13,13 +14,20 @@ var (
 
 // Transaction represents the details of a matched order
 type Transaction struct {
-	ID          string
+	// ID is the sequence number of the transaction in its Matcher, starting from 1
+	ID          uint64
 	Symbol      string
 	BuyOrderID  string
 	SellOrderID string
-	Price       float64
-	Quantity    int64
-	CreatedAt   time.Time
+	// MakerOrderID is the resting order and TakerOrderID is the incoming order
+	MakerOrderID string
+	TakerOrderID string
+	TakerSide    OrderType
+	Price        float64
+	Quantity     int64
+	BuyFee       Fee
+	SellFee      Fee
+	CreatedAt    time.Time
 }
 
 // Tick represents the total quantity of a price
@@ -33,22 +41,41 @@ type MatchingType string
 
 type Matching struct {
 	Type         MatchingType
+	Order        Order
 	Transactions []Transaction
 	BuyTicks     []Tick
 	SellTicks    []Tick
+	// Queued reports the order is queued until the trading resumes
+	Queued bool
+	// Status is set when the trading status changed
+	Status *StatusChange
+	// Indicative is set while in auction and when the auction uncrosses
+	Indicative *Indicative
+	// Session is set when the session phase changed
+	Session *SessionChange
+	// Cancelled are the orders cancelled at once by CancelOrders
+	Cancelled []Order
+	// Rejection is set when an order queued while halted is rejected as the trading resumes
+	Rejection *RejectionError
 }
 
 // ENUM(Buy, Sell)
 type OrderType string
 
+// ENUM(GTC, Day)
+type TimeInForce string
+
 // Order represents a buy or sell order
 type Order struct {
 	ID        string
+	AccountID string
 	Symbol    string
 	Type      OrderType
 	Price     float64
 	Quantity  int64
-	CreatedAt time.Time
+	// TimeInForce is GTC by default, Day orders are cancelled when the session closes
+	TimeInForce TimeInForce
+	CreatedAt   time.Time
 }
 
 type OrderNode struct {
@@ -69,6 +96,8 @@ type PriceLevel struct {
 	TailOrders *OrderNode
 	Prev       *PriceLevel
 	Next       *PriceLevel
+	// forward are the skip list pointers of the priceIndex above Next
+	forward []*PriceLevel
 }
 
 // OrderBook maintains buy and sell price levels along with auxiliary maps
@@ -83,6 +112,12 @@ type OrderBook struct {
 	buyPriceMap map[float64]*PriceLevel
 	// sellPriceMap maps Sell Price to PriceLevel
 	sellPriceMap map[float64]*PriceLevel
+	// buyIndex and sellIndex keep the PriceLevels sorted for inserting a new price
+	buyIndex  *priceIndex
+	sellIndex *priceIndex
+	// freeNodes and freeLevels are the pools of deleted OrderNodes and PriceLevels, linked by Next
+	freeNodes  *OrderNode
+	freeLevels *PriceLevel
 }
 
 // NewOrderBook initializes and returns a new OrderBook
@@ -93,93 +128,69 @@ func NewOrderBook() *OrderBook {
 		orderMap:     make(map[string]*OrderNode),
 		buyPriceMap:  make(map[float64]*PriceLevel),
 		sellPriceMap: make(map[float64]*PriceLevel),
+		buyIndex:     newPriceIndex(func(price1, price2 float64) bool { return price1 > price2 }),
+		sellIndex:    newPriceIndex(func(price1, price2 float64) bool { return price1 < price2 }),
 	}
 }
 
 // InsertOrder inserts an order into the order book in the correct position
 func (ob *OrderBook) InsertOrder(order Order) {
 	if order.Type == OrderTypeBuy {
-		ob.BuyLevels = ob.insertOrderToPriceLevel(ob.BuyLevels, order, ob.buyPriceMap, true)
+		ob.BuyLevels = ob.insertOrderToPriceLevel(order, ob.buyPriceMap, ob.buyIndex)
 	} else {
-		ob.SellLevels = ob.insertOrderToPriceLevel(ob.SellLevels, order, ob.sellPriceMap, false)
+		ob.SellLevels = ob.insertOrderToPriceLevel(order, ob.sellPriceMap, ob.sellIndex)
 	}
 }
 
-// insertOrderToPriceLevel inserts an order into the buy or sell price levels
-func (ob *OrderBook) insertOrderToPriceLevel(headPriceLevel *PriceLevel, order Order, priceMap map[float64]*PriceLevel, isBuy bool) *PriceLevel {
-	newOrderNode := &OrderNode{Order: order}
-
-	// No PriceLevel: Create a new price level at the head
-	if headPriceLevel == nil {
-		newLevel := &PriceLevel{
-			Type:          order.Type,
-			Price:         order.Price,
-			TotalQuantity: order.Quantity,
-			HeadOrders:    newOrderNode,
-			TailOrders:    newOrderNode,
-			Next:          headPriceLevel,
-		}
-
-		if isBuy {
-			ob.BuyLevels = newLevel
-		} else {
-			ob.SellLevels = newLevel
-		}
-
-		priceMap[order.Price] = newLevel
-		ob.orderMap[order.ID] = newOrderNode
-		newOrderNode.PriceLevel = newLevel
-		return newLevel
-	}
-
-	currPriceLevel := headPriceLevel
-	prevPriceLevel := currPriceLevel.Prev
-	for currPriceLevel != nil {
-		if currPriceLevel.Price == order.Price {
-			// Find the same PriceLevel: Insert order to the tail
-			currPriceLevel.TailOrders.Next = newOrderNode
-			newOrderNode.Prev = currPriceLevel.TailOrders
-			currPriceLevel.TailOrders = newOrderNode
-			currPriceLevel.TotalQuantity += newOrderNode.Order.Quantity
-
-			ob.orderMap[order.ID] = newOrderNode
-			return headPriceLevel
-		}
+// insertOrderToPriceLevel appends an order to its PriceLevel found in the price map in O(1),
+// or inserts a new PriceLevel into the index in O(log n). It returns the best PriceLevel.
+func (ob *OrderBook) insertOrderToPriceLevel(order Order, priceMap map[float64]*PriceLevel, index *priceIndex) *PriceLevel {
+	newOrderNode := ob.newOrderNode(order)
+	ob.orderMap[order.ID] = newOrderNode
 
-		prevPriceLevel = currPriceLevel
-		currPriceLevel = currPriceLevel.Next
+	// Find the same PriceLevel: Insert order to the tail
+	if level, exists := priceMap[order.Price]; exists {
+		level.TailOrders.Next = newOrderNode
+		newOrderNode.Prev = level.TailOrders
+		level.TailOrders = newOrderNode
+		level.TotalQuantity += order.Quantity
+		newOrderNode.PriceLevel = level
+		return index.Best()
 	}
 
 	// PriceLevel not found: Create a new PriceLevel
-	newLevel := &PriceLevel{
-		Type:          order.Type,
-		Price:         order.Price,
-		TotalQuantity: order.Quantity,
-		HeadOrders:    newOrderNode,
-		TailOrders:    newOrderNode,
-		Prev:          prevPriceLevel,
-		Next:          nil,
-	}
-
-	prevPriceLevel.Next = newLevel
-
+	newLevel := ob.newPriceLevel()
+	newLevel.Type = order.Type
+	newLevel.Price = order.Price
+	newLevel.TotalQuantity = order.Quantity
+	newLevel.HeadOrders = newOrderNode
+	newLevel.TailOrders = newOrderNode
+	index.Insert(newLevel)
 	priceMap[order.Price] = newLevel
-	ob.orderMap[order.ID] = newOrderNode
 	newOrderNode.PriceLevel = newLevel
+	return index.Best()
+}
 
-	return headPriceLevel
+// GetOrder returns the resting order of the ID in O(1) time, it reports whether the order is in
+// the book
+func (ob *OrderBook) GetOrder(orderID string) (Order, bool) {
+	orderNode, exists := ob.orderMap[orderID]
+	if !exists {
+		return Order{}, false
+	}
+	return orderNode.Order, true
 }
 
-// DeleteOrder deletes an order by ID in O(1) time
+// DeleteOrder deletes an order by ID in O(1) time, or O(log n) if its PriceLevel gets empty
 func (ob *OrderBook) DeleteOrder(orderID string) error {
 	orderNode, exists := ob.orderMap[orderID]
 	if !exists {
-		return errors.New("order not found")
+		return ErrOrderNotFound
 	}
 
 	pl := orderNode.PriceLevel
 	if pl == nil {
-		return errors.New("price level not found")
+		return ErrPriceLevelNotFound
 	}
 
 	// Adjust total quantity
@@ -198,10 +209,9 @@ func (ob *OrderBook) DeleteOrder(orderID string) error {
 		pl.TailOrders = orderNode.Prev
 	}
 
-	orderNode = nil
-
 	// Remove from orderMap
 	delete(ob.orderMap, orderID)
+	ob.releaseOrderNode(orderNode)
 
 	// Check if PriceLevel is empty
 	if pl.HeadOrders == nil {
@@ -211,38 +221,89 @@ func (ob *OrderBook) DeleteOrder(orderID string) error {
 	return nil
 }
 
-// deletePriceLevel deletes a PriceLevel from the order book
-func (ob *OrderBook) deletePriceLevel(pl *PriceLevel) {
-	if pl.Type == OrderTypeBuy {
-		if pl.Prev != nil {
-			pl.Prev.Next = pl.Next
-		} else {
-			ob.BuyLevels = pl.Next
+// DeleteOrders deletes the orders matched by the filter, and returns them in their time priority
+func (ob *OrderBook) DeleteOrders(match func(order Order) bool) []Order {
+	var orders []Order
+	for _, orderNode := range ob.orderMap {
+		if match(orderNode.Order) {
+			orders = append(orders, orderNode.Order)
 		}
+	}
+	sortByTime(orders)
+
+	for _, order := range orders {
+		// The orders are in the map, so they are always deleted
+		_ = ob.DeleteOrder(order.ID)
+	}
+	return orders
+}
 
-		if pl.Next != nil {
-			pl.Next.Prev = pl.Prev
+// sortByTime sorts the orders by their creation time, then by ID, as the iteration order of a map
+// is random
+func sortByTime(orders []Order) {
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+			return orders[i].CreatedAt.Before(orders[j].CreatedAt)
 		}
+		return orders[i].ID < orders[j].ID
+	})
+}
+
+// deletePriceLevel deletes a PriceLevel from the order book
+func (ob *OrderBook) deletePriceLevel(pl *PriceLevel) {
+	if pl.Type == OrderTypeBuy {
+		ob.buyIndex.Remove(pl)
+		ob.BuyLevels = ob.buyIndex.Best()
 		delete(ob.buyPriceMap, pl.Price)
 	} else {
-		if pl.Prev != nil {
-			pl.Prev.Next = pl.Next
-		} else {
-			ob.SellLevels = pl.Next
-		}
-
-		if pl.Next != nil {
-			pl.Next.Prev = pl.Prev
-		}
+		ob.sellIndex.Remove(pl)
+		ob.SellLevels = ob.sellIndex.Best()
 		delete(ob.sellPriceMap, pl.Price)
 	}
+	ob.releasePriceLevel(pl)
+}
+
+// newOrderNode takes an OrderNode from the pool, or allocates one if the pool is empty
+func (ob *OrderBook) newOrderNode(order Order) *OrderNode {
+	node := ob.freeNodes
+	if node == nil {
+		return &OrderNode{Order: order}
+	}
+	ob.freeNodes = node.Next
+	*node = OrderNode{Order: order}
+	return node
+}
+
+// releaseOrderNode returns a deleted OrderNode to the pool
+func (ob *OrderBook) releaseOrderNode(node *OrderNode) {
+	*node = OrderNode{Next: ob.freeNodes}
+	ob.freeNodes = node
+}
+
+// newPriceLevel takes a PriceLevel from the pool, or allocates one if the pool is empty
+func (ob *OrderBook) newPriceLevel() *PriceLevel {
+	level := ob.freeLevels
+	if level == nil {
+		return &PriceLevel{}
+	}
+	ob.freeLevels = level.Next
+	level.Next = nil
+	return level
+}
+
+// releasePriceLevel returns a deleted PriceLevel to the pool, keeping its skip list pointers buffer
+func (ob *OrderBook) releasePriceLevel(pl *PriceLevel) {
+	*pl = PriceLevel{Next: ob.freeLevels, forward: pl.forward[:0]}
+	ob.freeLevels = pl
 }
 
 // GetTopTicks returns the top N buy and sell ticks
 func (ob *OrderBook) GetTopTicks(n int8) ([]Tick, []Tick) {
-	buyTicks := []Tick{}
-	sellTicks := []Tick{}
+	return ob.AppendTopTicks([]Tick{}, []Tick{}, n)
+}
 
+// AppendTopTicks appends the top N buy and sell ticks to the given buffers
+func (ob *OrderBook) AppendTopTicks(buyTicks, sellTicks []Tick, n int8) ([]Tick, []Tick) {
 	current := ob.BuyLevels
 	for i := 0; i < int(n) && current != nil; i++ {
 		buyTicks = append(buyTicks, Tick{Price: current.Price, Quantity: current.TotalQuantity})
@@ -108,3 +108,52 @@ func (x *OrderType) UnmarshalText(text []byte) error {
 	*x = tmp
 	return nil
 }
+
+const (
+	// TimeInForceGTC is a TimeInForce of type GTC.
+	TimeInForceGTC TimeInForce = "GTC"
+	// TimeInForceDay is a TimeInForce of type Day.
+	TimeInForceDay TimeInForce = "Day"
+)
+
+var ErrInvalidTimeInForce = errors.New("not a valid TimeInForce")
+
+// String implements the Stringer interface.
+func (x TimeInForce) String() string {
+	return string(x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x TimeInForce) IsValid() bool {
+	_, err := ParseTimeInForce(string(x))
+	return err == nil
+}
+
+var _TimeInForceValue = map[string]TimeInForce{
+	"GTC": TimeInForceGTC,
+	"Day": TimeInForceDay,
+}
+
+// ParseTimeInForce attempts to convert a string to a TimeInForce.
+func ParseTimeInForce(name string) (TimeInForce, error) {
+	if x, ok := _TimeInForceValue[name]; ok {
+		return x, nil
+	}
+	return TimeInForce(""), fmt.Errorf("%s is %w", name, ErrInvalidTimeInForce)
+}
+
+// MarshalText implements the text marshaller method.
+func (x TimeInForce) MarshalText() ([]byte, error) {
+	return []byte(string(x)), nil
+}
+
+// UnmarshalText implements the text unmarshaller method.
+func (x *TimeInForce) UnmarshalText(text []byte) error {
+	tmp, err := ParseTimeInForce(string(text))
+	if err != nil {
+		return err
+	}
+	*x = tmp
+	return nil
+}
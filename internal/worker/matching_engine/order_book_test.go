@@ -1,6 +1,9 @@
 package matchingengine
 
 import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
 	"testing"
 	"time"
 
@@ -118,6 +121,29 @@ func (suite *OrderBookTestSuite) TestDeleteOrder() {
 	suite.Nil(suite.orderBook.BuyLevels)
 }
 
+func (suite *OrderBookTestSuite) TestDeleteOrders() {
+	orders := []Order{
+		{ID: "order3", AccountID: "alice", Symbol: suite.symbol, Type: OrderTypeBuy, Price: 100.0, Quantity: 5, CreatedAt: suite.now},
+		{ID: "order1", AccountID: "alice", Symbol: suite.symbol, Type: OrderTypeSell, Price: 102.0, Quantity: 10, CreatedAt: suite.now},
+		{ID: "order2", AccountID: "bob", Symbol: suite.symbol, Type: OrderTypeBuy, Price: 100.0, Quantity: 7, CreatedAt: suite.now},
+		{ID: "order4", AccountID: "alice", Symbol: suite.symbol, Type: OrderTypeBuy, Price: 99.0, Quantity: 3, CreatedAt: suite.now.Add(-time.Second)},
+	}
+	for _, order := range orders {
+		suite.orderBook.InsertOrder(order)
+	}
+
+	// The orders are returned by their time, then their ID
+	deleted := suite.orderBook.DeleteOrders(func(order Order) bool { return order.AccountID == "alice" })
+	suite.Equal([]Order{orders[3], orders[1], orders[0]}, deleted)
+
+	suite.Len(suite.orderBook.orderMap, 1)
+	suite.Nil(suite.orderBook.SellLevels)
+	suite.Equal(int64(7), suite.orderBook.BuyLevels.TotalQuantity)
+	suite.Nil(suite.orderBook.BuyLevels.Next)
+
+	suite.Empty(suite.orderBook.DeleteOrders(func(order Order) bool { return order.AccountID == "alice" }))
+}
+
 func (suite *OrderBookTestSuite) TestGetTopTicks() {
 	orders := []Order{
 		{
@@ -189,3 +215,116 @@ func (suite *OrderBookTestSuite) TestGetTopTicks() {
 	suite.Len(buyTicks, 0)
 	suite.Len(sellTicks, 0)
 }
+
+func (suite *OrderBookTestSuite) TestPriceLevelsSorted() {
+	prices := []float64{100, 103, 99, 101, 105, 98, 102, 104}
+	for i, price := range prices {
+		suite.orderBook.InsertOrder(Order{ID: fmt.Sprintf("buy%d", i), Symbol: suite.symbol, Type: OrderTypeBuy, Price: price, Quantity: 1, CreatedAt: suite.now})
+		suite.orderBook.InsertOrder(Order{ID: fmt.Sprintf("sell%d", i), Symbol: suite.symbol, Type: OrderTypeSell, Price: price + 10, Quantity: 1, CreatedAt: suite.now})
+	}
+	// Delete a head, a middle and a tail level
+	suite.NoError(suite.orderBook.DeleteOrder("buy4"))
+	suite.NoError(suite.orderBook.DeleteOrder("buy1"))
+	suite.NoError(suite.orderBook.DeleteOrder("buy5"))
+	suite.NoError(suite.orderBook.DeleteOrder("sell5"))
+
+	suite.Equal([]float64{104, 102, 101, 100, 99}, levelPrices(suite.T(), suite.orderBook.BuyLevels))
+	suite.Equal([]float64{109, 110, 111, 112, 113, 114, 115}, levelPrices(suite.T(), suite.orderBook.SellLevels))
+}
+
+func (suite *OrderBookTestSuite) TestPriceLevelsSortedRandomized() {
+	rng := rand.New(rand.NewPCG(1, 2))
+	live := make(map[string]float64)
+	for i := 0; i < 5000; i++ {
+		if len(live) > 0 && rng.IntN(3) == 0 {
+			for id := range live {
+				suite.NoError(suite.orderBook.DeleteOrder(id))
+				delete(live, id)
+				break
+			}
+			continue
+		}
+		id := fmt.Sprintf("order%d", i)
+		price := float64(rng.IntN(500))
+		suite.orderBook.InsertOrder(Order{ID: id, Symbol: suite.symbol, Type: OrderTypeSell, Price: price, Quantity: 1, CreatedAt: suite.now})
+		live[id] = price
+	}
+
+	var expected []float64
+	for _, price := range live {
+		if !slices.Contains(expected, price) {
+			expected = append(expected, price)
+		}
+	}
+	slices.Sort(expected)
+	suite.Equal(expected, levelPrices(suite.T(), suite.orderBook.SellLevels))
+}
+
+// levelPrices returns the prices of the PriceLevels and checks the Prev pointers
+func levelPrices(t *testing.T, head *PriceLevel) []float64 {
+	var prices []float64
+	var prev *PriceLevel
+	for level := head; level != nil; level = level.Next {
+		if level.Prev != prev {
+			t.Fatalf("price level %v has a wrong Prev", level.Price)
+		}
+		prices = append(prices, level.Price)
+		prev = level
+	}
+	return prices
+}
+
+// newBenchmarkOrderBook returns an OrderBook with levels buy price levels of one order each
+func newBenchmarkOrderBook(levels int) *OrderBook {
+	orderBook := NewOrderBook()
+	for i := 0; i < levels; i++ {
+		orderBook.InsertOrder(Order{ID: fmt.Sprintf("resting%d", i), Type: OrderTypeBuy, Price: float64(i), Quantity: 1})
+	}
+	return orderBook
+}
+
+// BenchmarkInsertOrderNewLevel inserts and deletes a new worst price level of a deep book
+func BenchmarkInsertOrderNewLevel(b *testing.B) {
+	for _, levels := range []int{1_000, 10_000, 100_000} {
+		b.Run(fmt.Sprintf("levels=%d", levels), func(b *testing.B) {
+			orderBook := newBenchmarkOrderBook(levels)
+			order := Order{ID: "new", Type: OrderTypeBuy, Price: -1, Quantity: 1}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				orderBook.InsertOrder(order)
+				orderBook.DeleteOrder(order.ID)
+			}
+		})
+	}
+}
+
+// BenchmarkInsertOrderExistingLevel appends orders to the worst price level of a deep book
+func BenchmarkInsertOrderExistingLevel(b *testing.B) {
+	for _, levels := range []int{1_000, 10_000, 100_000} {
+		b.Run(fmt.Sprintf("levels=%d", levels), func(b *testing.B) {
+			orderBook := newBenchmarkOrderBook(levels)
+			order := Order{ID: "new", Type: OrderTypeBuy, Price: 0, Quantity: 1}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				orderBook.InsertOrder(order)
+				orderBook.DeleteOrder(order.ID)
+			}
+		})
+	}
+}
+
+// BenchmarkInsertOrderRandomLevels builds a book of 10k random price levels
+func BenchmarkInsertOrderRandomLevels(b *testing.B) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	orders := make([]Order, 10_000)
+	for i := range orders {
+		orders[i] = Order{ID: fmt.Sprintf("order%d", i), Type: OrderTypeSell, Price: float64(rng.IntN(1_000_000)), Quantity: 1}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		orderBook := NewOrderBook()
+		for _, order := range orders {
+			orderBook.InsertOrder(order)
+		}
+	}
+}
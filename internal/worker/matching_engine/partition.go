@@ -0,0 +1,188 @@
+package matchingengine
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	"github.com/Hao1995/order-matching-system/pkg/journalkit"
+	"github.com/Hao1995/order-matching-system/pkg/logger"
+)
+
+// EngineFactory creates the Engine of a symbol with the options
+type EngineFactory func(symbol string, opts ...EngineOption) (*Engine, error)
+
+// Partition applies the order events of a partition of the order topic to one Engine per symbol,
+// which is the key of the order events. The engines share the journal and the sequence numbers
+// of the matching events, so a partition is rebuilt and handed off between workers as a whole.
+type Partition struct {
+	mu        sync.Mutex
+	id        int
+	newEngine EngineFactory
+	journal   Journal
+	engines   map[string]*Engine
+	// lastSeq is the sequence number of the latest matching event of all the engines
+	lastSeq uint64
+	// lastOffset is the offset of the latest applied order event, -1 before any
+	lastOffset int64
+}
+
+// NewPartition creates a Partition which journals to the journal, if not nil
+func NewPartition(id int, newEngine EngineFactory, journal Journal) *Partition {
+	return &Partition{
+		id:         id,
+		newEngine:  newEngine,
+		journal:    journal,
+		engines:    map[string]*Engine{},
+		lastOffset: -1,
+	}
+}
+
+// ID returns the partition of the order topic
+func (p *Partition) ID() int {
+	return p.id
+}
+
+// Handle applies the order event at the offset to the Engine of the symbol in the key. An order
+// event at or before the latest applied offset is a redelivery and is skipped.
+func (p *Partition) Handle(offset int64, key, val []byte) ([]events.MatchingEvent, error) {
+	return p.HandleEncoded(offset, key, events.JSONCodec, val)
+}
+
+// HandleEncoded is Handle for an order event encoded by the codec
+func (p *Partition) HandleEncoded(offset int64, key []byte, codec events.Codec, val []byte) ([]events.MatchingEvent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if offset <= p.lastOffset {
+		logger.Warn("skip the applied event", zap.Int("partition", p.id), zap.Int64("offset", offset), zap.Int64("lastOffset", p.lastOffset))
+		return nil, nil
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("%w: no symbol key at offset %d", ErrMalformedEvent, offset)
+	}
+
+	engine, err := p.engine(string(key))
+	if err != nil {
+		return nil, err
+	}
+	matchingEvents, err := engine.HandleEncodedAt(offset, codec, val)
+	if engine.LastOffset() == offset {
+		p.lastOffset = offset
+	}
+	return matchingEvents, err
+}
+
+// Skip journals the order event at the offset as skipped, e.g. once it is moved to the dead-letter
+// topic, so the partition resumes after it when rebuilt. An applied order event is not skipped.
+func (p *Partition) Skip(offset int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if offset <= p.lastOffset {
+		return nil
+	}
+	if p.journal != nil {
+		data, err := json.Marshal(JournalEntry{Type: JournalEntryTypeSkip, Offset: offset, Time: now()})
+		if err != nil {
+			return err
+		}
+		if _, err := p.journal.Append(data); err != nil {
+			return err
+		}
+	}
+	p.lastOffset = offset
+	return nil
+}
+
+// Engine returns the Engine of the symbol, which is created if the partition has none
+func (p *Partition) Engine(symbol string) (*Engine, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.engine(symbol)
+}
+
+// Symbols returns the symbols of the engines in order
+func (p *Partition) Symbols() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	symbols := make([]string, 0, len(p.engines))
+	for symbol := range p.engines {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// LastOffset returns the offset of the latest applied order event, -1 before any
+func (p *Partition) LastOffset() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.lastOffset
+}
+
+// Replay applies the entries of the journal in dir to the engines of their symbols, which must
+// start from empty order books. The entries which were rejected or malformed when they were
+// handled are skipped, and so are the order events of the Skip entries; any other failure stops
+// the replay. The matching events of every entry
+// are passed to output with the sequence number of the entry.
+func (p *Partition) Replay(dir string, output func(seq uint64, matchingEvents []events.MatchingEvent) error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return journalkit.Replay(dir, func(record journalkit.Record) error {
+		var entry JournalEntry
+		if err := json.Unmarshal(record.Data, &entry); err != nil {
+			return err
+		}
+		if entry.Type == JournalEntryTypeSkip {
+			p.lastOffset = entry.Offset
+			return output(record.Seq, nil)
+		}
+		if entry.Symbol == "" {
+			return fmt.Errorf("journal entry %d has no symbol", record.Seq)
+		}
+
+		engine, err := p.engine(entry.Symbol)
+		if err != nil {
+			return err
+		}
+		engine.mu.Lock()
+		matchingEvents, err := engine.apply(entry)
+		engine.mu.Unlock()
+		if err != nil {
+			if !skippable(err) {
+				return fmt.Errorf("journal entry %d: %w", record.Seq, err)
+			}
+			logger.Warn("failed to replay journal entry, pass it", zap.Error(err), zap.Uint64("seq", record.Seq))
+		}
+		if entry.Type == JournalEntryTypeEvent {
+			p.lastOffset = entry.Offset
+		}
+		return output(record.Seq, matchingEvents)
+	})
+}
+
+func (p *Partition) engine(symbol string) (*Engine, error) {
+	if engine, ok := p.engines[symbol]; ok {
+		return engine, nil
+	}
+
+	opts := []EngineOption{WithSequence(&p.lastSeq)}
+	if p.journal != nil {
+		opts = append(opts, WithJournal(p.journal))
+	}
+	engine, err := p.newEngine(symbol, opts...)
+	if err != nil {
+		return nil, err
+	}
+	p.engines[symbol] = engine
+	return engine, nil
+}
@@ -0,0 +1,158 @@
+package matchingengine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	"github.com/Hao1995/order-matching-system/pkg/journalkit"
+	"github.com/Hao1995/order-matching-system/pkg/mqkit"
+)
+
+type PartitionTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func TestPartitionTestSuite(t *testing.T) {
+	suite.Run(t, new(PartitionTestSuite))
+}
+
+func (suite *PartitionTestSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+}
+
+func newTestEngine(symbol string, opts ...EngineOption) (*Engine, error) {
+	return NewEngine(symbol, NewMatcher(NewOrderBook(), 5), NewRiskChecker(RiskConfig{}), opts...), nil
+}
+
+func (suite *PartitionTestSuite) createOrder(partition *Partition, offset int64, orderEvent events.OrderEvent) []events.MatchingEvent {
+	val, err := json.Marshal(events.Event{EventType: events.EventTypeCreateOrder, Data: orderEvent})
+	suite.Require().NoError(err)
+
+	matchingEvents, err := partition.Handle(offset, []byte(orderEvent.Symbol), val)
+	suite.Require().NoError(err)
+	return matchingEvents
+}
+
+func (suite *PartitionTestSuite) TestHandle_EngineBySymbol() {
+	partition := NewPartition(1, newTestEngine, nil)
+
+	matchingEvents := suite.createOrder(partition, 0, events.OrderEvent{ID: "aapl1", Symbol: "AAPL", Type: "Sell", Price: 100.0, Quantity: 10})
+	suite.Require().Len(matchingEvents, 1)
+	suite.Equal(uint64(1), matchingEvents[0].Seq)
+
+	// The order of another symbol does not match the book of AAPL
+	matchingEvents = suite.createOrder(partition, 1, events.OrderEvent{ID: "msft1", Symbol: "MSFT", Type: "Buy", Price: 100.0, Quantity: 10})
+	suite.Require().Len(matchingEvents, 1)
+	suite.Empty(matchingEvents[0].Transactions)
+	suite.Equal(uint64(2), matchingEvents[0].Seq)
+	suite.Equal(int64(1), matchingEvents[0].InputOffset)
+
+	matchingEvents = suite.createOrder(partition, 2, events.OrderEvent{ID: "aapl2", Symbol: "AAPL", Type: "Buy", Price: 100.0, Quantity: 4})
+	suite.Require().Len(matchingEvents, 1)
+	suite.Len(matchingEvents[0].Transactions, 1)
+	suite.Equal(uint64(3), matchingEvents[0].Seq)
+
+	// A redelivery is skipped, and an order event without a symbol is malformed
+	suite.Empty(suite.createOrder(partition, 2, events.OrderEvent{ID: "aapl2", Symbol: "AAPL", Type: "Buy", Price: 100.0, Quantity: 4}))
+	_, err := partition.Handle(3, nil, []byte(`{"event_type":"Heartbeat"}`))
+	suite.ErrorIs(err, ErrMalformedEvent)
+
+	suite.Equal([]string{"AAPL", "MSFT"}, partition.Symbols())
+	suite.Equal(int64(2), partition.LastOffset())
+}
+
+func (suite *PartitionTestSuite) TestReplay() {
+	journal, err := journalkit.Open(suite.dir, journalkit.Options{})
+	suite.Require().NoError(err)
+	partition := NewPartition(1, newTestEngine, journal)
+	suite.createOrder(partition, 3, events.OrderEvent{ID: "aapl1", Symbol: "AAPL", Type: "Sell", Price: 100.0, Quantity: 10})
+	suite.createOrder(partition, 5, events.OrderEvent{ID: "msft1", Symbol: "MSFT", Type: "Buy", Price: 100.0, Quantity: 10})
+	suite.createOrder(partition, 8, events.OrderEvent{ID: "aapl2", Symbol: "AAPL", Type: "Buy", Price: 100.0, Quantity: 4})
+	suite.NoError(journal.Close())
+
+	// The partition is rebuilt by another worker
+	rebuilt := NewPartition(1, newTestEngine, nil)
+	var seqs []uint64
+	suite.Require().NoError(rebuilt.Replay(suite.dir, func(_ uint64, matchingEvents []events.MatchingEvent) error {
+		for _, matchingEvent := range matchingEvents {
+			seqs = append(seqs, matchingEvent.Seq)
+		}
+		return nil
+	}))
+	suite.Equal([]uint64{1, 2, 3}, seqs)
+	suite.Equal(int64(8), rebuilt.LastOffset())
+
+	for _, symbol := range []string{"AAPL", "MSFT"} {
+		engine, err := partition.Engine(symbol)
+		suite.Require().NoError(err)
+		rebuiltEngine, err := rebuilt.Engine(symbol)
+		suite.Require().NoError(err)
+		suite.Equal(snapshotOrderBook(engine.matcher.orderBook), snapshotOrderBook(rebuiltEngine.matcher.orderBook))
+	}
+
+	// The sequence numbers continue after the rebuild
+	matchingEvents := suite.createOrder(rebuilt, 9, events.OrderEvent{ID: "msft2", Symbol: "MSFT", Type: "Sell", Price: 100.0, Quantity: 1})
+	suite.Require().Len(matchingEvents, 1)
+	suite.Equal(uint64(4), matchingEvents[0].Seq)
+}
+
+func (suite *PartitionTestSuite) TestSkip_DeadLetterOnceAfterRestart() {
+	broker := mqkit.NewMemoryBroker()
+	broker.CreateTopic("ORDER", 1)
+	broker.CreateTopic("ORDER_DLQ", 1)
+	order, err := json.Marshal(events.Event{EventType: events.EventTypeCreateOrder, Data: events.OrderEvent{ID: "aapl1", Symbol: "AAPL", Type: "Sell", Price: 100.0, Quantity: 10}})
+	suite.Require().NoError(err)
+	broker.Append("ORDER", []byte("AAPL"), order)
+	broker.Append("ORDER", []byte("AAPL"), []byte(`{"event_type":"CreateOrder","data":[]}`))
+
+	// consume runs a worker of the partition, rebuilt from the journal, until the order topic is
+	// consumed
+	consume := func() *Partition {
+		journal, err := journalkit.Open(suite.dir, journalkit.Options{})
+		suite.Require().NoError(err)
+		defer journal.Close()
+		partition := NewPartition(0, newTestEngine, journal)
+		suite.Require().NoError(partition.Replay(suite.dir, func(uint64, []events.MatchingEvent) error {
+			return nil
+		}))
+
+		consumer := mqkit.NewDeadLetterConsumer(
+			mqkit.NewMemoryPartitionConsumer(broker, "ORDER", 0, partition.LastOffset()+1),
+			mqkit.NewMemoryProducer(broker, "ORDER_DLQ", nil),
+			mqkit.DeadLetterOptions{
+				ShouldDeadLetter: func(err error) bool {
+					return errors.Is(err, ErrMalformedEvent)
+				},
+				OnDeadLetter: func(msg mqkit.Message) error {
+					return partition.Skip(msg.Offset)
+				},
+			},
+		)
+		defer consumer.Close()
+		for offset := partition.LastOffset() + 1; offset < 2; offset++ {
+			suite.Require().NoError(consumer.Consume(context.Background(), func(msg mqkit.Message) error {
+				_, err := partition.Handle(msg.Offset, msg.Key, msg.Value)
+				return err
+			}))
+		}
+		return partition
+	}
+
+	consume()
+	suite.Len(broker.Messages("ORDER_DLQ", 0), 1)
+
+	// The restarted worker resumes after the dead-lettered order event
+	partition := consume()
+	suite.Len(broker.Messages("ORDER_DLQ", 0), 1)
+	suite.Equal(int64(1), partition.LastOffset())
+	engine, err := partition.Engine("AAPL")
+	suite.Require().NoError(err)
+	_, ok := engine.matcher.Order("aapl1")
+	suite.True(ok)
+}
@@ -0,0 +1,130 @@
+package matchingengine
+
+const (
+	// priceIndexMaxHeight bounds the skip list height, enough for 4^16 price levels
+	priceIndexMaxHeight = 16
+	// priceIndexBranching is the inverse probability of promoting a level to the next height
+	priceIndexBranching = 4
+)
+
+// priceIndex is a skip list keeping the PriceLevels of one side sorted from the best price.
+// The bottom layer is the PriceLevel.Prev/Next linked list, so the best level is the head
+// and walking the book stays a linked list traversal.
+type priceIndex struct {
+	// heads are the forward pointers of the sentinel, heads[0] is the best level
+	heads  [priceIndexMaxHeight]*PriceLevel
+	height int
+	// better reports whether price1 ranks before price2
+	better func(price1, price2 float64) bool
+	seed   uint64
+}
+
+func newPriceIndex(better func(price1, price2 float64) bool) *priceIndex {
+	return &priceIndex{
+		height: 1,
+		better: better,
+		seed:   0x9E3779B97F4A7C15,
+	}
+}
+
+// Best returns the best PriceLevel, nil if the side is empty
+func (pi *priceIndex) Best() *PriceLevel {
+	return pi.heads[0]
+}
+
+// Insert links a new PriceLevel in price order in O(log n)
+func (pi *priceIndex) Insert(pl *PriceLevel) {
+	var update [priceIndexMaxHeight]*PriceLevel
+	pi.predecessors(pl.Price, &update)
+
+	height := pi.randomHeight()
+	if height > pi.height {
+		// update is already nil, i.e. the sentinel, above the current height
+		pi.height = height
+	}
+
+	if cap(pl.forward) >= height-1 {
+		pl.forward = pl.forward[:height-1]
+	} else {
+		pl.forward = make([]*PriceLevel, height-1)
+	}
+	for i := 0; i < height; i++ {
+		pi.setNext(pl, i, pi.next(update[i], i))
+		pi.setNext(update[i], i, pl)
+	}
+
+	pl.Prev = update[0]
+	if pl.Next != nil {
+		pl.Next.Prev = pl
+	}
+}
+
+// Remove unlinks a PriceLevel in O(log n)
+func (pi *priceIndex) Remove(pl *PriceLevel) {
+	var update [priceIndexMaxHeight]*PriceLevel
+	pi.predecessors(pl.Price, &update)
+
+	for i := 0; i <= len(pl.forward); i++ {
+		if pi.next(update[i], i) == pl {
+			pi.setNext(update[i], i, pi.next(pl, i))
+		}
+	}
+	for pi.height > 1 && pi.heads[pi.height-1] == nil {
+		pi.height--
+	}
+
+	if pl.Next != nil {
+		pl.Next.Prev = pl.Prev
+	}
+	pl.Prev, pl.Next = nil, nil
+}
+
+// predecessors fills update with the last PriceLevel ranked before the price at every height,
+// nil stands for the sentinel
+func (pi *priceIndex) predecessors(price float64, update *[priceIndexMaxHeight]*PriceLevel) {
+	var current *PriceLevel
+	for i := pi.height - 1; i >= 0; i-- {
+		for next := pi.next(current, i); next != nil && pi.better(next.Price, price); next = pi.next(current, i) {
+			current = next
+		}
+		update[i] = current
+	}
+}
+
+// next returns the following PriceLevel at a height, the bottom height is PriceLevel.Next
+func (pi *priceIndex) next(pl *PriceLevel, i int) *PriceLevel {
+	switch {
+	case pl == nil:
+		return pi.heads[i]
+	case i == 0:
+		return pl.Next
+	default:
+		return pl.forward[i-1]
+	}
+}
+
+func (pi *priceIndex) setNext(pl *PriceLevel, i int, next *PriceLevel) {
+	switch {
+	case pl == nil:
+		pi.heads[i] = next
+	case i == 0:
+		pl.Next = next
+	default:
+		pl.forward[i-1] = next
+	}
+}
+
+// randomHeight draws a geometric height with a xorshift generator, deterministic per index
+func (pi *priceIndex) randomHeight() int {
+	height := 1
+	for height < priceIndexMaxHeight {
+		pi.seed ^= pi.seed << 13
+		pi.seed ^= pi.seed >> 7
+		pi.seed ^= pi.seed << 17
+		if pi.seed%priceIndexBranching != 0 {
+			break
+		}
+		height++
+	}
+	return height
+}
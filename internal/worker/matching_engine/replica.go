@@ -0,0 +1,106 @@
+package matchingengine
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+)
+
+var (
+	ErrReplicaGap = errors.New("matching events were lost between the previous leader and the standby")
+)
+
+// Replica publishes the matching events of the leader replica only. Every replica applies the
+// same order topic and numbers the same matching events, so a standby keeps its latest matching
+// events to publish the ones the previous leader did not when it is promoted.
+type Replica struct {
+	mu     sync.Mutex
+	leader bool
+	// publishedSeq is the sequence number of the latest published matching event
+	publishedSeq uint64
+	// pending are the latest matching events suppressed by a standby, at most maxPending
+	pending    []events.MatchingEvent
+	maxPending int
+	publish    func([]events.MatchingEvent) error
+}
+
+// NewReplica creates a standby Replica which publishes the matching events in batches
+func NewReplica(publish func([]events.MatchingEvent) error, maxPending int) *Replica {
+	return &Replica{
+		publish:    publish,
+		maxPending: maxPending,
+	}
+}
+
+// IsLeader reports whether the replica publishes its matching events
+func (r *Replica) IsLeader() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.leader
+}
+
+// Publish publishes the matching events if the replica is the leader, otherwise keeps them. The
+// matching events failed to be published are kept and published first by the next call.
+func (r *Replica) Publish(matchingEvents []events.MatchingEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pending = append(r.pending, matchingEvents...)
+	if r.leader {
+		return r.flush()
+	}
+
+	if over := len(r.pending) - r.maxPending; over > 0 {
+		r.pending = append(r.pending[:0], r.pending[over:]...)
+	}
+	return nil
+}
+
+// Promote makes the replica the leader from the next matching event after lastPublishedSeq, the
+// latest one published by the previous leader. The kept matching events after it are published
+// first, and the ones a lagging replica has not applied yet are published as it catches up.
+// If the kept matching events do not reach back to lastPublishedSeq, or publishing fails, the
+// replica stays a standby and publishes nothing, the former returns ErrReplicaGap.
+func (r *Replica) Promote(lastPublishedSeq uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.leader {
+		return nil
+	}
+
+	publishedSeq := max(r.publishedSeq, lastPublishedSeq)
+	if len(r.pending) > 0 && r.pending[0].Seq > publishedSeq+1 {
+		return fmt.Errorf("%w: published up to %d, kept from %d", ErrReplicaGap, publishedSeq, r.pending[0].Seq)
+	}
+
+	r.publishedSeq = publishedSeq
+	if err := r.flush(); err != nil {
+		return err
+	}
+	r.leader = true
+	return nil
+}
+
+// flush publishes the kept matching events which are not published yet in a batch, and keeps
+// them after a failure
+func (r *Replica) flush() error {
+	i := 0
+	for i < len(r.pending) && r.pending[i].Seq <= r.publishedSeq {
+		i++
+	}
+	r.pending = append(r.pending[:0], r.pending[i:]...)
+	if len(r.pending) == 0 {
+		return nil
+	}
+
+	if err := r.publish(r.pending); err != nil {
+		return err
+	}
+	r.publishedSeq = r.pending[len(r.pending)-1].Seq
+	r.pending = r.pending[:0]
+	return nil
+}
@@ -0,0 +1,125 @@
+package matchingengine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+)
+
+type ReplicaTestSuite struct {
+	suite.Suite
+	replica    *Replica
+	published  []uint64
+	batches    int
+	publishErr error
+}
+
+func TestReplicaTestSuite(t *testing.T) {
+	suite.Run(t, new(ReplicaTestSuite))
+}
+
+func (suite *ReplicaTestSuite) SetupTest() {
+	suite.published = nil
+	suite.batches = 0
+	suite.publishErr = nil
+	suite.replica = NewReplica(func(matchingEvents []events.MatchingEvent) error {
+		if suite.publishErr != nil {
+			return suite.publishErr
+		}
+		for _, matchingEvent := range matchingEvents {
+			suite.published = append(suite.published, matchingEvent.Seq)
+		}
+		suite.batches++
+		return nil
+	}, 3)
+}
+
+func matchingEvents(seqs ...uint64) []events.MatchingEvent {
+	result := make([]events.MatchingEvent, 0, len(seqs))
+	for _, seq := range seqs {
+		result = append(result, events.MatchingEvent{Seq: seq})
+	}
+	return result
+}
+
+func (suite *ReplicaTestSuite) TestPromote_AheadOfLeader() {
+	suite.NoError(suite.replica.Publish(matchingEvents(1, 2, 3)))
+	suite.NoError(suite.replica.Publish(matchingEvents(4)))
+	suite.False(suite.replica.IsLeader())
+	suite.Empty(suite.published)
+
+	// The previous leader died after publishing 2
+	suite.NoError(suite.replica.Promote(2))
+	suite.True(suite.replica.IsLeader())
+	suite.Equal([]uint64{3, 4}, suite.published)
+
+	suite.NoError(suite.replica.Publish(matchingEvents(5)))
+	suite.Equal([]uint64{3, 4, 5}, suite.published)
+}
+
+func (suite *ReplicaTestSuite) TestPromote_BehindLeader() {
+	suite.NoError(suite.replica.Publish(matchingEvents(1)))
+
+	// The previous leader already published up to 3
+	suite.NoError(suite.replica.Promote(3))
+	suite.Empty(suite.published)
+
+	suite.NoError(suite.replica.Publish(matchingEvents(2, 3, 4)))
+	suite.Equal([]uint64{4}, suite.published)
+}
+
+func (suite *ReplicaTestSuite) TestPromote_Gap() {
+	suite.NoError(suite.replica.Publish(matchingEvents(1, 2, 3, 4, 5)))
+
+	// Only 3 to 5 are kept, so 2 would be lost
+	suite.ErrorIs(suite.replica.Promote(1), ErrReplicaGap)
+	suite.False(suite.replica.IsLeader())
+	suite.Empty(suite.published)
+
+	// Another replica published up to 2 in the meantime
+	suite.NoError(suite.replica.Promote(2))
+	suite.True(suite.replica.IsLeader())
+	suite.Equal([]uint64{3, 4, 5}, suite.published)
+}
+
+func (suite *ReplicaTestSuite) TestPromote_PublishFailure() {
+	suite.NoError(suite.replica.Publish(matchingEvents(1, 2)))
+
+	suite.publishErr = errors.New("broker unavailable")
+	suite.Error(suite.replica.Promote(0))
+	suite.False(suite.replica.IsLeader())
+
+	suite.publishErr = nil
+	suite.NoError(suite.replica.Promote(0))
+	suite.Equal([]uint64{1, 2}, suite.published)
+}
+
+func (suite *ReplicaTestSuite) TestPublish_RetriesFailed() {
+	suite.NoError(suite.replica.Promote(0))
+
+	suite.publishErr = errors.New("broker unavailable")
+	suite.Error(suite.replica.Publish(matchingEvents(1, 2)))
+
+	// A redelivered order event is skipped by the Engine, so nothing new is published
+	suite.publishErr = nil
+	suite.NoError(suite.replica.Publish(nil))
+	suite.Equal([]uint64{1, 2}, suite.published)
+
+	suite.NoError(suite.replica.Publish(matchingEvents(2, 3)))
+	suite.Equal([]uint64{1, 2, 3}, suite.published)
+}
+
+func (suite *ReplicaTestSuite) TestPublish_Batch() {
+	suite.NoError(suite.replica.Publish(matchingEvents(1, 2, 3)))
+	suite.NoError(suite.replica.Promote(1))
+	suite.Equal(1, suite.batches)
+
+	// Only the unpublished matching events are in the batch, and an empty batch is not published
+	suite.NoError(suite.replica.Publish(matchingEvents(3, 4, 5)))
+	suite.NoError(suite.replica.Publish(matchingEvents(5)))
+	suite.Equal([]uint64{2, 3, 4, 5}, suite.published)
+	suite.Equal(2, suite.batches)
+}
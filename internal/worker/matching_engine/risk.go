@@ -0,0 +1,106 @@
+//go:generate go-enum --marshal
+package matchingengine
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrOrderRejected = errors.New("order rejected")
+)
+
+// ENUM(PriceCollar, MaxNotional, MaxQuantity, Halted, SessionClosed, UnknownOrder)
+type RejectRule string
+
+// RejectionError reports the rule that rejected an order
+type RejectionError struct {
+	Rule    RejectRule
+	Message string
+}
+
+func (e *RejectionError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Rule, e.Message)
+}
+
+func (e *RejectionError) Unwrap() error {
+	return ErrOrderRejected
+}
+
+// RiskLimits are the pre-trade limits of an order, a zero value disables the limit
+type RiskLimits struct {
+	// MaxPriceDeviationPct is the percentage the price may deviate from the reference price
+	MaxPriceDeviationPct float64 `json:"max_price_deviation_pct"`
+	// MaxNotional is the maximum price * quantity of an order
+	MaxNotional float64 `json:"max_notional"`
+	// MaxQuantity is the maximum quantity of an order
+	MaxQuantity int64 `json:"max_quantity"`
+}
+
+// RiskConfig holds the limits by symbol and by account
+type RiskConfig struct {
+	// Default applies to the symbols which are not listed in Symbols
+	Default RiskLimits `json:"default"`
+	// Symbols maps Symbol to its RiskLimits
+	Symbols map[string]RiskLimits `json:"symbols"`
+	// Accounts maps AccountID to the RiskLimits checked on top of the symbol ones
+	Accounts map[string]RiskLimits `json:"accounts"`
+}
+
+// RiskChecker rejects the orders which break the configured limits before they are matched
+type RiskChecker struct {
+	config RiskConfig
+}
+
+func NewRiskChecker(config RiskConfig) *RiskChecker {
+	return &RiskChecker{
+		config: config,
+	}
+}
+
+// Check returns a RejectionError if the order breaks a limit of its symbol or its account.
+// The price collar is skipped if the reference price is unknown.
+func (rc *RiskChecker) Check(order Order, referencePrice float64) error {
+	limits, exists := rc.config.Symbols[order.Symbol]
+	if !exists {
+		limits = rc.config.Default
+	}
+	if err := checkLimits(limits, order, referencePrice); err != nil {
+		return err
+	}
+
+	if limits, exists := rc.config.Accounts[order.AccountID]; exists {
+		return checkLimits(limits, order, referencePrice)
+	}
+	return nil
+}
+
+// checkLimits checks the quantity, the notional and the price collar of an order
+func checkLimits(limits RiskLimits, order Order, referencePrice float64) error {
+	if limits.MaxQuantity > 0 && order.Quantity > limits.MaxQuantity {
+		return &RejectionError{
+			Rule:    RejectRuleMaxQuantity,
+			Message: fmt.Sprintf("quantity %d exceeds %d", order.Quantity, limits.MaxQuantity),
+		}
+	}
+
+	notional := order.Price * float64(order.Quantity)
+	if limits.MaxNotional > 0 && notional > limits.MaxNotional {
+		return &RejectionError{
+			Rule:    RejectRuleMaxNotional,
+			Message: fmt.Sprintf("notional %v exceeds %v", notional, limits.MaxNotional),
+		}
+	}
+
+	if limits.MaxPriceDeviationPct > 0 && referencePrice > 0 {
+		deviationPct := math.Abs(order.Price-referencePrice) / referencePrice * 100
+		if deviationPct > limits.MaxPriceDeviationPct {
+			return &RejectionError{
+				Rule:    RejectRulePriceCollar,
+				Message: fmt.Sprintf("price %v deviates %.2f%% from %v", order.Price, deviationPct, referencePrice),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package matchingengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RiskCheckerTestSuite struct {
+	suite.Suite
+	riskChecker *RiskChecker
+	symbol      string
+}
+
+func TestRiskCheckerTestSuite(t *testing.T) {
+	suite.Run(t, new(RiskCheckerTestSuite))
+}
+
+func (suite *RiskCheckerTestSuite) SetupTest() {
+	suite.symbol = "AAPL"
+	suite.riskChecker = NewRiskChecker(RiskConfig{
+		Default: RiskLimits{
+			MaxPriceDeviationPct: 10,
+			MaxQuantity:          1000,
+		},
+		Symbols: map[string]RiskLimits{
+			"MSFT": {MaxQuantity: 10},
+		},
+		Accounts: map[string]RiskLimits{
+			"account1": {MaxNotional: 5000},
+		},
+	})
+}
+
+func (suite *RiskCheckerTestSuite) assertRejected(err error, rule RejectRule) {
+	var rejection *RejectionError
+	suite.Require().ErrorAs(err, &rejection)
+	suite.ErrorIs(err, ErrOrderRejected)
+	suite.Equal(rule, rejection.Rule)
+}
+
+func (suite *RiskCheckerTestSuite) TestCheck() {
+	order := Order{
+		ID:        "order1",
+		AccountID: "account2",
+		Symbol:    suite.symbol,
+		Type:      OrderTypeBuy,
+		Price:     100.0,
+		Quantity:  100,
+	}
+	suite.NoError(suite.riskChecker.Check(order, 95.0))
+
+	// No reference price yet: the price collar is skipped
+	order.Price = 1000.0
+	suite.NoError(suite.riskChecker.Check(order, 0))
+	suite.assertRejected(suite.riskChecker.Check(order, 100.0), RejectRulePriceCollar)
+
+	order.Price = 100.0
+	order.Quantity = 1001
+	suite.assertRejected(suite.riskChecker.Check(order, 100.0), RejectRuleMaxQuantity)
+}
+
+func (suite *RiskCheckerTestSuite) TestCheck_SymbolLimits() {
+	order := Order{
+		ID:        "order1",
+		AccountID: "account2",
+		Symbol:    "MSFT",
+		Type:      OrderTypeSell,
+		Price:     1000.0,
+		Quantity:  11,
+	}
+	suite.assertRejected(suite.riskChecker.Check(order, 100.0), RejectRuleMaxQuantity)
+
+	// The default price collar does not apply to a listed symbol
+	order.Quantity = 10
+	suite.NoError(suite.riskChecker.Check(order, 100.0))
+}
+
+func (suite *RiskCheckerTestSuite) TestCheck_AccountLimits() {
+	order := Order{
+		ID:        "order1",
+		AccountID: "account1",
+		Symbol:    suite.symbol,
+		Type:      OrderTypeBuy,
+		Price:     100.0,
+		Quantity:  51,
+	}
+	suite.assertRejected(suite.riskChecker.Check(order, 100.0), RejectRuleMaxNotional)
+
+	order.Quantity = 50
+	suite.NoError(suite.riskChecker.Check(order, 100.0))
+}
@@ -0,0 +1,205 @@
+//go:generate go-enum --marshal
+package matchingengine
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	sessionDateLayout = "2006-01-02"
+	sessionTimeLayout = "15:04"
+)
+
+// SessionPhase changes what the Matcher accepts: the pre-open and closing phases collect the
+// orders in a call auction, the continuous phase matches them, and the closed phase rejects them
+// and cancels the day orders as it starts. Every order is a limit order, so the pre-open rejection
+// of market orders is deferred until the Matcher supports them.
+// ENUM(PreOpen, Continuous, Closing, Closed)
+type SessionPhase string
+
+// SessionCalendar is the trading calendar of a symbol. The times are "HH:MM" in the Timezone,
+// an empty PreOpen or Closing skips the opening or closing auction.
+type SessionCalendar struct {
+	Timezone string `json:"timezone"`
+	PreOpen  string `json:"pre_open"`
+	Open     string `json:"open"`
+	Closing  string `json:"closing"`
+	Close    string `json:"close"`
+	// Weekdays are the trading days, Monday to Friday if empty
+	Weekdays []time.Weekday `json:"weekdays"`
+	// Holidays are the "YYYY-MM-DD" dates without trading
+	Holidays []string `json:"holidays"`
+}
+
+// SessionChange represents a transition of the session phase
+type SessionChange struct {
+	Phase SessionPhase
+}
+
+// SessionScheduler resolves the session phase of a time from a SessionCalendar
+type SessionScheduler struct {
+	location *time.Location
+	// preOpen, open, closing and close are the minutes since midnight
+	preOpen, open, closing, close int
+	weekdays                      map[time.Weekday]bool
+	holidays                      map[string]bool
+}
+
+// NewSessionScheduler validates the calendar and returns a SessionScheduler
+func NewSessionScheduler(calendar SessionCalendar) (*SessionScheduler, error) {
+	location, err := time.LoadLocation(calendar.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	ss := &SessionScheduler{
+		location: location,
+		weekdays: make(map[time.Weekday]bool),
+		holidays: make(map[string]bool),
+	}
+
+	if ss.open, err = parseSessionTime(calendar.Open, -1); err != nil {
+		return nil, err
+	}
+	if ss.close, err = parseSessionTime(calendar.Close, -1); err != nil {
+		return nil, err
+	}
+	if ss.preOpen, err = parseSessionTime(calendar.PreOpen, ss.open); err != nil {
+		return nil, err
+	}
+	if ss.closing, err = parseSessionTime(calendar.Closing, ss.close); err != nil {
+		return nil, err
+	}
+	if !(ss.preOpen <= ss.open && ss.open < ss.closing && ss.closing <= ss.close) {
+		return nil, fmt.Errorf("invalid session times: %s %s %s %s", calendar.PreOpen, calendar.Open, calendar.Closing, calendar.Close)
+	}
+
+	weekdays := calendar.Weekdays
+	if len(weekdays) == 0 {
+		weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	}
+	for _, weekday := range weekdays {
+		ss.weekdays[weekday] = true
+	}
+
+	for _, holiday := range calendar.Holidays {
+		if _, err := time.Parse(sessionDateLayout, holiday); err != nil {
+			return nil, err
+		}
+		ss.holidays[holiday] = true
+	}
+	return ss, nil
+}
+
+// Phase returns the session phase at a time
+func (ss *SessionScheduler) Phase(t time.Time) SessionPhase {
+	local := t.In(ss.location)
+	if !ss.weekdays[local.Weekday()] || ss.holidays[local.Format(sessionDateLayout)] {
+		return SessionPhaseClosed
+	}
+
+	minutes := local.Hour()*60 + local.Minute()
+	switch {
+	case minutes < ss.preOpen:
+		return SessionPhaseClosed
+	case minutes < ss.open:
+		return SessionPhasePreOpen
+	case minutes < ss.closing:
+		return SessionPhaseContinuous
+	case minutes < ss.close:
+		return SessionPhaseClosing
+	default:
+		return SessionPhaseClosed
+	}
+}
+
+// parseSessionTime parses "HH:MM" to the minutes since midnight, an empty value returns the fallback
+func parseSessionTime(value string, fallback int) (int, error) {
+	if value == "" && fallback >= 0 {
+		return fallback, nil
+	}
+
+	t, err := time.Parse(sessionTimeLayout, value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Phase returns the current session phase, empty if no session is scheduled yet
+func (me *Matcher) Phase() SessionPhase {
+	return me.phase
+}
+
+// tickSession moves the Matcher to the session phase of the current time: the pre-open and
+// closing phases run a call auction, and the close cancels the day orders
+func (me *Matcher) tickSession() []Matching {
+	phase := me.sessionScheduler.Phase(me.now())
+	if phase == me.phase {
+		return nil
+	}
+	me.phase = phase
+
+	var matchings []Matching
+	switch phase {
+	case SessionPhasePreOpen, SessionPhaseClosing:
+		if me.status == TradingStatusAuction {
+			break
+		}
+		reason := "opening auction"
+		if phase == SessionPhaseClosing {
+			reason = "closing auction"
+		}
+		matching, err := me.StartAuction(reason, time.Time{})
+		if err == nil {
+			matchings = append(matchings, matching)
+		}
+	case SessionPhaseContinuous, SessionPhaseClosed:
+		if me.status == TradingStatusAuction {
+			matching, err := me.Uncross()
+			if err == nil {
+				matchings = append(matchings, matching)
+			}
+		}
+		if phase == SessionPhaseClosed {
+			matchings = append(matchings, me.cancelDayOrders()...)
+		}
+	}
+
+	var matching Matching
+	matching.Session = &SessionChange{Phase: phase}
+	matching.BuyTicks, matching.SellTicks = me.orderBook.GetTopTicks(me.tickNum)
+	return append(matchings, matching)
+}
+
+// cancelDayOrders cancels the resting and queued day orders in price-time priority
+func (me *Matcher) cancelDayOrders() []Matching {
+	var dayOrders []Order
+	for _, head := range []*PriceLevel{me.orderBook.BuyLevels, me.orderBook.SellLevels} {
+		for level := head; level != nil; level = level.Next {
+			for node := level.HeadOrders; node != nil; node = node.Next {
+				if node.Order.TimeInForce == TimeInForceDay {
+					dayOrders = append(dayOrders, node.Order)
+				}
+			}
+		}
+	}
+	for _, order := range me.queuedOrders {
+		if order.TimeInForce == TimeInForceDay {
+			dayOrders = append(dayOrders, order)
+		}
+	}
+
+	matchings := make([]Matching, 0, len(dayOrders))
+	for _, order := range dayOrders {
+		matching, err := me.CancelOrder(order.ID)
+		if err != nil {
+			continue
+		}
+		matching.Type = MatchingTypeCancel
+		matching.Order = order
+		matchings = append(matchings, matching)
+	}
+	return matchings
+}
@@ -0,0 +1,179 @@
+package matchingengine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SessionTestSuite struct {
+	suite.Suite
+	scheduler *SessionScheduler
+	location  *time.Location
+	symbol    string
+}
+
+func TestSessionTestSuite(t *testing.T) {
+	suite.Run(t, new(SessionTestSuite))
+}
+
+func (suite *SessionTestSuite) SetupTest() {
+	var err error
+	suite.symbol = "AAPL"
+	suite.location, err = time.LoadLocation("America/New_York")
+	suite.Require().NoError(err)
+
+	suite.scheduler, err = NewSessionScheduler(SessionCalendar{
+		Timezone: "America/New_York",
+		PreOpen:  "09:00",
+		Open:     "09:30",
+		Closing:  "15:50",
+		Close:    "16:00",
+		Holidays: []string{"2025-01-20"},
+	})
+	suite.Require().NoError(err)
+}
+
+func (suite *SessionTestSuite) at(day, hour, minute int) time.Time {
+	return time.Date(2025, 1, day, hour, minute, 0, 0, suite.location)
+}
+
+func (suite *SessionTestSuite) TestNewSessionScheduler_Invalid() {
+	_, err := NewSessionScheduler(SessionCalendar{Timezone: "Unknown/Zone", Open: "09:30", Close: "16:00"})
+	suite.Error(err)
+
+	_, err = NewSessionScheduler(SessionCalendar{Timezone: "UTC", Open: "16:00", Close: "09:30"})
+	suite.Error(err)
+
+	_, err = NewSessionScheduler(SessionCalendar{Timezone: "UTC", Open: "9h30", Close: "16:00"})
+	suite.Error(err)
+}
+
+func (suite *SessionTestSuite) TestPhase() {
+	// 2025-01-21 is a Tuesday
+	suite.Equal(SessionPhaseClosed, suite.scheduler.Phase(suite.at(21, 8, 59)))
+	suite.Equal(SessionPhasePreOpen, suite.scheduler.Phase(suite.at(21, 9, 0)))
+	suite.Equal(SessionPhaseContinuous, suite.scheduler.Phase(suite.at(21, 9, 30)))
+	suite.Equal(SessionPhaseClosing, suite.scheduler.Phase(suite.at(21, 15, 55)))
+	suite.Equal(SessionPhaseClosed, suite.scheduler.Phase(suite.at(21, 16, 0)))
+
+	// The phase is resolved in the calendar timezone
+	suite.Equal(SessionPhaseContinuous, suite.scheduler.Phase(time.Date(2025, 1, 21, 15, 0, 0, 0, time.UTC)))
+
+	// Weekends and holidays are closed
+	suite.Equal(SessionPhaseClosed, suite.scheduler.Phase(suite.at(18, 10, 0)))
+	suite.Equal(SessionPhaseClosed, suite.scheduler.Phase(suite.at(20, 10, 0)))
+}
+
+func (suite *SessionTestSuite) TestMatcherTick() {
+	current := suite.at(21, 8, 0)
+	now = func() time.Time {
+		return current
+	}
+	defer func() {
+		now = time.Now
+	}()
+
+	matcher := NewMatcher(NewOrderBook(), 5, WithSessionScheduler(suite.scheduler))
+	matchings := matcher.Tick()
+	suite.Require().Len(matchings, 1)
+	suite.Equal(&SessionChange{Phase: SessionPhaseClosed}, matchings[0].Session)
+
+	_, err := matcher.CreateOrder(Order{ID: "buy1", Symbol: suite.symbol, Type: OrderTypeBuy, Price: 100.0, Quantity: 10})
+	suite.ErrorIs(err, ErrOrderRejected)
+
+	// The pre-open accumulates the orders in an opening auction
+	current = suite.at(21, 9, 0)
+	matchings = matcher.Tick()
+	suite.Require().Len(matchings, 2)
+	suite.Equal(TradingStatusAuction, matchings[0].Status.Status)
+	suite.Equal(SessionPhasePreOpen, matchings[1].Session.Phase)
+	suite.Empty(matcher.Tick())
+
+	for _, order := range []Order{
+		{ID: "buy1", Type: OrderTypeBuy, Price: 100.0, Quantity: 10, TimeInForce: TimeInForceDay},
+		{ID: "buy2", Type: OrderTypeBuy, Price: 99.0, Quantity: 10, TimeInForce: TimeInForceGTC},
+		{ID: "sell1", Type: OrderTypeSell, Price: 100.0, Quantity: 5, TimeInForce: TimeInForceGTC},
+	} {
+		order.Symbol = suite.symbol
+		matching, err := matcher.CreateOrder(order)
+		suite.Require().NoError(err)
+		suite.Empty(matching.Transactions)
+	}
+
+	// The open uncrosses the auction
+	current = suite.at(21, 9, 30)
+	matchings = matcher.Tick()
+	suite.Require().Len(matchings, 2)
+	suite.Len(matchings[0].Transactions, 1)
+	suite.Equal(TradingStatusContinuous, matchings[0].Status.Status)
+	suite.Equal(SessionPhaseContinuous, matchings[1].Session.Phase)
+
+	current = suite.at(21, 15, 50)
+	matchings = matcher.Tick()
+	suite.Require().Len(matchings, 2)
+	suite.Equal(TradingStatusAuction, matchings[0].Status.Status)
+	suite.Equal(SessionPhaseClosing, matchings[1].Session.Phase)
+
+	// The close uncrosses the closing auction and cancels the day orders
+	current = suite.at(21, 16, 0)
+	matchings = matcher.Tick()
+	suite.Require().Len(matchings, 3)
+	suite.Empty(matchings[0].Transactions)
+	suite.Equal(MatchingTypeCancel, matchings[1].Type)
+	suite.Equal("buy1", matchings[1].Order.ID)
+	suite.Equal(int64(5), matchings[1].Order.Quantity)
+	suite.Equal(SessionPhaseClosed, matchings[2].Session.Phase)
+	suite.Equal([]Tick{{Price: 99.0, Quantity: 10}}, matchings[2].BuyTicks)
+	suite.Equal(SessionPhaseClosed, matcher.Phase())
+}
+
+func (suite *SessionTestSuite) TestMatcherResume_SessionClosed() {
+	current := suite.at(21, 15, 0)
+	now = func() time.Time {
+		return current
+	}
+	defer func() {
+		now = time.Now
+	}()
+
+	scheduler, err := NewSessionScheduler(SessionCalendar{
+		Timezone: "America/New_York",
+		Open:     "09:30",
+		Close:    "16:00",
+	})
+	suite.Require().NoError(err)
+	matcher := NewMatcher(NewOrderBook(), 5, WithSessionScheduler(scheduler), WithCircuitBreaker(CircuitBreakerConfig{
+		BandPct:     10,
+		Window:      time.Minute,
+		QueueOrders: true,
+	}))
+	matcher.Tick()
+
+	_, err = matcher.Halt("news")
+	suite.Require().NoError(err)
+	for _, id := range []string{"buy1", "buy2"} {
+		matching, err := matcher.CreateOrder(Order{ID: id, Symbol: suite.symbol, Type: OrderTypeBuy, Price: 100.0, Quantity: 10})
+		suite.Require().NoError(err)
+		suite.True(matching.Queued)
+	}
+
+	// The session closes during the halt, so every queued order is rejected as the trading resumes
+	current = suite.at(21, 16, 0)
+	matchings := matcher.Tick()
+	suite.Require().Len(matchings, 1)
+	suite.Equal(SessionPhaseClosed, matchings[0].Session.Phase)
+
+	matchings, err = matcher.Resume()
+	suite.Require().NoError(err)
+	suite.Require().Len(matchings, 3)
+	suite.Equal(TradingStatusContinuous, matchings[0].Status.Status)
+	for i, id := range []string{"buy1", "buy2"} {
+		suite.Equal(id, matchings[i+1].Order.ID)
+		suite.Require().NotNil(matchings[i+1].Rejection)
+		suite.Equal(RejectRuleSessionClosed, matchings[i+1].Rejection.Rule)
+	}
+	suite.Empty(matcher.queuedOrders)
+	suite.Nil(matcher.orderBook.BuyLevels)
+}
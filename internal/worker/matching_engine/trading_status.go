@@ -0,0 +1,169 @@
+//go:generate go-enum --marshal
+package matchingengine
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrAlreadyHalted    = errors.New("trading is already halted")
+	ErrNotHalted        = errors.New("trading is not halted")
+	ErrAlreadyInAuction = errors.New("trading is already in auction")
+	ErrInAuction        = errors.New("trading is in auction")
+)
+
+// ENUM(Continuous, Halted, Auction)
+type TradingStatus string
+
+// StatusChange represents a transition of the trading status
+type StatusChange struct {
+	Status TradingStatus
+	Reason string
+	// ResumeAt is when a halted trading resumes, zero if it must be resumed manually
+	ResumeAt time.Time
+}
+
+// Status returns the current trading status
+func (me *Matcher) Status() TradingStatus {
+	return me.status
+}
+
+// Halt stops matching new orders until Resume is called. An auction cannot be halted, it
+// must be uncrossed first so the book is not left crossed when the trading resumes.
+func (me *Matcher) Halt(reason string) (Matching, error) {
+	if me.status == TradingStatusHalted {
+		return Matching{}, ErrAlreadyHalted
+	}
+	if me.status == TradingStatusAuction {
+		return Matching{}, ErrInAuction
+	}
+
+	return me.halt(reason, time.Time{}), nil
+}
+
+// Resume restarts the trading and matches the orders queued while halted. The first Matching
+// carries the status change, followed by one Matching per queued order. A queued order which
+// is rejected, e.g. as the session closed during the halt, has a Matching with its Rejection.
+// If a queued order trips the circuit breaker again, the remaining orders stay queued.
+// Resuming an auction uncrosses it.
+func (me *Matcher) Resume() ([]Matching, error) {
+	if me.status == TradingStatusAuction {
+		matching, err := me.Uncross()
+		return []Matching{matching}, err
+	}
+	if me.status != TradingStatusHalted {
+		return nil, ErrNotHalted
+	}
+
+	me.status = TradingStatusContinuous
+	me.resumeAt = time.Time{}
+	if me.circuitBreaker != nil {
+		me.circuitBreaker.Reset()
+	}
+
+	var resumed Matching
+	resumed.Status = &StatusChange{Status: TradingStatusContinuous, Reason: "resumed"}
+	resumed.BuyTicks, resumed.SellTicks = me.orderBook.GetTopTicks(me.tickNum)
+	matchings := []Matching{resumed}
+
+	for len(me.queuedOrders) > 0 && me.status == TradingStatusContinuous {
+		order := me.queuedOrders[0]
+		me.queuedOrders = me.queuedOrders[1:]
+
+		matching, err := me.CreateOrder(order)
+		var rejection *RejectionError
+		if errors.As(err, &rejection) {
+			matching = Matching{Order: order, Rejection: rejection}
+		} else if err != nil {
+			return matchings, err
+		}
+		matchings = append(matchings, matching)
+	}
+	return matchings, nil
+}
+
+// Tick drives the time based transitions: it moves to the scheduled session phase, resumes
+// the trading once the cooldown of a circuit breaker halt has passed, and publishes the
+// indicative or uncrosses an auction
+func (me *Matcher) Tick() []Matching {
+	var matchings []Matching
+	if me.sessionScheduler != nil {
+		matchings = me.tickSession()
+	}
+	return append(matchings, me.tickStatus()...)
+}
+
+// tickDue reports whether a Tick at the time of the Matcher would change its state, i.e. move
+// to another session phase, publish the indicative or uncross an auction, or end a halt
+func (me *Matcher) tickDue() bool {
+	current := me.now()
+	if me.sessionScheduler != nil && me.sessionScheduler.Phase(current) != me.phase {
+		return true
+	}
+
+	switch me.status {
+	case TradingStatusAuction:
+		if !me.auctionEndsAt.IsZero() && !current.Before(me.auctionEndsAt) {
+			return true
+		}
+		return me.auctionConfig.IndicativeInterval > 0 && current.Sub(me.lastIndicativeAt) >= me.auctionConfig.IndicativeInterval
+	case TradingStatusHalted:
+		return !me.resumeAt.IsZero() && !current.Before(me.resumeAt)
+	}
+	return false
+}
+
+// tickStatus drives the time based transitions of the trading status
+func (me *Matcher) tickStatus() []Matching {
+	if me.status == TradingStatusAuction {
+		return me.tickAuction()
+	}
+
+	if me.status != TradingStatusHalted || me.resumeAt.IsZero() || me.now().Before(me.resumeAt) {
+		return nil
+	}
+
+	// Re-open with an auction which collects the queued orders
+	if me.circuitBreaker.config.ReopenAuction > 0 {
+		me.circuitBreaker.Reset()
+		matching, _ := me.StartAuction("re-opening auction", me.now().Add(me.circuitBreaker.config.ReopenAuction))
+		return []Matching{matching}
+	}
+
+	matchings, _ := me.Resume()
+	return matchings
+}
+
+// checkCircuitBreaker halts the trading if trading at the price breaches the band
+func (me *Matcher) checkCircuitBreaker(price float64) *StatusChange {
+	if me.circuitBreaker == nil || !me.circuitBreaker.Breaches(price, me.now()) {
+		return nil
+	}
+
+	reason := fmt.Sprintf("price %v breached the %v%% band", price, me.circuitBreaker.config.BandPct)
+	return me.halt(reason, me.now().Add(me.circuitBreaker.config.Cooldown)).Status
+}
+
+// halt switches the trading status to halted
+func (me *Matcher) halt(reason string, resumeAt time.Time) Matching {
+	me.status = TradingStatusHalted
+	me.resumeAt = resumeAt
+
+	var matching Matching
+	matching.Status = &StatusChange{Status: TradingStatusHalted, Reason: reason, ResumeAt: resumeAt}
+	matching.BuyTicks, matching.SellTicks = me.orderBook.GetTopTicks(me.tickNum)
+	return matching
+}
+
+// dequeueOrder removes an order queued while halted, it reports whether the order was queued
+func (me *Matcher) dequeueOrder(orderID string) bool {
+	for i, order := range me.queuedOrders {
+		if order.ID == orderID {
+			me.queuedOrders = append(me.queuedOrders[:i], me.queuedOrders[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,366 @@
+package journalkit
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	segmentExt = ".wal"
+	// headerSize is the length and the CRC of a record
+	headerSize = 8
+	seqSize    = 8
+	// maxRecordBytes guards against allocating a corrupted length
+	maxRecordBytes = 64 << 20
+)
+
+var (
+	ErrClosed    = errors.New("journal is closed")
+	ErrCorrupted = errors.New("journal is corrupted")
+
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// Options configures the durability and the segments of a Journal
+type Options struct {
+	// MaxSegmentBytes rotates to a new segment once the current one reaches it, 0 never rotates
+	MaxSegmentBytes int64
+	// SyncBatch fsyncs once this many records are pending, 0 or 1 syncs every record
+	SyncBatch int
+	// SyncInterval fsyncs the pending records periodically, 0 disables the background sync
+	SyncInterval time.Duration
+}
+
+// Record is an entry of the journal
+type Record struct {
+	Seq  uint64
+	Data []byte
+}
+
+// Journal is an append-only log split in segment files named by their first sequence number.
+// A record is laid out as length (4 bytes), CRC-32C (4 bytes) of the payload, then the payload
+// of the sequence number (8 bytes) and the data, all little endian.
+type Journal struct {
+	mu          sync.Mutex
+	dir         string
+	options     Options
+	file        *os.File
+	writer      *bufio.Writer
+	segmentSize int64
+	lastSeq     uint64
+	// pending is the number of records appended since the last fsync
+	pending int
+	closed  bool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Open opens the journal in dir, creating it if needed. A torn record at the end of the last
+// segment, e.g. left by a crash, is truncated.
+func Open(dir string, options Options) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &Journal{
+		dir:     dir,
+		options: options,
+		done:    make(chan struct{}),
+	}
+
+	if len(segments) == 0 {
+		if err := j.createSegment(1); err != nil {
+			return nil, err
+		}
+	} else {
+		last := segments[len(segments)-1]
+		path := filepath.Join(dir, segmentName(last))
+		validSize, lastSeq, err := scanSegment(path, last, nil)
+		if err != nil && !errors.Is(err, ErrCorrupted) {
+			return nil, err
+		}
+
+		file, err := os.OpenFile(path, os.O_RDWR, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		if err := file.Truncate(validSize); err != nil {
+			file.Close()
+			return nil, err
+		}
+		if _, err := file.Seek(validSize, io.SeekStart); err != nil {
+			file.Close()
+			return nil, err
+		}
+		j.file = file
+		j.writer = bufio.NewWriter(file)
+		j.segmentSize = validSize
+		j.lastSeq = lastSeq
+	}
+
+	if options.SyncInterval > 0 {
+		j.wg.Add(1)
+		go j.syncPeriodically()
+	}
+	return j, nil
+}
+
+// LastSeq returns the sequence number of the latest appended record, 0 if the journal is empty
+func (j *Journal) LastSeq() uint64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.lastSeq
+}
+
+// Append writes a record and returns its sequence number. The record is durable once synced,
+// either by the SyncBatch, the SyncInterval or an explicit Sync.
+func (j *Journal) Append(data []byte) (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.closed {
+		return 0, ErrClosed
+	}
+	if j.options.MaxSegmentBytes > 0 && j.segmentSize >= j.options.MaxSegmentBytes {
+		if err := j.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	seq := j.lastSeq + 1
+	var header [headerSize + seqSize]byte
+	binary.LittleEndian.PutUint64(header[headerSize:], seq)
+	crc := crc32.Update(crc32.Checksum(header[headerSize:], crcTable), crcTable, data)
+	binary.LittleEndian.PutUint32(header[0:], uint32(seqSize+len(data)))
+	binary.LittleEndian.PutUint32(header[4:], crc)
+
+	if _, err := j.writer.Write(header[:]); err != nil {
+		return 0, err
+	}
+	if _, err := j.writer.Write(data); err != nil {
+		return 0, err
+	}
+	j.lastSeq = seq
+	j.segmentSize += int64(len(header) + len(data))
+	j.pending++
+
+	if j.pending >= j.options.SyncBatch {
+		if err := j.sync(); err != nil {
+			return 0, err
+		}
+	}
+	return seq, nil
+}
+
+// Sync flushes and fsyncs the pending records
+func (j *Journal) Sync() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.closed {
+		return ErrClosed
+	}
+	return j.sync()
+}
+
+// Close syncs the pending records and closes the current segment
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	if j.closed {
+		j.mu.Unlock()
+		return nil
+	}
+	j.closed = true
+	close(j.done)
+	err := j.sync()
+	if closeErr := j.file.Close(); err == nil {
+		err = closeErr
+	}
+	j.mu.Unlock()
+
+	j.wg.Wait()
+	return err
+}
+
+func (j *Journal) sync() error {
+	if j.pending == 0 {
+		return nil
+	}
+	if err := j.writer.Flush(); err != nil {
+		return err
+	}
+	if err := j.file.Sync(); err != nil {
+		return err
+	}
+	j.pending = 0
+	return nil
+}
+
+func (j *Journal) syncPeriodically() {
+	defer j.wg.Done()
+
+	ticker := time.NewTicker(j.options.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-j.done:
+			return
+		case <-ticker.C:
+			j.Sync()
+		}
+	}
+}
+
+// rotate syncs and closes the current segment, then starts a new one
+func (j *Journal) rotate() error {
+	if err := j.sync(); err != nil {
+		return err
+	}
+	if err := j.file.Close(); err != nil {
+		return err
+	}
+	return j.createSegment(j.lastSeq + 1)
+}
+
+func (j *Journal) createSegment(firstSeq uint64) error {
+	file, err := os.OpenFile(filepath.Join(j.dir, segmentName(firstSeq)), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	// Persist the new directory entry
+	if err := syncDir(j.dir); err != nil {
+		file.Close()
+		return err
+	}
+
+	j.file = file
+	j.writer = bufio.NewWriter(file)
+	j.segmentSize = 0
+	return nil
+}
+
+// Replay reads the records of the journal in dir in order. A torn or corrupted record ends the
+// last segment like Open truncates it, anywhere else it fails with ErrCorrupted.
+func Replay(dir string, fn func(Record) error) error {
+	segments, err := listSegments(dir)
+	if err != nil {
+		return err
+	}
+
+	for i, firstSeq := range segments {
+		_, lastSeq, err := scanSegment(filepath.Join(dir, segmentName(firstSeq)), firstSeq, fn)
+		if errors.Is(err, ErrCorrupted) && i == len(segments)-1 {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if i+1 < len(segments) && segments[i+1] != lastSeq+1 {
+			return fmt.Errorf("%w: segment %s follows sequence %d", ErrCorrupted, segmentName(segments[i+1]), lastSeq)
+		}
+	}
+	return nil
+}
+
+// scanSegment reads the records of a segment and passes them to fn if not nil. It returns the
+// size of the valid records and the last sequence number, or firstSeq-1 if it is empty.
+func scanSegment(path string, firstSeq uint64, fn func(Record) error) (int64, uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	lastSeq := firstSeq - 1
+	for {
+		var header [headerSize]byte
+		if _, err := io.ReadFull(reader, header[:]); err == io.EOF {
+			return offset, lastSeq, nil
+		} else if err != nil {
+			return offset, lastSeq, corrupted(path, offset, err)
+		}
+
+		length := binary.LittleEndian.Uint32(header[0:])
+		if length < seqSize || length > maxRecordBytes {
+			return offset, lastSeq, corrupted(path, offset, fmt.Errorf("invalid length %d", length))
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return offset, lastSeq, corrupted(path, offset, err)
+		}
+		if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(header[4:]) {
+			return offset, lastSeq, corrupted(path, offset, errors.New("checksum mismatch"))
+		}
+		seq := binary.LittleEndian.Uint64(payload)
+		if seq != lastSeq+1 {
+			return offset, lastSeq, corrupted(path, offset, fmt.Errorf("sequence %d follows %d", seq, lastSeq))
+		}
+
+		if fn != nil {
+			if err := fn(Record{Seq: seq, Data: payload[seqSize:]}); err != nil {
+				return offset, lastSeq, err
+			}
+		}
+		offset += int64(headerSize) + int64(length)
+		lastSeq = seq
+	}
+}
+
+func corrupted(path string, offset int64, err error) error {
+	return fmt.Errorf("%w: %s at offset %d: %v", ErrCorrupted, filepath.Base(path), offset, err)
+}
+
+// listSegments returns the first sequence numbers of the segments in ascending order
+func listSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		firstSeq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, firstSeq)
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i] < segments[j] })
+	return segments, nil
+}
+
+func segmentName(firstSeq uint64) string {
+	return fmt.Sprintf("%020d%s", firstSeq, segmentExt)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
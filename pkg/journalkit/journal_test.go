@@ -0,0 +1,134 @@
+package journalkit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type JournalTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func TestJournalTestSuite(t *testing.T) {
+	suite.Run(t, new(JournalTestSuite))
+}
+
+func (suite *JournalTestSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+}
+
+func (suite *JournalTestSuite) append(journal *Journal, n int) {
+	for i := 0; i < n; i++ {
+		_, err := journal.Append([]byte(fmt.Sprintf("record%d", journal.LastSeq()+1)))
+		suite.Require().NoError(err)
+	}
+}
+
+func (suite *JournalTestSuite) replay() ([]Record, error) {
+	var records []Record
+	err := Replay(suite.dir, func(record Record) error {
+		records = append(records, record)
+		return nil
+	})
+	return records, err
+}
+
+func (suite *JournalTestSuite) segments() []string {
+	matches, err := filepath.Glob(filepath.Join(suite.dir, "*"+segmentExt))
+	suite.Require().NoError(err)
+	return matches
+}
+
+func (suite *JournalTestSuite) TestAppendAndReplay() {
+	journal, err := Open(suite.dir, Options{SyncBatch: 2})
+	suite.Require().NoError(err)
+	suite.append(journal, 3)
+	suite.NoError(journal.Close())
+
+	records, err := suite.replay()
+	suite.NoError(err)
+	suite.Equal([]Record{
+		{Seq: 1, Data: []byte("record1")},
+		{Seq: 2, Data: []byte("record2")},
+		{Seq: 3, Data: []byte("record3")},
+	}, records)
+
+	// Appending after a reopen continues the sequence
+	journal, err = Open(suite.dir, Options{})
+	suite.Require().NoError(err)
+	suite.Equal(uint64(3), journal.LastSeq())
+	seq, err := journal.Append([]byte("record4"))
+	suite.NoError(err)
+	suite.Equal(uint64(4), seq)
+	suite.NoError(journal.Close())
+
+	_, err = journal.Append([]byte("record5"))
+	suite.ErrorIs(err, ErrClosed)
+}
+
+func (suite *JournalTestSuite) TestRotation() {
+	// Every record is 8 bytes of header, 8 bytes of sequence and 7 bytes of data
+	journal, err := Open(suite.dir, Options{MaxSegmentBytes: 46})
+	suite.Require().NoError(err)
+	suite.append(journal, 5)
+	suite.NoError(journal.Close())
+
+	segments := suite.segments()
+	suite.Require().Len(segments, 3)
+	suite.Equal(segmentName(3), filepath.Base(segments[1]))
+
+	records, err := suite.replay()
+	suite.NoError(err)
+	suite.Len(records, 5)
+	suite.Equal(uint64(5), records[4].Seq)
+}
+
+func (suite *JournalTestSuite) TestTornTail() {
+	journal, err := Open(suite.dir, Options{})
+	suite.Require().NoError(err)
+	suite.append(journal, 2)
+	suite.NoError(journal.Close())
+
+	// Simulate a crash in the middle of writing the last record
+	path := suite.segments()[0]
+	info, err := os.Stat(path)
+	suite.Require().NoError(err)
+	suite.Require().NoError(os.Truncate(path, info.Size()-3))
+
+	records, err := suite.replay()
+	suite.NoError(err)
+	suite.Len(records, 1)
+
+	// Open truncates the torn record and appends after the last valid one
+	journal, err = Open(suite.dir, Options{})
+	suite.Require().NoError(err)
+	suite.Equal(uint64(1), journal.LastSeq())
+	suite.append(journal, 1)
+	suite.NoError(journal.Close())
+
+	records, err = suite.replay()
+	suite.NoError(err)
+	suite.Equal([]Record{{Seq: 1, Data: []byte("record1")}, {Seq: 2, Data: []byte("record2")}}, records)
+}
+
+func (suite *JournalTestSuite) TestCorruptedSegment() {
+	journal, err := Open(suite.dir, Options{MaxSegmentBytes: 23})
+	suite.Require().NoError(err)
+	suite.append(journal, 3)
+	suite.NoError(journal.Close())
+
+	// Flip a data byte of the first segment
+	path := suite.segments()[0]
+	data, err := os.ReadFile(path)
+	suite.Require().NoError(err)
+	data[len(data)-1] ^= 0xff
+	suite.Require().NoError(os.WriteFile(path, data, 0o644))
+
+	_, err = suite.replay()
+	suite.ErrorIs(err, ErrCorrupted)
+}
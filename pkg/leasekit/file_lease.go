@@ -0,0 +1,75 @@
+//go:build unix
+
+package leasekit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// FileLease is a Lease held through an exclusive lock on a file shared by the replicas, e.g. a
+// volume of the replicas on the same host. The lock is released by the OS when the process
+// exits, so the lease is never held by a dead replica.
+type FileLease struct {
+	path          string
+	retryInterval time.Duration
+	file          *os.File
+}
+
+// NewFileLease creates a FileLease which retries to lock the file every retryInterval
+func NewFileLease(path string, retryInterval time.Duration) Lease {
+	return &FileLease{
+		path:          path,
+		retryInterval: retryInterval,
+	}
+}
+
+// Acquire locks the file and writes the process ID into it for troubleshooting
+func (l *FileLease) Acquire(ctx context.Context) error {
+	file, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+
+	for {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			file.Close()
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return ctx.Err()
+		case <-time.After(l.retryInterval):
+		}
+	}
+
+	if err := file.Truncate(0); err == nil {
+		file.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+	l.file = file
+	return nil
+}
+
+// Release unlocks the file
+func (l *FileLease) Release() error {
+	if l.file == nil {
+		return nil
+	}
+
+	err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file = nil
+	return err
+}
@@ -0,0 +1,49 @@
+//go:build unix
+
+package leasekit
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type FileLeaseTestSuite struct {
+	suite.Suite
+	path string
+}
+
+func TestFileLeaseTestSuite(t *testing.T) {
+	suite.Run(t, new(FileLeaseTestSuite))
+}
+
+func (suite *FileLeaseTestSuite) SetupTest() {
+	suite.path = filepath.Join(suite.T().TempDir(), "lease")
+}
+
+func (suite *FileLeaseTestSuite) TestAcquire() {
+	primary := NewFileLease(suite.path, 10*time.Millisecond)
+	standby := NewFileLease(suite.path, 10*time.Millisecond)
+	suite.Require().NoError(primary.Acquire(context.Background()))
+
+	// The standby waits while the primary holds the lease
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	suite.ErrorIs(standby.Acquire(ctx), context.DeadlineExceeded)
+
+	acquired := make(chan error, 1)
+	go func() {
+		acquired <- standby.Acquire(context.Background())
+	}()
+	suite.NoError(primary.Release())
+	select {
+	case err := <-acquired:
+		suite.NoError(err)
+	case <-time.After(time.Second):
+		suite.Fail("standby did not take over the lease")
+	}
+	suite.NoError(standby.Release())
+}
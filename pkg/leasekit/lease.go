@@ -0,0 +1,13 @@
+package leasekit
+
+import (
+	"context"
+)
+
+// Lease elects the leader among the replicas of a worker
+type Lease interface {
+	// Acquire blocks until the lease is held or the context is done
+	Acquire(ctx context.Context) error
+	// Release gives up the lease
+	Release() error
+}
@@ -46,6 +46,11 @@ func Sync() error {
 	return nil
 }
 
+// L returns the zap logger, e.g. to inject it into a library
+func L() *zap.Logger {
+	return logger
+}
+
 // Debug logs a message at DebugLevel
 func Debug(msg string, fields ...zap.Field) {
 	logger.Debug(msg, fields...)
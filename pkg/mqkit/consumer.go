@@ -2,12 +2,37 @@ package mqkit
 
 import (
 	"context"
+	"time"
 )
 
+// Message is a message received from the message broker
+type Message struct {
+	Topic     string
+	Partition int
+	// Offset is the position of the message in its partition
+	Offset int64
+	Key    []byte
+	Value  []byte
+	// Headers are the metadata of the message, e.g. for tracing
+	Headers map[string]string
+}
+
+// BatchOptions bounds the batches of ConsumeBatch
+type BatchOptions struct {
+	// MaxMessages is the most messages in a batch, 1 if less
+	MaxMessages int
+	// MaxWait is how long a batch waits for more messages after the first one
+	MaxWait time.Duration
+}
+
 type Consumer interface {
 	// Consume receives a message from the message broker and manually
 	// commit messages after completely executing the handler
-	Consume(ctx context.Context, handler func(val []byte) error) error
+	Consume(ctx context.Context, handler func(msg Message) error) error
+	// ConsumeBatch receives the messages until the batch is full or MaxWait passes after the
+	// first one, and commits them once after completely executing the handler. The messages of a
+	// partition are in order, and a batch the handler failed is received again.
+	ConsumeBatch(ctx context.Context, opts BatchOptions, handler func(msgs []Message) error) error
 	// Close closes the stream, preventing the program from reading any more
 	// messages from it.
 	Close() error
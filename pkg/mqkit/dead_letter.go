@@ -0,0 +1,139 @@
+package mqkit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+var now = time.Now
+
+// DeadLetter is a message moved to the dead-letter topic after the handler failed it
+type DeadLetter struct {
+	Topic     string            `json:"topic"`
+	Partition int               `json:"partition"`
+	Offset    int64             `json:"offset"`
+	Key       []byte            `json:"key,omitempty"`
+	Value     []byte            `json:"value"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Error     string            `json:"error"`
+	Attempts  int               `json:"attempts"`
+	FailedAt  time.Time         `json:"failed_at"`
+}
+
+type DeadLetterOptions struct {
+	// MaxAttempts is the number of times the handler is called before the message is moved
+	MaxAttempts int
+	// Backoff is the delay between the attempts
+	Backoff time.Duration
+	// ShouldDeadLetter reports whether a failed message is moved, e.g. not for a transient error
+	// which is retried by the next Consume. Every failed message is moved if nil.
+	ShouldDeadLetter func(err error) bool
+	// OnDeadLetter is called once the message is moved, e.g. to skip it after a restart when the
+	// consumer commits no offsets. The message is received and moved again if it fails.
+	OnDeadLetter func(msg Message) error
+}
+
+// DeadLetterConsumer retries the handler of a message and moves the message to the dead-letter
+// topic if it still fails, so a poison message does not block the consumer
+type DeadLetterConsumer struct {
+	consumer Consumer
+	producer Producer
+	opts     DeadLetterOptions
+}
+
+// NewDeadLetterConsumer creates a DeadLetterConsumer which moves the failed messages of the
+// consumer through the producer of the dead-letter topic
+func NewDeadLetterConsumer(consumer Consumer, producer Producer, opts DeadLetterOptions) Consumer {
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = 1
+	}
+	return &DeadLetterConsumer{
+		consumer: consumer,
+		producer: producer,
+		opts:     opts,
+	}
+}
+
+// Consume receives a message and calls the handler up to MaxAttempts times. A message which still
+// fails is published to the dead-letter topic and committed. If the error should not be dead
+// lettered or the publishing fails, the error is returned and the message is received again.
+func (c *DeadLetterConsumer) Consume(ctx context.Context, handler func(msg Message) error) error {
+	return c.consumer.Consume(ctx, func(msg Message) error {
+		return c.handle(ctx, msg, handler)
+	})
+}
+
+// ConsumeBatch receives a batch of messages and calls the handler with it. If the batch fails with
+// an error to dead letter, the handler is called with every message of the batch alone like
+// Consume, so the handler must skip the messages it already applied.
+func (c *DeadLetterConsumer) ConsumeBatch(ctx context.Context, opts BatchOptions, handler func(msgs []Message) error) error {
+	return c.consumer.ConsumeBatch(ctx, opts, func(msgs []Message) error {
+		err := handler(msgs)
+		if err == nil || (c.opts.ShouldDeadLetter != nil && !c.opts.ShouldDeadLetter(err)) {
+			return err
+		}
+
+		for _, msg := range msgs {
+			if err := c.handle(ctx, msg, func(msg Message) error {
+				return handler([]Message{msg})
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// handle calls the handler up to MaxAttempts times and moves the message if it still fails
+func (c *DeadLetterConsumer) handle(ctx context.Context, msg Message, handler func(msg Message) error) error {
+	var err error
+	for attempt := 1; attempt <= c.opts.MaxAttempts; attempt++ {
+		if err = handler(msg); err == nil {
+			return nil
+		}
+		if c.opts.ShouldDeadLetter != nil && !c.opts.ShouldDeadLetter(err) {
+			return err
+		}
+		if attempt == c.opts.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.opts.Backoff):
+		}
+	}
+
+	val, marshalErr := json.Marshal(DeadLetter{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       msg.Key,
+		Value:     msg.Value,
+		Headers:   msg.Headers,
+		Error:     err.Error(),
+		Attempts:  c.opts.MaxAttempts,
+		FailedAt:  now(),
+	})
+	if marshalErr != nil {
+		return marshalErr
+	}
+	if err := c.producer.PublishWithKey(ctx, msg.Key, val); err != nil {
+		return err
+	}
+	if c.opts.OnDeadLetter != nil {
+		return c.opts.OnDeadLetter(msg)
+	}
+	return nil
+}
+
+// Close closes the consumer and the producer of the dead-letter topic
+func (c *DeadLetterConsumer) Close() error {
+	err := c.consumer.Close()
+	if producerErr := c.producer.Close(); err == nil {
+		err = producerErr
+	}
+	return err
+}
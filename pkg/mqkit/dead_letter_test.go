@@ -0,0 +1,174 @@
+package mqkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+var (
+	errPoison    = errors.New("poison")
+	errTransient = errors.New("transient")
+)
+
+// fakeConsumer receives the messages in order, and the same ones again after a failure
+type fakeConsumer struct {
+	messages []Message
+}
+
+func (c *fakeConsumer) ConsumeBatch(ctx context.Context, opts BatchOptions, handler func(msgs []Message) error) error {
+	n := min(max(opts.MaxMessages, 1), len(c.messages))
+	if err := handler(c.messages[:n]); err != nil {
+		return err
+	}
+	c.messages = c.messages[n:]
+	return nil
+}
+
+func (c *fakeConsumer) Consume(ctx context.Context, handler func(msg Message) error) error {
+	if err := handler(c.messages[0]); err != nil {
+		return err
+	}
+	c.messages = c.messages[1:]
+	return nil
+}
+
+func (c *fakeConsumer) Close() error {
+	return nil
+}
+
+type fakeProducer struct {
+	published [][]byte
+}
+
+func (p *fakeProducer) Publish(ctx context.Context, val []byte) error {
+	return p.PublishWithKey(ctx, nil, val)
+}
+
+func (p *fakeProducer) PublishWithKey(ctx context.Context, key, val []byte) error {
+	p.published = append(p.published, val)
+	return nil
+}
+
+func (p *fakeProducer) PublishBatch(ctx context.Context, msgs []Message) error {
+	for _, msg := range msgs {
+		p.published = append(p.published, msg.Value)
+	}
+	return nil
+}
+
+func (p *fakeProducer) Close() error {
+	return nil
+}
+
+type DeadLetterTestSuite struct {
+	suite.Suite
+	consumer *fakeConsumer
+	producer *fakeProducer
+	current  time.Time
+	restore  func() time.Time
+}
+
+func TestDeadLetterTestSuite(t *testing.T) {
+	suite.Run(t, new(DeadLetterTestSuite))
+}
+
+func (suite *DeadLetterTestSuite) SetupTest() {
+	suite.consumer = &fakeConsumer{messages: []Message{
+		{Topic: "AAPL_ORDER", Offset: 7, Key: []byte("AAPL"), Value: []byte("poison"), Headers: map[string]string{"content-type": "application/json"}},
+		{Topic: "AAPL_ORDER", Offset: 8, Key: []byte("AAPL"), Value: []byte("order")},
+	}}
+	suite.producer = &fakeProducer{}
+	suite.current = time.Date(2025, 1, 15, 18, 0, 0, 0, time.UTC)
+	suite.restore = now
+	now = func() time.Time {
+		return suite.current
+	}
+}
+
+func (suite *DeadLetterTestSuite) TearDownTest() {
+	now = suite.restore
+}
+
+func (suite *DeadLetterTestSuite) TestConsume_MovesPoisonMessage() {
+	consumer := NewDeadLetterConsumer(suite.consumer, suite.producer, DeadLetterOptions{MaxAttempts: 3})
+
+	var attempts int
+	var handled []string
+	handler := func(msg Message) error {
+		if string(msg.Value) == "poison" {
+			attempts++
+			return errPoison
+		}
+		handled = append(handled, string(msg.Value))
+		return nil
+	}
+	suite.NoError(consumer.Consume(context.Background(), handler))
+	suite.NoError(consumer.Consume(context.Background(), handler))
+	suite.Equal(3, attempts)
+	suite.Equal([]string{"order"}, handled)
+
+	suite.Require().Len(suite.producer.published, 1)
+	var deadLetter DeadLetter
+	suite.Require().NoError(json.Unmarshal(suite.producer.published[0], &deadLetter))
+	suite.Equal(DeadLetter{
+		Topic:    "AAPL_ORDER",
+		Offset:   7,
+		Key:      []byte("AAPL"),
+		Value:    []byte("poison"),
+		Headers:  map[string]string{"content-type": "application/json"},
+		Error:    "poison",
+		Attempts: 3,
+		FailedAt: suite.current,
+	}, deadLetter)
+}
+
+func (suite *DeadLetterTestSuite) TestConsume_RetriesTransientError() {
+	consumer := NewDeadLetterConsumer(suite.consumer, suite.producer, DeadLetterOptions{
+		MaxAttempts: 3,
+		ShouldDeadLetter: func(err error) bool {
+			return errors.Is(err, errPoison)
+		},
+	})
+
+	err := consumer.Consume(context.Background(), func(msg Message) error {
+		return errTransient
+	})
+	suite.ErrorIs(err, errTransient)
+	suite.Empty(suite.producer.published)
+
+	// The message is received again
+	suite.NoError(consumer.Consume(context.Background(), func(msg Message) error {
+		suite.Equal(int64(7), msg.Offset)
+		return nil
+	}))
+}
+
+func (suite *DeadLetterTestSuite) TestConsumeBatch_MovesPoisonMessage() {
+	consumer := NewDeadLetterConsumer(suite.consumer, suite.producer, DeadLetterOptions{MaxAttempts: 3})
+
+	var attempts int
+	var handled []string
+	suite.NoError(consumer.ConsumeBatch(context.Background(), BatchOptions{MaxMessages: 2}, func(msgs []Message) error {
+		for _, msg := range msgs {
+			if string(msg.Value) == "poison" {
+				attempts++
+				return errPoison
+			}
+		}
+		for _, msg := range msgs {
+			handled = append(handled, string(msg.Value))
+		}
+		return nil
+	}))
+
+	// The failed batch is handled message by message
+	suite.Equal(4, attempts)
+	suite.Equal([]string{"order"}, handled)
+	suite.Len(suite.producer.published, 1)
+	suite.Empty(suite.consumer.messages)
+}
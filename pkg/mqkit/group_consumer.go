@@ -0,0 +1,30 @@
+package mqkit
+
+import (
+	"context"
+)
+
+// Claim is a partition owned by a member of a consumer group, until the context passed with it
+// is done
+type Claim struct {
+	Topic     string
+	Partition int
+	// Offset is the next offset committed by the group, negative if nothing is committed
+	Offset int64
+	commit func(offset int64) error
+}
+
+// Commit commits the next offset of the partition to the group
+func (c Claim) Commit(offset int64) error {
+	return c.commit(offset)
+}
+
+type GroupConsumer interface {
+	// Run joins the consumer group and calls the handler with every partition the member owns in
+	// a generation of the group. The context of the handlers is done when the group rebalances,
+	// and the partitions of the next generation are claimed after every handler returned, so a
+	// partition is never owned by two members. Run returns when the context is done.
+	Run(ctx context.Context, handler func(ctx context.Context, claim Claim)) error
+	// Close leaves the consumer group
+	Close() error
+}
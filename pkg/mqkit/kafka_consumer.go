@@ -10,40 +10,126 @@ import (
 // KafkaConsumer is responsible sending order data to the matching engine.
 type KafkaConsumer struct {
 	reader *kafka.Reader
+	// failed is the batch the handler failed, which is received again by the next Consume
+	failed []kafka.Message
 }
 
-// NewKafkaConsumer creates a new KafkaConsumer
-func NewKafkaConsumer(brokers []string, topic string) Consumer {
+// NewKafkaConsumer creates a new KafkaConsumer which commits the offsets of the consumer group
+func NewKafkaConsumer(brokers []string, topic string, groupID string) Consumer {
 	return &KafkaConsumer{
 		reader: kafka.NewReader(kafka.ReaderConfig{
 			Brokers:   brokers,
 			Topic:     topic,
 			MaxWait:   3 * time.Second,
 			Partition: 0,
-			GroupID:   "MATCHING_WORKER",
+			GroupID:   groupID,
 		}),
 	}
 }
 
+// NewKafkaPartitionConsumer creates a new KafkaConsumer which reads a partition from the offset.
+// It commits nothing, the caller keeps its own position, e.g. in the output it produces.
+func NewKafkaPartitionConsumer(brokers []string, topic string, partition int, offset int64) (Consumer, error) {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   brokers,
+		Topic:     topic,
+		MaxWait:   3 * time.Second,
+		Partition: partition,
+	})
+	if err := reader.SetOffset(offset); err != nil {
+		reader.Close()
+		return nil, err
+	}
+	return &KafkaConsumer{reader: reader}, nil
+}
+
 // Consume receives a message to the Kafka topic and manually
 // commit messages after completely executing the handler
-func (op *KafkaConsumer) Consume(ctx context.Context, handler func(val []byte) error) error {
-	msg, err := op.reader.FetchMessage(ctx)
+func (op *KafkaConsumer) Consume(ctx context.Context, handler func(msg Message) error) error {
+	return op.ConsumeBatch(ctx, BatchOptions{MaxMessages: 1}, func(msgs []Message) error {
+		for _, msg := range msgs {
+			if err := handler(msg); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ConsumeBatch receives a batch of messages from the Kafka topic and commits them once after
+// completely executing the handler
+func (op *KafkaConsumer) ConsumeBatch(ctx context.Context, opts BatchOptions, handler func(msgs []Message) error) error {
+	batch, err := op.fetch(ctx, opts)
 	if err != nil {
 		return err
 	}
 
-	if err := handler(msg.Value); err != nil {
+	msgs := make([]Message, 0, len(batch))
+	for _, msg := range batch {
+		msgs = append(msgs, Message{
+			Topic:     msg.Topic,
+			Partition: msg.Partition,
+			Offset:    msg.Offset,
+			Key:       msg.Key,
+			Value:     msg.Value,
+			Headers:   KafkaHeaders(msg.Headers),
+		})
+	}
+	if err := handler(msgs); err != nil {
+		op.failed = batch
 		return err
 	}
+	op.failed = nil
 
-	if err := op.reader.CommitMessages(ctx, msg); err != nil {
+	if op.reader.Config().GroupID == "" {
+		return nil
+	}
+	if err := op.reader.CommitMessages(ctx, batch...); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// fetch returns the failed batch if any, otherwise the next message and the ones following it
+// within the batch options
+func (op *KafkaConsumer) fetch(ctx context.Context, opts BatchOptions) ([]kafka.Message, error) {
+	if op.failed != nil {
+		return op.failed, nil
+	}
+
+	msg, err := op.reader.FetchMessage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	batch := []kafka.Message{msg}
+
+	waitCtx, cancel := context.WithTimeout(ctx, opts.MaxWait)
+	defer cancel()
+	for len(batch) < opts.MaxMessages {
+		// A message is not taken from the reader when the wait is over
+		msg, err := op.reader.FetchMessage(waitCtx)
+		if err != nil {
+			break
+		}
+		batch = append(batch, msg)
+	}
+	return batch, nil
+}
+
+// KafkaHeaders converts the Kafka headers, a later header replaces an earlier one of the same key
+func KafkaHeaders(kafkaHeaders []kafka.Header) map[string]string {
+	if len(kafkaHeaders) == 0 {
+		return nil
+	}
+
+	result := make(map[string]string, len(kafkaHeaders))
+	for _, header := range kafkaHeaders {
+		result[header.Key] = string(header.Value)
+	}
+	return result
+}
+
 // Close closes the Kafka reader.
 func (op *KafkaConsumer) Close() error {
 	return op.reader.Close()
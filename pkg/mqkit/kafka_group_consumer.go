@@ -0,0 +1,64 @@
+package mqkit
+
+import (
+	"context"
+	"errors"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaGroupConsumer is a member of a Kafka consumer group which owns a set of the partitions
+type KafkaGroupConsumer struct {
+	topic string
+	group *kafka.ConsumerGroup
+}
+
+// NewKafkaGroupConsumer joins the consumer group of the topic
+func NewKafkaGroupConsumer(brokers []string, topic string, groupID string) (GroupConsumer, error) {
+	group, err := kafka.NewConsumerGroup(kafka.ConsumerGroupConfig{
+		ID:                    groupID,
+		Brokers:               brokers,
+		Topics:                []string{topic},
+		WatchPartitionChanges: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &KafkaGroupConsumer{
+		topic: topic,
+		group: group,
+	}, nil
+}
+
+// Run calls the handler with every partition assigned to the member in a generation
+func (c *KafkaGroupConsumer) Run(ctx context.Context, handler func(ctx context.Context, claim Claim)) error {
+	for {
+		// The next generation starts after the handlers of the previous one returned
+		generation, err := c.group.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, kafka.ErrGroupClosed) {
+				return nil
+			}
+			return err
+		}
+
+		for _, assignment := range generation.Assignments[c.topic] {
+			claim := Claim{
+				Topic:     c.topic,
+				Partition: assignment.ID,
+				Offset:    assignment.Offset,
+				commit: func(offset int64) error {
+					return generation.CommitOffsets(map[string]map[int]int64{c.topic: {assignment.ID: offset}})
+				},
+			}
+			generation.Start(func(ctx context.Context) {
+				handler(ctx, claim)
+			})
+		}
+	}
+}
+
+// Close leaves the consumer group
+func (c *KafkaGroupConsumer) Close() error {
+	return c.group.Close()
+}
@@ -2,6 +2,7 @@ package mqkit
 
 import (
 	"context"
+	"time"
 
 	"github.com/segmentio/kafka-go"
 )
@@ -9,7 +10,7 @@ import (
 // KafkaProducer is responsible sending order data to the matching engine.
 type KafkaProducer struct {
 	writer *kafka.Writer
-	// key is used as symbol to guarantee in order
+	// key is used as symbol to guarantee in order, a key is always in the same partition
 	key []byte
 }
 
@@ -19,8 +20,10 @@ func NewKafkaProducer(brokers []string, topic string, key []byte) Producer {
 		writer: &kafka.Writer{
 			Addr:                   kafka.TCP(brokers...),
 			Topic:                  topic,
-			Balancer:               &kafka.LeastBytes{},
+			Balancer:               keyBalancer,
 			AllowAutoTopicCreation: true,
+			// A partial batch is written after the timeout, a second by default
+			BatchTimeout: 10 * time.Millisecond,
 		},
 		key: key,
 	}
@@ -28,8 +31,13 @@ func NewKafkaProducer(brokers []string, topic string, key []byte) Producer {
 
 // Publish sends a message to the Kafka topic.
 func (kp *KafkaProducer) Publish(ctx context.Context, val []byte) error {
+	return kp.PublishWithKey(ctx, kp.key, val)
+}
+
+// PublishWithKey sends a message to the partition of the key.
+func (kp *KafkaProducer) PublishWithKey(ctx context.Context, key, val []byte) error {
 	msg := kafka.Message{
-		Key:   kp.key,
+		Key:   key,
 		Value: val,
 	}
 	if err := kp.writer.WriteMessages(ctx, msg); err != nil {
@@ -38,6 +46,29 @@ func (kp *KafkaProducer) Publish(ctx context.Context, val []byte) error {
 	return nil
 }
 
+// PublishBatch sends the messages to the partitions of their keys in as few requests as possible.
+func (kp *KafkaProducer) PublishBatch(ctx context.Context, msgs []Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	kafkaMsgs := make([]kafka.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		kafkaMsg := kafka.Message{
+			Key:   msg.Key,
+			Value: msg.Value,
+		}
+		if kafkaMsg.Key == nil {
+			kafkaMsg.Key = kp.key
+		}
+		for key, value := range msg.Headers {
+			kafkaMsg.Headers = append(kafkaMsg.Headers, kafka.Header{Key: key, Value: []byte(value)})
+		}
+		kafkaMsgs = append(kafkaMsgs, kafkaMsg)
+	}
+	return kp.writer.WriteMessages(ctx, kafkaMsgs...)
+}
+
 // Close closes the Kafka writer.
 func (kp *KafkaProducer) Close() error {
 	return kp.writer.Close()
@@ -0,0 +1,64 @@
+package mqkit
+
+import (
+	"context"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaRange is an inclusive offset range of a topic partition, a negative To reads up to the
+// last message at the start
+type KafkaRange struct {
+	Brokers   []string
+	Topic     string
+	Partition int
+	From      int64
+	To        int64
+}
+
+// ReadKafkaRange passes the messages of an offset range
+func ReadKafkaRange(ctx context.Context, r KafkaRange, fn func(kafka.Message) error) error {
+	if r.To < 0 {
+		conn, err := kafka.DialLeader(ctx, "tcp", r.Brokers[0], r.Topic, r.Partition)
+		if err != nil {
+			return err
+		}
+		last, err := conn.ReadLastOffset()
+		conn.Close()
+		if err != nil {
+			return err
+		}
+		r.To = last - 1
+	}
+	if r.From > r.To {
+		return nil
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   r.Brokers,
+		Topic:     r.Topic,
+		Partition: r.Partition,
+		MaxWait:   time.Second,
+	})
+	defer reader.Close()
+	if err := reader.SetOffset(r.From); err != nil {
+		return err
+	}
+
+	for {
+		msg, err := reader.ReadMessage(ctx)
+		if err != nil {
+			return err
+		}
+		if msg.Offset > r.To {
+			return nil
+		}
+		if err := fn(msg); err != nil {
+			return err
+		}
+		if msg.Offset == r.To {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package mqkit
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrClosed = errors.New("closed")
+)
+
+// MemoryBroker is an in-memory message broker for tests. A topic has partitions of messages
+// with offsets from 0, and a consumer group commits the next offset of every partition.
+type MemoryBroker struct {
+	mu     sync.Mutex
+	topics map[string][][]Message
+	// committed is the next offset of the group, topic and partition
+	committed map[string]map[string][]int64
+	// appended is closed and replaced when a message is appended
+	appended chan struct{}
+	groups   map[string]*memoryGroup
+	// rebalanced is closed and replaced when the groups change
+	rebalanced chan struct{}
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		topics:     map[string][][]Message{},
+		committed:  map[string]map[string][]int64{},
+		appended:   make(chan struct{}),
+		groups:     map[string]*memoryGroup{},
+		rebalanced: make(chan struct{}),
+	}
+}
+
+// CreateTopic creates a topic with the number of partitions, a topic is created with a single
+// partition when it is first used otherwise
+func (b *MemoryBroker) CreateTopic(topic string, partitions int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.topics[topic]; !ok {
+		b.topics[topic] = make([][]Message, partitions)
+	}
+}
+
+// Messages returns the messages of a topic partition
+func (b *MemoryBroker) Messages(topic string, partition int) []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]Message(nil), b.partitions(topic)[partition]...)
+}
+
+// Append appends a message to the partition of its key by PartitionFor and returns the message
+// with its partition and offset
+func (b *MemoryBroker) Append(topic string, key, value []byte) Message {
+	return b.AppendMessage(Message{Topic: topic, Key: key, Value: value})
+}
+
+// AppendMessage appends a message with its headers like Append
+func (b *MemoryBroker) AppendMessage(msg Message) Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	partitions := b.partitions(msg.Topic)
+	msg.Partition = PartitionFor(msg.Key, len(partitions))
+	msg.Offset = int64(len(partitions[msg.Partition]))
+	partitions[msg.Partition] = append(partitions[msg.Partition], msg)
+
+	close(b.appended)
+	b.appended = make(chan struct{})
+	return msg
+}
+
+// Fetch blocks until the message at the offset of the partition is appended
+func (b *MemoryBroker) Fetch(ctx context.Context, topic string, partition int, offset int64) (Message, error) {
+	for {
+		b.mu.Lock()
+		messages := b.partitions(topic)[partition]
+		appended := b.appended
+		b.mu.Unlock()
+
+		if offset < int64(len(messages)) {
+			return messages[offset], nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		case <-appended:
+		}
+	}
+}
+
+// Committed returns the next offset of the group in the partition
+func (b *MemoryBroker) Committed(groupID, topic string, partition int) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.offsets(groupID, topic)[partition]
+}
+
+// Commit commits the offset after the message for the group
+func (b *MemoryBroker) Commit(groupID string, msg Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	offsets := b.offsets(groupID, msg.Topic)
+	offsets[msg.Partition] = max(offsets[msg.Partition], msg.Offset+1)
+}
+
+func (b *MemoryBroker) partitions(topic string) [][]Message {
+	partitions, ok := b.topics[topic]
+	if !ok {
+		partitions = make([][]Message, 1)
+		b.topics[topic] = partitions
+	}
+	return partitions
+}
+
+func (b *MemoryBroker) offsets(groupID, topic string) []int64 {
+	groups, ok := b.committed[groupID]
+	if !ok {
+		groups = map[string][]int64{}
+		b.committed[groupID] = groups
+	}
+	offsets, ok := groups[topic]
+	if !ok {
+		offsets = make([]int64, len(b.partitions(topic)))
+		groups[topic] = offsets
+	}
+	return offsets
+}
+
+// MemoryProducer publishes to a topic of a MemoryBroker
+type MemoryProducer struct {
+	broker *MemoryBroker
+	topic  string
+	key    []byte
+}
+
+// NewMemoryProducer creates a new MemoryProducer like NewKafkaProducer
+func NewMemoryProducer(broker *MemoryBroker, topic string, key []byte) Producer {
+	return &MemoryProducer{
+		broker: broker,
+		topic:  topic,
+		key:    key,
+	}
+}
+
+// Publish appends a message to the topic
+func (p *MemoryProducer) Publish(ctx context.Context, val []byte) error {
+	return p.PublishWithKey(ctx, p.key, val)
+}
+
+// PublishWithKey appends a message to the partition of the key
+func (p *MemoryProducer) PublishWithKey(ctx context.Context, key, val []byte) error {
+	p.broker.Append(p.topic, key, val)
+	return nil
+}
+
+// PublishBatch appends the messages to the partitions of their keys
+func (p *MemoryProducer) PublishBatch(ctx context.Context, msgs []Message) error {
+	for _, msg := range msgs {
+		key := msg.Key
+		if key == nil {
+			key = p.key
+		}
+		p.broker.AppendMessage(Message{Topic: p.topic, Key: key, Value: msg.Value, Headers: msg.Headers})
+	}
+	return nil
+}
+
+func (p *MemoryProducer) Close() error {
+	return nil
+}
+
+// MemoryConsumer consumes a topic of a MemoryBroker
+type MemoryConsumer struct {
+	broker  *MemoryBroker
+	topic   string
+	groupID string
+	// partition and offset are the next message to consume without a group
+	partition int
+	offset    int64
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewMemoryConsumer creates a new MemoryConsumer like NewKafkaConsumer, which consumes the first
+// partition from the offset committed by the group
+func NewMemoryConsumer(broker *MemoryBroker, topic string, groupID string) Consumer {
+	return &MemoryConsumer{
+		broker:  broker,
+		topic:   topic,
+		groupID: groupID,
+		closed:  make(chan struct{}),
+	}
+}
+
+// NewMemoryPartitionConsumer creates a new MemoryConsumer like NewKafkaPartitionConsumer, which
+// consumes the partition from the offset and commits nothing
+func NewMemoryPartitionConsumer(broker *MemoryBroker, topic string, partition int, offset int64) Consumer {
+	return &MemoryConsumer{
+		broker:    broker,
+		topic:     topic,
+		partition: partition,
+		offset:    offset,
+		closed:    make(chan struct{}),
+	}
+}
+
+// Consume receives the next message and commits it after completely executing the handler. A
+// message the handler failed is received again.
+func (c *MemoryConsumer) Consume(ctx context.Context, handler func(msg Message) error) error {
+	return c.ConsumeBatch(ctx, BatchOptions{MaxMessages: 1}, func(msgs []Message) error {
+		return handler(msgs[0])
+	})
+}
+
+// ConsumeBatch receives the next messages and commits them after completely executing the
+// handler. A batch the handler failed is received again, with the messages appended since.
+func (c *MemoryConsumer) ConsumeBatch(ctx context.Context, opts BatchOptions, handler func(msgs []Message) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-c.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	offset := c.offset
+	if c.groupID != "" {
+		offset = c.broker.Committed(c.groupID, c.topic, c.partition)
+	}
+	msg, err := c.broker.Fetch(ctx, c.topic, c.partition, offset)
+	if err != nil {
+		select {
+		case <-c.closed:
+			return ErrClosed
+		default:
+			return err
+		}
+	}
+	msgs := []Message{msg}
+
+	waitCtx, cancelWait := context.WithTimeout(ctx, opts.MaxWait)
+	defer cancelWait()
+	for len(msgs) < opts.MaxMessages {
+		msg, err := c.broker.Fetch(waitCtx, c.topic, c.partition, msg.Offset+1)
+		if err != nil {
+			break
+		}
+		msgs = append(msgs, msg)
+	}
+
+	if err := handler(msgs); err != nil {
+		return err
+	}
+
+	last := msgs[len(msgs)-1]
+	if c.groupID != "" {
+		c.broker.Commit(c.groupID, last)
+	} else {
+		c.offset = last.Offset + 1
+	}
+	return nil
+}
+
+// Close stops the blocked Consume
+func (c *MemoryConsumer) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	return nil
+}
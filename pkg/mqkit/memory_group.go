@@ -0,0 +1,184 @@
+package mqkit
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// memoryGroup is the membership of a consumer group of a MemoryBroker
+type memoryGroup struct {
+	members    []*MemoryGroupConsumer
+	generation int
+	// synced is the latest generation every member started, after revoking its previous partitions
+	synced map[*MemoryGroupConsumer]int
+}
+
+// MemoryGroupConsumer is a member of a consumer group of a MemoryBroker. The partitions are
+// assigned round-robin to the members in the order they joined, and the group rebalances when a
+// member joins or leaves.
+type MemoryGroupConsumer struct {
+	broker    *MemoryBroker
+	topic     string
+	groupID   string
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewMemoryGroupConsumer creates a new MemoryGroupConsumer like NewKafkaGroupConsumer
+func NewMemoryGroupConsumer(broker *MemoryBroker, topic string, groupID string) GroupConsumer {
+	return &MemoryGroupConsumer{
+		broker:  broker,
+		topic:   topic,
+		groupID: groupID,
+		closed:  make(chan struct{}),
+	}
+}
+
+// Run calls the handler with every partition assigned to the member in a generation
+func (c *MemoryGroupConsumer) Run(ctx context.Context, handler func(ctx context.Context, claim Claim)) error {
+	c.broker.join(c)
+	defer c.broker.leave(c)
+
+	for {
+		// Wait until every member revoked the partitions of the previous generation
+		generation, partitions, rebalanced, ready := c.broker.sync(c)
+		if !ready {
+			select {
+			case <-rebalanced:
+				continue
+			case <-ctx.Done():
+				return nil
+			case <-c.closed:
+				return nil
+			}
+		}
+
+		claimCtx, cancel := context.WithCancel(ctx)
+		var wg sync.WaitGroup
+		for _, partition := range partitions {
+			claim := Claim{
+				Topic:     c.topic,
+				Partition: partition,
+				Offset:    c.broker.Committed(c.groupID, c.topic, partition),
+				commit: func(offset int64) error {
+					c.broker.Commit(c.groupID, Message{Topic: c.topic, Partition: partition, Offset: offset - 1})
+					return nil
+				},
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				handler(claimCtx, claim)
+			}()
+		}
+
+		done := false
+		for !done && c.broker.generation(c.groupID) == generation {
+			select {
+			case <-rebalanced:
+				rebalanced = c.broker.groupsChanged()
+			case <-ctx.Done():
+				done = true
+			case <-c.closed:
+				done = true
+			}
+		}
+		cancel()
+		wg.Wait()
+		if done {
+			return nil
+		}
+	}
+}
+
+// Close leaves the consumer group
+func (c *MemoryGroupConsumer) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	return nil
+}
+
+func (b *MemoryBroker) join(c *MemoryGroupConsumer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	group := b.group(c.groupID)
+	group.members = append(group.members, c)
+	b.rebalance(group)
+}
+
+func (b *MemoryBroker) leave(c *MemoryGroupConsumer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	group := b.group(c.groupID)
+	group.members = slices.DeleteFunc(group.members, func(member *MemoryGroupConsumer) bool {
+		return member == c
+	})
+	delete(group.synced, c)
+	b.rebalance(group)
+}
+
+// sync marks the member started the current generation, and returns its partitions and whether
+// every member started it
+func (b *MemoryBroker) sync(c *MemoryGroupConsumer) (int, []int, chan struct{}, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	group := b.group(c.groupID)
+	if group.synced[c] != group.generation {
+		group.synced[c] = group.generation
+		b.notifyGroups()
+	}
+
+	ready := true
+	for _, member := range group.members {
+		ready = ready && group.synced[member] == group.generation
+	}
+
+	var partitions []int
+	index := slices.Index(group.members, c)
+	for partition := range b.partitions(c.topic) {
+		if partition%len(group.members) == index {
+			partitions = append(partitions, partition)
+		}
+	}
+	return group.generation, partitions, b.rebalanced, ready
+}
+
+// groupsChanged returns the channel closed when the groups change
+func (b *MemoryBroker) groupsChanged() chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.rebalanced
+}
+
+func (b *MemoryBroker) generation(groupID string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.group(groupID).generation
+}
+
+func (b *MemoryBroker) group(groupID string) *memoryGroup {
+	group, ok := b.groups[groupID]
+	if !ok {
+		group = &memoryGroup{synced: map[*MemoryGroupConsumer]int{}}
+		b.groups[groupID] = group
+	}
+	return group
+}
+
+func (b *MemoryBroker) rebalance(group *memoryGroup) {
+	group.generation++
+	b.notifyGroups()
+}
+
+// notifyGroups wakes up the members waiting for a change of the groups
+func (b *MemoryBroker) notifyGroups() {
+	close(b.rebalanced)
+	b.rebalanced = make(chan struct{})
+}
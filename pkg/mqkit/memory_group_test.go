@@ -0,0 +1,101 @@
+package mqkit
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MemoryGroupTestSuite struct {
+	suite.Suite
+	broker *MemoryBroker
+	mu     sync.Mutex
+	// owners is the member owning every claimed partition
+	owners map[int]string
+}
+
+func TestMemoryGroupTestSuite(t *testing.T) {
+	suite.Run(t, new(MemoryGroupTestSuite))
+}
+
+func (suite *MemoryGroupTestSuite) SetupTest() {
+	suite.broker = NewMemoryBroker()
+	suite.broker.CreateTopic("ORDER", 4)
+	suite.owners = map[int]string{}
+}
+
+// run runs a member which claims its partitions until they are revoked
+func (suite *MemoryGroupTestSuite) run(ctx context.Context, member string) GroupConsumer {
+	consumer := NewMemoryGroupConsumer(suite.broker, "ORDER", "WORKER")
+	go consumer.Run(ctx, func(ctx context.Context, claim Claim) {
+		suite.mu.Lock()
+		if owner, ok := suite.owners[claim.Partition]; ok {
+			suite.Failf("partition owned twice", "partition %d is owned by %s and %s", claim.Partition, owner, member)
+		}
+		suite.owners[claim.Partition] = member
+		suite.mu.Unlock()
+
+		<-ctx.Done()
+		suite.mu.Lock()
+		delete(suite.owners, claim.Partition)
+		suite.mu.Unlock()
+	})
+	return consumer
+}
+
+// partitions waits until the members own the partitions
+func (suite *MemoryGroupTestSuite) partitions(expected map[string][]int) {
+	suite.Eventually(func() bool {
+		suite.mu.Lock()
+		defer suite.mu.Unlock()
+
+		actual := map[string][]int{}
+		for partition, owner := range suite.owners {
+			actual[owner] = append(actual[owner], partition)
+		}
+		for _, partitions := range actual {
+			sort.Ints(partitions)
+		}
+		return reflect.DeepEqual(expected, actual)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func (suite *MemoryGroupTestSuite) TestRun_Rebalance() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first := suite.run(ctx, "first")
+	suite.partitions(map[string][]int{"first": {0, 1, 2, 3}})
+
+	// The partitions are handed off to the joining member
+	second := suite.run(ctx, "second")
+	suite.partitions(map[string][]int{"first": {0, 2}, "second": {1, 3}})
+
+	// The partitions of the leaving member are taken over
+	suite.NoError(first.Close())
+	suite.partitions(map[string][]int{"second": {0, 1, 2, 3}})
+	suite.NoError(second.Close())
+	suite.partitions(map[string][]int{})
+}
+
+func (suite *MemoryGroupTestSuite) TestClaim_Commit() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	claims := make(chan Claim, 4)
+	consumer := NewMemoryGroupConsumer(suite.broker, "ORDER", "WORKER")
+	go consumer.Run(ctx, func(ctx context.Context, claim Claim) {
+		claims <- claim
+		<-ctx.Done()
+	})
+	claim := <-claims
+	suite.Equal(int64(0), claim.Offset)
+	suite.NoError(claim.Commit(3))
+	suite.Equal(int64(3), suite.broker.Committed("WORKER", "ORDER", claim.Partition))
+	suite.NoError(consumer.Close())
+}
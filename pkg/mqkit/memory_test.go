@@ -0,0 +1,153 @@
+package mqkit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MemoryBrokerTestSuite struct {
+	suite.Suite
+	broker *MemoryBroker
+}
+
+func TestMemoryBrokerTestSuite(t *testing.T) {
+	suite.Run(t, new(MemoryBrokerTestSuite))
+}
+
+func (suite *MemoryBrokerTestSuite) SetupTest() {
+	suite.broker = NewMemoryBroker()
+}
+
+func (suite *MemoryBrokerTestSuite) consume(consumer Consumer) Message {
+	var consumed Message
+	suite.Require().NoError(consumer.Consume(context.Background(), func(msg Message) error {
+		consumed = msg
+		return nil
+	}))
+	return consumed
+}
+
+func (suite *MemoryBrokerTestSuite) TestAppend_PartitionsByKey() {
+	suite.broker.CreateTopic("ORDER", 4)
+
+	first := suite.broker.Append("ORDER", []byte("AAPL"), []byte("order1"))
+	second := suite.broker.Append("ORDER", []byte("AAPL"), []byte("order2"))
+	suite.Equal(first.Partition, second.Partition)
+	suite.Equal(int64(0), first.Offset)
+	suite.Equal(int64(1), second.Offset)
+
+	// A topic used before it is created has a single partition
+	suite.Equal(0, suite.broker.Append("MATCHING", []byte("AAPL"), []byte("matching1")).Partition)
+}
+
+func (suite *MemoryBrokerTestSuite) TestPublishBatch_KeysAndHeaders() {
+	producer := NewMemoryProducer(suite.broker, "ORDER", []byte("AAPL"))
+	suite.Require().NoError(producer.PublishBatch(context.Background(), []Message{
+		{Key: []byte("MSFT"), Value: []byte("order1"), Headers: map[string]string{"content-type": "application/x-protobuf"}},
+		{Value: []byte("order2")},
+	}))
+
+	consumer := NewMemoryConsumer(suite.broker, "ORDER", "WORKER")
+	first := suite.consume(consumer)
+	suite.Equal([]byte("MSFT"), first.Key)
+	suite.Equal(map[string]string{"content-type": "application/x-protobuf"}, first.Headers)
+
+	// A message without a key has the key of the producer
+	suite.Equal([]byte("AAPL"), suite.consume(consumer).Key)
+}
+
+func (suite *MemoryBrokerTestSuite) TestConsume_CommitsGroupOffset() {
+	producer := NewMemoryProducer(suite.broker, "ORDER", []byte("AAPL"))
+	for _, val := range []string{"order1", "order2", "order3"} {
+		suite.Require().NoError(producer.Publish(context.Background(), []byte(val)))
+	}
+
+	consumer := NewMemoryConsumer(suite.broker, "ORDER", "WORKER")
+	suite.Equal("order1", string(suite.consume(consumer).Value))
+
+	// A failed message is received again and not committed
+	err := consumer.Consume(context.Background(), func(msg Message) error {
+		return errors.New("failed")
+	})
+	suite.Error(err)
+	suite.Equal(int64(1), suite.broker.Committed("WORKER", "ORDER", 0))
+	suite.Equal("order2", string(suite.consume(consumer).Value))
+
+	// Another consumer of the group resumes from the committed offset, another group from the start
+	suite.Equal("order3", string(suite.consume(NewMemoryConsumer(suite.broker, "ORDER", "WORKER")).Value))
+	suite.Equal("order1", string(suite.consume(NewMemoryConsumer(suite.broker, "ORDER", "REPLAY")).Value))
+}
+
+func (suite *MemoryBrokerTestSuite) TestConsume_PartitionFromOffset() {
+	for _, val := range []string{"order1", "order2"} {
+		suite.broker.Append("ORDER", nil, []byte(val))
+	}
+
+	consumer := NewMemoryPartitionConsumer(suite.broker, "ORDER", 0, 1)
+	msg := suite.consume(consumer)
+	suite.Equal("order2", string(msg.Value))
+	suite.Equal(int64(1), msg.Offset)
+
+	// Consume blocks until the next message is appended
+	consumed := make(chan Message, 1)
+	go func() {
+		consumed <- suite.consume(consumer)
+	}()
+	suite.broker.Append("ORDER", nil, []byte("order3"))
+	select {
+	case msg := <-consumed:
+		suite.Equal(int64(2), msg.Offset)
+	case <-time.After(time.Second):
+		suite.Fail("the appended message was not consumed")
+	}
+}
+
+func (suite *MemoryBrokerTestSuite) TestClose_StopsConsume() {
+	consumer := NewMemoryConsumer(suite.broker, "ORDER", "WORKER")
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- consumer.Consume(context.Background(), func(msg Message) error {
+			return nil
+		})
+	}()
+	suite.NoError(consumer.Close())
+	select {
+	case err := <-stopped:
+		suite.ErrorIs(err, ErrClosed)
+	case <-time.After(time.Second):
+		suite.Fail("Consume was not stopped")
+	}
+}
+
+func (suite *MemoryBrokerTestSuite) TestConsumeBatch() {
+	for _, val := range []string{"order1", "order2", "order3"} {
+		suite.broker.Append("ORDER", nil, []byte(val))
+	}
+	consumer := NewMemoryConsumer(suite.broker, "ORDER", "WORKER")
+	opts := BatchOptions{MaxMessages: 2, MaxWait: 10 * time.Millisecond}
+
+	var batch []string
+	handler := func(msgs []Message) error {
+		batch = nil
+		for _, msg := range msgs {
+			batch = append(batch, string(msg.Value))
+		}
+		return nil
+	}
+	suite.Require().NoError(consumer.ConsumeBatch(context.Background(), opts, handler))
+	suite.Equal([]string{"order1", "order2"}, batch)
+	suite.Equal(int64(2), suite.broker.Committed("WORKER", "ORDER", 0))
+
+	// A failed batch is received again, and a batch is not full after the wait
+	suite.Error(consumer.ConsumeBatch(context.Background(), opts, func(msgs []Message) error {
+		return errors.New("failed")
+	}))
+	suite.Require().NoError(consumer.ConsumeBatch(context.Background(), opts, handler))
+	suite.Equal([]string{"order3"}, batch)
+	suite.Equal(int64(3), suite.broker.Committed("WORKER", "ORDER", 0))
+}
@@ -0,0 +1,22 @@
+package mqkit
+
+import (
+	"hash/crc32"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// PartitionFor maps a key, e.g. a symbol, to a partition deterministically, so the messages of a
+// key are in order and topics with the same number of partitions put a key in the same partition.
+// A message without a key goes to the first partition.
+func PartitionFor(key []byte, partitions int) int {
+	if len(key) == 0 || partitions <= 1 {
+		return 0
+	}
+	return int(crc32.ChecksumIEEE(key) % uint32(partitions))
+}
+
+// keyBalancer balances the messages of a kafka.Writer by PartitionFor
+var keyBalancer = kafka.BalancerFunc(func(msg kafka.Message, partitions ...int) int {
+	return partitions[PartitionFor(msg.Key, len(partitions))]
+})
@@ -5,6 +5,12 @@ import (
 )
 
 type Producer interface {
+	// Publish sends a message with the key of the producer
 	Publish(ctx context.Context, val []byte) error
+	// PublishWithKey sends a message to the partition of the key
+	PublishWithKey(ctx context.Context, key, val []byte) error
+	// PublishBatch sends the messages with their keys and headers at once, a message without a
+	// key has the key of the producer
+	PublishBatch(ctx context.Context, msgs []Message) error
 	Close() error
 }
@@ -2,62 +2,111 @@ package pubsubkit
 
 import (
 	"context"
-	"log"
+	"errors"
+	"io"
+	"time"
 
 	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/Hao1995/order-matching-system/pkg/mqkit"
 )
 
 // KafkaPubSub implements both Publisher and Subscriber
 type KafkaPubSub struct {
-	// @todo: allow to use a custom logger
-
 	Writer *kafka.Writer
 	Reader *kafka.Reader
+	logger *zap.Logger
 }
 
-// NewKafkaPublisher creates a new Kafka publisher
-func NewKafkaPublisher(brokers []string, topic string) Publisher {
+// NewKafkaPublisher creates a new Kafka publisher, which partitions the messages by
+// mqkit.PartitionFor
+func NewKafkaPublisher(brokers []string, topic string, opts ...Option) Publisher {
+	o := newOptions(opts)
 	return &KafkaPubSub{
 		Writer: &kafka.Writer{
 			Addr:  kafka.TCP(brokers...),
 			Topic: topic,
+			Balancer: kafka.BalancerFunc(func(msg kafka.Message, partitions ...int) int {
+				return partitions[mqkit.PartitionFor(msg.Key, len(partitions))]
+			}),
+			// Do not hold a synchronous write for the default second to fill the batch
+			BatchTimeout: 10 * time.Millisecond,
 		},
+		logger: o.logger,
 	}
 }
 
 // Publish sends a message to a Kafka topic
-func (k *KafkaPubSub) Publish(value []byte) error {
-	return k.Writer.WriteMessages(context.Background(), kafka.Message{
-		Value: value,
-	})
+func (k *KafkaPubSub) Publish(ctx context.Context, msg Message) error {
+	return k.PublishBatch(ctx, []Message{msg})
+}
+
+// PublishBatch sends the messages to a Kafka topic in as few requests as possible
+func (k *KafkaPubSub) PublishBatch(ctx context.Context, msgs []Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	messages := make([]kafka.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		message := kafka.Message{
+			Key:   msg.Key,
+			Value: msg.Value,
+		}
+		for key, value := range msg.Headers {
+			message.Headers = append(message.Headers, kafka.Header{Key: key, Value: []byte(value)})
+		}
+		messages = append(messages, message)
+	}
+	return k.Writer.WriteMessages(ctx, messages...)
 }
 
 // NewKafkaSubscriber creates a new Kafka subscriber
-func NewKafkaSubscriber(brokers []string, topic string, groupID string) Subscriber {
+func NewKafkaSubscriber(brokers []string, topic string, groupID string, opts ...Option) Subscriber {
+	o := newOptions(opts)
 	return &KafkaPubSub{
 		Reader: kafka.NewReader(kafka.ReaderConfig{
 			Brokers: brokers,
 			GroupID: groupID,
 			Topic:   topic,
 		}),
+		logger: o.logger,
 	}
 }
 
-// Subscribe listens to messages from a Kafka topic
-func (k *KafkaPubSub) Subscribe(handler func(value []byte) error) error {
-	k.Reader.SetOffset(kafka.LastOffset)
-
+// Subscribe listens to messages from a Kafka topic and commits every handled message, a message
+// retried is fetched again by the next subscription if it is cancelled meanwhile
+func (k *KafkaPubSub) Subscribe(ctx context.Context, handler func(ctx context.Context, msg Message) error) error {
 	for {
-		message, err := k.Reader.FetchMessage(context.Background())
+		message, err := k.Reader.FetchMessage(ctx)
 		if err != nil {
+			// The reader returns io.EOF when it is closed
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return nil
+			}
 			return err
 		}
-		if err := handler(message.Value); err != nil {
-			log.Printf("Error processing message: %v", err)
+
+		msg := Message{
+			Key:   message.Key,
+			Value: message.Value,
+		}
+		if len(message.Headers) > 0 {
+			msg.Headers = make(map[string]string, len(message.Headers))
+			for _, header := range message.Headers {
+				msg.Headers[header.Key] = string(header.Value)
+			}
+		}
+		if !handle(ctx, handler, msg, k.logger, zap.String("topic", message.Topic),
+			zap.Int("partition", message.Partition), zap.Int64("offset", message.Offset)) {
+			return nil
 		}
 
-		if err := k.Reader.CommitMessages(context.Background(), message); err != nil {
-			log.Printf("Error committing message: %v", err)
+		// The handled message is committed even if the subscription is cancelled meanwhile
+		if err := k.Reader.CommitMessages(context.WithoutCancel(ctx), message); err != nil {
+			k.logger.Error("failed to commit message", zap.Error(err), zap.String("topic", message.Topic),
+				zap.Int("partition", message.Partition), zap.Int64("offset", message.Offset))
 		}
 	}
 }
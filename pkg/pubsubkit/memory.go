@@ -0,0 +1,83 @@
+package pubsubkit
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+
+	"github.com/Hao1995/order-matching-system/pkg/mqkit"
+)
+
+// MemoryPubSub implements both Publisher and Subscriber on a topic of an mqkit.MemoryBroker
+type MemoryPubSub struct {
+	broker   *mqkit.MemoryBroker
+	topic    string
+	consumer mqkit.Consumer
+	logger   *zap.Logger
+}
+
+// NewMemoryPublisher creates a new in-memory publisher
+func NewMemoryPublisher(broker *mqkit.MemoryBroker, topic string, opts ...Option) Publisher {
+	o := newOptions(opts)
+	return &MemoryPubSub{
+		broker: broker,
+		topic:  topic,
+		logger: o.logger,
+	}
+}
+
+// Publish appends a message to the partition of its key
+func (m *MemoryPubSub) Publish(ctx context.Context, msg Message) error {
+	return m.PublishBatch(ctx, []Message{msg})
+}
+
+// PublishBatch appends the messages to the partitions of their keys
+func (m *MemoryPubSub) PublishBatch(ctx context.Context, msgs []Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		m.broker.AppendMessage(mqkit.Message{Topic: m.topic, Key: msg.Key, Value: msg.Value, Headers: msg.Headers})
+	}
+	return nil
+}
+
+// NewMemorySubscriber creates a new in-memory subscriber of the group, which receives the first
+// partition only
+func NewMemorySubscriber(broker *mqkit.MemoryBroker, topic string, groupID string, opts ...Option) Subscriber {
+	o := newOptions(opts)
+	return &MemoryPubSub{
+		broker:   broker,
+		topic:    topic,
+		consumer: mqkit.NewMemoryConsumer(broker, topic, groupID),
+		logger:   o.logger,
+	}
+}
+
+// Subscribe listens to the messages after the offset committed by the group until the context is
+// done or it is closed
+func (m *MemoryPubSub) Subscribe(ctx context.Context, handler func(ctx context.Context, msg Message) error) error {
+	for {
+		if err := m.consumer.Consume(ctx, func(msg mqkit.Message) error {
+			if !handle(ctx, handler, Message{Key: msg.Key, Value: msg.Value, Headers: msg.Headers}, m.logger,
+				zap.String("topic", msg.Topic), zap.Int("partition", msg.Partition), zap.Int64("offset", msg.Offset)) {
+				return ctx.Err()
+			}
+			return nil
+		}); err != nil {
+			if ctx.Err() != nil || errors.Is(err, mqkit.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Close stops the subscription
+func (m *MemoryPubSub) Close() error {
+	if m.consumer != nil {
+		return m.consumer.Close()
+	}
+	return nil
+}
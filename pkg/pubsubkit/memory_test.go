@@ -0,0 +1,146 @@
+package pubsubkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/Hao1995/order-matching-system/pkg/mqkit"
+)
+
+type MemoryPubSubTestSuite struct {
+	suite.Suite
+	broker *mqkit.MemoryBroker
+	logs   *observer.ObservedLogs
+	logger *zap.Logger
+}
+
+func TestMemoryPubSubTestSuite(t *testing.T) {
+	suite.Run(t, new(MemoryPubSubTestSuite))
+}
+
+func (suite *MemoryPubSubTestSuite) SetupTest() {
+	suite.broker = mqkit.NewMemoryBroker()
+	core, logs := observer.New(zap.ErrorLevel)
+	suite.logs = logs
+	suite.logger = zap.New(core)
+}
+
+func (suite *MemoryPubSubTestSuite) TestSubscribe_KeysAndHeaders() {
+	publisher := NewMemoryPublisher(suite.broker, "MATCHING")
+	suite.Require().NoError(publisher.PublishBatch(context.Background(), []Message{
+		{Key: []byte("AAPL"), Value: []byte("matching1"), Headers: map[string]string{"trace_id": "1"}},
+		{Key: []byte("AAPL"), Value: []byte("poison")},
+		{Key: []byte("AAPL"), Value: []byte("matching2")},
+	}))
+
+	subscriber := NewMemorySubscriber(suite.broker, "MATCHING", "order", WithLogger(suite.logger))
+	ctx, cancel := context.WithCancel(context.Background())
+	var received []Message
+	done := make(chan error, 1)
+	go func() {
+		done <- subscriber.Subscribe(ctx, func(ctx context.Context, msg Message) error {
+			if string(msg.Value) == "poison" {
+				return errors.New("poison")
+			}
+			received = append(received, msg)
+			if len(received) == 2 {
+				cancel()
+			}
+			return nil
+		})
+	}()
+
+	// The subscription returns when the context is done, after skipping the failed message
+	select {
+	case err := <-done:
+		suite.NoError(err)
+	case <-time.After(time.Second):
+		suite.FailNow("Subscribe did not return")
+	}
+	suite.Require().Len(received, 2)
+	suite.Equal([]byte("AAPL"), received[0].Key)
+	suite.Equal(map[string]string{"trace_id": "1"}, received[0].Headers)
+	suite.Equal("matching2", string(received[1].Value))
+	suite.Equal(1, suite.logs.FilterMessage("failed to handle message").Len())
+	suite.Equal(int64(3), suite.broker.Committed("order", "MATCHING", 0))
+}
+
+func (suite *MemoryPubSubTestSuite) TestSubscribe_Close() {
+	subscriber := NewMemorySubscriber(suite.broker, "MATCHING", "order")
+	done := make(chan error, 1)
+	go func() {
+		done <- subscriber.Subscribe(context.Background(), func(ctx context.Context, msg Message) error {
+			return nil
+		})
+	}()
+
+	suite.NoError(subscriber.Close())
+	select {
+	case err := <-done:
+		suite.NoError(err)
+	case <-time.After(time.Second):
+		suite.FailNow("Subscribe did not return")
+	}
+}
+
+func (suite *MemoryPubSubTestSuite) TestSubscribe_Retry() {
+	publisher := NewMemoryPublisher(suite.broker, "MATCHING")
+	suite.Require().NoError(publisher.Publish(context.Background(), Message{Key: []byte("AAPL"), Value: []byte("matching1")}))
+
+	subscriber := NewMemorySubscriber(suite.broker, "MATCHING", "order", WithLogger(suite.logger))
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	done := make(chan error, 1)
+	go func() {
+		done <- subscriber.Subscribe(ctx, func(ctx context.Context, msg Message) error {
+			attempts++
+			if attempts < 3 {
+				return fmt.Errorf("%w: not durable", ErrRetry)
+			}
+			cancel()
+			return nil
+		})
+	}()
+
+	// The message is handled again until it succeeds, then committed
+	select {
+	case err := <-done:
+		suite.NoError(err)
+	case <-time.After(time.Second):
+		suite.FailNow("Subscribe did not return")
+	}
+	suite.Equal(3, attempts)
+	suite.Equal(2, suite.logs.FilterMessage("failed to handle message").Len())
+	suite.Equal(int64(1), suite.broker.Committed("order", "MATCHING", 0))
+}
+
+func (suite *MemoryPubSubTestSuite) TestSubscribe_RetryCancelled() {
+	publisher := NewMemoryPublisher(suite.broker, "MATCHING")
+	suite.Require().NoError(publisher.Publish(context.Background(), Message{Key: []byte("AAPL"), Value: []byte("matching1")}))
+
+	subscriber := NewMemorySubscriber(suite.broker, "MATCHING", "order", WithLogger(suite.logger))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- subscriber.Subscribe(ctx, func(ctx context.Context, msg Message) error {
+			cancel()
+			return fmt.Errorf("%w: not durable", ErrRetry)
+		})
+	}()
+
+	// The message cancelled while retried is not committed, so it is received again
+	select {
+	case err := <-done:
+		suite.NoError(err)
+	case <-time.After(time.Second):
+		suite.FailNow("Subscribe did not return")
+	}
+	suite.Equal(int64(0), suite.broker.Committed("order", "MATCHING", 0))
+}
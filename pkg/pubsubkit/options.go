@@ -0,0 +1,28 @@
+package pubsubkit
+
+import (
+	"go.uber.org/zap"
+)
+
+// Option configures a Publisher or a Subscriber
+type Option func(*options)
+
+type options struct {
+	logger *zap.Logger
+}
+
+// WithLogger sets the logger of the failures which do not stop a Subscriber, the global zap
+// logger by default
+func WithLogger(logger *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{logger: zap.L()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
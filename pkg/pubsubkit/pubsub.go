@@ -1,13 +1,69 @@
 package pubsubkit
 
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// retryInterval is the first wait before a message is handled again, doubled up to
+	// maxRetryInterval
+	retryInterval    = 100 * time.Millisecond
+	maxRetryInterval = 5 * time.Second
+)
+
+// ErrRetry is wrapped by a handler error to handle the message again instead of skipping it, e.g.
+// when its effect is not durable yet
+var ErrRetry = errors.New("retry message")
+
+// Message is a message published to or received from a topic
+type Message struct {
+	// Key puts the messages of a key in order, e.g. a symbol
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
+}
+
 // Publisher defines the interface for publishing messages
 type Publisher interface {
-	Publish(value []byte) error
+	// Publish sends a message until the context is done
+	Publish(ctx context.Context, msg Message) error
+	// PublishBatch sends the messages at once
+	PublishBatch(ctx context.Context, msgs []Message) error
 	Close() error
 }
 
 // Subscriber defines the interface for subscribing to messages
 type Subscriber interface {
-	Subscribe(handler func(value []byte) error) error
+	// Subscribe calls the handler with every message until the context is done or the subscriber
+	// is closed, then returns nil. A message the handler failed is logged and skipped, unless the
+	// error wraps ErrRetry: then it is handled again with a backoff and not committed meanwhile.
+	Subscribe(ctx context.Context, handler func(ctx context.Context, msg Message) error) error
 	Close() error
 }
+
+// handle calls the handler with the message until it does not return ErrRetry, and returns false
+// if the context is done before, so that the message is not committed
+func handle(ctx context.Context, handler func(ctx context.Context, msg Message) error, msg Message, logger *zap.Logger, fields ...zap.Field) bool {
+	interval := retryInterval
+	for {
+		err := handler(ctx, msg)
+		if err == nil {
+			return true
+		}
+		logger.Error("failed to handle message", append(fields, zap.Error(err))...)
+		if !errors.Is(err, ErrRetry) {
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(interval):
+		}
+		interval = min(2*interval, maxRetryInterval)
+	}
+}
@@ -0,0 +1,378 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/Hao1995/order-matching-system/internal/api/account"
+	"github.com/Hao1995/order-matching-system/internal/api/auth"
+	"github.com/Hao1995/order-matching-system/internal/api/order"
+	"github.com/Hao1995/order-matching-system/internal/api/ratelimit"
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	matchingengine "github.com/Hao1995/order-matching-system/internal/worker/matching_engine"
+	"github.com/Hao1995/order-matching-system/pkg/mqkit"
+	"github.com/Hao1995/order-matching-system/pkg/pubsubkit"
+)
+
+const (
+	symbol        = "AAPL"
+	orderTopic    = "AAPL_ORDER"
+	matchingTopic = "AAPL_MATCHING"
+)
+
+func newEngine(symbol string, opts ...matchingengine.EngineOption) (*matchingengine.Engine, error) {
+	matcher := matchingengine.NewMatcher(matchingengine.NewOrderBook(), 5)
+	return matchingengine.NewEngine(symbol, matcher, matchingengine.NewRiskChecker(matchingengine.RiskConfig{}), opts...), nil
+}
+
+// OrderMatchingTestSuite runs the order API and the matching engine worker on an in-memory broker
+type OrderMatchingTestSuite struct {
+	suite.Suite
+	broker *mqkit.MemoryBroker
+	router *gin.Engine
+	// handler serves the order routes of the router, without the auth middleware
+	handler *order.Handler
+	// accounts serves the account routes of the router, without the auth middleware
+	accounts *account.Handler
+	cancel   context.CancelFunc
+	closes   []func() error
+}
+
+func TestOrderMatchingTestSuite(t *testing.T) {
+	suite.Run(t, new(OrderMatchingTestSuite))
+}
+
+func (suite *OrderMatchingTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	suite.broker = mqkit.NewMemoryBroker()
+	var ctx context.Context
+	ctx, suite.cancel = context.WithCancel(context.Background())
+
+	// Order API
+	producer := mqkit.NewMemoryProducer(suite.broker, orderTopic, []byte(symbol))
+	ledger := account.NewLedger("USD")
+	subscriber := pubsubkit.NewMemorySubscriber(suite.broker, matchingTopic, "order")
+	settler := account.NewSettler(ledger)
+	go subscriber.Subscribe(ctx, func(ctx context.Context, msg pubsubkit.Message) error {
+		codec, err := events.CodecFor(msg.Headers[events.ContentTypeHeader])
+		if err != nil {
+			return err
+		}
+		return settler.HandleEncoded(codec, msg.Value)
+	})
+	suite.router = gin.New()
+	suite.handler = order.NewHandler(order.NewService(producer, events.ProtobufCodec, orderTopic, ledger))
+	suite.accounts = account.NewHandler(ledger)
+	order.RegisterRoutes(suite.router, suite.handler)
+	account.RegisterRoutes(suite.router, suite.accounts)
+	account.RegisterAdminRoutes(suite.router, suite.accounts)
+
+	// Matching engine worker, with the protobuf wire format of the events
+	publisher := pubsubkit.NewMemoryPublisher(suite.broker, matchingTopic)
+	partition := matchingengine.NewPartition(0, newEngine, nil)
+	replica := matchingengine.NewReplica(func(matchingEvents []events.MatchingEvent) error {
+		msgs := make([]pubsubkit.Message, 0, len(matchingEvents))
+		for _, matchingEvent := range matchingEvents {
+			val, err := events.ProtobufCodec.EncodeMatchingEvent(matchingEvent)
+			if err != nil {
+				return err
+			}
+			msgs = append(msgs, pubsubkit.Message{
+				Key:     []byte(matchingEvent.Order.Symbol),
+				Value:   val,
+				Headers: map[string]string{events.ContentTypeHeader: events.ContentTypeProtobuf},
+			})
+		}
+		return publisher.PublishBatch(ctx, msgs)
+	}, 100)
+	suite.Require().NoError(replica.Promote(0))
+	consumer := mqkit.NewMemoryPartitionConsumer(suite.broker, orderTopic, 0, partition.LastOffset()+1)
+	go func() {
+		for ctx.Err() == nil {
+			consumer.Consume(ctx, func(msg mqkit.Message) error {
+				codec, err := events.CodecFor(msg.Headers[events.ContentTypeHeader])
+				if err != nil {
+					return err
+				}
+				matchingEvents, err := partition.HandleEncoded(msg.Offset, msg.Key, codec, msg.Value)
+				if err != nil {
+					return err
+				}
+				return replica.Publish(matchingEvents)
+			})
+		}
+	}()
+
+	suite.closes = []func() error{subscriber.Close, consumer.Close}
+}
+
+func (suite *OrderMatchingTestSuite) TearDownTest() {
+	suite.cancel()
+	for _, close := range suite.closes {
+		suite.NoError(close())
+	}
+}
+
+func (suite *OrderMatchingTestSuite) request(method, path string, body interface{}) *httptest.ResponseRecorder {
+	recorder, err := serve(suite.router, method, path, body)
+	suite.Require().NoError(err)
+	return recorder
+}
+
+// serve sends a request with the JSON body to the router
+func serve(router *gin.Engine, method, path string, body interface{}) (*httptest.ResponseRecorder, error) {
+	reader := bytes.NewReader(nil)
+	if body != nil {
+		val, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(val)
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, path, reader))
+	return recorder, nil
+}
+
+// matchingEvents waits for n matching events to be published
+func (suite *OrderMatchingTestSuite) matchingEvents(n int) []events.MatchingEvent {
+	suite.Require().Eventually(func() bool {
+		return len(suite.broker.Messages(matchingTopic, 0)) >= n
+	}, time.Second, 10*time.Millisecond)
+
+	var result []events.MatchingEvent
+	for _, msg := range suite.broker.Messages(matchingTopic, 0) {
+		suite.Equal(events.ContentTypeProtobuf, msg.Headers[events.ContentTypeHeader])
+		matchingEvent, err := events.ProtobufCodec.DecodeMatchingEvent(msg.Value)
+		suite.Require().NoError(err)
+		result = append(result, matchingEvent)
+	}
+	return result
+}
+
+func (suite *OrderMatchingTestSuite) balances(accountID string) map[string]account.Balance {
+	recorder := suite.request(http.MethodGet, "/accounts/"+accountID+"/balances", nil)
+	suite.Require().Equal(http.StatusOK, recorder.Code)
+
+	var balances []account.Balance
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &balances))
+	result := map[string]account.Balance{}
+	for _, balance := range balances {
+		result[balance.Asset] = balance
+	}
+	return result
+}
+
+func (suite *OrderMatchingTestSuite) TestCreateOrders_Match() {
+	suite.Equal(http.StatusCreated, suite.request(http.MethodPost, "/accounts/seller/deposits", gin.H{"asset": symbol, "amount": 10}).Code)
+	suite.Equal(http.StatusCreated, suite.request(http.MethodPost, "/accounts/buyer/deposits", gin.H{"asset": "USD", "amount": 1000}).Code)
+
+	suite.Equal(http.StatusCreated, suite.request(http.MethodPost, "/orders", gin.H{
+		"account_id": "seller", "symbol": symbol, "type": "Sell", "price": 100.0, "quantity": 10,
+	}).Code)
+	suite.Equal(http.StatusCreated, suite.request(http.MethodPost, "/orders", gin.H{
+		"account_id": "buyer", "symbol": symbol, "type": "Buy", "price": 100.0, "quantity": 4,
+	}).Code)
+
+	matchingEvents := suite.matchingEvents(2)
+	suite.Require().Len(matchingEvents, 2)
+	suite.Equal(uint64(1), matchingEvents[0].Seq)
+	suite.Equal(int64(0), matchingEvents[0].InputOffset)
+	suite.Equal(events.MatchingEventTypeCreate, matchingEvents[0].Type)
+	suite.Equal([]events.TickEvent{{Price: 100.0, Quantity: 10}}, matchingEvents[0].SellTicks)
+
+	suite.Equal(uint64(2), matchingEvents[1].Seq)
+	suite.Equal(int64(1), matchingEvents[1].InputOffset)
+	suite.Require().Len(matchingEvents[1].Transactions, 1)
+	transaction := matchingEvents[1].Transactions[0]
+	suite.Equal("AAPL-1", transaction.ID)
+	suite.Equal(matchingEvents[0].Order.ID, transaction.SellOrderID)
+	suite.Equal(matchingEvents[1].Order.ID, transaction.BuyOrderID)
+	suite.Equal(100.0, transaction.Price)
+	suite.Equal(int64(4), transaction.Quantity)
+	suite.Equal([]events.TickEvent{{Price: 100.0, Quantity: 6}}, matchingEvents[1].SellTicks)
+
+	// The settler of the order API moves the funds of the fill
+	suite.Eventually(func() bool {
+		return suite.balances("buyer")[symbol].Available == 4
+	}, time.Second, 10*time.Millisecond)
+	suite.Equal(account.Balance{Asset: "USD", Available: 600}, suite.balances("buyer")["USD"])
+	suite.Equal(account.Balance{Asset: "USD", Available: 400}, suite.balances("seller")["USD"])
+	suite.Equal(account.Balance{Asset: symbol, Held: 6}, suite.balances("seller")[symbol])
+}
+
+func (suite *OrderMatchingTestSuite) TestCancelOrder() {
+	suite.Equal(http.StatusCreated, suite.request(http.MethodPost, "/accounts/buyer/deposits", gin.H{"asset": "USD", "amount": 1000}).Code)
+	recorder := suite.request(http.MethodPost, "/orders", gin.H{
+		"account_id": "buyer", "symbol": symbol, "type": "Buy", "price": 100.0, "quantity": 4,
+	})
+	suite.Equal(http.StatusCreated, recorder.Code)
+
+	// The response has the ID of the order, which cancels it
+	var created struct {
+		ID string `json:"id"`
+	}
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &created))
+	orderID := created.ID
+	suite.Equal(suite.matchingEvents(1)[0].Order.ID, orderID)
+
+	suite.Equal(http.StatusCreated, suite.request(http.MethodDelete, "/orders/"+orderID, nil).Code)
+
+	matchingEvents := suite.matchingEvents(2)
+	suite.Equal(events.MatchingEventTypeCancel, matchingEvents[1].Type)
+	suite.Empty(matchingEvents[1].BuyTicks)
+
+	// The hold of the cancelled order is released
+	suite.Eventually(func() bool {
+		return suite.balances("buyer")["USD"].Available == 1000
+	}, time.Second, 10*time.Millisecond)
+}
+
+func (suite *OrderMatchingTestSuite) TestBatchCreateAndMassCancel() {
+	suite.Equal(http.StatusCreated, suite.request(http.MethodPost, "/accounts/buyer/deposits", gin.H{"asset": "USD", "amount": 1000}).Code)
+
+	recorder := suite.request(http.MethodPost, "/orders/batch", gin.H{"orders": []gin.H{
+		{"account_id": "buyer", "symbol": symbol, "type": "Buy", "price": 100.0, "quantity": 4},
+		{"account_id": "buyer", "symbol": symbol, "type": "Hold", "price": 100.0, "quantity": 4},
+		{"account_id": "buyer", "symbol": symbol, "type": "Buy", "price": 99.0, "quantity": 2},
+		{"account_id": "buyer", "symbol": symbol, "type": "Buy", "price": 99.0, "quantity": 20},
+	}})
+	suite.Require().Equal(http.StatusMultiStatus, recorder.Code)
+	var response struct {
+		Results []order.BatchResult `json:"results"`
+	}
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.Require().Len(response.Results, 4)
+	statuses := make([]order.BatchStatus, 0, len(response.Results))
+	for _, result := range response.Results {
+		statuses = append(statuses, result.Status)
+	}
+	suite.Equal([]order.BatchStatus{order.BatchStatusAccepted, order.BatchStatusRejected, order.BatchStatusAccepted, order.BatchStatusRejected}, statuses)
+	suite.Contains(response.Results[3].Error, account.ErrInsufficientBalance.Error())
+
+	matchingEvents := suite.matchingEvents(2)
+	suite.Equal(response.Results[0].ID, matchingEvents[0].Order.ID)
+	suite.Equal(response.Results[2].ID, matchingEvents[1].Order.ID)
+	suite.Equal(account.Balance{Asset: "USD", Available: 402, Held: 598}, suite.balances("buyer")["USD"])
+
+	// The orders are cancelled in one matching event, and their holds are released
+	suite.Equal(http.StatusCreated, suite.request(http.MethodDelete, "/orders?account_id=buyer&symbol="+symbol+"&side=Buy", nil).Code)
+	matchingEvents = suite.matchingEvents(3)
+	suite.Require().Len(matchingEvents, 3)
+	suite.Equal(events.MatchingEventTypeMassCancel, matchingEvents[2].Type)
+	suite.Len(matchingEvents[2].Cancelled, 2)
+	suite.Empty(matchingEvents[2].BuyTicks)
+	suite.Eventually(func() bool {
+		return suite.balances("buyer")["USD"].Available == 1000
+	}, time.Second, 10*time.Millisecond)
+
+	suite.Equal(http.StatusUnprocessableEntity, suite.request(http.MethodDelete, "/orders?symbol="+symbol, nil).Code)
+	suite.Equal(http.StatusBadRequest, suite.request(http.MethodPost, "/orders/batch", gin.H{"orders": []gin.H{}}).Code)
+	tooLarge := make([]gin.H, 101)
+	for i := range tooLarge {
+		tooLarge[i] = gin.H{"account_id": "buyer", "symbol": symbol, "type": "Buy", "price": 1.0, "quantity": 1}
+	}
+	suite.Equal(http.StatusBadRequest, suite.request(http.MethodPost, "/orders/batch", gin.H{"orders": tooLarge}).Code)
+}
+
+func (suite *OrderMatchingTestSuite) TestAuth() {
+	authenticator := auth.NewAuthenticator(auth.Config{
+		Keys:         []auth.Key{{ID: "alice-key", AccountID: "alice", Secret: "alice-secret"}, {ID: "bob-key", AccountID: "bob", Secret: "bob-secret"}},
+		AdminKeys:    []auth.Key{{ID: "ops-key", AccountID: "ops", Secret: "ops-secret"}},
+		ReplayWindow: time.Minute,
+	})
+	router := gin.New()
+	order.RegisterRoutes(router.Group("", authenticator.Middleware()), suite.handler)
+	account.RegisterRoutes(router.Group("", authenticator.Middleware()), suite.accounts)
+	account.RegisterAdminRoutes(router.Group("", authenticator.AdminMiddleware()), suite.accounts)
+	signed := func(keyID, secret, method, path string, body interface{}) int {
+		var val []byte
+		if body != nil {
+			var err error
+			val, err = json.Marshal(body)
+			suite.Require().NoError(err)
+		}
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req := httptest.NewRequest(method, path, bytes.NewReader(val))
+		req.Header.Set(auth.KeyHeader, keyID)
+		req.Header.Set(auth.TimestampHeader, timestamp)
+		req.Header.Set(auth.SignatureHeader, auth.Sign(secret, method, path, timestamp, val))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+	// Only the admin keys deposit
+	deposit := gin.H{"asset": "USD", "amount": 1000}
+	suite.Equal(http.StatusUnauthorized, signed("alice-key", "alice-secret", http.MethodPost, "/accounts/alice/deposits", deposit))
+	suite.Equal(http.StatusCreated, signed("ops-key", "ops-secret", http.MethodPost, "/accounts/alice/deposits", deposit))
+
+	// The balances and entries are read by their account only
+	suite.Equal(http.StatusOK, signed("alice-key", "alice-secret", http.MethodGet, "/accounts/alice/balances", nil))
+	suite.Equal(http.StatusForbidden, signed("bob-key", "bob-secret", http.MethodGet, "/accounts/alice/balances", nil))
+	suite.Equal(http.StatusForbidden, signed("bob-key", "bob-secret", http.MethodGet, "/accounts/alice/entries", nil))
+	suite.Equal(http.StatusUnauthorized, signed("ops-key", "ops-secret", http.MethodGet, "/accounts/alice/entries", nil))
+
+	// The account of the order is the one of the key
+	suite.Equal(http.StatusCreated, signed("alice-key", "alice-secret", http.MethodPost, "/orders", gin.H{
+		"symbol": symbol, "type": "Buy", "price": 100.0, "quantity": 4,
+	}))
+	created := suite.matchingEvents(1)[0]
+	suite.Equal("alice", created.Order.AccountID)
+	suite.Equal(http.StatusForbidden, signed("alice-key", "alice-secret", http.MethodPost, "/orders", gin.H{
+		"account_id": "bob", "symbol": symbol, "type": "Buy", "price": 100.0, "quantity": 4,
+	}))
+	unsigned, err := serve(router, http.MethodDelete, "/orders/"+created.Order.ID, nil)
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusUnauthorized, unsigned.Code)
+
+	// Only the owner cancels the order
+	suite.Equal(http.StatusForbidden, signed("bob-key", "bob-secret", http.MethodDelete, "/orders/"+created.Order.ID, nil))
+	suite.Equal(http.StatusNotFound, signed("bob-key", "bob-secret", http.MethodDelete, "/orders/3f1c2b9e-6d4a-4e8b-9c7d-2a1b0c9d8e7f", nil))
+	suite.Equal(http.StatusCreated, signed("alice-key", "alice-secret", http.MethodDelete, "/orders/"+created.Order.ID, nil))
+	suite.Equal(events.MatchingEventTypeCancel, suite.matchingEvents(2)[1].Type)
+}
+
+func (suite *OrderMatchingTestSuite) TestRateLimits() {
+	router := gin.New()
+	order.RegisterRoutes(router, suite.handler, order.WithRateLimits(ratelimit.NewMemoryStore(), ratelimit.Limit{Rate: 0.001, Burst: 3}, ratelimit.Limit{Rate: 0.001, Burst: 2}))
+	suite.Equal(http.StatusCreated, suite.request(http.MethodPost, "/accounts/buyer/deposits", gin.H{"asset": "USD", "amount": 1000}).Code)
+
+	body := gin.H{"account_id": "buyer", "symbol": symbol, "type": "Buy", "price": 100.0, "quantity": 1}
+	recorder, err := serve(router, http.MethodPost, "/orders", body)
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusCreated, recorder.Code)
+	orderID := suite.matchingEvents(1)[0].Order.ID
+
+	// The batches share the bucket of the creations, and take a token per order
+	recorder, err = serve(router, http.MethodPost, "/orders/batch", gin.H{"orders": []gin.H{body, body, body}})
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusTooManyRequests, recorder.Code)
+	suite.NotEmpty(recorder.Header().Get(ratelimit.RetryAfterHeader))
+	suite.Equal("2", recorder.Header().Get(ratelimit.RemainingHeader))
+
+	recorder, err = serve(router, http.MethodPost, "/orders/batch", gin.H{"orders": []gin.H{body, body}})
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusMultiStatus, recorder.Code)
+	suite.Equal("0", recorder.Header().Get(ratelimit.RemainingHeader))
+
+	// The cancellations have a bucket of their own
+	recorder, err = serve(router, http.MethodDelete, "/orders/"+orderID, nil)
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusCreated, recorder.Code)
+	suite.Equal("1", recorder.Header().Get(ratelimit.RemainingHeader))
+
+	// A mass cancel takes a token per open order it cancels
+	recorder, err = serve(router, http.MethodDelete, "/orders?account_id=buyer&symbol="+symbol, nil)
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusTooManyRequests, recorder.Code)
+}
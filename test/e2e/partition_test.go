@@ -0,0 +1,199 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/Hao1995/order-matching-system/internal/api/account"
+	"github.com/Hao1995/order-matching-system/internal/api/order"
+	"github.com/Hao1995/order-matching-system/internal/common/models/events"
+	matchingengine "github.com/Hao1995/order-matching-system/internal/worker/matching_engine"
+	"github.com/Hao1995/order-matching-system/pkg/mqkit"
+)
+
+const (
+	partitionedOrderTopic    = "ORDER"
+	partitionedMatchingTopic = "MATCHING"
+	consumerGroup            = "matching-engine"
+)
+
+// PartitionTestSuite runs the order API and a consumer group of matching engine workers on the
+// partitioned topics of an in-memory broker
+type PartitionTestSuite struct {
+	suite.Suite
+	broker  *mqkit.MemoryBroker
+	router  *gin.Engine
+	workers []context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func TestPartitionTestSuite(t *testing.T) {
+	suite.Run(t, new(PartitionTestSuite))
+}
+
+func (suite *PartitionTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	suite.broker = mqkit.NewMemoryBroker()
+	suite.broker.CreateTopic(partitionedOrderTopic, 2)
+	suite.broker.CreateTopic(partitionedMatchingTopic, 2)
+	suite.Require().NotEqual(mqkit.PartitionFor([]byte("AAPL"), 2), mqkit.PartitionFor([]byte("MSFT"), 2))
+
+	// Order API
+	producer := mqkit.NewMemoryProducer(suite.broker, partitionedOrderTopic, nil)
+	ledger := account.NewLedger("USD")
+	suite.router = gin.New()
+	order.RegisterRoutes(suite.router, order.NewHandler(order.NewService(producer, events.JSONCodec, partitionedOrderTopic, ledger)))
+	accounts := account.NewHandler(ledger)
+	account.RegisterRoutes(suite.router, accounts)
+	account.RegisterAdminRoutes(suite.router, accounts)
+
+	// Matching engine workers
+	suite.workers = nil
+	suite.startWorker()
+	suite.startWorker()
+}
+
+func (suite *PartitionTestSuite) TearDownTest() {
+	for _, stop := range suite.workers {
+		stop()
+	}
+	suite.wg.Wait()
+}
+
+// startWorker joins a matching engine worker to the consumer group
+func (suite *PartitionTestSuite) startWorker() {
+	ctx, cancel := context.WithCancel(context.Background())
+	suite.workers = append(suite.workers, cancel)
+
+	group := mqkit.NewMemoryGroupConsumer(suite.broker, partitionedOrderTopic, consumerGroup)
+	suite.wg.Add(1)
+	go func() {
+		defer suite.wg.Done()
+		group.Run(ctx, suite.runPartition)
+	}()
+}
+
+// runPartition rebuilds the partition from the order topic and publishes after the last published
+// matching event of the partition, like the worker without a journal
+func (suite *PartitionTestSuite) runPartition(ctx context.Context, claim mqkit.Claim) {
+	publisher := mqkit.NewMemoryProducer(suite.broker, partitionedMatchingTopic, nil)
+	partition := matchingengine.NewPartition(claim.Partition, newEngine, nil)
+	replica := matchingengine.NewReplica(func(matchingEvents []events.MatchingEvent) error {
+		msgs := make([]mqkit.Message, 0, len(matchingEvents))
+		for _, matchingEvent := range matchingEvents {
+			val, err := json.Marshal(matchingEvent)
+			if err != nil {
+				return err
+			}
+			msgs = append(msgs, mqkit.Message{Key: []byte(matchingEvent.Order.Symbol), Value: val})
+		}
+		return publisher.PublishBatch(ctx, msgs)
+	}, 100)
+
+	var lastPublished events.MatchingEvent
+	if published := suite.broker.Messages(partitionedMatchingTopic, claim.Partition); len(published) > 0 {
+		suite.Require().NoError(json.Unmarshal(published[len(published)-1].Value, &lastPublished))
+	}
+	suite.Require().NoError(replica.Promote(lastPublished.Seq))
+
+	consumer := mqkit.NewMemoryPartitionConsumer(suite.broker, partitionedOrderTopic, claim.Partition, partition.LastOffset()+1)
+	defer consumer.Close()
+	for ctx.Err() == nil {
+		consumer.ConsumeBatch(ctx, mqkit.BatchOptions{MaxMessages: 10, MaxWait: time.Millisecond}, func(msgs []mqkit.Message) error {
+			var matchingEvents []events.MatchingEvent
+			for _, msg := range msgs {
+				handled, err := partition.Handle(msg.Offset, msg.Key, msg.Value)
+				if err != nil {
+					return err
+				}
+				matchingEvents = append(matchingEvents, handled...)
+			}
+			return replica.Publish(matchingEvents)
+		})
+	}
+}
+
+func (suite *PartitionTestSuite) request(method, path string, body interface{}) *httptest.ResponseRecorder {
+	recorder, err := serve(suite.router, method, path, body)
+	suite.Require().NoError(err)
+	return recorder
+}
+
+// partitionEvents waits for n matching events in the partition of the symbol
+func (suite *PartitionTestSuite) partitionEvents(symbol string, n int) []events.MatchingEvent {
+	partition := mqkit.PartitionFor([]byte(symbol), 2)
+	suite.Require().Eventually(func() bool {
+		return len(suite.broker.Messages(partitionedMatchingTopic, partition)) >= n
+	}, time.Second, 10*time.Millisecond)
+
+	var result []events.MatchingEvent
+	for _, msg := range suite.broker.Messages(partitionedMatchingTopic, partition) {
+		var matchingEvent events.MatchingEvent
+		suite.Require().NoError(json.Unmarshal(msg.Value, &matchingEvent))
+		result = append(result, matchingEvent)
+	}
+	return result
+}
+
+func (suite *PartitionTestSuite) createOrder(accountID, symbol, side string, quantity int64) {
+	suite.Equal(http.StatusCreated, suite.request(http.MethodPost, "/orders", gin.H{
+		"account_id": accountID, "symbol": symbol, "type": side, "price": 100.0, "quantity": quantity,
+	}).Code)
+}
+
+func (suite *PartitionTestSuite) TestCreateOrders_MatchPerSymbol() {
+	for _, symbol := range []string{"AAPL", "MSFT"} {
+		suite.Equal(http.StatusCreated, suite.request(http.MethodPost, "/accounts/seller/deposits", gin.H{"asset": symbol, "amount": 10}).Code)
+	}
+	suite.Equal(http.StatusCreated, suite.request(http.MethodPost, "/accounts/buyer/deposits", gin.H{"asset": "USD", "amount": 1000}).Code)
+
+	suite.createOrder("seller", "AAPL", "Sell", 10)
+	suite.createOrder("seller", "MSFT", "Sell", 10)
+	suite.createOrder("buyer", "MSFT", "Buy", 3)
+
+	aapl := suite.partitionEvents("AAPL", 1)
+	suite.Require().Len(aapl, 1)
+	suite.Equal("AAPL", aapl[0].Order.Symbol)
+	suite.Equal(uint64(1), aapl[0].Seq)
+
+	msft := suite.partitionEvents("MSFT", 2)
+	suite.Require().Len(msft, 2)
+	suite.Equal([]uint64{1, 2}, []uint64{msft[0].Seq, msft[1].Seq})
+	suite.Require().Len(msft[1].Transactions, 1)
+	suite.Equal("MSFT-1", msft[1].Transactions[0].ID)
+	suite.Equal(int64(3), msft[1].Transactions[0].Quantity)
+}
+
+func (suite *PartitionTestSuite) TestRebalance_HandsOffPartition() {
+	suite.Equal(http.StatusCreated, suite.request(http.MethodPost, "/accounts/seller/deposits", gin.H{"asset": "AAPL", "amount": 10}).Code)
+	suite.Equal(http.StatusCreated, suite.request(http.MethodPost, "/accounts/buyer/deposits", gin.H{"asset": "USD", "amount": 1000}).Code)
+	suite.createOrder("seller", "AAPL", "Sell", 10)
+	suite.partitionEvents("AAPL", 1)
+
+	// A new worker takes over the partitions of the stopped ones and rebuilds the book of AAPL
+	// without publishing its matching event again
+	for _, stop := range suite.workers {
+		stop()
+	}
+	suite.startWorker()
+	suite.createOrder("buyer", "AAPL", "Buy", 4)
+
+	aapl := suite.partitionEvents("AAPL", 2)
+	suite.Require().Len(aapl, 2)
+	suite.Equal([]uint64{1, 2}, []uint64{aapl[0].Seq, aapl[1].Seq})
+	suite.Require().Len(aapl[1].Transactions, 1)
+	suite.Equal(aapl[0].Order.ID, aapl[1].Transactions[0].SellOrderID)
+	suite.Equal([]events.TickEvent{{Price: 100.0, Quantity: 6}}, aapl[1].SellTicks)
+
+	// Nothing is published twice
+	time.Sleep(50 * time.Millisecond)
+	suite.Len(suite.broker.Messages(partitionedMatchingTopic, mqkit.PartitionFor([]byte("AAPL"), 2)), 2)
+}